@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"SYS_DESIGN_PLAYGROUND/internal/api"
+)
+
+// apigen writes the typed API client generated from the operations in internal/api.
+// It is run through go generate in pkg/client.
+func main() {
+	out := flag.String("o", "client.gen.go", "output file")
+	flag.Parse()
+
+	src, err := api.GenerateClient()
+	if err != nil {
+		log.Fatalf("apigen: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("apigen: %v", err)
+	}
+}
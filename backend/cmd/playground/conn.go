@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"SYS_DESIGN_PLAYGROUND/internal/server"
+	"SYS_DESIGN_PLAYGROUND/pkg/client"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conn holds the flags shared by the client commands, which choose how to reach the playground.
+type conn struct {
+	server string
+	token  string
+	local  bool
+}
+
+func (cn *conn) register(fs *flag.FlagSet) {
+	fs.StringVar(&cn.server, "server", envOr("PLAYGROUND_SERVER", "http://localhost:8080"), "base URL of the playground server")
+	fs.StringVar(&cn.token, "token", os.Getenv("PLAYGROUND_TOKEN"), "API token, if the server requires one")
+	fs.BoolVar(&cn.local, "local", false, "run the scenarios in this process instead of calling a server")
+}
+
+// client returns a client for the server, or with -local for a server started in this
+// process with only the given scenarios enabled (all if none are given). The caller
+// must call stop when done.
+func (cn *conn) client(ctx context.Context, scenarios ...string) (c *client.Client, stop func(), err error) {
+	if !cn.local {
+		c = client.New(cn.server)
+		c.Token = cn.token
+		return c, func() {}, nil
+	}
+
+	// The server logs to the console; keep only its warnings so they do not drown the output.
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	opts := server.OptionsFromEnv()
+	opts.Scenarios = scenarios
+	opts.TraceExporter = ""
+	cleanup, err := server.Setup(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	srv := &http.Server{Handler: server.NewRouter(nil)}
+	go func() { _ = srv.Serve(ln) }()
+	stop = func() {
+		_ = srv.Close()
+		cleanup()
+	}
+	return client.New("http://" + ln.Addr().String()), stop, nil
+}
+
+// newFlagSet returns the flag set of a command; synopsis follows "playground" in its usage.
+func newFlagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: playground %s\n\nFlags:\n", synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags that may come before, between or after the positional
+// arguments, which it returns. It exits with usage if fewer than min remain.
+func parseArgs(fs *flag.FlagSet, args []string, min int) []string {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < min {
+		fs.Usage()
+		os.Exit(2)
+	}
+	return positional
+}
+
+// params collects repeated -param name=value flags. A value that is valid JSON,
+// such as 12.5, true or {"a":1}, is decoded; anything else is a string.
+type params map[string]interface{}
+
+func (p params) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(map[string]interface{}(p))
+	return string(data)
+}
+
+func (p params) Set(s string) error {
+	name, raw, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("want name=value, got %q", s)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		v = raw
+	}
+	p[name] = v
+	return nil
+}
+
+// printJSON writes v as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// fail reports err and returns the exit code of a failed command.
+func fail(err error) int {
+	fmt.Fprintf(os.Stderr, "playground: %v\n", err)
+	return 1
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/client"
+)
+
+func logsCmd(args []string) int {
+	var cn conn
+	fs := newFlagSet("logs", "logs [flags] <scenario>")
+	cn.register(fs)
+	follow := fs.Bool("f", false, "follow: keep streaming new entries")
+	level := fs.String("level", "", "minimum level: debug, info, warn or error")
+	action := fs.String("action", "", "only show entries logged by this action")
+	tail := fs.Int("n", 20, "number of recent entries to show")
+	asJSON := fs.Bool("json", false, "print one JSON entry per line")
+	id := parseArgs(fs, args, 1)[0]
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	c, stop, err := cn.client(ctx, id)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	emit := printEntry
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		emit = func(e apitypes.LogEntry) error { return enc.Encode(e) }
+	}
+
+	if *follow {
+		// The server replays its default backlog for a tail of 0, so ask for one entry at least.
+		err := c.StreamLogs(ctx, id, &client.StreamLogsParams{Level: *level, Action: *action, Tail: max(*tail, 1)}, emit)
+		if err != nil {
+			return fail(err)
+		}
+		return 0
+	}
+
+	entries, err := c.ListLogs(ctx, id, &client.ListLogsParams{Level: *level, Action: *action, Limit: *tail})
+	if err != nil {
+		return fail(err)
+	}
+	for _, e := range entries {
+		if err := emit(e); err != nil {
+			return fail(err)
+		}
+	}
+	return 0
+}
+
+// printEntry prints a log entry as a line such as
+//
+//	12:00:01.250 INFO  [update_naive] Cache update failed key=product:101
+func printEntry(e apitypes.LogEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s ", e.Time.Local().Format("15:04:05.000"), e.Level)
+	if e.Action != "" {
+		fmt.Fprintf(&b, "[%s] ", e.Action)
+	}
+	b.WriteString(e.Message)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
+	}
+	_, err := fmt.Println(b.String())
+	return err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// playground is the single-binary distribution of the playground. Its serve command
+// starts the server together with the embedded UI; the other commands drive a running
+// server over its HTTP API, or with -local run the scenarios in process, for use in
+// terminals, scripts and recordings.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(cmd.run(os.Args[2:]))
+}
+
+// command is a subcommand; run returns the exit code.
+type command struct {
+	summary string
+	run     func(args []string) int
+}
+
+var commands = map[string]command{
+	"serve":    {summary: "run the server with the embedded UI", run: serve},
+	"list":     {summary: "list the scenarios", run: list},
+	"describe": {summary: "show the descriptions, actions and workloads of a scenario", run: describe},
+	"run":      {summary: "run an action of a scenario", run: run},
+	"state":    {summary: "print the state of a scenario, or -watch it change", run: state},
+	"logs":     {summary: "print the logs of a scenario, or follow them with -f", run: logsCmd},
+	"runbook":  {summary: "run runbook files and report whether they pass", run: runbookCmd},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: playground <command> [flags]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'playground <command> -h' for the flags of a command.\n")
+}
+
+// interruptContext returns a context that is cancelled on Ctrl-C or SIGTERM.
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParams(t *testing.T) {
+	p := params{}
+	for _, s := range []string{"price=99.5", "name=widget", "force=true", `tags=["a","b"]`, `quoted="12"`, "empty="} {
+		assert.NoError(t, p.Set(s), s)
+	}
+	assert.Equal(t, params{
+		"price":  99.5,
+		"name":   "widget",
+		"force":  true,
+		"tags":   []interface{}{"a", "b"},
+		"quoted": "12",
+		"empty":  "",
+	}, p)
+
+	assert.Error(t, p.Set("price"))
+	assert.Error(t, p.Set("=1"))
+}
+
+func TestParseArgs(t *testing.T) {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	p := params{}
+	fs.Var(p, "param", "")
+	asJSON := fs.Bool("json", false, "")
+
+	pos := parseArgs(fs, []string{"-param", "a=1", "cache_inconsistency", "--json", "update_naive", "--param=b=x"}, 2)
+	assert.Equal(t, []string{"cache_inconsistency", "update_naive"}, pos)
+	assert.True(t, *asJSON)
+	assert.Equal(t, params{"a": 1.0, "b": "x"}, p)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+)
+
+// runbookCmd runs runbook files and prints a pass/fail report for each. It exits
+// non-zero if any runbook fails, so it can be used as a regression test in scripts and CI.
+func runbookCmd(args []string) int {
+	var cn conn
+	fs := newFlagSet("runbook", "runbook [flags] <runbook.yaml>...")
+	cn.register(fs)
+	paths := parseArgs(fs, args, 1)
+
+	// Parse locally first so syntax errors are reported without a round trip.
+	books := make([]*runbook.Runbook, len(paths))
+	var scenarios []string
+	for i, path := range paths {
+		rb, err := runbook.Load(path)
+		if err != nil {
+			return fail(err)
+		}
+		books[i] = rb
+		scenarios = append(scenarios, rb.Scenario)
+	}
+
+	ctx := context.Background()
+	c, stop, err := cn.client(ctx, scenarios...)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	code := 0
+	for i, rb := range books {
+		report, err := c.RunRunbook(ctx, *rb)
+		if err != nil {
+			fmt.Printf("ERROR %s: %v\n", paths[i], err)
+			code = 1
+			continue
+		}
+		printReport(paths[i], &report)
+		if !report.Passed {
+			code = 1
+		}
+	}
+	return code
+}
+
+func printReport(path string, r *runbook.Report) {
+	status := "PASS"
+	if !r.Passed {
+		status = "FAIL"
+	}
+	fmt.Printf("%s %s (%s) [%s] %s\n", status, r.Runbook, r.Scenario, path, time.Duration(r.Duration))
+	for _, e := range r.Timeline {
+		mark := "ok"
+		if !e.Passed {
+			mark = "FAILED"
+		}
+		fmt.Printf("  %2d. %-7s %-40s %-8s %s\n", e.Step, e.Kind, e.Name, time.Duration(e.Duration).Round(time.Millisecond), mark)
+		if e.Error != "" {
+			fmt.Printf("      error: %s\n", e.Error)
+		}
+		for _, a := range e.Assertions {
+			if !a.Passed {
+				fmt.Printf("      %s: %s (actual: %v)\n", a.Path, a.Reason, a.Actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/client"
+)
+
+func list(args []string) int {
+	var cn conn
+	fs := newFlagSet("list", "list [flags]")
+	cn.register(fs)
+	tag := fs.String("tag", "", "only list scenarios with this tag")
+	asJSON := fs.Bool("json", false, "print JSON")
+	parseArgs(fs, args, 0)
+
+	ctx := context.Background()
+	c, stop, err := cn.client(ctx)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	scenarios, err := c.ListScenarios(ctx, &client.ListScenariosParams{Tag: *tag})
+	if err != nil {
+		return fail(err)
+	}
+	if *asJSON {
+		return exitCode(printJSON(scenarios))
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCATEGORY\tTITLE\tTAGS")
+	for _, s := range scenarios {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.ID, s.Category, s.Title, strings.Join(s.Tags, ","))
+	}
+	return exitCode(w.Flush())
+}
+
+func describe(args []string) int {
+	var cn conn
+	fs := newFlagSet("describe", "describe [flags] <scenario>")
+	cn.register(fs)
+	asJSON := fs.Bool("json", false, "print JSON")
+	id := parseArgs(fs, args, 1)[0]
+
+	ctx := context.Background()
+	c, stop, err := cn.client(ctx, id)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	s, err := c.GetScenario(ctx, id)
+	if err != nil {
+		return fail(err)
+	}
+	if *asJSON {
+		return exitCode(printJSON(s))
+	}
+
+	fmt.Printf("%s (%s)\n", s.Title, s.ID)
+	fmt.Printf("Category: %s\n", s.Category)
+	if s.DeepDiveLink != "" {
+		fmt.Printf("Deep dive: %s\n", s.DeepDiveLink)
+	}
+	fmt.Printf("\nProblem:\n%s\n\nSolution:\n%s\n", indent(s.ProblemDescription), indent(s.SolutionDescription))
+
+	fmt.Printf("\nActions:\n")
+	for _, a := range s.Actions {
+		var notes []string
+		if a.Kind != "" {
+			notes = append(notes, a.Kind)
+		}
+		if a.Role != "" {
+			notes = append(notes, "role "+a.Role)
+		}
+		line := "  " + a.ID
+		if a.Name != "" && a.Name != a.ID {
+			line += " - " + a.Name
+		}
+		if len(notes) > 0 {
+			line += " [" + strings.Join(notes, ", ") + "]"
+		}
+		fmt.Println(line)
+		if a.Description != "" {
+			fmt.Printf("      %s\n", a.Description)
+		}
+		printParams(a.Params)
+	}
+
+	if len(s.Workloads) > 0 {
+		fmt.Printf("\nWorkloads:\n")
+		for _, wl := range s.Workloads {
+			fmt.Printf("  %s - %s\n", wl.ID, wl.Name)
+			printParams(wl.Params)
+		}
+	}
+	return 0
+}
+
+func printParams(params []apitypes.ActionParam) {
+	for _, p := range params {
+		line := fmt.Sprintf("      -param %s=<%s>", p.Name, p.Type)
+		if p.Required {
+			line += " (required)"
+		}
+		if p.Default != nil {
+			line += fmt.Sprintf(" (default %v)", p.Default)
+		}
+		if p.Description != "" {
+			line += "  " + p.Description
+		}
+		fmt.Println(line)
+	}
+}
+
+func run(args []string) int {
+	var cn conn
+	fs := newFlagSet("run", "run [flags] <scenario> <action>")
+	cn.register(fs)
+	ps := params{}
+	fs.Var(ps, "param", "action parameter as name=value; repeatable")
+	asJSON := fs.Bool("json", false, "print the JSON response")
+	pos := parseArgs(fs, args, 2)
+	id, action := pos[0], pos[1]
+
+	ctx := context.Background()
+	c, stop, err := cn.client(ctx, id)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	resp, err := c.ExecuteAction(ctx, id, action, apitypes.ExecuteActionRequest{Params: ps}, nil)
+	if err != nil {
+		return fail(err)
+	}
+	if *asJSON {
+		return exitCode(printJSON(resp))
+	}
+
+	fmt.Printf("%s: %s\n", resp.Status, resp.Message)
+	if resp.Error != nil {
+		fmt.Printf("error: %s: %s\n", resp.Error.Code, resp.Error.Message)
+	}
+	if resp.JobID != "" {
+		fmt.Printf("job: %s (%s)\n", resp.JobID, resp.StatusURL)
+	}
+	if resp.Result != nil {
+		return exitCode(printJSON(resp.Result))
+	}
+	return 0
+}
+
+func state(args []string) int {
+	var cn conn
+	fs := newFlagSet("state", "state [flags] <scenario>")
+	cn.register(fs)
+	watch := fs.Bool("watch", false, "keep polling and print the state whenever it changes")
+	interval := fs.Duration("interval", time.Second, "polling interval of -watch")
+	id := parseArgs(fs, args, 1)[0]
+
+	ctx, cancel := interruptContext()
+	defer cancel()
+	c, stop, err := cn.client(ctx, id)
+	if err != nil {
+		return fail(err)
+	}
+	defer stop()
+
+	var last apitypes.State
+	for {
+		st, err := c.GetState(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return 0
+			}
+			return fail(err)
+		}
+		if !*watch {
+			return exitCode(printJSON(st))
+		}
+		if last == nil || !reflect.DeepEqual(st, last) {
+			fmt.Printf("--- %s\n", time.Now().Format("15:04:05.000"))
+			if err := printJSON(st); err != nil {
+				return fail(err)
+			}
+			last = st
+		}
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(*interval):
+		}
+	}
+}
+
+// indent indents every line of text for printing under a heading.
+func indent(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "  -"
+	}
+	return "  " + strings.ReplaceAll(text, "\n", "\n  ")
+}
+
+// exitCode returns the exit code for the error of printing a command's output.
+func exitCode(err error) int {
+	if err != nil {
+		return fail(err)
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/server"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/all" // Import for side-effect of registration
+	"SYS_DESIGN_PLAYGROUND/web"
+)
+
+// serve runs the server and the UI. The flags default to the PLAYGROUND_* environment
+// variables read by cmd/server.
+func serve(args []string) int {
+	opts := server.OptionsFromEnv()
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&opts.Addr, "addr", opts.Addr, "listen address")
+	config := fs.String("config", "", "configuration file (default $PLAYGROUND_CONFIG or "+opts.ConfigPath+" if present)")
+	fs.StringVar(&opts.ScenarioDir, "scenario-dir", opts.ScenarioDir, "read scenario definitions from this directory and reload them on change")
+	fs.StringVar(&opts.HistoryDSN, "history-dsn", opts.HistoryDSN, "MySQL DSN to persist the action history in")
+	logLevel := fs.String("log-level", envOr("PLAYGROUND_LOG_LEVEL", "info"), "console log level")
+	noUI := fs.Bool("no-ui", false, "serve the API only")
+	_ = fs.Parse(args)
+	if *config != "" {
+		opts.ConfigPath, opts.ConfigRequired = *config, true
+	}
+
+	level, err := logs.ParseLevel(*logLevel)
+	if err != nil {
+		log.Printf("Failed to configure logging: %v", err)
+		return 2
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if !*noUI {
+		opts.Frontend = web.FS()
+		if opts.Frontend == nil {
+			slog.Warn("This binary was built without the UI; serving the API only. Run 'npm run build:embed' in frontend and rebuild.")
+		}
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if err := server.Run(ctx, opts); err != nil {
+		log.Printf("Server failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// envOr returns the value of the environment variable key, or def if it is unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// scenariogen writes the blank imports of scenarios/all: every package directly under
+// the scenarios directory that registers itself with the registry. It is run through
+// go generate in scenarios/all.
+func main() {
+	root := flag.String("root", "..", "scenarios directory")
+	out := flag.String("o", "all.gen.go", "output file")
+	flag.Parse()
+
+	src, err := generate(*root)
+	if err != nil {
+		log.Fatalf("scenariogen: %v", err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatalf("scenariogen: %v", err)
+	}
+}
+
+// generate renders the import file for the scenario packages under root.
+func generate(root string) ([]byte, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	modDir, modPath, err := findModule(root)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var imports []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, e.Name())
+		ok, err := registersScenario(dir)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		rel, err := filepath.Rel(modDir, dir)
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, modPath+"/"+filepath.ToSlash(rel))
+	}
+	sort.Strings(imports)
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by cmd/scenariogen; DO NOT EDIT.\n\n")
+	buf.WriteString("package all\n\n")
+	buf.WriteString("import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&buf, "\t_ %q\n", imp)
+	}
+	buf.WriteString(")\n")
+	return format.Source(buf.Bytes())
+}
+
+// registersScenario reports whether a non-test Go file in dir calls registry.Register
+// or registry.RegisterHandlers. Directories whose files do not parse, such as work in
+// progress, are skipped.
+func registersScenario(dir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), name, nil, 0)
+		if err != nil {
+			log.Printf("scenariogen: skipping %s: %v", filepath.Base(dir), err)
+			return false, nil
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); ok && pkg.Name == "registry" &&
+				(sel.Sel.Name == "Register" || sel.Sel.Name == "RegisterHandlers") {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found, nil
+}
+
+// findModule returns the directory and path of the module containing dir.
+func findModule(dir string) (string, string, error) {
+	for d := dir; ; d = filepath.Dir(d) {
+		f, err := os.Open(filepath.Join(d, "go.mod"))
+		if err == nil {
+			defer f.Close()
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				if path, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+					return d, strings.Trim(strings.TrimSpace(path), `"`), nil
+				}
+			}
+			return "", "", fmt.Errorf("no module line in %s", f.Name())
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return "", "", fmt.Errorf("no go.mod above %s", dir)
+		}
+	}
+}
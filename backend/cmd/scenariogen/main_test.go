@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGeneratedImportsUpToDate fails when a scenario package was added or removed
+// without running go generate in scenarios/all.
+func TestGeneratedImportsUpToDate(t *testing.T) {
+	want, err := generate("../../scenarios")
+	assert.NoError(t, err)
+	got, err := os.ReadFile("../../scenarios/all/all.gen.go")
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), string(got), "run go generate ./scenarios/all")
+	assert.Contains(t, string(want), `_ "SYS_DESIGN_PLAYGROUND/scenarios/xdc_cache_sync"`)
+}
@@ -1,41 +1,42 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
-
-	"SYS_DESIGN_PLAYGROUND/internal/api"
-	"SYS_DESIGN_PLAYGROUND/internal/registry"
-	_ "SYS_DESIGN_PLAYGROUND/scenarios/cache_inconsistency" // Import for side-effect of registration
-
-	"github.com/gin-gonic/gin"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/server"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/all" // Import for side-effect of registration
 )
 
+// server serves the playground API only; the UI is served by the frontend container.
+// The playground command serves both from one binary.
 func main() {
 	fmt.Println("Starting Backend Problem Playground server...")
 
-	// Initialize all registered scenarios
-	if err := registry.InitializeAll(); err != nil {
-		log.Fatalf("Failed to initialize scenarios: %v", err)
+	// Console log level; scenario logs are buffered for the API at every level regardless.
+	level, err := logs.ParseLevel(envOr("PLAYGROUND_LOG_LEVEL", "info"))
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
 	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
 
-	// Initialize Gin router
-	router := gin.Default()
-
-	// Setup API routes
-	api.SetupRouter(router)
-
-	// Simple health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "UP",
-		})
-	})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Run(ctx, server.OptionsFromEnv()); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
 
-	// Start the server
-	port := "8080"
-	log.Printf("Server listening on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to run server: %v", err)
+// envOr returns the value of the environment variable key, or def if it is unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return def
 }
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// principalKey is the gin context key holding the auth.Principal of the current request.
+const principalKey = "principal"
+
+// AuthMiddleware authenticates every request; see internal/auth. Requests with
+// invalid credentials are rejected before they reach a handler.
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, err := auth.Authenticate(c.Request)
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// RequireRole rejects requests whose caller does not have at least the given role.
+func RequireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := authorize(c, role); err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// authorize checks that the caller has at least the given role. Anonymous callers are
+// told to authenticate, others that their role is insufficient.
+func authorize(c *gin.Context, role auth.Role) error {
+	p := principal(c)
+	if p.Role.Allows(role) {
+		return nil
+	}
+	if p.Anonymous() {
+		return scenario.UnauthorizedError("authentication required").WithDetail("required_role", role)
+	}
+	return scenario.ForbiddenError("role %s may not do this, %s is required", p.Role, role).WithDetail("required_role", role)
+}
+
+// authorizeActions checks that the caller may run every one of the actions.
+func authorizeActions(c *gin.Context, actions ...scenario.Action) error {
+	for _, a := range actions {
+		if err := authorize(c, auth.ActionRole(a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// principal returns the caller of the request. Without AuthMiddleware, e.g. in
+// handlers mounted elsewhere, the caller is treated as unauthenticated.
+func principal(c *gin.Context) auth.Principal {
+	if p, ok := c.Get(principalKey); ok {
+		return p.(auth.Principal)
+	}
+	return auth.Principal{Name: "anonymous", Role: auth.RoleViewer, Method: "anonymous"}
+}
+
+// WhoAmIHandler handles the GET /api/auth/whoami endpoint.
+// It tells the UI who the caller is and whether authentication is enabled.
+func WhoAmIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, apitypes.WhoAmI{Principal: principal(c), AuthEnabled: auth.Enabled()})
+}
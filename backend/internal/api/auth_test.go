@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// withTokens enables authentication with a token per role until the test ends.
+func withTokens(t *testing.T) {
+	err := auth.Configure(config.Auth{Tokens: []config.Token{
+		{Name: "alice", Token: "viewer-token", Role: "viewer"},
+		{Name: "bob", Token: "operator-token", Role: "operator"},
+		{Name: "carol", Token: "admin-token", Role: "admin"},
+	}})
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = auth.Configure(config.Auth{}) })
+}
+
+// doAs is do with a bearer token and extra headers.
+func doAs(r *gin.Engine, token, method, path, body string, headers ...string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var out map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w, out
+}
+
+func TestAuthDisabled(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodGet, "/api/auth/whoami", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, false, body["auth_enabled"])
+	assert.Equal(t, "admin", body["role"])
+}
+
+func TestActionRoles(t *testing.T) {
+	withTokens(t)
+	r := newTestRouter()
+	const path = "/api/scenarios/api_stub/actions/ok"
+	const params = `{"params":{"price":1}}`
+
+	w, body := doAs(r, "", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "unauthorized", errorCode(body))
+
+	w, body = doAs(r, "wrong-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "unauthorized", errorCode(body))
+
+	w, body = doAs(r, "viewer-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "forbidden", errorCode(body))
+
+	w, _ = doAs(r, "operator-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Anonymous callers are viewers and may still read.
+	w, _ = doAs(r, "", http.MethodGet, "/api/scenarios/api_stub", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, body = doAs(r, "viewer-token", http.MethodGet, "/api/auth/whoami", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, true, body["auth_enabled"])
+	assert.Equal(t, "alice", body["name"])
+	assert.Equal(t, "viewer", body["role"])
+	assert.Equal(t, "token", body["method"])
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	withTokens(t)
+	ConfigureRateLimit(config.RateLimit{PerIP: 1, Burst: 1})
+	defer ConfigureRateLimit(config.RateLimit{})
+	r := newTestRouter()
+	const path = "/api/scenarios/api_stub/actions/ok"
+	const params = `{"params":{"price":1}}`
+
+	w, _ := doAs(r, "operator-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, body := doAs(r, "operator-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "rate_limited", errorCode(body))
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+	// Admins are not limited.
+	w, _ = doAs(r, "admin-token", http.MethodPost, path, params)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimitWithoutAuth(t *testing.T) {
+	// Every caller is an admin while authentication is off, and is limited all the same.
+	ConfigureRateLimit(config.RateLimit{PerIP: 1, Burst: 1})
+	defer ConfigureRateLimit(config.RateLimit{})
+	r := newTestRouter()
+	const path = "/api/scenarios/api_stub/actions/ok"
+	const params = `{"params":{"price":1}}`
+
+	w, _ := do(r, http.MethodPost, path, params)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w, body := do(r, http.MethodPost, path, params)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "rate_limited", errorCode(body))
+}
+
+func TestLimiter(t *testing.T) {
+	l, err := newLimiter(config.RateLimit{}, 60, 2, nil, "")
+	assert.NoError(t, err)
+	ctx, now := context.Background(), time.Now()
+
+	for i := 0; i < 2; i++ {
+		res, _ := l.Allow(ctx, "a", now)
+		assert.True(t, res.Allowed)
+	}
+	res, _ := l.Allow(ctx, "a", now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	// Other clients have buckets of their own.
+	res, _ = l.Allow(ctx, "b", now)
+	assert.True(t, res.Allowed)
+
+	res, _ = l.Allow(ctx, "a", now.Add(time.Second))
+	assert.True(t, res.Allowed)
+
+	// A rate below one per minute is one request per longer window.
+	l, err = newLimiter(config.RateLimit{Algorithm: "fixed_window"}, 0.5, 1, nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, l.Config().Window)
+	_, err = newLimiter(config.RateLimit{Algorithm: "gcra"}, 1, 1, nil, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/internal/compare"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// CompareHandler handles the POST /api/scenarios/:id/compare endpoint.
+// It runs the problem and the solution action on two forks of the scenario and
+// returns both state timelines with a diff of the final states.
+func CompareHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	s, ok := registry.GetScenario(scenarioID)
+	if !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	// The request body is optional; both actions default to the ones marked by their kind.
+	var req apitypes.CompareRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, scenario.InvalidParamsError("invalid request body: %v", err))
+			return
+		}
+	}
+	problem, solution, err := compare.Actions(s, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := authorizeActions(c, problem, solution); err != nil {
+		respondError(c, err)
+		return
+	}
+	// Both actions get the same params, so they must be valid for each.
+	for _, action := range []scenario.Action{problem, solution} {
+		if err := validateActionParams(action, req.Params); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	cmp, err := compare.Run(c.Request.Context(), s, req, callerFromRequest(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cmp)
+}
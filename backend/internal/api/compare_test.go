@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// compareStub is a forkable scenario whose state is a price. Its forks share the
+// prices map, keyed by fork name.
+type compareStub struct {
+	stubScenario
+	mu     *sync.Mutex
+	prices map[string]float64
+	name   string
+}
+
+func init() {
+	registry.Register(&compareStub{mu: &sync.Mutex{}, prices: map[string]float64{"main": 1}, name: "main"})
+}
+
+func (s *compareStub) ID() string { return "api_compare" }
+
+func (s *compareStub) Actions() []scenario.Action {
+	price := []scenario.ActionParam{{Name: "price", Type: "number", Required: true}}
+	return []scenario.Action{
+		{ID: "naive", Kind: scenario.KindProblem, Params: price},
+		{ID: "fix", Kind: scenario.KindSolution, Params: price},
+	}
+}
+
+func (s *compareStub) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if actionID == "naive" {
+		return nil, scenario.DemonstratedError("price not updated")
+	}
+	s.prices[s.name] = params["price"].(float64)
+	return "updated", nil
+}
+
+func (s *compareStub) FetchState() (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return map[string]interface{}{"price": s.prices[s.name]}, nil
+}
+
+func (s *compareStub) Fork(ctx context.Context, name string) (scenario.Scenario, func(ctx context.Context) error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices[name] = s.prices[s.name]
+	fork := &compareStub{mu: s.mu, prices: s.prices, name: name}
+	return fork, func(ctx context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.prices, name)
+		return nil
+	}, nil
+}
+
+func TestCompare(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_compare/compare", `{"params":{"price":2}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	problem, _ := body["problem"].(map[string]interface{})
+	solution, _ := body["solution"].(map[string]interface{})
+	assert.Equal(t, "naive", problem["action"])
+	assert.Equal(t, "demonstrated", problem["error_code"])
+	assert.Equal(t, "fix", solution["action"])
+	assert.Len(t, solution["timeline"], 2)
+	assert.Equal(t, []interface{}{map[string]interface{}{"path": "price", "problem": 1.0, "solution": 2.0}}, body["diff"])
+
+	// The params must be valid for both actions.
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_compare/compare", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	// The plain stub has no problem/solution pair and cannot be forked.
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/compare", `{"problem_action":"naive","solution_action":"ok"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	w, _ = do(r, http.MethodPost, "/api/scenarios/unknown/compare", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
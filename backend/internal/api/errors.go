@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// traceIDHeader carries the trace ID in requests and responses.
+	traceIDHeader = "X-Request-ID"
+	// traceIDKey is the gin context key holding the trace ID of the current request.
+	traceIDKey = "trace_id"
+)
+
+// TraceIDMiddleware assigns every request a trace ID, reusing the caller's X-Request-ID if present,
+// and echoes it in the response so that errors can be correlated with logs.
+func TraceIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(traceIDHeader)
+		if id == "" {
+			// Prefer the OpenTelemetry trace ID so that the error can be looked up in the traces.
+			if sc := trace.SpanContextFromContext(c.Request.Context()); sc.HasTraceID() {
+				id = sc.TraceID().String()
+			} else {
+				id = uuid.NewString()
+			}
+		}
+		c.Set(traceIDKey, id)
+		c.Header(traceIDHeader, id)
+		c.Next()
+	}
+}
+
+// httpStatus maps an error code to the HTTP status returned to the client.
+func httpStatus(code scenario.ErrorCode) int {
+	switch code {
+	case scenario.CodeNotFound:
+		return http.StatusNotFound
+	case scenario.CodeInvalidParams:
+		return http.StatusBadRequest
+	case scenario.CodeConflict:
+		return http.StatusConflict
+	case scenario.CodeUnauthorized:
+		return http.StatusUnauthorized
+	case scenario.CodeForbidden:
+		return http.StatusForbidden
+	case scenario.CodeUnprocessable:
+		return http.StatusUnprocessableEntity
+	case scenario.CodeRateLimited:
+		return http.StatusTooManyRequests
+	case scenario.CodeDependencyUnavailable:
+		return http.StatusServiceUnavailable
+	case scenario.CodeDemonstrated:
+		return http.StatusOK
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// newErrorBody converts err into the API error shape.
+func newErrorBody(c *gin.Context, err error) apitypes.ErrorBody {
+	body := apitypes.ErrorBody{
+		Code:    scenario.CodeOf(err),
+		Message: err.Error(),
+		TraceID: c.GetString(traceIDKey),
+	}
+	var e *scenario.Error
+	if errors.As(err, &e) {
+		body.Details = e.Details
+	}
+	return body
+}
+
+// respondError writes err as an error envelope with the matching HTTP status.
+func respondError(c *gin.Context, err error) {
+	body := newErrorBody(c, err)
+	c.JSON(httpStatus(body.Code), apitypes.ErrorResponse{Error: body})
+}
+
+// errScenarioNotFound is returned by every endpoint addressing an unknown scenario.
+func errScenarioNotFound(id string) error {
+	return scenario.NotFoundError("scenario not found: %s", id).WithDetail("scenario_id", id)
+}
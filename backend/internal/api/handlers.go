@@ -1,56 +1,78 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
+	"SYS_DESIGN_PLAYGROUND/internal/executor"
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
 	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ListScenariosHandler handles the GET /api/scenarios endpoint.
-// It returns a list of metadata for all registered scenarios.
+// It returns a list of metadata for all enabled scenarios in navigator order,
+// optionally only those with the tag given by ?tag=.
 func ListScenariosHandler(c *gin.Context) {
-	allScenarios := registry.ListScenarios()
+	tag := c.Query("tag")
 	// We only want to return the metadata, not the full scenario object.
-	type scenarioMetadata struct {
-		ID       string `json:"id"`
-		Title    string `json:"title"`
-		Category string `json:"category"`
-	}
-	metadata := make([]scenarioMetadata, len(allScenarios))
-	for i, s := range allScenarios {
-		metadata[i] = scenarioMetadata{
-			ID:       s.ID(),
-			Title:    s.Name(),
-			Category: s.Category(),
+	metadata := []apitypes.ScenarioSummary{}
+	for _, s := range registry.ListScenarios() {
+		summary := scenarioSummary(s)
+		if tag == "" || hasTag(summary.Tags, tag) {
+			metadata = append(metadata, summary)
 		}
 	}
 	c.JSON(http.StatusOK, metadata)
 }
 
+// ListCategoriesHandler handles the GET /api/categories endpoint.
+// It returns the enabled scenarios grouped by category, as shown in the navigator.
+func ListCategoriesHandler(c *gin.Context) {
+	categories := []apitypes.Category{}
+	for _, cat := range registry.Categories() {
+		summaries := make([]apitypes.ScenarioSummary, len(cat.Scenarios))
+		for i, s := range cat.Scenarios {
+			summaries[i] = scenarioSummary(s)
+		}
+		categories = append(categories, apitypes.Category{Name: cat.Name, Scenarios: summaries})
+	}
+	c.JSON(http.StatusOK, categories)
+}
+
+func scenarioSummary(s scenario.Scenario) apitypes.ScenarioSummary {
+	return apitypes.ScenarioSummary{
+		ID:       s.ID(),
+		Title:    s.Name(),
+		Category: s.Category(),
+		Tags:     registry.Tags(s),
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // GetScenarioHandler handles the GET /api/scenarios/:id endpoint.
 // It returns the full configuration for a single scenario.
 func GetScenarioHandler(c *gin.Context) {
 	scenarioID := c.Param("id")
 	s, ok := registry.GetScenario(scenarioID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Scenario not found"})
+		respondError(c, errScenarioNotFound(scenarioID))
 		return
 	}
 
 	// Return the full scenario configuration.
-	type scenarioConfig struct {
-		ID                  string                        `json:"id"`
-		Title               string                        `json:"title"`
-		Category            string                        `json:"category"`
-		ProblemDescription  string                        `json:"problem_description"`
-		SolutionDescription string                        `json:"solution_description"`
-		DeepDiveLink        string                        `json:"deep_dive_link"`
-		Actions             interface{}                   `json:"actions"`
-		DashboardComponents interface{}                   `json:"dashboard_components"`
-	}
-
-	config := scenarioConfig{
+	config := apitypes.ScenarioDetail{
 		ID:                  s.ID(),
 		Title:               s.Name(),
 		Category:            s.Category(),
@@ -59,34 +81,103 @@ func GetScenarioHandler(c *gin.Context) {
 		DeepDiveLink:        s.DeepDiveLink(),
 		Actions:             s.Actions(),
 		DashboardComponents: s.DashboardComponents(),
+		Workloads:           []apitypes.Workload{},
+	}
+	if p, ok := s.(scenario.WorkloadProvider); ok {
+		config.Workloads = append(config.Workloads, p.Workloads()...)
 	}
 
 	c.JSON(http.StatusOK, config)
 }
 
 // ExecuteActionHandler handles the POST /api/scenarios/:id/actions/:action_id endpoint.
-// It executes a specific action for a given scenario.
+// It executes a specific action for a given scenario, either synchronously or, with
+// ?async=true, as a background job.
 func ExecuteActionHandler(c *gin.Context) {
 	scenarioID := c.Param("id")
 	actionID := c.Param("action_id")
 
 	s, ok := registry.GetScenario(scenarioID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Scenario not found"})
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	action, ok := findAction(s, actionID)
+	if !ok {
+		respondError(c, scenario.UnknownActionError(actionID))
+		return
+	}
+	if err := authorizeActions(c, action); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// For now, we don't handle request body params.
-	result, err := s.ExecuteAction(actionID, nil)
+	// The request body is optional: {"params": {...}}
+	var req apitypes.ExecuteActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, scenario.InvalidParamsError("invalid request body: %v", err))
+			return
+		}
+	}
+	if err := validateActionParams(action, req.Params); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	caller := callerFromRequest(c)
+
+	// With ?async=true the action runs as a background job; the client polls GET /api/jobs/:id.
+	if c.Query("async") == "true" {
+		// The job outlives the request; keep it in the request's trace.
+		spanCtx := trace.SpanContextFromContext(c.Request.Context())
+		job := jobs.Submit("action", scenarioID, actionID, func(ctx context.Context) (interface{}, error) {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, spanCtx)
+			rec, err := executor.Execute(ctx, s, actionID, req.Params, caller)
+			return gin.H{"execution_id": rec.ID, "result": rec.Result}, err
+		})
+		c.JSON(http.StatusAccepted, apitypes.ActionResponse{
+			Status:    apitypes.ActionStatusAccepted,
+			Message:   "Action submitted.",
+			JobID:     job.ID,
+			StatusURL: "/api/jobs/" + job.ID,
+		})
+		return
+	}
+
+	rec, err := executor.Execute(c.Request.Context(), s, actionID, req.Params, caller)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		body := newErrorBody(c, err)
+		// body.Details is the map of the error itself, which the action may return
+		// again to other requests; the execution ID goes into a copy.
+		details := make(map[string]interface{}, len(body.Details)+1)
+		for k, v := range body.Details {
+			details[k] = v
+		}
+		details["execution_id"] = rec.ID
+		body.Details = details
+
+		// An expected failure is the demo working as intended, not a server error.
+		if body.Code == scenario.CodeDemonstrated {
+			c.JSON(http.StatusOK, apitypes.ActionResponse{
+				Status:      apitypes.ActionStatusDemonstrated,
+				Message:     body.Message,
+				Result:      rec.Result,
+				ExecutionID: rec.ID,
+				Error:       &body,
+			})
+			return
+		}
+		c.JSON(httpStatus(body.Code), apitypes.ErrorResponse{Error: body})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "success",
-		"message": "Action executed successfully.",
-		"result":  result,
+	c.JSON(http.StatusOK, apitypes.ActionResponse{
+		Status:      apitypes.ActionStatusSuccess,
+		Message:     "Action executed successfully.",
+		Result:      rec.Result,
+		ExecutionID: rec.ID,
 	})
 }
 
@@ -96,15 +187,40 @@ func GetStateHandler(c *gin.Context) {
 	scenarioID := c.Param("id")
 	s, ok := registry.GetScenario(scenarioID)
 	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Scenario not found"})
+		respondError(c, errScenarioNotFound(scenarioID))
 		return
 	}
 
 	state, err := s.FetchState()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, state)
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, apitypes.State(state))
+}
+
+// callerFromRequest identifies the client that triggered an action: the authenticated
+// caller's name, or the client IP. The optional X-Session-ID header lets a browser tab
+// group its actions together.
+func callerFromRequest(c *gin.Context) executor.Caller {
+	id := c.ClientIP()
+	if p := principal(c); !p.Anonymous() && p.Name != "" {
+		id = p.Name
+	}
+	return executor.Caller{
+		ID:      id,
+		Session: c.GetHeader("X-Session-ID"),
+		TraceID: c.GetString(traceIDKey),
+	}
+}
+
+// findAction looks up an action declared by s.
+func findAction(s scenario.Scenario, actionID string) (scenario.Action, bool) {
+	for _, a := range s.Actions() {
+		if a.ID == actionID {
+			return a, true
+		}
+	}
+	return scenario.Action{}, false
+}
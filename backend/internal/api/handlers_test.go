@@ -0,0 +1,197 @@
+package api
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/idempotency"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubScenario is an in-memory scenario whose actions return each kind of error.
+type stubScenario struct{}
+
+// errSoldOut is returned by the sold_out action of every request.
+var errSoldOut = scenario.UnprocessableError("sold out").WithDetail("sku", "A1")
+
+func (s *stubScenario) ID() string                  { return "api_stub" }
+func (s *stubScenario) Name() string                { return "API Stub" }
+func (s *stubScenario) Category() string            { return "Test" }
+func (s *stubScenario) ProblemDescription() string  { return "" }
+func (s *stubScenario) SolutionDescription() string { return "" }
+func (s *stubScenario) DeepDiveLink() string        { return "" }
+func (s *stubScenario) Actions() []scenario.Action {
+	return []scenario.Action{
+		{ID: "ok"}, {ID: "naive"}, {ID: "broken"}, {ID: "down"}, {ID: "sold_out"},
+		{ID: "priced", Params: []scenario.ActionParam{{Name: "price", Type: "number", Required: true}}},
+	}
+}
+func (s *stubScenario) DashboardComponents() []scenario.DashboardComponent { return nil }
+func (s *stubScenario) Initialize() error                                  { return nil }
+func (s *stubScenario) FetchState() (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (s *stubScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	switch actionID {
+	case "ok", "priced":
+		scenario.Logger(ctx).Info("Stub called", "params", len(params))
+		return params, nil
+	case "naive":
+		return "stale", scenario.DemonstratedError("cache update failed")
+	case "broken":
+		return nil, errors.New("boom")
+	case "down":
+		return nil, scenario.DependencyError(errors.New("connection refused"), "failed to reach redis")
+	case "sold_out":
+		return nil, errSoldOut
+	default:
+		return nil, scenario.UnknownActionError(actionID)
+	}
+}
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	registry.Register(&stubScenario{})
+}
+
+func newTestRouter() *gin.Engine {
+	r := gin.New()
+	SetupRouter(r)
+	return r
+}
+
+func do(r *gin.Engine, method, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var out map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w, out
+}
+
+func errorCode(body map[string]interface{}) string {
+	e, _ := body["error"].(map[string]interface{})
+	code, _ := e["code"].(string)
+	return code
+}
+
+func TestExecuteActionStatusMapping(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_stub/actions/ok", `{"params":{"price":1}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "success", body["status"])
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/naive", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "demonstrated", body["status"])
+	assert.Equal(t, "demonstrated", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/missing", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/down", "")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "dependency_unavailable", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/broken", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "internal", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/ok", `{"params":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+}
+
+func TestExecuteActionSharedError(t *testing.T) {
+	r := newTestRouter()
+
+	// The execution ID is added to the response, not to the error the action returned.
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_stub/actions/sold_out", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	details, _ := body["error"].(map[string]interface{})["details"].(map[string]interface{})
+	assert.Equal(t, "A1", details["sku"])
+	assert.NotEmpty(t, details["execution_id"])
+	assert.Equal(t, map[string]interface{}{"sku": "A1"}, errSoldOut.Details)
+}
+
+func TestExecuteActionIdempotencyKey(t *testing.T) {
+	r := newTestRouter()
+	const path = "/api/scenarios/api_stub/actions/ok"
+
+	w, first := doAs(r, "", http.MethodPost, path, `{"params":{"price":1}}`, "Idempotency-Key", "order-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+
+	// The retry gets the response of the first request, execution ID included.
+	w, retry := doAs(r, "", http.MethodPost, path, `{"params":{"price":1}}`, "Idempotency-Key", "order-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first["execution_id"], retry["execution_id"])
+
+	w, body := doAs(r, "", http.MethodPost, path, `{"params":{"price":2}}`, "Idempotency-Key", "order-1")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "unprocessable", errorCode(body))
+
+	w, other := doAs(r, "", http.MethodPost, path, `{"params":{"price":1}}`, "Idempotency-Key", "order-2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, first["execution_id"], other["execution_id"])
+
+	// The key must fit in the store with the caller ID before it.
+	w, body = doAs(r, "", http.MethodPost, path, `{"params":{"price":1}}`, "Idempotency-Key", strings.Repeat("k", idempotency.MaxKeyLen))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+}
+
+func TestErrorEnvelopeCarriesTraceID(t *testing.T) {
+	r := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/scenarios/nope", nil)
+	req.Header.Set("X-Request-ID", "trace-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body apitypes.ErrorResponse
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, scenario.CodeNotFound, body.Error.Code)
+	assert.Equal(t, "trace-123", body.Error.TraceID)
+	assert.Equal(t, "nope", body.Error.Details["scenario_id"])
+	assert.Equal(t, "trace-123", w.Header().Get("X-Request-ID"))
+}
+
+func TestListCategories(t *testing.T) {
+	r := newTestRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/categories", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var categories []apitypes.Category
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &categories))
+	if assert.Len(t, categories, 1) {
+		assert.Equal(t, "Test", categories[0].Name)
+		var ids []string
+		for _, s := range categories[0].Scenarios {
+			ids = append(ids, s.ID)
+		}
+		assert.Equal(t, []string{"api_compare", "api_snapshot", "api_stub"}, ids)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/scenarios?tag=nothing", nil))
+	assert.Equal(t, "[]", w.Body.String())
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"SYS_DESIGN_PLAYGROUND/internal/history"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// ListHistoryHandler handles the GET /api/scenarios/:id/history endpoint.
+// It returns a page of recorded action invocations, newest first.
+// Query parameters: action (optional filter), page (1-based) and page_size.
+func ListHistoryHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	if _, ok := registry.GetScenario(scenarioID); !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	q := history.Query{
+		ScenarioID: scenarioID,
+		ActionID:   c.Query("action"),
+		Page:       page,
+		PageSize:   pageSize,
+	}
+
+	records, total, err := history.List(c.Request.Context(), q)
+	if err != nil {
+		respondError(c, scenario.DependencyError(err, "failed to query history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, apitypes.HistoryPage{
+		Items:    records,
+		Page:     page,
+		PageSize: pageSize,
+		Total:    total,
+	})
+}
+
+// ExportHistoryHandler handles the GET /api/scenarios/:id/history/export endpoint.
+// It returns the full history of a scenario as a chronological timeline, as a file download.
+func ExportHistoryHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	if _, ok := registry.GetScenario(scenarioID); !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	records, err := history.Export(c.Request.Context(), history.Query{
+		ScenarioID: scenarioID,
+		ActionID:   c.Query("action"),
+	})
+	if err != nil {
+		respondError(c, scenario.DependencyError(err, "failed to query history"))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-timeline.json"`, scenarioID))
+	c.JSON(http.StatusOK, records)
+}
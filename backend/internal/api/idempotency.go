@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/idempotency"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	_ "github.com/go-sql-driver/mysql"
+)
+
+var (
+	// idempotent is the idempotency middleware as configured. It is guarded by idempotentLock.
+	idempotent     = newIdempotencyMiddleware(config.Idempotency{}, idempotency.NewMemory())
+	idempotentLock = &sync.RWMutex{}
+)
+
+// ConfigureIdempotency sets the store used by IdempotencyMiddleware.
+func ConfigureIdempotency(cfg config.Idempotency) error {
+	var store idempotency.Store
+	switch cfg.Store {
+	case "", "memory":
+		store = idempotency.NewMemory()
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+		client.AddHook(telemetry.RedisHook())
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		store = idempotency.NewRedis(client, "idempotency:response:")
+	case "mysql":
+		db, err := sql.Open("mysql", cfg.DSN)
+		if err != nil {
+			return fmt.Errorf("failed to connect to mysql: %w", err)
+		}
+		if err := db.Ping(); err != nil {
+			return fmt.Errorf("failed to ping mysql: %w", err)
+		}
+		if err := idempotency.CreateMySQLTable(context.Background(), db); err != nil {
+			return err
+		}
+		mysqlStore := idempotency.NewMySQL(db)
+		go mysqlStore.PurgeEvery(context.Background(), time.Minute, func(err error) {
+			slog.Warn("Failed to purge expired idempotency records", "error", err)
+		})
+		store = mysqlStore
+	default:
+		return fmt.Errorf("unknown idempotency store %q", cfg.Store)
+	}
+
+	idempotentLock.Lock()
+	defer idempotentLock.Unlock()
+	idempotent = newIdempotencyMiddleware(cfg, store)
+	return nil
+}
+
+func newIdempotencyMiddleware(cfg config.Idempotency, store idempotency.Store) gin.HandlerFunc {
+	return idempotency.Middleware(idempotency.Config{
+		Store:     store,
+		Scope:     func(c *gin.Context) string { return callerFromRequest(c).ID },
+		Retention: cfg.Retention,
+		Wait:      cfg.Wait,
+		OnError:   respondIdempotencyError,
+	})
+}
+
+// IdempotencyMiddleware makes the endpoints that execute actions safe to retry. A
+// request with an Idempotency-Key header runs once per key and caller; a retry gets the
+// stored response with an Idempotent-Replayed header. A key reused for another request
+// is answered 422 unprocessable, and a retry while the first request still runs 409
+// conflict with a Retry-After header.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotentLock.RLock()
+		h := idempotent
+		idempotentLock.RUnlock()
+		h(c)
+	}
+}
+
+// respondIdempotencyError writes an error of the idempotency middleware as an error envelope.
+func respondIdempotencyError(c *gin.Context, err error) {
+	key := c.GetHeader(idempotency.DefaultHeader)
+	switch {
+	case errors.Is(err, idempotency.ErrMismatch):
+		err = scenario.UnprocessableError("Idempotency-Key %s was used for a different request", key).WithDetail("field", idempotency.DefaultHeader)
+	case errors.Is(err, idempotency.ErrInProgress):
+		err = scenario.ConflictError("a request with Idempotency-Key %s is still in progress, retry in 1s", key).WithDetail("retry_after", 1)
+	case errors.Is(err, idempotency.ErrInvalidKey):
+		err = scenario.InvalidParamsError("Idempotency-Key is too long: with the caller ID it must fit in %d bytes", idempotency.MaxKeyLen).WithDetail("field", idempotency.DefaultHeader)
+	default:
+		err = scenario.DependencyError(err, "idempotency store unavailable")
+	}
+	respondError(c, err)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// GetJobHandler handles the GET /api/jobs/:id endpoint.
+// It reports the status, progress, partial output and final result of an asynchronous execution.
+func GetJobHandler(c *gin.Context) {
+	job, ok := jobs.Get(c.Param("id"))
+	if !ok {
+		respondError(c, errJobNotFound(c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJobHandler handles the DELETE /api/jobs/:id endpoint.
+// It cancels the job's context; the job reports "cancelled" once the action observes it.
+func CancelJobHandler(c *gin.Context) {
+	job, err := jobs.Cancel(c.Param("id"))
+	if errors.Is(err, jobs.ErrNotFound) {
+		respondError(c, errJobNotFound(c.Param("id")))
+		return
+	}
+	if err != nil {
+		respondError(c, scenario.ConflictError("%v", err).WithDetail("job", job))
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func errJobNotFound(id string) error {
+	return scenario.NotFoundError("job not found: %s", id).WithDetail("job_id", id)
+}
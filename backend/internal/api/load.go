@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/load"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// loadStreamInterval is how often a load test stream sends the current report.
+var loadStreamInterval = time.Second
+
+// StartLoadHandler handles the POST /api/scenarios/:id/load endpoint.
+// It starts a load test of an action or workload as a job and responds 202 with the job.
+func StartLoadHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	s, ok := registry.GetScenario(scenarioID)
+	if !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	var spec apitypes.LoadSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		respondError(c, scenario.InvalidParamsError("invalid request body: %v", err))
+		return
+	}
+	target, err := load.Target(s, spec)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := validateActionParams(target, spec.Params); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	job, err := load.Start(s, spec)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetLoadHandler handles the GET /api/load/:id endpoint.
+func GetLoadHandler(c *gin.Context) {
+	report, ok := load.Get(c.Param("id"))
+	if !ok {
+		respondError(c, scenario.NotFoundError("load test %s not found", c.Param("id")))
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// StreamLoadHandler handles the GET /api/load/:id/stream endpoint.
+// It sends the report of a load test as a server-sent event every second until the
+// test has finished; the last event holds the final report.
+func StreamLoadHandler(c *gin.Context) {
+	id := c.Param("id")
+	report, ok := load.Get(id)
+	if !ok {
+		respondError(c, scenario.NotFoundError("load test %s not found", id))
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	ticker := time.NewTicker(loadStreamInterval)
+	defer ticker.Stop()
+	ctx := c.Request.Context()
+	for {
+		c.SSEvent("stats", report)
+		c.Writer.Flush()
+		if !report.Running && !report.StartedAt.IsZero() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, _ = load.Get(id)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/load"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// Workloads gives the API stub a workload for the load generator.
+func (s *stubScenario) Workloads() []scenario.Workload {
+	return []scenario.Workload{{ID: "ping", Name: "Ping", Run: func(ctx context.Context, params map[string]interface{}) error {
+		return nil
+	}}}
+}
+
+func TestLoadEndpoints(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodGet, "/api/scenarios/api_stub", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, body["workloads"], 1)
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/load", `{"workload":"ping","concurrency":2,"duration":"200ms"}`)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "load", body["kind"])
+	id, _ := body["id"].(string)
+
+	// The stream ends with the final report once the load test has finished.
+	old := loadStreamInterval
+	loadStreamInterval = 20 * time.Millisecond
+	defer func() { loadStreamInterval = old }()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/load/"+id+"/stream", nil))
+	events := strings.Split(strings.TrimSpace(rec.Body.String()), "\n\n")
+	last := strings.TrimPrefix(events[len(events)-1], "event:stats\ndata:")
+	var report load.Report
+	assert.NoError(t, json.Unmarshal([]byte(last), &report))
+	assert.False(t, report.Running)
+	assert.Greater(t, report.Requests, int64(0))
+
+	w, body = do(r, http.MethodGet, "/api/load/"+id, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "workload:ping", body["target"])
+
+	// The params are validated against the target.
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/load", `{"action":"priced","duration":"1s"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/load", `{"action":"ok","duration":"1h"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	w, _ = do(r, http.MethodGet, "/api/load/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
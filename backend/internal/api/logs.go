@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// logKeepAlive is how often an idle log stream sends a comment, so that proxies keep it open.
+var logKeepAlive = 15 * time.Second
+
+// ListLogsHandler handles the GET /api/scenarios/:id/logs endpoint.
+// Query parameters: level (minimum level), since and until (RFC 3339), action and limit.
+func ListLogsHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	if _, ok := registry.GetScenario(scenarioID); !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	f, err := logFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		raw := c.Query(p.name)
+		if raw == "" {
+			continue
+		}
+		if *p.dst, err = time.Parse(time.RFC3339, raw); err != nil {
+			respondError(c, scenario.InvalidParamsError("%s must be an RFC 3339 time", p.name).WithDetail("field", p.name))
+			return
+		}
+	}
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	c.JSON(http.StatusOK, logs.Query(scenarioID, f))
+}
+
+// StreamLogsHandler handles the GET /api/scenarios/:id/logs/stream endpoint.
+// It replays the newest buffered entries and then streams new ones as server-sent
+// events until the client disconnects. Query parameters: level, action and tail.
+func StreamLogsHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	if _, ok := registry.GetScenario(scenarioID); !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+
+	f, err := logFilter(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Subscribe before reading the backlog so no entry is lost in between;
+	// entries already replayed are skipped by sequence number.
+	live, cancel := logs.Subscribe(scenarioID, f)
+	defer cancel()
+
+	tail, err := strconv.Atoi(c.DefaultQuery("tail", "20"))
+	if err != nil || tail < 0 {
+		tail = 20
+	}
+	var last uint64
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if tail > 0 {
+		backlog := f
+		backlog.Limit = tail
+		for _, e := range logs.Query(scenarioID, backlog) {
+			c.SSEvent("log", e)
+			last = e.Seq
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(logKeepAlive)
+	defer keepAlive.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-live:
+			if e.Seq <= last {
+				continue
+			}
+			c.SSEvent("log", e)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// logFilter reads the level and action query parameters shared by both log endpoints.
+func logFilter(c *gin.Context) (logs.Filter, error) {
+	f := logs.Filter{Action: c.Query("action")}
+	if raw := c.Query("level"); raw != "" {
+		level, err := logs.ParseLevel(raw)
+		if err != nil {
+			return f, scenario.InvalidParamsError("%v", err).WithDetail("field", "level")
+		}
+		f.MinLevel = level
+	}
+	return f, nil
+}
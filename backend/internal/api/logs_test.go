@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListLogs(t *testing.T) {
+	logs.Reset(logs.DefaultCapacity)
+	r := newTestRouter()
+	do(r, http.MethodPost, "/api/scenarios/api_stub/actions/ok", "")
+	do(r, http.MethodPost, "/api/scenarios/api_stub/actions/broken", "")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/scenarios/api_stub/logs?level=info&action=ok", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var entries []apitypes.LogEntry
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "Stub called", entries[0].Message)
+		assert.Equal(t, "Action finished", entries[1].Message)
+		assert.Equal(t, "api_stub", entries[0].Scenario)
+		assert.NotEmpty(t, entries[0].TraceID)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/scenarios/api_stub/logs?level=error", nil))
+	entries = nil
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &entries))
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "broken", entries[0].Action)
+		assert.Equal(t, "boom", entries[0].Fields["error"])
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	w, _ = do(r, http.MethodGet, "/api/scenarios/api_stub/logs?since="+future, "")
+	assert.Equal(t, "[]", strings.TrimSpace(w.Body.String()))
+
+	w, body := do(r, http.MethodGet, "/api/scenarios/api_stub/logs?level=loud", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+	w, _ = do(r, http.MethodGet, "/api/scenarios/api_stub/logs?since=yesterday", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = do(r, http.MethodGet, "/api/scenarios/nope/logs", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestStreamLogs(t *testing.T) {
+	logs.Reset(logs.DefaultCapacity)
+	srv := httptest.NewServer(newTestRouter())
+	defer srv.Close()
+
+	logs.For("api_stub").Info("Before subscribing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/api/scenarios/api_stub/logs/stream?level=info", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	messages := make(chan string, 16)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var e apitypes.LogEntry
+			if json.Unmarshal([]byte(data), &e) == nil {
+				messages <- e.Message
+			}
+		}
+		close(messages)
+	}()
+
+	next := func() string {
+		select {
+		case m := <-messages:
+			return m
+		case <-time.After(2 * time.Second):
+			return "timeout"
+		}
+	}
+	assert.Equal(t, "Before subscribing", next())
+
+	logs.For("api_stub").Debug("Filtered out")
+	logs.For("api_stub").Info("Live entry")
+	assert.Equal(t, "Live entry", next())
+}
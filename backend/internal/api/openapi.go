@@ -0,0 +1,402 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/compare"
+	"SYS_DESIGN_PLAYGROUND/internal/history"
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
+	"SYS_DESIGN_PLAYGROUND/internal/load"
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/openapi"
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// Operations lists every API endpoint. It is the single source of the OpenAPI document,
+// the request validation in ValidationMiddleware and the generated client in pkg/client.
+// A route added to SetupRouter must be described here as well.
+//
+// Every operation may answer 401 once authentication is enabled; operations that need
+// more than the viewer role also list 403.
+func Operations() []openapi.Operation {
+	notFound := []int{http.StatusNotFound}
+	ops := []openapi.Operation{
+		{
+			ID: "whoAmI", Method: http.MethodGet, Path: "/api/auth/whoami", Tag: "auth",
+			Summary:  "Get the caller's name and role, and whether authentication is enabled.",
+			Response: apitypes.WhoAmI{},
+		},
+		{
+			ID: "listScenarios", Method: http.MethodGet, Path: "/api/scenarios", Tag: "scenarios",
+			Summary: "List the enabled scenarios, sorted by category, configured order and name.",
+			Query: []openapi.Param{
+				{Name: "tag", Type: "string", Description: "Only return scenarios with this tag."},
+			},
+			Response: []apitypes.ScenarioSummary{},
+		},
+		{
+			ID: "listCategories", Method: http.MethodGet, Path: "/api/categories", Tag: "scenarios",
+			Summary:  "List the enabled scenarios grouped by category, as shown in the navigator.",
+			Response: []apitypes.Category{},
+		},
+		{
+			ID: "getScenario", Method: http.MethodGet, Path: "/api/scenarios/:id", Tag: "scenarios",
+			Summary:  "Get the full configuration of a scenario.",
+			Response: apitypes.ScenarioDetail{},
+			Errors:   notFound,
+		},
+		{
+			ID: "executeAction", Method: http.MethodPost, Path: "/api/scenarios/:id/actions/:action_id", Tag: "scenarios",
+			Summary:     "Execute an action.",
+			Description: "Params are validated against the params declared by the action. With async=true the action runs as a job and the response is 202 with a job ID. " +
+				"A request with an Idempotency-Key header runs once per key; retries get the stored response with Idempotent-Replayed: true. " +
+				"Reusing a key for a different request is 422, retrying while the first request runs 409.",
+			Query: []openapi.Param{
+				{Name: "async", Type: "boolean", Description: "Run the action as a background job."},
+			},
+			Request:  apitypes.ExecuteActionRequest{},
+			Response: apitypes.ActionResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusForbidden, http.StatusTooManyRequests},
+		},
+		{
+			ID: "getState", Method: http.MethodGet, Path: "/api/scenarios/:id/state", Tag: "scenarios",
+			Summary:  "Get the dashboard state of a scenario.",
+			Response: apitypes.State{},
+			Errors:   []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+		{
+			ID: "listHistory", Method: http.MethodGet, Path: "/api/scenarios/:id/history", Tag: "history",
+			Summary: "List recorded action executions, newest first.",
+			Query: []openapi.Param{
+				{Name: "action", Type: "string", Description: "Only return executions of this action."},
+				{Name: "page", Type: "integer", Description: "1-based page number."},
+				{Name: "page_size", Type: "integer", Description: "Page size, at most 100."},
+			},
+			Response: apitypes.HistoryPage{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+		{
+			ID: "exportHistory", Method: http.MethodGet, Path: "/api/scenarios/:id/history/export", Tag: "history",
+			Summary: "Export the full history of a scenario as a chronological timeline.",
+			Query: []openapi.Param{
+				{Name: "action", Type: "string", Description: "Only return executions of this action."},
+			},
+			Response: []*apitypes.HistoryRecord{},
+			Errors:   []int{http.StatusNotFound, http.StatusServiceUnavailable},
+		},
+		{
+			ID: "listLogs", Method: http.MethodGet, Path: "/api/scenarios/:id/logs", Tag: "logs",
+			Summary: "Query the buffered structured logs of a scenario, oldest first.",
+			Query: []openapi.Param{
+				{Name: "level", Type: "string", Description: "Minimum level: debug, info, warn or error."},
+				{Name: "since", Type: "string", Description: "Only return entries at or after this RFC 3339 time."},
+				{Name: "until", Type: "string", Description: "Only return entries before this RFC 3339 time."},
+				{Name: "action", Type: "string", Description: "Only return entries logged by this action."},
+				{Name: "limit", Type: "integer", Description: "Only return the newest limit entries."},
+			},
+			Response: []apitypes.LogEntry{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			ID: "streamLogs", Method: http.MethodGet, Path: "/api/scenarios/:id/logs/stream", Tag: "logs",
+			Summary:     "Tail the structured logs of a scenario as server-sent events.",
+			Description: "Each event carries one LogEntry as JSON. The newest tail buffered entries are sent first.",
+			Query: []openapi.Param{
+				{Name: "level", Type: "string", Description: "Minimum level: debug, info, warn or error."},
+				{Name: "action", Type: "string", Description: "Only stream entries logged by this action."},
+				{Name: "tail", Type: "integer", Description: "Number of buffered entries to replay first, 20 by default."},
+			},
+			Response:  apitypes.LogEntry{},
+			Streaming: true,
+			Errors:    []int{http.StatusBadRequest, http.StatusNotFound},
+		},
+		{
+			ID: "listSnapshots", Method: http.MethodGet, Path: "/api/scenarios/:id/snapshots", Tag: "snapshots",
+			Summary:  "List the snapshots of a scenario, oldest first.",
+			Response: []apitypes.Snapshot{},
+			Errors:   notFound,
+		},
+		{
+			ID: "createSnapshot", Method: http.MethodPost, Path: "/api/scenarios/:id/snapshots", Tag: "snapshots",
+			Summary:     "Capture the state owned by a scenario under a name.",
+			Description: "Captures the scenario's MySQL tables, Redis keys and in-memory caches. An existing snapshot with the same name is replaced.",
+			Request:     apitypes.SnapshotRequest{},
+			Response:    apitypes.Snapshot{},
+			Status:      http.StatusCreated,
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable, http.StatusForbidden},
+		},
+		{
+			ID: "restoreSnapshot", Method: http.MethodPost, Path: "/api/scenarios/:id/restore", Tag: "snapshots",
+			Summary:  "Restore the state of a scenario from a named snapshot.",
+			Request:  apitypes.SnapshotRequest{},
+			Response: apitypes.Snapshot{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable, http.StatusForbidden},
+		},
+		{
+			ID: "compareActions", Method: http.MethodPost, Path: "/api/scenarios/:id/compare", Tag: "scenarios",
+			Summary:     "Run the problem and the solution action side by side.",
+			Description: "Forks the scenario twice and runs each action on its own fork with the same params and fault overrides. The actions default to those of kind problem and solution. The response holds the state timeline of each fork and the fields in which their final states differ.",
+			Request:     apitypes.CompareRequest{},
+			Response:    apitypes.Comparison{},
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusServiceUnavailable, http.StatusForbidden, http.StatusTooManyRequests},
+		},
+		{
+			ID: "startLoad", Method: http.MethodPost, Path: "/api/scenarios/:id/load", Tag: "load",
+			Summary:     "Start a load test of an action or workload.",
+			Description: "Runs the target at qps, or with concurrency workers back to back, for the duration, ramping up over ramp_up. The load test runs as a job and is cancelled through DELETE /api/jobs/:id; its result is the final LoadReport.",
+			Request:     apitypes.LoadSpec{},
+			Response:    apitypes.Job{},
+			Status:      http.StatusAccepted,
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusForbidden},
+		},
+		{
+			ID: "getLoad", Method: http.MethodGet, Path: "/api/load/:id", Tag: "load",
+			Summary:  "Get the current report of a load test: throughput, error rate and latency histogram.",
+			Response: apitypes.LoadReport{},
+			Errors:   notFound,
+		},
+		{
+			ID: "streamLoad", Method: http.MethodGet, Path: "/api/load/:id/stream", Tag: "load",
+			Summary:     "Stream the report of a load test as server-sent events.",
+			Description: "Sends a \"stats\" event with the report every second until the load test has finished.",
+			Response:    apitypes.LoadReport{},
+			Streaming:   true,
+			Errors:      notFound,
+		},
+		{
+			ID: "getJob", Method: http.MethodGet, Path: "/api/jobs/:id", Tag: "jobs",
+			Summary:  "Get the status, progress and result of an asynchronous execution.",
+			Response: apitypes.Job{},
+			Errors:   notFound,
+		},
+		{
+			ID: "cancelJob", Method: http.MethodDelete, Path: "/api/jobs/:id", Tag: "jobs",
+			Summary:  "Cancel a running job.",
+			Response: apitypes.Job{},
+			Status:   http.StatusAccepted,
+			Errors:   []int{http.StatusNotFound, http.StatusConflict, http.StatusForbidden},
+		},
+		{
+			ID: "runRunbook", Method: http.MethodPost, Path: "/api/runbooks/run", Tag: "runbooks",
+			Summary:             "Run a runbook and return its report.",
+			Request:             apitypes.Runbook{},
+			RequestContentTypes: []string{"application/json", "application/yaml"},
+			Response:            apitypes.RunbookReport{},
+			Errors:              []int{http.StatusBadRequest, http.StatusNotFound, http.StatusForbidden, http.StatusTooManyRequests},
+		},
+		{
+			ID: "getOpenAPI", Method: http.MethodGet, Path: "/api/openapi.json", Tag: "meta",
+			Summary:  "Get this OpenAPI document.",
+			Response: map[string]interface{}{},
+		},
+	}
+	for i := range ops {
+		ops[i].Errors = append([]int{http.StatusUnauthorized}, ops[i].Errors...)
+	}
+	return ops
+}
+
+// SchemaNames gives the component names of types owned by other packages, so that
+// the document reads "RunbookReport" rather than "Report". Every name matches the
+// alias under which pkg/apitypes exports the type.
+func SchemaNames() map[reflect.Type]string {
+	return map[reflect.Type]string{
+		reflect.TypeOf(auth.Principal{}):              "Principal",
+		reflect.TypeOf(compare.Request{}):             "CompareRequest",
+		reflect.TypeOf(compare.Comparison{}):          "Comparison",
+		reflect.TypeOf(compare.Side{}):                "CompareSide",
+		reflect.TypeOf(compare.StatePoint{}):          "StatePoint",
+		reflect.TypeOf(compare.Difference{}):          "StateDifference",
+		reflect.TypeOf(history.Record{}):              "HistoryRecord",
+		reflect.TypeOf(jobs.Job{}):                    "Job",
+		reflect.TypeOf(load.Report{}):                 "LoadReport",
+		reflect.TypeOf(load.Spec{}):                   "LoadSpec",
+		reflect.TypeOf(load.Latency{}):                "LoadLatency",
+		reflect.TypeOf(load.Bucket{}):                 "LoadLatencyBucket",
+		reflect.TypeOf(load.Point{}):                  "LoadPoint",
+		reflect.TypeOf(logs.Entry{}):                  "LogEntry",
+		reflect.TypeOf(runbook.Runbook{}):             "Runbook",
+		reflect.TypeOf(runbook.Report{}):              "RunbookReport",
+		reflect.TypeOf(runbook.Event{}):               "RunbookEvent",
+		reflect.TypeOf(runbook.Step{}):                "RunbookStep",
+		reflect.TypeOf(runbook.Assertion{}):           "RunbookAssertion",
+		reflect.TypeOf(runbook.AssertionResult{}):     "RunbookAssertionResult",
+		reflect.TypeOf(runbook.FaultToggle{}):         "FaultToggle",
+		reflect.TypeOf(snapshot.Snapshot{}):           "Snapshot",
+		reflect.TypeOf(scenario.Action{}):             "Action",
+		reflect.TypeOf(scenario.ActionParam{}):        "ActionParam",
+		reflect.TypeOf(scenario.DashboardComponent{}): "DashboardComponent",
+		reflect.TypeOf(scenario.Workload{}):           "Workload",
+	}
+}
+
+var (
+	specOnce sync.Once
+	spec     *openapi.Document
+	// operationsByRoute indexes Operations() by method and gin route, e.g. "GET /api/jobs/:id".
+	operationsByRoute map[string]openapi.Operation
+)
+
+// Spec returns the OpenAPI document of the API. It is built once, on first use.
+func Spec() *openapi.Document {
+	specOnce.Do(func() {
+		ops := Operations()
+		spec = openapi.Build(openapi.Info{
+			Title:       "System Design Playground API",
+			Version:     "1.0.0",
+			Description: "Drive the playground scenarios programmatically.",
+		}, ops, apitypes.ErrorResponse{}, SchemaNames())
+
+		operationsByRoute = make(map[string]openapi.Operation, len(ops))
+		for _, op := range ops {
+			operationsByRoute[op.Method+" "+op.Path] = op
+		}
+	})
+	return spec
+}
+
+// OpenAPIHandler handles the GET /api/openapi.json endpoint.
+func OpenAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, Spec())
+}
+
+// ValidationMiddleware rejects requests whose query parameters or JSON body do not match
+// the OpenAPI document with a 400 invalid_params error, before the handler runs.
+func ValidationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		doc := Spec()
+		op, ok := operationsByRoute[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+		if err := validateRequest(c, doc, op); err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func validateRequest(c *gin.Context, doc *openapi.Document, op openapi.Operation) error {
+	for _, p := range op.Query {
+		raw, present := c.GetQuery(p.Name)
+		if !present {
+			if p.Required {
+				return scenario.InvalidParamsError("query parameter %s is required", p.Name).WithDetail("field", p.Name)
+			}
+			continue
+		}
+		if err := openapi.ValidateQuery(p, raw); err != nil {
+			return invalidParams("invalid query parameter", err)
+		}
+	}
+
+	schema, ok := doc.RequestSchema(op.ID)
+	// Only JSON bodies are validated; e.g. YAML runbooks are checked by their handler.
+	if !ok || c.Request.ContentLength == 0 || !strings.HasPrefix(c.ContentType(), "application/json") {
+		return nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return scenario.InvalidParamsError("failed to read request body: %v", err)
+	}
+	// Let the handler read the body again.
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return scenario.InvalidParamsError("invalid request body: %v", err)
+	}
+	if err := doc.Validate(schema, value); err != nil {
+		return invalidParams("invalid request body", err)
+	}
+	return nil
+}
+
+// validateActionParams checks params against the parameters declared by the action.
+// Undeclared params are passed through untouched.
+func validateActionParams(action scenario.Action, params map[string]interface{}) error {
+	if len(action.Params) == 0 {
+		return nil
+	}
+	schema := &openapi.Schema{Type: "object", Properties: make(map[string]*openapi.Schema)}
+	for _, p := range action.Params {
+		schema.Properties[p.Name] = &openapi.Schema{Type: p.Type}
+		if p.Required {
+			schema.Required = append(schema.Required, p.Name)
+		}
+	}
+
+	var value interface{} = map[string]interface{}{}
+	if params != nil {
+		value = params
+	}
+	if err := (*openapi.Document)(nil).Validate(schema, value); err != nil {
+		return invalidParams("invalid params for action "+action.ID, err)
+	}
+	return nil
+}
+
+// invalidParams turns a validation failure into an invalid_params error naming the offending field.
+func invalidParams(msg string, err error) error {
+	e := scenario.InvalidParamsError("%s: %v", msg, err)
+	var ve *openapi.ValidationError
+	if errors.As(err, &ve) && ve.Field != "" {
+		e.WithDetail("field", ve.Field)
+	}
+	return e
+}
+
+const apitypesPkg = "SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+
+// GenerateClient renders pkg/client/client.gen.go. It is run by cmd/apigen via go generate.
+func GenerateClient() ([]byte, error) {
+	return openapi.GenerateClient(openapi.ClientConfig{
+		Package: "client",
+		Imports: []string{apitypesPkg},
+		GoType:  clientGoType,
+	}, Operations())
+}
+
+// clientGoType names t in terms of pkg/apitypes, which is the only package a client
+// outside this module can import.
+func clientGoType(t reflect.Type) (string, error) {
+	if t.Name() != "" {
+		if name, ok := SchemaNames()[t]; ok {
+			return "apitypes." + name, nil
+		}
+		if t.PkgPath() == apitypesPkg {
+			return "apitypes." + t.Name(), nil
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := clientGoType(t.Elem())
+		return "*" + elem, err
+	case reflect.Slice:
+		elem, err := clientGoType(t.Elem())
+		return "[]" + elem, err
+	case reflect.Map:
+		elem, err := clientGoType(t.Elem())
+		return "map[string]" + elem, err
+	case reflect.Interface:
+		return "interface{}", nil
+	}
+	return "", fmt.Errorf("type %s is not exported by pkg/apitypes", t)
+}
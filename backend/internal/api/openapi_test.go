@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutesMatchOperations(t *testing.T) {
+	r := newTestRouter()
+
+	routes := make(map[string]bool)
+	for _, route := range r.Routes() {
+		routes[route.Method+" "+route.Path] = true
+	}
+	ops := make(map[string]bool)
+	for _, op := range Operations() {
+		ops[op.Method+" "+op.Path] = true
+	}
+	assert.Equal(t, routes, ops)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodGet, "/api/openapi.json", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "3.0.3", body["openapi"])
+
+	paths, _ := body["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/api/scenarios/{id}/actions/{action_id}")
+
+	schemas := body["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"ScenarioDetail", "ActionResponse", "ErrorResponse", "HistoryRecord", "Job", "RunbookReport"} {
+		assert.Contains(t, schemas, name)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_stub/actions/ok?async=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/ok", `{"params":5}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	w, body = do(r, http.MethodGet, "/api/scenarios/api_stub/history?page=two", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "page", body["error"].(map[string]interface{})["details"].(map[string]interface{})["field"])
+}
+
+func TestActionParamValidation(t *testing.T) {
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_stub/actions/priced", `{"params":{"price":"cheap"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "price", body["error"].(map[string]interface{})["details"].(map[string]interface{})["field"])
+
+	w, _ = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/priced", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/actions/priced", `{"params":{"price":12.5}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{"price": 12.5}, body["result"])
+}
+
+// TestGeneratedClientUpToDate fails when Operations() changed without re-running go generate in pkg/client.
+func TestGeneratedClientUpToDate(t *testing.T) {
+	want, err := GenerateClient()
+	assert.Nil(t, err)
+
+	got, err := os.ReadFile("../../pkg/client/client.gen.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(want), string(got), "run go generate ./pkg/client")
+}
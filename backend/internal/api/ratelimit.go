@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/ratelimit"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// defaultBurst is the burst of a rate limit that does not configure one.
+const defaultBurst = 5
+
+var (
+	// limited is the rate limiting middleware as configured, nil while no limit is set.
+	// It is guarded by limitLock.
+	limited   gin.HandlerFunc
+	limitLock = &sync.RWMutex{}
+)
+
+// newLimiter returns a limiter of perMinute requests with burst, using the algorithm
+// named by cfg. Rates below one per minute become one request per longer window.
+func newLimiter(cfg config.RateLimit, perMinute float64, burst int, client *redis.Client, prefix string) (ratelimit.Limiter, error) {
+	limit, window := int(perMinute), time.Minute
+	if float64(limit) != perMinute {
+		limit, window = 1, time.Duration(float64(time.Minute)/perMinute)
+	}
+	rc := ratelimit.Config{Algorithm: ratelimit.Algorithm(cfg.Algorithm), Limit: limit, Window: window, Burst: burst}
+	if rc.Algorithm == "" {
+		rc.Algorithm = ratelimit.TokenBucket
+	}
+	if client != nil {
+		return ratelimit.NewRedis(client, prefix, rc)
+	}
+	return ratelimit.NewLocal(rc)
+}
+
+// ConfigureRateLimit sets the limits applied by RateLimitMiddleware. With a Redis
+// address the limits are shared by every server using it.
+func ConfigureRateLimit(cfg config.RateLimit) error {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = defaultBurst
+	}
+	var client *redis.Client
+	if cfg.RedisAddr != "" && (cfg.PerIP > 0 || cfg.PerSession > 0) {
+		client = redis.NewClient(&redis.Options{Addr: cfg.RedisAddr})
+		client.AddHook(telemetry.RedisHook())
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+	}
+	var rules []ratelimit.Rule
+	if cfg.PerIP > 0 {
+		l, err := newLimiter(cfg, cfg.PerIP, burst, client, "ratelimit:ip:")
+		if err != nil {
+			return err
+		}
+		rules = append(rules, ratelimit.Rule{Limiter: l, Key: ratelimit.ByIP})
+	}
+	if cfg.PerSession > 0 {
+		l, err := newLimiter(cfg, cfg.PerSession, burst, client, "ratelimit:session:")
+		if err != nil {
+			return err
+		}
+		rules = append(rules, ratelimit.Rule{Limiter: l, Key: ratelimit.ByHeader("X-Session-ID")})
+	}
+
+	limitLock.Lock()
+	defer limitLock.Unlock()
+	limited = nil
+	if len(rules) > 0 {
+		limited = ratelimit.Middleware(ratelimit.MiddlewareConfig{
+			Rules: rules,
+			// With authentication off every caller is an admin, so only authenticated
+			// admins are exempt.
+			Skip: func(c *gin.Context) bool { return auth.Enabled() && principal(c).Role.Allows(auth.RoleAdmin) },
+			OnLimited: func(c *gin.Context, res ratelimit.Result) {
+				seconds := ratelimit.RetryAfterSeconds(res)
+				respondError(c, scenario.RateLimitedError("too many requests, retry in %ds", seconds).WithDetail("retry_after", seconds))
+			},
+			OnError: func(c *gin.Context, err error) {
+				slog.Warn("Rate limiter failed, allowing the request", "error", err)
+			},
+		})
+	}
+	return nil
+}
+
+// RateLimitMiddleware limits how often a client IP and a session (X-Session-ID) may
+// call the endpoints that execute actions. Admins are not limited while authentication
+// is on. A limited request is answered 429 with a Retry-After header.
+func RateLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limitLock.RLock()
+		h := limited
+		limitLock.RUnlock()
+		if h == nil {
+			c.Next()
+			return
+		}
+		h(c)
+	}
+}
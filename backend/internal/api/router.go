@@ -1,19 +1,64 @@
 package api
 
-import "github.com/gin-gonic/gin"
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"github.com/gin-gonic/gin"
+)
 
 // SetupRouter configures the API routes for the application.
+// Reading needs the viewer role; the roles of the other routes are given below, and
+// actions check the role each declares. Routes that run actions are rate limited, and
+// executing an action honours the Idempotency-Key header.
 func SetupRouter(router *gin.Engine) {
 	// Group all API routes under /api
 	api := router.Group("/api")
+	api.Use(TraceIDMiddleware(), AuthMiddleware(), ValidationMiddleware())
 	{
+		// Machine-readable API contract
+		api.GET("/openapi.json", OpenAPIHandler)
+
+		// The caller's identity and role
+		api.GET("/auth/whoami", WhoAmIHandler)
+
+		// Scenario navigator grouped by category
+		api.GET("/categories", ListCategoriesHandler)
+
 		// Scenario-related endpoints
 		scenarios := api.Group("/scenarios")
 		{
 			scenarios.GET("", ListScenariosHandler)
 			scenarios.GET("/:id", GetScenarioHandler)
-			scenarios.POST("/:id/actions/:action_id", ExecuteActionHandler)
+			scenarios.POST("/:id/actions/:action_id", RateLimitMiddleware(), IdempotencyMiddleware(), ExecuteActionHandler)
 			scenarios.GET("/:id/state", GetStateHandler)
+			scenarios.GET("/:id/history", ListHistoryHandler)
+			scenarios.GET("/:id/history/export", ExportHistoryHandler)
+			scenarios.GET("/:id/logs", ListLogsHandler)
+			scenarios.GET("/:id/logs/stream", StreamLogsHandler)
+			scenarios.GET("/:id/snapshots", ListSnapshotsHandler)
+			scenarios.POST("/:id/snapshots", RequireRole(auth.RoleOperator), CreateSnapshotHandler)
+			scenarios.POST("/:id/restore", RequireRole(auth.RoleOperator), RestoreSnapshotHandler)
+			scenarios.POST("/:id/compare", RateLimitMiddleware(), CompareHandler)
+			scenarios.POST("/:id/load", RequireRole(auth.RoleAdmin), StartLoadHandler)
+		}
+
+		// Asynchronous job endpoints
+		jobs := api.Group("/jobs")
+		{
+			jobs.GET("/:id", GetJobHandler)
+			jobs.DELETE("/:id", RequireRole(auth.RoleOperator), CancelJobHandler)
+		}
+
+		// Load test endpoints; load tests are cancelled through their job
+		load := api.Group("/load")
+		{
+			load.GET("/:id", GetLoadHandler)
+			load.GET("/:id/stream", StreamLoadHandler)
+		}
+
+		// Runbook endpoints
+		runbooks := api.Group("/runbooks")
+		{
+			runbooks.POST("/run", RequireRole(auth.RoleOperator), RateLimitMiddleware(), RunRunbookHandler)
 		}
 	}
 }
\ No newline at end of file
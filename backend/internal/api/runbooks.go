@@ -0,0 +1,48 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// RunRunbookHandler handles the POST /api/runbooks/run endpoint.
+// The request body is a runbook in YAML (or JSON); the response is a pass/fail report with a timeline.
+func RunRunbookHandler(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, scenario.InvalidParamsError("failed to read request body: %v", err))
+		return
+	}
+
+	rb, err := runbook.Parse(body)
+	if err != nil {
+		respondError(c, scenario.InvalidParamsError("%v", err))
+		return
+	}
+
+	s, ok := registry.GetScenario(rb.Scenario)
+	if !ok {
+		respondError(c, errScenarioNotFound(rb.Scenario))
+		return
+	}
+	// The caller must be allowed to run every action of the runbook.
+	for _, step := range rb.Steps {
+		if step.Kind() != "action" {
+			continue
+		}
+		if action, ok := findAction(s, step.Action); ok {
+			if err := authorizeActions(c, action); err != nil {
+				respondError(c, err)
+				return
+			}
+		}
+	}
+
+	report := runbook.Run(c.Request.Context(), rb, s)
+	c.JSON(http.StatusOK, report)
+}
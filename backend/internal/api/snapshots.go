@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSnapshotsHandler handles the GET /api/scenarios/:id/snapshots endpoint.
+func ListSnapshotsHandler(c *gin.Context) {
+	scenarioID := c.Param("id")
+	if _, ok := registry.GetScenario(scenarioID); !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return
+	}
+	c.JSON(http.StatusOK, snapshot.List(scenarioID))
+}
+
+// CreateSnapshotHandler handles the POST /api/scenarios/:id/snapshots endpoint.
+// It captures the state owned by the scenario under the name given in the body.
+func CreateSnapshotHandler(c *gin.Context) {
+	s, name, ok := snapshotRequest(c)
+	if !ok {
+		return
+	}
+	snap, err := snapshot.Take(c.Request.Context(), s, name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, snap)
+}
+
+// RestoreSnapshotHandler handles the POST /api/scenarios/:id/restore endpoint.
+// It puts the scenario back to the state of the snapshot named in the body.
+func RestoreSnapshotHandler(c *gin.Context) {
+	s, name, ok := snapshotRequest(c)
+	if !ok {
+		return
+	}
+	snap, err := snapshot.Restore(c.Request.Context(), s, name)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, snap)
+}
+
+// snapshotRequest resolves the scenario and the snapshot name of a snapshot request,
+// responding with an error if either is missing.
+func snapshotRequest(c *gin.Context) (scenario.Scenario, string, bool) {
+	scenarioID := c.Param("id")
+	s, ok := registry.GetScenario(scenarioID)
+	if !ok {
+		respondError(c, errScenarioNotFound(scenarioID))
+		return nil, "", false
+	}
+
+	var req apitypes.SnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, scenario.InvalidParamsError("invalid request body: %v", err))
+		return nil, "", false
+	}
+	if req.Name == "" {
+		respondError(c, scenario.InvalidParamsError("name is required").WithDetail("field", "name"))
+		return nil, "", false
+	}
+	return s, req.Name, true
+}
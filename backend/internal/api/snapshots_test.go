@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"github.com/stretchr/testify/assert"
+)
+
+// snapshotStub is a scenario whose only state is an in-memory value.
+type snapshotStub struct {
+	stubScenario
+	value string
+}
+
+var snapStub = &snapshotStub{}
+
+func init() {
+	registry.Register(snapStub)
+}
+
+func (s *snapshotStub) ID() string { return "api_snapshot" }
+
+func (s *snapshotStub) SnapshotResources() []snapshot.Resource {
+	return []snapshot.Resource{snapshot.Memory("value", func() func() {
+		saved := s.value
+		return func() { s.value = saved }
+	})}
+}
+
+func TestSnapshotEndpoints(t *testing.T) {
+	snapshot.Reset()
+	s := snapStub
+	s.value = "clean"
+	r := newTestRouter()
+
+	w, body := do(r, http.MethodPost, "/api/scenarios/api_snapshot/snapshots", `{"name":"before_bug"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "before_bug", body["name"])
+	assert.Equal(t, []interface{}{"memory:value"}, body["resources"])
+
+	s.value = "stale"
+	w, _ = do(r, http.MethodPost, "/api/scenarios/api_snapshot/restore", `{"name":"before_bug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "clean", s.value)
+
+	w, _ = do(r, http.MethodGet, "/api/scenarios/api_snapshot/snapshots", "")
+	assert.Contains(t, w.Body.String(), `"name":"before_bug"`)
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_snapshot/restore", `{"name":"unknown"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", errorCode(body))
+
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_snapshot/snapshots", `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+
+	// The plain stub does not own any resources.
+	w, body = do(r, http.MethodPost, "/api/scenarios/api_stub/snapshots", `{"name":"x"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_params", errorCode(body))
+}
@@ -0,0 +1,225 @@
+// Package auth authenticates API callers and assigns them a role. Authentication is
+// off unless the configuration file lists API tokens or JWT validation; until then
+// every caller is an admin, as the playground has always behaved.
+//
+// Credentials are sent as "Authorization: Bearer <token>", or as the access_token
+// query parameter where headers cannot be set, e.g. by EventSource. Request URIs must
+// go through RedactToken before they are logged.
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+)
+
+// Role determines what a caller may do. Each role includes the ones below it.
+type Role string
+
+const (
+	// RoleViewer may read scenarios, state, history and logs.
+	RoleViewer Role = "viewer"
+	// RoleOperator may also run actions, take and restore snapshots and run runbooks.
+	RoleOperator Role = "operator"
+	// RoleAdmin may do everything, including actions marked admin and load tests.
+	RoleAdmin Role = "admin"
+	// roleNone is the anonymous role that rejects requests without credentials.
+	roleNone Role = "none"
+)
+
+var ranks = map[Role]int{roleNone: 0, RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// ParseRole checks the name of a role.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := ranks[r]; !ok || r == roleNone {
+		return "", fmt.Errorf("unknown role %q, want viewer, operator or admin", s)
+	}
+	return r, nil
+}
+
+// Allows reports whether r includes required.
+func (r Role) Allows(required Role) bool {
+	return ranks[r] >= ranks[required]
+}
+
+// ActionRole returns the role needed to run a, operator by default.
+func ActionRole(a scenario.Action) Role {
+	if a.Role == "" {
+		return RoleOperator
+	}
+	return Role(a.Role)
+}
+
+// Principal is the authenticated caller.
+type Principal struct {
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Method string `json:"method"` // "token", "jwt", "anonymous", or "none" when authentication is off
+}
+
+// Anonymous reports whether the caller sent no credentials.
+func (p Principal) Anonymous() bool {
+	return p.Method == "anonymous" || p.Method == "none"
+}
+
+// settings is the active configuration. It is guarded by lock.
+type settings struct {
+	tokens    []config.Token
+	jwt       *jwtValidator
+	anonymous Role
+}
+
+var (
+	active *settings
+	lock   = &sync.RWMutex{}
+)
+
+// Configure enables authentication as configured; an empty configuration turns it off.
+func Configure(cfg config.Auth) error {
+	s := &settings{anonymous: RoleViewer}
+	for _, t := range cfg.Tokens {
+		if t.Token == "" {
+			return fmt.Errorf("token %q is empty", t.Name)
+		}
+		if _, err := ParseRole(t.Role); err != nil {
+			return fmt.Errorf("token %q: %w", t.Name, err)
+		}
+		s.tokens = append(s.tokens, t)
+	}
+	if cfg.JWT != nil {
+		v, err := newJWTValidator(*cfg.JWT)
+		if err != nil {
+			return err
+		}
+		s.jwt = v
+	}
+	if cfg.AnonymousRole != "" {
+		if Role(cfg.AnonymousRole) == roleNone {
+			s.anonymous = roleNone
+		} else {
+			r, err := ParseRole(cfg.AnonymousRole)
+			if err != nil {
+				return fmt.Errorf("anonymous_role: %w", err)
+			}
+			s.anonymous = r
+		}
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	active = s
+	if len(s.tokens) == 0 && s.jwt == nil {
+		active = nil
+	}
+	return nil
+}
+
+// Enabled reports whether authentication is configured.
+func Enabled() bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return active != nil
+}
+
+// Authenticate identifies the caller of r. Invalid credentials are an error; missing
+// credentials yield the anonymous role, unless that is "none".
+func Authenticate(r *http.Request) (Principal, error) {
+	lock.RLock()
+	s := active
+	lock.RUnlock()
+	if s == nil {
+		return Principal{Name: "anonymous", Role: RoleAdmin, Method: "none"}, nil
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		if s.anonymous == roleNone {
+			return Principal{}, scenario.UnauthorizedError("authentication required")
+		}
+		return Principal{Name: "anonymous", Role: s.anonymous, Method: "anonymous"}, nil
+	}
+
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			return Principal{Name: t.Name, Role: Role(t.Role), Method: "token"}, nil
+		}
+	}
+	if s.jwt != nil && strings.Count(token, ".") == 2 {
+		p, err := s.jwt.validate(token)
+		if err != nil {
+			return Principal{}, scenario.UnauthorizedError("invalid token: %v", err)
+		}
+		return p, nil
+	}
+	return Principal{}, scenario.UnauthorizedError("invalid token")
+}
+
+// bearerToken returns the token of the Authorization header or the access_token query parameter.
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// RedactToken returns the request URI uri with the value of its access_token query
+// parameter replaced, so that it can be logged.
+func RedactToken(uri string) string {
+	path, query, ok := strings.Cut(uri, "?")
+	if !ok {
+		return uri
+	}
+	params := strings.Split(query, "&")
+	for i, p := range params {
+		// Keys are unescaped as URL.Query unescapes them, so access%5Ftoken is caught too.
+		k, _, _ := strings.Cut(p, "=")
+		if k, err := url.QueryUnescape(k); err == nil && k == "access_token" {
+			params[i] = "access_token=REDACTED"
+		}
+	}
+	return path + "?" + strings.Join(params, "&")
+}
+
+// loadPublicKey reads an RSA public key from a PEM file holding a public key or a certificate.
+func loadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	var key interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		key = cert.PublicKey
+	default:
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an RSA public key", path)
+	}
+	return rsaKey, nil
+}
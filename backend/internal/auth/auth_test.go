@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// signJWT builds a token with the given claims, signed with HS256 if key is a
+// []byte or RS256 if it is an *rsa.PrivateKey.
+func signJWT(t *testing.T, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	alg := "HS256"
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		alg = "RS256"
+	}
+	enc := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := enc(map[string]string{"alg": alg, "typ": "JWT"}) + "." + enc(claims)
+
+	var sig []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func authenticate(token string) (Principal, error) {
+	r := httptest.NewRequest("GET", "/api/scenarios", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return Authenticate(r)
+}
+
+func TestDisabled(t *testing.T) {
+	assert.NoError(t, Configure(config.Auth{}))
+	assert.False(t, Enabled())
+	p, err := authenticate("anything")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, p.Role)
+}
+
+func TestTokens(t *testing.T) {
+	defer Configure(config.Auth{})
+	assert.NoError(t, Configure(config.Auth{Tokens: []config.Token{{Name: "ci", Token: "s3cret", Role: "operator"}}}))
+	assert.True(t, Enabled())
+
+	p, err := authenticate("s3cret")
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Name: "ci", Role: RoleOperator, Method: "token"}, p)
+
+	_, err = authenticate("wrong")
+	assert.Equal(t, scenario.CodeUnauthorized, scenario.CodeOf(err))
+
+	// Without credentials the caller is an anonymous viewer...
+	p, err = authenticate("")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, p.Role)
+	assert.True(t, p.Anonymous())
+
+	// ...or rejected.
+	assert.NoError(t, Configure(config.Auth{Tokens: []config.Token{{Name: "ci", Token: "s3cret", Role: "operator"}}, AnonymousRole: "none"}))
+	_, err = authenticate("")
+	assert.Equal(t, scenario.CodeUnauthorized, scenario.CodeOf(err))
+
+	// The token may also be passed as a query parameter.
+	p, err = Authenticate(httptest.NewRequest("GET", "/api/scenarios/x/logs/stream?access_token=s3cret", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", p.Name)
+	assert.Equal(t, "/api/scenarios/x/logs/stream?since=1&access_token=REDACTED",
+		RedactToken("/api/scenarios/x/logs/stream?since=1&access_token=s3cret"))
+	assert.Equal(t, "/x?access_token=REDACTED", RedactToken("/x?access%5Ftoken=s3cret"))
+	assert.Equal(t, "/x?token=1", RedactToken("/x?token=1"))
+
+	assert.Error(t, Configure(config.Auth{Tokens: []config.Token{{Name: "bad", Token: "x", Role: "root"}}}))
+}
+
+func TestJWT(t *testing.T) {
+	defer Configure(config.Auth{})
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Unix(1000, 0) }
+
+	secret := []byte("hmac-secret")
+	assert.NoError(t, Configure(config.Auth{JWT: &config.JWT{Secret: string(secret), Issuer: "https://idp", Audience: "playground"}}))
+	claims := map[string]interface{}{"sub": "alice", "iss": "https://idp", "aud": []string{"playground"}, "exp": 2000, "role": []string{"other", "admin"}}
+
+	p, err := authenticate(signJWT(t, secret, claims))
+	assert.NoError(t, err)
+	assert.Equal(t, Principal{Name: "alice", Role: RoleAdmin, Method: "jwt"}, p)
+
+	for name, change := range map[string]func(map[string]interface{}){
+		"expired":      func(c map[string]interface{}) { c["exp"] = 500 },
+		"no expiry":    func(c map[string]interface{}) { delete(c, "exp") },
+		"not yet":      func(c map[string]interface{}) { c["nbf"] = 1500 },
+		"issuer":       func(c map[string]interface{}) { c["iss"] = "https://evil" },
+		"audience":     func(c map[string]interface{}) { c["aud"] = "other" },
+		"unknown role": func(c map[string]interface{}) { c["role"] = "root" },
+	} {
+		bad := map[string]interface{}{}
+		for k, v := range claims {
+			bad[k] = v
+		}
+		change(bad)
+		_, err := authenticate(signJWT(t, secret, bad))
+		assert.Equal(t, scenario.CodeUnauthorized, scenario.CodeOf(err), name)
+	}
+
+	_, err = authenticate(signJWT(t, []byte("other-secret"), claims))
+	assert.Error(t, err, "wrong key")
+
+	// An RS256 token is not accepted by an HS256 configuration, and vice versa.
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	_, err = authenticate(signJWT(t, key, claims))
+	assert.Error(t, err)
+
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644))
+	assert.NoError(t, Configure(config.Auth{JWT: &config.JWT{PublicKeyFile: path}}))
+	p, err = authenticate(signJWT(t, key, map[string]interface{}{"sub": "bob", "role": "viewer", "exp": 2000}))
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, p.Role)
+	_, err = authenticate(signJWT(t, secret, claims))
+	assert.Error(t, err)
+}
+
+func TestRoles(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleOperator))
+	assert.False(t, RoleViewer.Allows(RoleOperator))
+	assert.Equal(t, RoleOperator, ActionRole(scenario.Action{}))
+	assert.Equal(t, RoleViewer, ActionRole(scenario.Action{Role: "viewer"}))
+	_, err := ParseRole("none")
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// jwtValidator checks JSON Web Tokens locally, without calling the issuer.
+type jwtValidator struct {
+	secret    []byte         // HS256
+	key       *rsa.PublicKey // RS256
+	issuer    string
+	audience  string
+	roleClaim string
+	nameClaim string
+	leeway    time.Duration
+}
+
+func newJWTValidator(cfg config.JWT) (*jwtValidator, error) {
+	v := &jwtValidator{
+		issuer:    cfg.Issuer,
+		audience:  cfg.Audience,
+		roleClaim: cfg.RoleClaim,
+		nameClaim: cfg.NameClaim,
+		leeway:    cfg.Leeway,
+	}
+	if v.roleClaim == "" {
+		v.roleClaim = "role"
+	}
+	if v.nameClaim == "" {
+		v.nameClaim = "sub"
+	}
+	switch {
+	case cfg.Secret != "" && cfg.PublicKeyFile != "":
+		return nil, errors.New("jwt: set either secret or public_key_file, not both")
+	case cfg.Secret != "":
+		v.secret = []byte(cfg.Secret)
+	case cfg.PublicKeyFile != "":
+		key, err := loadPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		v.key = key
+	default:
+		return nil, errors.New("jwt: secret or public_key_file is required")
+	}
+	return v, nil
+}
+
+// validate verifies the signature and the registered claims of token and maps it to a principal.
+func (v *jwtValidator) validate(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, errors.New("malformed signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	// The algorithm is fixed by the configuration, never chosen by the token.
+	switch {
+	case v.secret != nil && header.Alg == "HS256":
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write(signed)
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return Principal{}, errors.New("bad signature")
+		}
+	case v.key != nil && header.Alg == "RS256":
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(v.key, crypto.SHA256, digest[:], sig); err != nil {
+			return Principal{}, errors.New("bad signature")
+		}
+	default:
+		return Principal{}, fmt.Errorf("unexpected algorithm %q", header.Alg)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, err
+	}
+	t := now()
+	// A token without an expiry would be valid forever.
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return Principal{}, errors.New("token has no expiry")
+	}
+	if t.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return Principal{}, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && t.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return Principal{}, errors.New("token not valid yet")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return Principal{}, errors.New("unexpected issuer")
+	}
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return Principal{}, errors.New("unexpected audience")
+	}
+
+	role, err := claimRole(claims[v.roleClaim])
+	if err != nil {
+		return Principal{}, err
+	}
+	name, _ := claims[v.nameClaim].(string)
+	return Principal{Name: name, Role: role, Method: "jwt"}, nil
+}
+
+func decodeSegment(seg string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return errors.New("malformed token")
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return errors.New("malformed token")
+	}
+	return nil
+}
+
+// hasAudience reports whether the aud claim, a string or a list, contains want.
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimRole returns the role of a role claim, or the highest known role of a list.
+// Roles the playground does not know, e.g. those of other applications, are ignored.
+func claimRole(claim interface{}) (Role, error) {
+	var names []interface{}
+	switch c := claim.(type) {
+	case string:
+		names = []interface{}{c}
+	case []interface{}:
+		names = c
+	}
+	var best Role
+	for _, n := range names {
+		s, _ := n.(string)
+		if r, err := ParseRole(s); err == nil && ranks[r] > ranks[best] {
+			best = r
+		}
+	}
+	if best == "" {
+		return "", errors.New("token has no playground role")
+	}
+	return best, nil
+}
@@ -0,0 +1,165 @@
+// Package compare runs the problem and the solution action of a scenario side by side.
+// The scenario is forked twice so that both actions start from the same state and
+// neither sees the changes of the other, and the resulting states are diffed.
+package compare
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/executor"
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/google/uuid"
+)
+
+// Request selects the two actions to compare and the inputs they share.
+type Request struct {
+	// ProblemAction and SolutionAction default to the actions of kind "problem" and "solution".
+	ProblemAction  string                 `json:"problem_action,omitempty"`
+	SolutionAction string                 `json:"solution_action,omitempty"`
+	Params         map[string]interface{} `json:"params,omitempty"`
+	// Faults turns fault points on or off for both actions only, see fault.WithOverrides.
+	Faults map[string]bool `json:"faults,omitempty"`
+}
+
+// Comparison is the outcome of running both actions.
+type Comparison struct {
+	ScenarioID string                 `json:"scenario_id"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Faults     map[string]bool        `json:"faults,omitempty"`
+	Problem    Side                   `json:"problem"`
+	Solution   Side                   `json:"solution"`
+	// Diff lists the state fields that differ after both actions ran.
+	Diff []Difference `json:"diff"`
+}
+
+// Side is the run of one action on its own fork.
+type Side struct {
+	Action string      `json:"action"`
+	Result interface{} `json:"result,omitempty"`
+	// ExecutionID links the run to its entry in the action history.
+	ExecutionID string             `json:"execution_id"`
+	DurationMs  int64              `json:"duration_ms"`
+	Error       string             `json:"error,omitempty"`
+	ErrorCode   scenario.ErrorCode `json:"error_code,omitempty"`
+	// Timeline holds the state of the fork before and after the action.
+	Timeline []StatePoint `json:"timeline"`
+}
+
+// StatePoint is the state of a fork at one point of its timeline.
+type StatePoint struct {
+	Label string                 `json:"label"` // "before" or "after"
+	Time  time.Time              `json:"time"`
+	State map[string]interface{} `json:"state"`
+}
+
+// Actions resolves the problem and the solution action of req against s.
+func Actions(s scenario.Scenario, req Request) (problem, solution scenario.Action, err error) {
+	if problem, err = action(s, req.ProblemAction, scenario.KindProblem); err != nil {
+		return
+	}
+	solution, err = action(s, req.SolutionAction, scenario.KindSolution)
+	return
+}
+
+// action returns the action with the given ID or, if id is empty, the action of the given kind.
+func action(s scenario.Scenario, id, kind string) (scenario.Action, error) {
+	for _, a := range s.Actions() {
+		if (id != "" && a.ID == id) || (id == "" && a.Kind == kind) {
+			return a, nil
+		}
+	}
+	if id == "" {
+		return scenario.Action{}, scenario.InvalidParamsError("scenario %s has no %s action; name it in %s_action", s.ID(), kind, kind).
+			WithDetail("field", kind+"_action")
+	}
+	return scenario.Action{}, scenario.UnknownActionError(id)
+}
+
+// Run forks s twice, runs the problem action on one fork and the solution action on
+// the other with the same params and fault overrides, and releases the forks again.
+// Failures of the actions are reported in the Comparison; the error is only set if
+// the comparison could not be run at all.
+func Run(ctx context.Context, s scenario.Scenario, req Request, caller executor.Caller) (*Comparison, error) {
+	forkable, ok := s.(scenario.Forkable)
+	if !ok {
+		return nil, scenario.InvalidParamsError("scenario %s does not support comparison", s.ID())
+	}
+	problem, solution, err := Actions(s, req)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logs.For(s.ID())
+	// Fork names end up in table names and keys, hence no dashes.
+	prefix := "cmp" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	// The forks are released even if the client goes away.
+	releaseCtx := context.WithoutCancel(ctx)
+
+	actions := []string{problem.ID, solution.ID}
+	forks := make([]scenario.Scenario, len(actions))
+	for i, kind := range []string{scenario.KindProblem, scenario.KindSolution} {
+		name := prefix + "_" + kind
+		fork, release, err := forkable.Fork(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := release(releaseCtx); err != nil {
+				logger.Error("Failed to release fork", "fork", name, "error", err)
+			}
+		}()
+		forks[i] = fork
+	}
+	logger.InfoContext(ctx, "Comparing actions", "problem", problem.ID, "solution", solution.ID, "forks", prefix)
+
+	ctx = fault.WithOverrides(ctx, req.Faults)
+	sides := make([]Side, len(actions))
+	var wg sync.WaitGroup
+	for i := range forks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sides[i] = runSide(ctx, forks[i], actions[i], req.Params, caller)
+		}(i)
+	}
+	wg.Wait()
+
+	return &Comparison{
+		ScenarioID: s.ID(),
+		Params:     req.Params,
+		Faults:     req.Faults,
+		Problem:    sides[0],
+		Solution:   sides[1],
+		Diff:       Diff(lastState(sides[0]), lastState(sides[1])),
+	}, nil
+}
+
+// runSide executes one action on its fork.
+func runSide(ctx context.Context, fork scenario.Scenario, actionID string, params map[string]interface{}, caller executor.Caller) Side {
+	caller.ID = "compare:" + actionID
+	rec, err := executor.Execute(ctx, fork, actionID, params, caller)
+	side := Side{
+		Action:      actionID,
+		Result:      rec.Result,
+		ExecutionID: rec.ID,
+		DurationMs:  rec.DurationMs,
+		Timeline: []StatePoint{
+			{Label: "before", Time: rec.StartedAt, State: rec.StateBefore},
+			{Label: "after", Time: rec.FinishedAt, State: rec.StateAfter},
+		},
+	}
+	if err != nil {
+		side.Error = err.Error()
+		side.ErrorCode = scenario.CodeOf(err)
+	}
+	return side
+}
+
+func lastState(side Side) map[string]interface{} {
+	return side.Timeline[len(side.Timeline)-1].State
+}
@@ -0,0 +1,140 @@
+package compare
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/executor"
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// store holds the database and cache of every fork of a priceScenario, keyed by fork name.
+type store struct {
+	mu       sync.Mutex
+	db       map[string]float64
+	cache    map[string]float64
+	released []string
+}
+
+// priceScenario is a miniature cache inconsistency scenario. Methods not used by
+// the compare package are left to the nil embedded interface.
+type priceScenario struct {
+	scenario.Scenario
+	store *store
+	name  string
+}
+
+func newPriceScenario() *priceScenario {
+	st := &store{db: map[string]float64{"main": 10}, cache: map[string]float64{"main": 10}}
+	return &priceScenario{store: st, name: "main"}
+}
+
+func (s *priceScenario) ID() string { return "price" }
+
+func (s *priceScenario) Actions() []scenario.Action {
+	return []scenario.Action{{ID: "naive", Kind: scenario.KindProblem}, {ID: "fix", Kind: scenario.KindSolution}, {ID: "reset"}}
+}
+
+func (s *priceScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	s.store.db[s.name] = params["price"].(float64)
+	if actionID == "naive" {
+		return nil, scenario.DemonstratedError("cache update failed")
+	}
+	if fault.EnabledContext(ctx, "cache_delete") {
+		return nil, scenario.DemonstratedError("injected fault")
+	}
+	delete(s.store.cache, s.name)
+	return "ok", nil
+}
+
+func (s *priceScenario) FetchState() (map[string]interface{}, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	state := map[string]interface{}{"db": s.store.db[s.name]}
+	if v, ok := s.store.cache[s.name]; ok {
+		state["cache"] = v
+	}
+	return state, nil
+}
+
+func (s *priceScenario) Fork(ctx context.Context, name string) (scenario.Scenario, func(ctx context.Context) error, error) {
+	st := s.store
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.db[name] = st.db[s.name]
+	if v, ok := st.cache[s.name]; ok {
+		st.cache[name] = v
+	}
+	return &priceScenario{store: st, name: name}, func(ctx context.Context) error {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		delete(st.db, name)
+		delete(st.cache, name)
+		st.released = append(st.released, name)
+		return nil
+	}, nil
+}
+
+func TestRun(t *testing.T) {
+	s := newPriceScenario()
+	cmp, err := Run(context.Background(), s, Request{Params: map[string]interface{}{"price": 20.0}}, executor.Caller{ID: "test"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "naive", cmp.Problem.Action)
+	assert.Equal(t, scenario.CodeDemonstrated, cmp.Problem.ErrorCode)
+	assert.Equal(t, "fix", cmp.Solution.Action)
+	assert.Empty(t, cmp.Solution.Error)
+	assert.NotEmpty(t, cmp.Solution.ExecutionID)
+
+	// Both forks start from the state of the original.
+	assert.Equal(t, 10.0, cmp.Problem.Timeline[0].State["cache"])
+	assert.Equal(t, 10.0, cmp.Solution.Timeline[0].State["cache"])
+	assert.Equal(t, []Difference{{Path: "cache", Problem: 10.0, Solution: nil}}, cmp.Diff)
+
+	// The original is untouched and both forks are released.
+	assert.Equal(t, map[string]float64{"main": 10}, s.store.db)
+	assert.Len(t, s.store.released, 2)
+}
+
+func TestRunWithFaults(t *testing.T) {
+	fault.Reset()
+	s := newPriceScenario()
+	req := Request{ProblemAction: "naive", SolutionAction: "fix", Params: map[string]interface{}{"price": 20.0}, Faults: map[string]bool{"cache_delete": true}}
+	cmp, err := Run(context.Background(), s, req, executor.Caller{})
+	assert.NoError(t, err)
+	assert.Equal(t, scenario.CodeDemonstrated, cmp.Solution.ErrorCode)
+	assert.Empty(t, cmp.Diff)
+	assert.False(t, fault.Enabled("cache_delete"), "fault overrides must stay scoped to the comparison")
+}
+
+func TestRunErrors(t *testing.T) {
+	s := newPriceScenario()
+	_, err := Run(context.Background(), s, Request{ProblemAction: "missing"}, executor.Caller{})
+	assert.Equal(t, scenario.CodeNotFound, scenario.CodeOf(err))
+
+	// Wrapped in a plain scenario.Scenario, the scenario no longer offers Fork.
+	_, err = Run(context.Background(), struct{ scenario.Scenario }{s}, Request{}, executor.Caller{})
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+}
+
+func TestDiff(t *testing.T) {
+	type record struct {
+		Price float64 `json:"price"`
+	}
+	diffs := Diff(
+		map[string]interface{}{"record": record{Price: 1}, "items": []int{1, 2}, "same": "x"},
+		map[string]interface{}{"record": map[string]interface{}{"price": 2.0}, "items": []int{1}, "same": "x", "extra": true},
+	)
+	assert.Equal(t, []Difference{
+		{Path: "extra", Problem: nil, Solution: true},
+		{Path: "items[1]", Problem: 2.0, Solution: nil},
+		{Path: "record.price", Problem: 1.0, Solution: 2.0},
+	}, diffs)
+}
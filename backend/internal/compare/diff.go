@@ -0,0 +1,83 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Difference is a state field whose value differs between the two sides.
+// Path addresses the field like "mysql_record.price" or "items[2]"; a field missing on
+// one side is reported as null.
+type Difference struct {
+	Path     string      `json:"path"`
+	Problem  interface{} `json:"problem"`
+	Solution interface{} `json:"solution"`
+}
+
+// Diff compares two states field by field. States are compared in their JSON form, so
+// a struct and the map it encodes to are equal.
+func Diff(problem, solution map[string]interface{}) []Difference {
+	diffs := []Difference{}
+	diffValues("", normalize(problem), normalize(solution), &diffs)
+	return diffs
+}
+
+func diffValues(path string, a, b interface{}, diffs *[]Difference) {
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := make([]string, 0, len(am)+len(bm))
+		for k := range am {
+			keys = append(keys, k)
+		}
+		for k := range bm {
+			if _, ok := am[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			diffValues(p, am[k], bm[k], diffs)
+		}
+		return
+	}
+
+	as, aIsSlice := a.([]interface{})
+	bs, bIsSlice := b.([]interface{})
+	if aIsSlice && bIsSlice {
+		for i := 0; i < len(as) || i < len(bs); i++ {
+			var av, bv interface{}
+			if i < len(as) {
+				av = as[i]
+			}
+			if i < len(bs) {
+				bv = bs[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), av, bv, diffs)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, Difference{Path: path, Problem: a, Solution: b})
+	}
+}
+
+// normalize converts v to the generic values encoding/json decodes into.
+func normalize(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -0,0 +1,140 @@
+// Package config loads the optional playground configuration file. It controls which
+// scenarios are enabled and how the navigator orders and tags them, and who may use
+// the API how often.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is read when PLAYGROUND_CONFIG is not set; it may be absent.
+const DefaultPath = "config.yaml"
+
+// Config is the content of the configuration file.
+//
+//	scenarios:
+//	  xdc_cache_sync:
+//	    enabled: false
+//	  cache_inconsistency:
+//	    order: 1
+//	    tags: [beginner]
+//	auth:
+//	  tokens:
+//	    - {name: ci, token: "${PLAYGROUND_CI_TOKEN}", role: operator}
+//	rate_limit:
+//	  per_ip: 30
+//	idempotency:
+//	  store: redis
+//	  redis_addr: redis:6379
+type Config struct {
+	Scenarios   map[string]Scenario `yaml:"scenarios"`
+	Auth        Auth                `yaml:"auth"`
+	RateLimit   RateLimit           `yaml:"rate_limit"`
+	Idempotency Idempotency         `yaml:"idempotency"`
+}
+
+// Scenario configures one scenario, by ID.
+type Scenario struct {
+	// Enabled defaults to true. A disabled scenario is neither initialized nor served.
+	Enabled *bool `yaml:"enabled"`
+	// Order sorts scenarios within their category; lower comes first, ties go by name.
+	Order int `yaml:"order"`
+	// Tags are added to the tags the scenario declares itself.
+	Tags []string `yaml:"tags"`
+}
+
+// IsEnabled reports whether the scenario is enabled.
+func (s Scenario) IsEnabled() bool {
+	return s.Enabled == nil || *s.Enabled
+}
+
+// Auth enables authentication as soon as a token or JWT validation is configured.
+// Secrets may be written as ${VAR} to read them from the environment.
+type Auth struct {
+	Tokens []Token `yaml:"tokens"`
+	JWT    *JWT    `yaml:"jwt"`
+	// AnonymousRole is the role of requests without credentials: "viewer" by default,
+	// or "none" to reject them.
+	AnonymousRole string `yaml:"anonymous_role"`
+}
+
+// Token is a static API token, sent as "Authorization: Bearer <token>".
+type Token struct {
+	Name  string `yaml:"name"` // recorded as the caller in the history
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// JWT configures the local validation of JSON Web Tokens, e.g. ID tokens of an OIDC
+// provider. Exactly one of Secret (HS256) and PublicKeyFile (RS256, PEM) is set.
+type JWT struct {
+	Secret        string `yaml:"secret"`
+	PublicKeyFile string `yaml:"public_key_file"`
+	Issuer        string `yaml:"issuer"`   // checked if set
+	Audience      string `yaml:"audience"` // checked if set
+	// RoleClaim names the claim holding the role, or a list of roles; "role" by default.
+	RoleClaim string `yaml:"role_claim"`
+	// NameClaim names the claim identifying the caller; "sub" by default.
+	NameClaim string `yaml:"name_claim"`
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration `yaml:"leeway"`
+}
+
+// RateLimit limits how often a caller may run actions, comparisons, load tests and
+// runbooks. Rates are per minute; zero disables a limit. Admins are not limited.
+type RateLimit struct {
+	PerIP      float64 `yaml:"per_ip"`
+	PerSession float64 `yaml:"per_session"` // by X-Session-ID
+	// Burst is how many requests may be sent at once before the rate applies; 5 by default.
+	Burst int `yaml:"burst"`
+	// Algorithm is one of the algorithms of pkg/ratelimit; token_bucket by default.
+	Algorithm string `yaml:"algorithm"`
+	// RedisAddr, if set, keeps the limits in Redis, so that every server enforces the
+	// same limit instead of one each.
+	RedisAddr string `yaml:"redis_addr"`
+}
+
+// Idempotency configures where the action endpoint keeps the responses of requests
+// with an Idempotency-Key header, to replay them to retries.
+type Idempotency struct {
+	// Store is "memory" (the default), "redis" or "mysql". The memory store only suits
+	// a single server.
+	Store     string `yaml:"store"`
+	RedisAddr string `yaml:"redis_addr"` // for the redis store
+	DSN       string `yaml:"dsn"`        // for the mysql store
+	// Retention is how long a response is replayed; 24h by default.
+	Retention time.Duration `yaml:"retention"`
+	// Wait is how long a retry waits for the request in progress before it gets 409;
+	// by default it gets 409 at once.
+	Wait time.Duration `yaml:"wait"`
+}
+
+// Load reads the configuration file at path. A missing file yields an empty
+// configuration unless required is set.
+func Load(path string, required bool) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	for i := range cfg.Auth.Tokens {
+		cfg.Auth.Tokens[i].Token = os.ExpandEnv(cfg.Auth.Tokens[i].Token)
+	}
+	if cfg.Auth.JWT != nil {
+		cfg.Auth.JWT.Secret = os.ExpandEnv(cfg.Auth.JWT.Secret)
+	}
+	cfg.Idempotency.DSN = os.ExpandEnv(cfg.Idempotency.DSN)
+	return cfg, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+scenarios:
+  off:
+    enabled: false
+  ordered:
+    order: 2
+    tags: [beginner]
+`), 0o644))
+
+	cfg, err := Load(path, true)
+	assert.NoError(t, err)
+	assert.False(t, cfg.Scenarios["off"].IsEnabled())
+	assert.True(t, cfg.Scenarios["ordered"].IsEnabled())
+	assert.True(t, cfg.Scenarios["unknown"].IsEnabled())
+	assert.Equal(t, 2, cfg.Scenarios["ordered"].Order)
+	assert.Equal(t, []string{"beginner"}, cfg.Scenarios["ordered"].Tags)
+
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+	cfg, err = Load(missing, false)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Scenarios)
+	_, err = Load(missing, true)
+	assert.Error(t, err)
+}
+
+func TestLoadAuth(t *testing.T) {
+	t.Setenv("TEST_PLAYGROUND_TOKEN", "s3cret")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+auth:
+  tokens:
+    - {name: ci, token: "${TEST_PLAYGROUND_TOKEN}", role: operator}
+  jwt:
+    secret: plain
+    leeway: 30s
+rate_limit:
+  per_ip: 30
+`), 0o644))
+
+	cfg, err := Load(path, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Auth.Tokens[0].Token)
+	assert.Equal(t, "plain", cfg.Auth.JWT.Secret)
+	assert.Equal(t, 30*time.Second, cfg.Auth.JWT.Leeway)
+	assert.Equal(t, 30.0, cfg.RateLimit.PerIP)
+}
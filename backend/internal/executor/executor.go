@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/history"
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Caller identifies who triggered an action.
+type Caller struct {
+	ID      string // e.g. the client IP or "runbook:<name>"
+	Session string
+	// TraceID is the request's trace ID as returned to the client; it defaults to the
+	// OpenTelemetry trace ID of the action span.
+	TraceID string
+}
+
+// Execute runs an action on s and records the invocation, including the scenario
+// state before and after, in the history store. It is the single entry point used by
+// the API and the runbook runner so that every action shows up in the history.
+//
+// Each execution is traced as an "action" span and counted in the action metrics.
+// The returned record is always non-nil; err is the error returned by the action itself.
+func Execute(ctx context.Context, s scenario.Scenario, actionID string, params map[string]interface{}, caller Caller) (*history.Record, error) {
+	rec := &history.Record{
+		ID:         uuid.NewString(),
+		ScenarioID: s.ID(),
+		ActionID:   actionID,
+		Params:     params,
+		Caller:     caller.ID,
+		Session:    caller.Session,
+	}
+
+	// The action span is the parent of the spans its gorm, Redis and MQ calls create.
+	ctx, span := telemetry.Tracer().Start(ctx, "action "+s.ID()+"/"+actionID, trace.WithAttributes(
+		attribute.String("scenario.id", s.ID()),
+		attribute.String("scenario.action", actionID),
+		attribute.String("execution.id", rec.ID),
+	))
+
+	// Everything the action logs is tagged with the execution so it can be found in the log stream.
+	logger := logs.For(s.ID()).With("action", actionID, "execution_id", rec.ID)
+	if caller.Session != "" {
+		logger = logger.With("session", caller.Session)
+	}
+	if caller.TraceID != "" {
+		logger = logger.With("trace_id", caller.TraceID)
+	} else if sc := span.SpanContext(); sc.HasTraceID() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	ctx = scenario.WithLogger(ctx, logger)
+	logger.Debug("Action started", "caller", caller.ID, "params", params)
+
+	rec.StateBefore = snapshotState(s)
+	rec.StartedAt = time.Now()
+	result, err := s.ExecuteAction(ctx, actionID, params)
+	rec.FinishedAt = time.Now()
+	rec.StateAfter = snapshotState(s)
+
+	telemetry.ObserveAction(s.ID(), actionID, rec.FinishedAt.Sub(rec.StartedAt), err)
+	if err != nil {
+		span.SetAttributes(attribute.String("scenario.error_code", string(scenario.CodeOf(err))))
+	}
+	// A demonstrated failure is the scenario working as intended, not an error in the trace.
+	if scenario.IsDemonstrated(err) {
+		telemetry.EndSpan(span, nil)
+	} else {
+		telemetry.EndSpan(span, err)
+	}
+
+	rec.DurationMs = rec.FinishedAt.Sub(rec.StartedAt).Milliseconds()
+	rec.Result = result
+	switch {
+	case err == nil:
+		logger.Info("Action finished", "duration_ms", rec.DurationMs)
+	case scenario.IsDemonstrated(err):
+		logger.Warn("Action demonstrated the problem", "duration_ms", rec.DurationMs, "error", err)
+	default:
+		logger.Error("Action failed", "duration_ms", rec.DurationMs, "code", scenario.CodeOf(err), "error", err)
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	if saveErr := history.Save(ctx, rec); saveErr != nil {
+		logger.Error("Failed to save history record", "error", saveErr)
+	}
+	return rec, err
+}
+
+// snapshotState captures the scenario state for the history record.
+// A failure to fetch state must not fail the action, so it is recorded in the snapshot instead.
+func snapshotState(s scenario.Scenario) map[string]interface{} {
+	state, err := s.FetchState()
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	return state
+}
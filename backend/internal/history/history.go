@@ -0,0 +1,107 @@
+package history
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is a single action invocation, together with the scenario state before and after it ran.
+type Record struct {
+	ID          string                 `json:"id"`
+	ScenarioID  string                 `json:"scenario_id"`
+	ActionID    string                 `json:"action_id"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Caller      string                 `json:"caller,omitempty"`
+	Session     string                 `json:"session,omitempty"`
+	StartedAt   time.Time              `json:"started_at"`
+	FinishedAt  time.Time              `json:"finished_at"`
+	DurationMs  int64                  `json:"duration_ms"`
+	Result      interface{}            `json:"result,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	StateBefore map[string]interface{} `json:"state_before,omitempty"`
+	StateAfter  map[string]interface{} `json:"state_after,omitempty"`
+}
+
+// Query selects a page of records for one scenario, newest first.
+type Query struct {
+	ScenarioID string
+	// ActionID optionally restricts the result to one action, e.g. to compare "naive" and "fix" runs.
+	ActionID string
+	Page     int // 1-based
+	PageSize int
+}
+
+// Store persists action records.
+type Store interface {
+	Save(ctx context.Context, r *Record) error
+	// List returns the requested page and the total number of matching records.
+	List(ctx context.Context, q Query) ([]*Record, int, error)
+}
+
+var (
+	// store is the process-wide history store. It defaults to an in-memory store.
+	store Store = NewMemoryStore(1000)
+	// lock is used to protect access to the store variable.
+	lock = &sync.RWMutex{}
+)
+
+// SetStore replaces the process-wide history store.
+func SetStore(s Store) {
+	lock.Lock()
+	defer lock.Unlock()
+	store = s
+}
+
+// Save records r in the process-wide store.
+func Save(ctx context.Context, r *Record) error {
+	lock.RLock()
+	defer lock.RUnlock()
+	return store.Save(ctx, r)
+}
+
+// List queries the process-wide store.
+func List(ctx context.Context, q Query) ([]*Record, int, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	return store.List(ctx, q.normalize())
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func (q Query) normalize() Query {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 {
+		q.PageSize = defaultPageSize
+	}
+	if q.PageSize > maxPageSize {
+		q.PageSize = maxPageSize
+	}
+	return q
+}
+
+// Export returns every record matching q in chronological order, e.g. to download a timeline.
+func Export(ctx context.Context, q Query) ([]*Record, error) {
+	q.PageSize = maxPageSize
+	var all []*Record
+	for q.Page = 1; ; q.Page++ {
+		page, total, err := List(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) == 0 || len(all) >= total {
+			break
+		}
+	}
+	// List returns newest first; a timeline reads oldest first.
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all, nil
+}
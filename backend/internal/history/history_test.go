@@ -0,0 +1,71 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStorePaging(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(10)
+	start := time.Now()
+	for i := 0; i < 12; i++ {
+		action := "update_naive"
+		if i%2 == 1 {
+			action = "update_with_fix"
+		}
+		err := s.Save(ctx, &Record{
+			ID:         fmt.Sprintf("r%d", i),
+			ScenarioID: "cache_inconsistency",
+			ActionID:   action,
+			StartedAt:  start.Add(time.Duration(i) * time.Second),
+		})
+		assert.Nil(t, err)
+	}
+
+	// Only the 10 most recent records are retained, newest first.
+	page, total, err := s.List(ctx, Query{ScenarioID: "cache_inconsistency", Page: 1, PageSize: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, 10, total)
+	assert.Equal(t, []string{"r11", "r10", "r9", "r8"}, ids(page))
+
+	page, _, err = s.List(ctx, Query{ScenarioID: "cache_inconsistency", Page: 3, PageSize: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"r3", "r2"}, ids(page))
+
+	page, total, err = s.List(ctx, Query{ScenarioID: "cache_inconsistency", ActionID: "update_with_fix", Page: 1, PageSize: 20})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, total)
+	assert.Equal(t, []string{"r11", "r9", "r7", "r5", "r3"}, ids(page))
+
+	page, total, err = s.List(ctx, Query{ScenarioID: "unknown", Page: 1, PageSize: 20})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, page)
+}
+
+func TestExportIsChronological(t *testing.T) {
+	ctx := context.Background()
+	SetStore(NewMemoryStore(500))
+	for i := 0; i < 250; i++ {
+		assert.Nil(t, Save(ctx, &Record{ID: fmt.Sprintf("r%d", i), ScenarioID: "s", ActionID: "a"}))
+	}
+
+	all, err := Export(ctx, Query{ScenarioID: "s"})
+	assert.Nil(t, err)
+	assert.Equal(t, 250, len(all))
+	assert.Equal(t, "r0", all[0].ID)
+	assert.Equal(t, "r249", all[249].ID)
+}
+
+func ids(records []*Record) []string {
+	out := make([]string, len(records))
+	for i, r := range records {
+		out[i] = r.ID
+	}
+	return out
+}
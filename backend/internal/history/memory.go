@@ -0,0 +1,56 @@
+package history
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps the most recent records of each scenario in memory.
+type MemoryStore struct {
+	limit   int
+	records map[string][]*Record // oldest first, per scenario
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates a store that retains at most limit records per scenario.
+func NewMemoryStore(limit int) *MemoryStore {
+	return &MemoryStore{
+		limit:   limit,
+		records: make(map[string][]*Record),
+	}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, r *Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := append(m.records[r.ScenarioID], r)
+	if len(list) > m.limit {
+		list = list[len(list)-m.limit:]
+	}
+	m.records[r.ScenarioID] = list
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context, q Query) ([]*Record, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := m.records[q.ScenarioID]
+	matched := make([]*Record, 0, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		if q.ActionID == "" || list[i].ActionID == q.ActionID {
+			matched = append(matched, list[i])
+		}
+	}
+
+	start := (q.Page - 1) * q.PageSize
+	if start >= len(matched) {
+		return []*Record{}, len(matched), nil
+	}
+	end := start + q.PageSize
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched), nil
+}
@@ -0,0 +1,142 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+const createTableSQL = `
+	CREATE TABLE IF NOT EXISTS action_history (
+		id           VARCHAR(36)  NOT NULL,
+		scenario_id  VARCHAR(100) NOT NULL,
+		action_id    VARCHAR(100) NOT NULL,
+		caller       VARCHAR(255) NOT NULL DEFAULT '',
+		session_id   VARCHAR(255) NOT NULL DEFAULT '',
+		params       JSON         NULL,
+		result       JSON         NULL,
+		error        TEXT         NULL,
+		state_before JSON         NULL,
+		state_after  JSON         NULL,
+		started_at   TIMESTAMP(3) NOT NULL,
+		finished_at  TIMESTAMP(3) NOT NULL,
+		PRIMARY KEY (id),
+		KEY idx_scenario_action_started (scenario_id, action_id, started_at)
+	)
+`
+
+// MySQLStore persists records in the action_history table.
+type MySQLStore struct {
+	db *sql.DB
+}
+
+// NewMySQLStore connects to MySQL and creates the action_history table if needed.
+// The DSN must include parseTime=true.
+func NewMySQLStore(dsn string) (*MySQLStore, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	if err = db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping mysql: %w", err)
+	}
+	if _, err = db.Exec(createTableSQL); err != nil {
+		return nil, fmt.Errorf("failed to create action_history table: %w", err)
+	}
+	return &MySQLStore{db: db}, nil
+}
+
+func (m *MySQLStore) Save(ctx context.Context, r *Record) error {
+	params, err := marshalNullable(r.Params)
+	if err != nil {
+		return err
+	}
+	result, err := marshalNullable(r.Result)
+	if err != nil {
+		return err
+	}
+	before, err := marshalNullable(r.StateBefore)
+	if err != nil {
+		return err
+	}
+	after, err := marshalNullable(r.StateAfter)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		INSERT INTO action_history
+		(id, scenario_id, action_id, caller, session_id, params, result, error, state_before, state_after, started_at, finished_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, r.ID, r.ScenarioID, r.ActionID, r.Caller, r.Session, params, result, r.Error, before, after, r.StartedAt, r.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert history record: %w", err)
+	}
+	return nil
+}
+
+func (m *MySQLStore) List(ctx context.Context, q Query) ([]*Record, int, error) {
+	where := "scenario_id = ?"
+	args := []interface{}{q.ScenarioID}
+	if q.ActionID != "" {
+		where += " AND action_id = ?"
+		args = append(args, q.ActionID)
+	}
+
+	var total int
+	if err := m.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM action_history WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count history records: %w", err)
+	}
+
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT id, scenario_id, action_id, caller, session_id, params, result, error, state_before, state_after, started_at, finished_at
+		FROM action_history WHERE `+where+`
+		ORDER BY started_at DESC LIMIT ? OFFSET ?
+	`, append(args, q.PageSize, (q.Page-1)*q.PageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query history records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*Record, 0, q.PageSize)
+	for rows.Next() {
+		var (
+			r                             Record
+			params, result, before, after []byte
+			errMsg                        sql.NullString
+		)
+		if err := rows.Scan(&r.ID, &r.ScenarioID, &r.ActionID, &r.Caller, &r.Session,
+			&params, &result, &errMsg, &before, &after, &r.StartedAt, &r.FinishedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan history record: %w", err)
+		}
+		r.Error = errMsg.String
+		r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+		unmarshalNullable(params, &r.Params)
+		unmarshalNullable(result, &r.Result)
+		unmarshalNullable(before, &r.StateBefore)
+		unmarshalNullable(after, &r.StateAfter)
+		records = append(records, &r)
+	}
+	return records, total, rows.Err()
+}
+
+func marshalNullable(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal history field: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalNullable(data []byte, v interface{}) {
+	if len(data) == 0 {
+		return
+	}
+	_ = json.Unmarshal(data, v)
+}
@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+const (
+	// maxJobs bounds how many jobs are retained; the oldest finished jobs are evicted first.
+	maxJobs = 500
+	// maxOutputLines bounds the partial output kept per job.
+	maxOutputLines = 1000
+)
+
+// ErrNotFound is returned for unknown job IDs.
+var ErrNotFound = errors.New("job not found")
+
+// Job is a point-in-time view of an asynchronous execution.
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"` // e.g. "action"
+	ScenarioID string      `json:"scenario_id"`
+	Name       string      `json:"name"` // e.g. the action ID
+	Status     Status      `json:"status"`
+	Progress   int         `json:"progress"`
+	Output     []string    `json:"output"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	// ErrorCode tells an expected failure ("demonstrated") apart from a real one.
+	ErrorCode  scenario.ErrorCode `json:"error_code,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job has reached a terminal state.
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// entry is the mutable job state owned by the manager. It doubles as the job's progress reporter.
+type entry struct {
+	job    Job
+	cancel context.CancelFunc
+	mu     sync.RWMutex
+}
+
+var _ scenario.ProgressReporter = (*entry)(nil)
+
+// Report implements scenario.ProgressReporter.
+func (e *entry) Report(percent int, message string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	e.job.Progress = percent
+	if message != "" {
+		e.job.Output = append(e.job.Output, message)
+		if len(e.job.Output) > maxOutputLines {
+			e.job.Output = e.job.Output[len(e.job.Output)-maxOutputLines:]
+		}
+	}
+}
+
+func (e *entry) snapshot() Job {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	j := e.job
+	j.Output = append([]string(nil), e.job.Output...)
+	return j
+}
+
+var (
+	// jobs holds all retained jobs by ID, and order records their submission order.
+	jobs  = make(map[string]*entry)
+	order []string
+	// lock is used to protect access to jobs and order.
+	lock = &sync.RWMutex{}
+)
+
+// Submit starts run in the background and returns the new job.
+// run receives a context that is cancelled by Cancel and that carries the job as a
+// scenario.ProgressReporter, so actions can call scenario.ReportProgress.
+func Submit(kind, scenarioID, name string, run func(ctx context.Context) (interface{}, error)) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &entry{
+		job: Job{
+			ID:         uuid.NewString(),
+			Kind:       kind,
+			ScenarioID: scenarioID,
+			Name:       name,
+			Status:     StatusPending,
+			Output:     []string{},
+			CreatedAt:  time.Now(),
+		},
+		cancel: cancel,
+	}
+
+	lock.Lock()
+	evict()
+	jobs[e.job.ID] = e
+	order = append(order, e.job.ID)
+	lock.Unlock()
+
+	go func() {
+		defer cancel()
+
+		e.mu.Lock()
+		started := time.Now()
+		e.job.Status = StatusRunning
+		e.job.StartedAt = &started
+		e.mu.Unlock()
+
+		result, err := run(scenario.WithProgress(ctx, e))
+
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		finished := time.Now()
+		e.job.FinishedAt = &finished
+		e.job.Result = result
+		switch {
+		case errors.Is(ctx.Err(), context.Canceled):
+			e.job.Status = StatusCancelled
+			e.job.Error = "cancelled"
+		case err != nil:
+			e.job.Status = StatusFailed
+			e.job.Error = err.Error()
+			e.job.ErrorCode = scenario.CodeOf(err)
+		default:
+			e.job.Status = StatusSucceeded
+			e.job.Progress = 100
+		}
+	}()
+
+	return e.snapshot()
+}
+
+// Get returns the current view of a job.
+func Get(id string) (Job, bool) {
+	lock.RLock()
+	e, ok := jobs[id]
+	lock.RUnlock()
+	if !ok {
+		return Job{}, false
+	}
+	return e.snapshot(), true
+}
+
+// Cancel requests cancellation of a running job. The job's status becomes
+// "cancelled" once the running action observes its context.
+func Cancel(id string) (Job, error) {
+	lock.RLock()
+	e, ok := jobs[id]
+	lock.RUnlock()
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+
+	j := e.snapshot()
+	if j.Done() {
+		return j, fmt.Errorf("job %s already %s", id, j.Status)
+	}
+	e.cancel()
+	return e.snapshot(), nil
+}
+
+// evict drops the oldest finished jobs once more than maxJobs are retained.
+// The caller must hold lock.
+func evict() {
+	if len(order) < maxJobs {
+		return
+	}
+	kept := order[:0]
+	excess := len(order) - maxJobs + 1
+	for _, id := range order {
+		if excess > 0 && jobs[id].snapshot().Done() {
+			delete(jobs, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	order = kept
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitDone(t *testing.T, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, ok := Get(id); ok && j.Done() {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestSubmitReportsProgressAndResult(t *testing.T) {
+	job := Submit("action", "demo", "work", func(ctx context.Context) (interface{}, error) {
+		scenario.ReportProgress(ctx, 50, "half way")
+		scenario.ReportProgress(ctx, 90, "almost")
+		return "done", nil
+	})
+
+	j := waitDone(t, job.ID)
+	assert.Equal(t, StatusSucceeded, j.Status)
+	assert.Equal(t, 100, j.Progress)
+	assert.Equal(t, []string{"half way", "almost"}, j.Output)
+	assert.Equal(t, "done", j.Result)
+	assert.NotNil(t, j.StartedAt)
+	assert.NotNil(t, j.FinishedAt)
+}
+
+func TestSubmitFailure(t *testing.T) {
+	job := Submit("action", "demo", "work", func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+
+	j := waitDone(t, job.ID)
+	assert.Equal(t, StatusFailed, j.Status)
+	assert.Equal(t, "boom", j.Error)
+}
+
+func TestCancel(t *testing.T) {
+	started := make(chan struct{})
+	job := Submit("action", "demo", "slow", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	_, err := Cancel(job.ID)
+	assert.Nil(t, err)
+
+	j := waitDone(t, job.ID)
+	assert.Equal(t, StatusCancelled, j.Status)
+
+	_, err = Cancel(job.ID)
+	assert.NotNil(t, err)
+
+	_, err = Cancel("missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
@@ -0,0 +1,332 @@
+// Package load is the built-in load generator. It runs a scenario action or workload
+// at a target rate or concurrency for a duration, with an optional ramp-up, and
+// collects latency histograms, error rates and throughput while it runs.
+//
+// Load tests run as jobs (see internal/jobs), so they are polled and cancelled like
+// asynchronous actions. Their requests bypass the action history: recording every one
+// of them would drown the history, so they are summarized in the Report instead.
+package load
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+)
+
+// Limits of a load test, so that a typo cannot take the playground down.
+const (
+	MaxDuration    = 10 * time.Minute
+	MaxConcurrency = 1000
+	MaxQPS         = 10000
+
+	// DefaultConcurrency is used when a spec does not set one.
+	DefaultConcurrency = 10
+	// maxReports bounds how many finished load tests are retained.
+	maxReports = 50
+)
+
+// Spec describes a load test. Exactly one of Action and Workload is set.
+//
+// With QPS set, requests are started at that rate by up to Concurrency workers; if all
+// of them are busy the rate drops, which is itself a symptom worth seeing. Without
+// QPS, Concurrency workers send requests back to back. RampUp raises the rate, or
+// starts the workers, gradually over its length.
+type Spec struct {
+	Action      string                 `json:"action,omitempty"`
+	Workload    string                 `json:"workload,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	QPS         float64                `json:"qps,omitempty"`
+	Concurrency int                    `json:"concurrency,omitempty"`
+	Duration    runbook.Duration       `json:"duration"`
+	RampUp      runbook.Duration       `json:"ramp_up,omitempty"`
+}
+
+// Target returns the action, or the workload described as an action, that spec runs
+// on s, so that the caller can validate the params against it.
+func Target(s scenario.Scenario, spec Spec) (scenario.Action, error) {
+	switch {
+	case spec.Action != "" && spec.Workload != "":
+		return scenario.Action{}, scenario.InvalidParamsError("set either action or workload, not both")
+	case spec.Action != "":
+		for _, a := range s.Actions() {
+			if a.ID == spec.Action {
+				return a, nil
+			}
+		}
+		return scenario.Action{}, scenario.UnknownActionError(spec.Action)
+	case spec.Workload != "":
+		w, ok := workload(s, spec.Workload)
+		if !ok {
+			return scenario.Action{}, scenario.NotFoundError("scenario %s has no workload %s", s.ID(), spec.Workload)
+		}
+		return scenario.Action{ID: w.ID, Name: w.Name, Description: w.Description, Params: w.Params}, nil
+	default:
+		return scenario.Action{}, scenario.InvalidParamsError("action or workload is required")
+	}
+}
+
+// Validate checks the rate, concurrency and duration of spec against the limits.
+func Validate(spec Spec) error {
+	switch d := time.Duration(spec.Duration); {
+	case d <= 0:
+		return scenario.InvalidParamsError("duration is required").WithDetail("field", "duration")
+	case d > MaxDuration:
+		return scenario.InvalidParamsError("duration must be at most %s", MaxDuration).WithDetail("field", "duration")
+	}
+	if spec.RampUp < 0 || spec.RampUp > spec.Duration {
+		return scenario.InvalidParamsError("ramp_up must be between 0 and the duration").WithDetail("field", "ramp_up")
+	}
+	if spec.QPS < 0 || spec.QPS > MaxQPS {
+		return scenario.InvalidParamsError("qps must be between 0 and %d", MaxQPS).WithDetail("field", "qps")
+	}
+	if spec.Concurrency < 0 || spec.Concurrency > MaxConcurrency {
+		return scenario.InvalidParamsError("concurrency must be between 0 and %d", MaxConcurrency).WithDetail("field", "concurrency")
+	}
+	return nil
+}
+
+func workload(s scenario.Scenario, id string) (scenario.Workload, bool) {
+	if p, ok := s.(scenario.WorkloadProvider); ok {
+		for _, w := range p.Workloads() {
+			if w.ID == id {
+				return w, true
+			}
+		}
+	}
+	return scenario.Workload{}, false
+}
+
+var (
+	// running holds the stats of retained load tests by job ID, and order their start order.
+	running = make(map[string]*stats)
+	order   []string
+	// lock is used to protect access to running and order.
+	lock = &sync.RWMutex{}
+)
+
+// Start submits a load test of s as a job and returns the job. The caller must have
+// checked spec with Target and Validate.
+func Start(s scenario.Scenario, spec Spec) (jobs.Job, error) {
+	if _, err := Target(s, spec); err != nil {
+		return jobs.Job{}, err
+	}
+	if err := Validate(spec); err != nil {
+		return jobs.Job{}, err
+	}
+	if spec.Concurrency == 0 {
+		spec.Concurrency = DefaultConcurrency
+	}
+
+	name, request := requestFunc(s, spec)
+	st := newStats(Report{ScenarioID: s.ID(), Target: name, Spec: spec})
+
+	// The report carries the job ID, which is only known once the job is submitted.
+	ready := make(chan struct{})
+	job := jobs.Submit("load", s.ID(), name, func(ctx context.Context) (interface{}, error) {
+		<-ready
+		return run(ctx, s.ID(), spec, st, request), nil
+	})
+	st.report.ID = job.ID
+	close(ready)
+
+	lock.Lock()
+	defer lock.Unlock()
+	evict()
+	running[job.ID] = st
+	order = append(order, job.ID)
+	return job, nil
+}
+
+// Get returns the current report of a load test.
+func Get(id string) (Report, bool) {
+	lock.RLock()
+	st, ok := running[id]
+	lock.RUnlock()
+	if !ok {
+		return Report{}, false
+	}
+	return st.snapshot(), true
+}
+
+// evict drops the oldest finished load tests once more than maxReports are retained.
+// The caller must hold lock.
+func evict() {
+	if len(order) < maxReports {
+		return
+	}
+	kept := order[:0]
+	excess := len(order) - maxReports + 1
+	for _, id := range order {
+		if excess > 0 && !running[id].snapshot().Running {
+			delete(running, id)
+			excess--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	order = kept
+}
+
+// discard swallows the logs of individual requests, which would flood the log stream.
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// requestFunc returns the target name and the function performing one request of spec.
+func requestFunc(s scenario.Scenario, spec Spec) (string, func(ctx context.Context) error) {
+	if spec.Workload != "" {
+		w, _ := workload(s, spec.Workload)
+		return "workload:" + w.ID, func(ctx context.Context) error {
+			return w.Run(ctx, spec.Params)
+		}
+	}
+	return "action:" + spec.Action, func(ctx context.Context) error {
+		started := time.Now()
+		_, err := s.ExecuteAction(scenario.WithLogger(ctx, discard), spec.Action, spec.Params)
+		telemetry.ObserveAction(s.ID(), spec.Action, time.Since(started), err)
+		return err
+	}
+}
+
+// run drives the load test until its duration has passed or ctx is cancelled.
+func run(ctx context.Context, scenarioID string, spec Spec, st *stats, request func(ctx context.Context) error) Report {
+	duration := time.Duration(spec.Duration)
+	rampUp := time.Duration(spec.RampUp)
+	logger := logs.For(scenarioID)
+
+	st.mu.Lock()
+	st.report.Running = true
+	st.report.StartedAt = time.Now()
+	started, id, target := st.report.StartedAt, st.report.ID, st.report.Target
+	st.mu.Unlock()
+	logger.Info("Load test started", "load_test", id, "target", target,
+		"qps", spec.QPS, "concurrency", spec.Concurrency, "duration", duration.String())
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var tokens chan struct{}
+	if spec.QPS > 0 {
+		tokens = make(chan struct{})
+		go pace(ctx, tokens, spec.QPS, rampUp, started)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < spec.Concurrency; i++ {
+		// Without a rate, the ramp-up starts the workers one after another.
+		var delay time.Duration
+		if tokens == nil {
+			delay = rampUp * time.Duration(i) / time.Duration(spec.Concurrency)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx, delay, tokens, st, request)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ticker.C:
+			st.tick()
+			r := st.snapshot()
+			scenario.ReportProgress(ctx, int(100*time.Since(started)/duration), fmt.Sprintf(
+				"%ds: %d requests, %d errors, p99 %.1fms", len(r.Series), r.Requests, r.Errors, r.Latency.P99Ms))
+		}
+	}
+
+	st.finish()
+	r := st.snapshot()
+	logger.Info("Load test finished", "load_test", id, "requests", r.Requests, "errors", r.Errors,
+		"throughput", r.Throughput, "p99_ms", r.Latency.P99Ms)
+	return r
+}
+
+// worker sends requests until ctx is done, waiting for a token before each one if tokens is set.
+func worker(ctx context.Context, delay time.Duration, tokens <-chan struct{}, st *stats, request func(ctx context.Context) error) {
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+	for {
+		if tokens != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tokens:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+
+		st.begin()
+		started := time.Now()
+		err := request(ctx)
+		if ctx.Err() != nil {
+			// Cut short by the end of the test; it says nothing about the target.
+			st.abort()
+			return
+		}
+		st.end(time.Since(started), err)
+	}
+}
+
+// pace sends tokens at qps, rising linearly from zero over rampUp, until ctx is done.
+func pace(ctx context.Context, tokens chan<- struct{}, qps float64, rampUp time.Duration, started time.Time) {
+	base := started
+	for k := 1; ; k++ {
+		at := base.Add(tokenTime(k, qps, rampUp))
+		// After a stall, e.g. with every worker busy, resume at the rate instead of bursting.
+		if now := time.Now(); at.Before(now.Add(-time.Second)) {
+			base = base.Add(now.Sub(at))
+			at = now
+		}
+
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case tokens <- struct{}{}:
+		}
+	}
+}
+
+// tokenTime returns when the k-th request is due if the rate rises linearly from zero
+// to qps over rampUp and stays there: the number of requests by time t is qps*t²/2r
+// during the ramp-up and qps*(t - r/2) after it.
+func tokenTime(k int, qps float64, rampUp time.Duration) time.Duration {
+	r := rampUp.Seconds()
+	var t float64
+	if float64(k) <= qps*r/2 {
+		t = math.Sqrt(2 * r * float64(k) / qps)
+	} else {
+		t = float64(k)/qps + r/2
+	}
+	return time.Duration(t * float64(time.Second))
+}
@@ -0,0 +1,158 @@
+package load
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// busyScenario counts its requests; every third one fails. Methods not used by the
+// load generator are left to the nil embedded interface.
+type busyScenario struct {
+	scenario.Scenario
+	calls atomic.Int64
+}
+
+func (s *busyScenario) ID() string { return "busy" }
+
+func (s *busyScenario) Actions() []scenario.Action {
+	return []scenario.Action{{ID: "work"}}
+}
+
+func (s *busyScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	time.Sleep(time.Millisecond)
+	if s.calls.Add(1)%3 == 0 {
+		return nil, scenario.DemonstratedError("contention")
+	}
+	return nil, nil
+}
+
+func (s *busyScenario) Workloads() []scenario.Workload {
+	return []scenario.Workload{{ID: "read", Run: func(ctx context.Context, params map[string]interface{}) error {
+		s.calls.Add(1)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Millisecond):
+			return nil
+		}
+	}}}
+}
+
+func waitReport(t *testing.T, id string) Report {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, ok := jobs.Get(id); ok && j.Done() {
+			r, _ := Get(id)
+			return r
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("load test %s did not finish", id)
+	return Report{}
+}
+
+func TestConcurrencyLoad(t *testing.T) {
+	s := &busyScenario{}
+	job, err := Start(s, Spec{Action: "work", Concurrency: 4, Duration: runbook.Duration(300 * time.Millisecond)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "load", job.Kind)
+
+	r := waitReport(t, job.ID)
+	assert.False(t, r.Running)
+	assert.Equal(t, "action:work", r.Target)
+	assert.Greater(t, r.Requests, int64(10))
+	// Requests cut short by the end of the test are not counted.
+	assert.LessOrEqual(t, s.calls.Load()-r.Requests, int64(4))
+	assert.Equal(t, r.Errors, r.ErrorCodes[scenario.CodeDemonstrated])
+	assert.InDelta(t, 1.0/3, r.ErrorRate, 0.05)
+	assert.Greater(t, r.Throughput, 0.0)
+	assert.GreaterOrEqual(t, r.Latency.P99Ms, r.Latency.P50Ms)
+	assert.Zero(t, r.InFlight)
+
+	j, _ := jobs.Get(job.ID)
+	assert.Equal(t, jobs.StatusSucceeded, j.Status)
+	assert.IsType(t, Report{}, j.Result)
+}
+
+func TestRateLoad(t *testing.T) {
+	s := &busyScenario{}
+	job, err := Start(s, Spec{Workload: "read", QPS: 100, Duration: runbook.Duration(time.Second), RampUp: runbook.Duration(200 * time.Millisecond)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	r := waitReport(t, job.ID)
+	assert.Equal(t, "workload:read", r.Target)
+	// 100 QPS for a second, minus about half of the ramp-up.
+	assert.InDelta(t, 90, r.Requests, 15)
+	assert.Zero(t, r.Errors)
+	assert.Len(t, r.Series, 1)
+}
+
+func TestTokenTime(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, tokenTime(1, 10, 0))
+	// 10 QPS ramped over 2s: 10 requests are due by the end of the ramp-up, 20 a second later.
+	assert.Equal(t, 2*time.Second, tokenTime(10, 10, 2*time.Second))
+	assert.Equal(t, 3*time.Second, tokenTime(20, 10, 2*time.Second))
+}
+
+func TestCancelLoad(t *testing.T) {
+	s := &busyScenario{}
+	job, err := Start(s, Spec{Workload: "read", Concurrency: 2, Duration: runbook.Duration(time.Minute)})
+	if !assert.NoError(t, err) {
+		return
+	}
+	time.Sleep(50 * time.Millisecond)
+	_, err = jobs.Cancel(job.ID)
+	assert.NoError(t, err)
+
+	r := waitReport(t, job.ID)
+	assert.False(t, r.Running)
+	j, _ := jobs.Get(job.ID)
+	assert.Equal(t, jobs.StatusCancelled, j.Status)
+}
+
+func TestStartRejectsInvalidSpecs(t *testing.T) {
+	s := &busyScenario{}
+	second := runbook.Duration(time.Second)
+	for _, spec := range []Spec{
+		{Duration: second},
+		{Action: "work", Workload: "read", Duration: second},
+		{Action: "work"},
+		{Action: "work", Duration: runbook.Duration(time.Hour)},
+		{Action: "work", Duration: second, RampUp: runbook.Duration(2 * time.Second)},
+		{Action: "work", Duration: second, Concurrency: MaxConcurrency + 1},
+	} {
+		_, err := Start(s, spec)
+		assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err), "%+v", spec)
+	}
+
+	_, err := Start(s, Spec{Workload: "missing", Duration: second})
+	assert.Equal(t, scenario.CodeNotFound, scenario.CodeOf(err))
+	_, err = Start(s, Spec{Action: "missing", Duration: second})
+	assert.Equal(t, scenario.CodeNotFound, scenario.CodeOf(err))
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram()
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	l := h.summary()
+	assert.Equal(t, 1.0, l.MinMs)
+	assert.Equal(t, 100.0, l.MaxMs)
+	assert.InDelta(t, 50.5, l.MeanMs, 0.01)
+	assert.InDelta(t, 50, l.P50Ms, 1)
+	assert.InDelta(t, 99, l.P99Ms, 2)
+	assert.Equal(t, int64(1), l.Buckets[0].Count)
+	assert.Zero(t, l.Buckets[len(l.Buckets)-1].UpperMs, "the last bucket is unbounded")
+}
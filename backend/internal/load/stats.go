@@ -0,0 +1,239 @@
+package load
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+)
+
+// latencyBounds are the upper bounds, in milliseconds, of the latency histogram
+// buckets. Slower requests fall into a final, unbounded bucket.
+var latencyBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000}
+
+// Report is a point-in-time view of a load test. It is streamed while the test runs
+// and is the result of its job once it has finished.
+type Report struct {
+	ID         string    `json:"id"` // the job ID
+	ScenarioID string    `json:"scenario_id"`
+	Target     string    `json:"target"` // "action:<id>" or "workload:<id>"
+	Spec       Spec      `json:"spec"`
+	Running    bool      `json:"running"`
+	StartedAt  time.Time `json:"started_at"`
+	ElapsedMs  int64     `json:"elapsed_ms"`
+	// Requests counts the completed requests, Errors those that returned an error.
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	// Throughput is the number of completed requests per second since the start.
+	Throughput float64 `json:"throughput"`
+	InFlight   int64   `json:"in_flight"`
+	// ErrorCodes counts the errors by code; "demonstrated" errors are the scenario
+	// showing its problem under load.
+	ErrorCodes map[scenario.ErrorCode]int64 `json:"error_codes"`
+	Latency    Latency                      `json:"latency"`
+	// Series holds one point per elapsed second.
+	Series []Point `json:"series"`
+}
+
+// Latency summarizes the latency histogram. Percentiles are interpolated within
+// their bucket, so they are estimates.
+type Latency struct {
+	MinMs   float64  `json:"min_ms"`
+	MeanMs  float64  `json:"mean_ms"`
+	P50Ms   float64  `json:"p50_ms"`
+	P90Ms   float64  `json:"p90_ms"`
+	P99Ms   float64  `json:"p99_ms"`
+	MaxMs   float64  `json:"max_ms"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Bucket counts the requests that took at most UpperMs and more than the bound of the
+// previous bucket. The last bucket has no upper bound.
+type Bucket struct {
+	UpperMs float64 `json:"upper_ms,omitempty"`
+	Count   int64   `json:"count"`
+}
+
+// Point is the activity during one second of a load test.
+type Point struct {
+	Second   int     `json:"second"`
+	Requests int64   `json:"requests"`
+	Errors   int64   `json:"errors"`
+	P99Ms    float64 `json:"p99_ms"`
+	InFlight int64   `json:"in_flight"`
+}
+
+// histogram records request latencies in the latencyBounds buckets.
+type histogram struct {
+	counts   []int64
+	n        int64
+	sum      time.Duration
+	min, max time.Duration
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int64, len(latencyBounds)+1)}
+}
+
+func (h *histogram) record(d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	i := 0
+	for i < len(latencyBounds) && ms > latencyBounds[i] {
+		i++
+	}
+	h.counts[i]++
+	if h.n == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.n++
+	h.sum += d
+}
+
+// percentile estimates the latency in milliseconds below which a fraction q of the requests fall.
+func (h *histogram) percentile(q float64) float64 {
+	if h.n == 0 {
+		return 0
+	}
+	rank := q * float64(h.n)
+	var seen float64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		if seen+float64(c) >= rank {
+			if i == len(latencyBounds) {
+				return millis(h.max)
+			}
+			lower := 0.0
+			if i > 0 {
+				lower = latencyBounds[i-1]
+			}
+			v := lower + (latencyBounds[i]-lower)*(rank-seen)/float64(c)
+			return math.Min(math.Max(v, millis(h.min)), millis(h.max))
+		}
+		seen += float64(c)
+	}
+	return millis(h.max)
+}
+
+func (h *histogram) summary() Latency {
+	l := Latency{
+		P50Ms:   h.percentile(0.5),
+		P90Ms:   h.percentile(0.9),
+		P99Ms:   h.percentile(0.99),
+		MinMs:   millis(h.min),
+		MaxMs:   millis(h.max),
+		Buckets: make([]Bucket, len(h.counts)),
+	}
+	if h.n > 0 {
+		l.MeanMs = millis(h.sum / time.Duration(h.n))
+	}
+	for i, c := range h.counts {
+		l.Buckets[i].Count = c
+		if i < len(latencyBounds) {
+			l.Buckets[i].UpperMs = latencyBounds[i]
+		}
+	}
+	return l
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// stats aggregates the requests of a load test.
+type stats struct {
+	mu       sync.Mutex
+	report   Report
+	total    *histogram
+	current  *histogram // latencies of the current second
+	requests int64      // requests at the start of the current second
+	errors   int64      // errors at the start of the current second
+	finished time.Time
+}
+
+func newStats(report Report) *stats {
+	report.ErrorCodes = make(map[scenario.ErrorCode]int64)
+	report.Series = []Point{}
+	return &stats{report: report, total: newHistogram(), current: newHistogram()}
+}
+
+func (s *stats) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.InFlight++
+}
+
+func (s *stats) end(d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.InFlight--
+	s.report.Requests++
+	if err != nil {
+		s.report.Errors++
+		s.report.ErrorCodes[scenario.CodeOf(err)]++
+	}
+	s.total.record(d)
+	s.current.record(d)
+}
+
+// abort undoes begin for a request that was cut short by the end of the test.
+func (s *stats) abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.InFlight--
+}
+
+// tick closes the current second and appends it to the series.
+func (s *stats) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.Series = append(s.report.Series, Point{
+		Second:   len(s.report.Series) + 1,
+		Requests: s.report.Requests - s.requests,
+		Errors:   s.report.Errors - s.errors,
+		P99Ms:    s.current.percentile(0.99),
+		InFlight: s.report.InFlight,
+	})
+	s.requests, s.errors = s.report.Requests, s.report.Errors
+	s.current = newHistogram()
+}
+
+func (s *stats) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.Running = false
+	s.finished = time.Now()
+}
+
+// snapshot returns the current report.
+func (s *stats) snapshot() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.report
+	end := time.Now()
+	if !s.report.Running {
+		end = s.finished
+	}
+	elapsed := end.Sub(r.StartedAt)
+	r.ElapsedMs = elapsed.Milliseconds()
+	if r.Requests > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Requests)
+	}
+	if elapsed > 0 {
+		r.Throughput = float64(r.Requests) / elapsed.Seconds()
+	}
+	r.Latency = s.total.summary()
+	r.ErrorCodes = make(map[scenario.ErrorCode]int64, len(s.report.ErrorCodes))
+	for code, n := range s.report.ErrorCodes {
+		r.ErrorCodes[code] = n
+	}
+	r.Series = append([]Point(nil), s.report.Series...)
+	return r
+}
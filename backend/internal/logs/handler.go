@@ -0,0 +1,126 @@
+package logs
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// handler is the slog.Handler behind For. It records into the scenario's ring buffer
+// and forwards to slog.Default() for the process log.
+type handler struct {
+	scenario string
+	attrs    []slog.Attr // attributes added with WithAttrs, keys already qualified by group
+	group    string      // dot-separated group prefix for attributes added later
+}
+
+// Enabled always returns true: the buffer keeps debug output even when the process log does not.
+func (h *handler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	e := Entry{
+		Time:     r.Time,
+		Level:    r.Level.String(),
+		Message:  r.Message,
+		Scenario: h.scenario,
+		level:    r.Level,
+	}
+	for _, a := range h.attrs {
+		e.set(a.Key, a.Value)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.flatten(h.group, a, e.set)
+		return true
+	})
+	if e.TraceID == "" {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			e.TraceID = sc.TraceID().String()
+		}
+	}
+	bufferFor(h.scenario).add(e)
+
+	next := slog.Default().Handler()
+	if !next.Enabled(ctx, r.Level) {
+		return nil
+	}
+	attrs := append([]slog.Attr{slog.String("scenario", h.scenario)}, h.attrs...)
+	if h.group != "" {
+		// Attributes of the record are flattened the same way as in the buffer.
+		forwarded := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			h.flatten(h.group, a, func(key string, v slog.Value) { forwarded.AddAttrs(slog.Attr{Key: key, Value: v}) })
+			return true
+		})
+		r = forwarded
+	}
+	return next.WithAttrs(attrs).Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]slog.Attr{}, h.attrs...)
+	for _, a := range attrs {
+		h.flatten(h.group, a, func(key string, v slog.Value) {
+			clone.attrs = append(clone.attrs, slog.Attr{Key: key, Value: v})
+		})
+	}
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.group = qualify(h.group, name)
+	return &clone
+}
+
+// flatten resolves a and calls emit for each leaf attribute, qualifying keys with groups.
+func (h *handler) flatten(prefix string, a slog.Attr, emit func(key string, v slog.Value)) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, child := range v.Group() {
+			h.flatten(qualify(prefix, a.Key), child, emit)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	emit(qualify(prefix, a.Key), v)
+}
+
+func qualify(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// set stores an attribute, lifting the well-known correlation fields out of Fields.
+func (e *Entry) set(key string, v slog.Value) {
+	switch key {
+	case "scenario":
+		// Fixed by the handler.
+	case "action":
+		e.Action = v.String()
+	case "session":
+		e.Session = v.String()
+	case "trace_id":
+		e.TraceID = v.String()
+	default:
+		if e.Fields == nil {
+			e.Fields = make(map[string]interface{})
+		}
+		val := v.Any()
+		// Errors have no exported fields and would encode as {}.
+		if err, ok := val.(error); ok {
+			val = err.Error()
+		}
+		e.Fields[key] = val
+	}
+}
@@ -0,0 +1,184 @@
+// Package logs keeps the structured log output of each scenario in an in-memory ring
+// buffer, so that it can be queried and tailed through the API. Scenarios log through
+// the *slog.Logger returned by For, or the one carried in the action context
+// (see scenario.Logger); every record is also forwarded to slog.Default().
+package logs
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of entries kept per scenario.
+const DefaultCapacity = 1000
+
+// Entry is one structured log record of a scenario.
+type Entry struct {
+	Seq      uint64                 `json:"seq"` // increasing per scenario
+	Time     time.Time              `json:"time"`
+	Level    string                 `json:"level"` // DEBUG, INFO, WARN or ERROR
+	Message  string                 `json:"message"`
+	Scenario string                 `json:"scenario"`
+	Action   string                 `json:"action,omitempty"`
+	Session  string                 `json:"session,omitempty"`
+	TraceID  string                 `json:"trace_id,omitempty"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+
+	level slog.Level
+}
+
+// Filter selects entries in Query and Subscribe. The zero value matches everything.
+type Filter struct {
+	MinLevel slog.Leveler // nil matches every level
+	Since    time.Time
+	Until    time.Time
+	Action   string
+	// Limit keeps only the newest Limit matches; 0 means no limit.
+	Limit int
+}
+
+// Match reports whether e passes the filter (ignoring Limit).
+func (f Filter) Match(e Entry) bool {
+	return (f.MinLevel == nil || e.level >= f.MinLevel.Level()) &&
+		(f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until)) &&
+		(f.Action == "" || e.Action == f.Action)
+}
+
+var (
+	// buffers holds the ring buffer of each scenario, created on first use.
+	buffers  = make(map[string]*ring)
+	capacity = DefaultCapacity
+	lock     = &sync.RWMutex{}
+)
+
+// For returns the logger of a scenario. Its records are stored in the scenario's buffer
+// with the "scenario" field set.
+func For(scenarioID string) *slog.Logger {
+	return slog.New(&handler{scenario: scenarioID})
+}
+
+// Query returns the buffered entries of a scenario matching f, oldest first.
+func Query(scenarioID string, f Filter) []Entry {
+	return bufferFor(scenarioID).query(f)
+}
+
+// Subscribe delivers every new entry of a scenario matching f until cancel is called.
+// Entries are dropped rather than blocking the logger when the subscriber falls behind.
+func Subscribe(scenarioID string, f Filter) (entries <-chan Entry, cancel func()) {
+	return bufferFor(scenarioID).subscribe(f)
+}
+
+// Reset drops all buffered entries and sets the per-scenario capacity. It is used by tests.
+func Reset(n int) {
+	lock.Lock()
+	defer lock.Unlock()
+	buffers = make(map[string]*ring)
+	capacity = n
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error" (case-insensitive).
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return l, nil
+}
+
+func bufferFor(scenarioID string) *ring {
+	lock.RLock()
+	r, ok := buffers[scenarioID]
+	lock.RUnlock()
+	if ok {
+		return r
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if r, ok = buffers[scenarioID]; !ok {
+		r = newRing(capacity)
+		buffers[scenarioID] = r
+	}
+	return r
+}
+
+// ring is a fixed-size buffer of the newest entries of one scenario.
+type ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int // index of the slot written next
+	full    bool
+	seq     uint64
+	subs    map[chan Entry]Filter
+}
+
+func newRing(n int) *ring {
+	return &ring{entries: make([]Entry, n), subs: make(map[chan Entry]Filter)}
+}
+
+func (r *ring) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	e.Seq = r.seq
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+
+	for ch, f := range r.subs {
+		if !f.Match(e) {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// ordered returns the buffered entries, oldest first. The caller must hold r.mu.
+func (r *ring) ordered() []Entry {
+	if !r.full {
+		return r.entries[:r.next]
+	}
+	return append(append([]Entry{}, r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+func (r *ring) query(f Filter) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := []Entry{}
+	for _, e := range r.ordered() {
+		if f.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if f.Limit > 0 && len(matched) > f.Limit {
+		matched = matched[len(matched)-f.Limit:]
+	}
+	return matched
+}
+
+func (r *ring) subscribe(f Filter) (<-chan Entry, func()) {
+	ch := make(chan Entry, 256)
+	r.mu.Lock()
+	r.subs[ch] = f
+	r.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.subs, ch)
+			r.mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package logs
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingKeepsNewestEntries(t *testing.T) {
+	Reset(3)
+	defer Reset(DefaultCapacity)
+
+	logger := For("ring")
+	for _, msg := range []string{"one", "two", "three", "four"} {
+		logger.Info(msg)
+	}
+
+	entries := Query("ring", Filter{})
+	var messages []string
+	for _, e := range entries {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{"two", "three", "four"}, messages)
+	assert.Equal(t, uint64(4), entries[2].Seq)
+	assert.Empty(t, Query("other", Filter{}))
+}
+
+func TestQueryFilter(t *testing.T) {
+	Reset(DefaultCapacity)
+
+	start := time.Now()
+	logger := For("filter")
+	logger.Debug("debug")
+	logger.With("action", "update").Warn("warn", "error", errors.New("boom"))
+	logger.Error("error")
+
+	assert.Len(t, Query("filter", Filter{}), 3)
+	assert.Len(t, Query("filter", Filter{MinLevel: slog.LevelWarn}), 2)
+	assert.Len(t, Query("filter", Filter{Since: start.Add(-time.Second), Until: start.Add(time.Second)}), 3)
+	assert.Empty(t, Query("filter", Filter{Since: time.Now().Add(time.Second)}))
+	assert.Equal(t, "error", Query("filter", Filter{Limit: 1})[0].Message)
+
+	updates := Query("filter", Filter{Action: "update"})
+	if assert.Len(t, updates, 1) {
+		assert.Equal(t, "WARN", updates[0].Level)
+		assert.Equal(t, "filter", updates[0].Scenario)
+		assert.Equal(t, "boom", updates[0].Fields["error"])
+	}
+}
+
+func TestGroupsAreFlattened(t *testing.T) {
+	Reset(DefaultCapacity)
+
+	For("groups").WithGroup("cache").With("tier", "redis").Info("hit", slog.Group("key", "id", 1), "session", "s1")
+
+	e := Query("groups", Filter{})[0]
+	assert.Equal(t, "redis", e.Fields["cache.tier"])
+	assert.Equal(t, int64(1), e.Fields["cache.key.id"])
+	// Correlation fields are only lifted at the top level.
+	assert.Equal(t, "s1", e.Fields["cache.session"])
+	assert.Empty(t, e.Session)
+}
+
+func TestSubscribe(t *testing.T) {
+	Reset(DefaultCapacity)
+
+	entries, cancel := Subscribe("sub", Filter{MinLevel: slog.LevelInfo})
+	logger := For("sub")
+	logger.Debug("skipped")
+	logger.Info("delivered")
+
+	select {
+	case e := <-entries:
+		assert.Equal(t, "delivered", e.Message)
+	case <-time.After(time.Second):
+		t.Fatal("no entry delivered")
+	}
+
+	cancel()
+	logger.Info("after cancel")
+	select {
+	case e := <-entries:
+		t.Fatalf("unexpected entry %q", e.Message)
+	default:
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, l)
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
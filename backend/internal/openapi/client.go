@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// ClientConfig configures GenerateClient.
+type ClientConfig struct {
+	Package string   // package name of the generated file
+	Imports []string // import paths needed by the type expressions returned by GoType
+	// GoType returns the Go type expression for a request or response type, e.g. "apitypes.Job".
+	GoType func(t reflect.Type) (string, error)
+}
+
+// GenerateClient renders a Go source file with one method on *Client per operation.
+// Streaming operations are skipped; they need a hand-written method.
+// The file relies on a hand-written Client type in the same package providing:
+//
+//	func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error
+func GenerateClient(cfg ClientConfig, ops []Operation) ([]byte, error) {
+	data := clientData{Package: cfg.Package, Imports: cfg.Imports}
+	for _, op := range ops {
+		if op.Streaming {
+			continue
+		}
+		m := clientMethod{
+			Name:    exportedName(op.ID),
+			Summary: op.Summary,
+			Route:   op.Method + " " + op.Path,
+			Method:  methodConst(op.Method),
+			Path:    pathExpr(op.Path),
+		}
+		for _, p := range op.PathParams() {
+			m.PathArgs = append(m.PathArgs, goIdent(p))
+			data.NeedURL = true
+		}
+		if op.Request != nil {
+			typ, err := cfg.GoType(reflect.TypeOf(op.Request))
+			if err != nil {
+				return nil, fmt.Errorf("%s: request: %w", op.ID, err)
+			}
+			m.Request = typ
+		}
+		typ, err := cfg.GoType(reflect.TypeOf(op.Response))
+		if err != nil {
+			return nil, fmt.Errorf("%s: response: %w", op.ID, err)
+		}
+		m.Response = typ
+		for _, q := range op.Query {
+			m.Query = append(m.Query, clientQuery{
+				Name:        q.Name,
+				Field:       exportedName(q.Name),
+				Type:        queryGoType(q.Type),
+				Description: q.Description,
+			})
+			data.NeedURL = true
+			data.NeedStrconv = data.NeedStrconv || q.Type == "integer"
+		}
+		data.Methods = append(data.Methods, m)
+	}
+
+	var buf bytes.Buffer
+	if err := clientTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated client: %w\n%s", err, buf.Bytes())
+	}
+	return src, nil
+}
+
+type clientData struct {
+	Package     string
+	Imports     []string
+	Methods     []clientMethod
+	NeedURL     bool // path or query parameters are used
+	NeedStrconv bool // integer query parameters are used
+}
+
+type clientMethod struct {
+	Name     string
+	Summary  string
+	Route    string
+	Method   string
+	Path     string
+	PathArgs []string
+	Request  string
+	Response string
+	Query    []clientQuery
+}
+
+type clientQuery struct {
+	Name        string
+	Field       string
+	Type        string
+	Description string
+}
+
+// HasQuery reports whether the method takes a query parameter struct.
+func (m clientMethod) HasQuery() bool { return len(m.Query) > 0 }
+
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by cmd/apigen from the API operations; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"net/http"
+{{- if .NeedURL}}
+	"net/url"
+{{- end}}
+{{- if .NeedStrconv}}
+	"strconv"
+{{- end}}
+{{range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+
+{{range .Methods}}{{if .HasQuery}}
+// {{.Name}}Params holds the optional query parameters of {{.Name}}.
+type {{.Name}}Params struct {
+{{- range .Query}}
+	// {{.Field}} maps to ?{{.Name}}. {{.Description}}
+	{{.Field}} {{.Type}}
+{{- end}}
+}
+
+func (p *{{.Name}}Params) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+{{- range .Query}}
+{{- if eq .Type "string"}}
+	if p.{{.Field}} != "" {
+		q.Set("{{.Name}}", p.{{.Field}})
+	}
+{{- else if eq .Type "int"}}
+	if p.{{.Field}} != 0 {
+		q.Set("{{.Name}}", strconv.Itoa(p.{{.Field}}))
+	}
+{{- else}}
+	if p.{{.Field}} {
+		q.Set("{{.Name}}", "true")
+	}
+{{- end}}
+{{- end}}
+	return q
+}
+{{end}}
+// {{.Name}} calls {{.Route}}.
+// {{.Summary}}
+func (c *Client) {{.Name}}(ctx context.Context{{range .PathArgs}}, {{.}} string{{end}}{{if .Request}}, body {{.Request}}{{end}}{{if .HasQuery}}, params *{{.Name}}Params{{end}}) ({{.Response}}, error) {
+	var out {{.Response}}
+	err := c.do(ctx, {{.Method}}, {{.Path}}, {{if .HasQuery}}params.values(){{else}}nil{{end}}, {{if .Request}}body{{else}}nil{{end}}, &out)
+	return out, err
+}
+{{end}}`))
+
+// exportedName converts an operation ID or snake_case name to an exported Go name:
+// listScenarios -> ListScenarios, page_size -> PageSize.
+func exportedName(s string) string {
+	ident := goIdent(s)
+	if ident == "" {
+		return ""
+	}
+	r := []rune(ident)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// goIdent converts a snake_case name to a lowerCamelCase Go identifier; "id" becomes "id"
+// and "action_id" becomes "actionID".
+func goIdent(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if i == 0 {
+			continue
+		}
+		if p == "id" {
+			parts[i] = "ID"
+			continue
+		}
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// pathExpr renders a gin-style path as a Go string expression with escaped path params.
+func pathExpr(path string) string {
+	var parts []string
+	var lit strings.Builder
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		lit.WriteString("/")
+		if strings.HasPrefix(seg, ":") {
+			parts = append(parts, fmt.Sprintf("%q", lit.String()), "url.PathEscape("+goIdent(seg[1:])+")")
+			lit.Reset()
+			continue
+		}
+		lit.WriteString(seg)
+	}
+	if lit.Len() > 0 {
+		parts = append(parts, fmt.Sprintf("%q", lit.String()))
+	}
+	return strings.Join(parts, " + ")
+}
+
+func methodConst(method string) string {
+	return "http.Method" + string(method[0]) + strings.ToLower(method[1:])
+}
+
+func queryGoType(typ string) string {
+	switch typ {
+	case "integer":
+		return "int"
+	case "boolean":
+		return "bool"
+	default:
+		return "string"
+	}
+}
@@ -0,0 +1,236 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation describes one API endpoint in Go terms. The OpenAPI document, the request
+// validation and the generated client are all derived from a list of operations.
+type Operation struct {
+	ID          string // operationId, also the generated client method name
+	Method      string // e.g. http.MethodGet
+	Path        string // gin-style path, e.g. /api/scenarios/:id
+	Summary     string
+	Description string
+	Tag         string
+	Query       []Param
+	// Request is a value of the request body type, or nil if the operation takes no body.
+	Request interface{}
+	// RequestContentTypes lists the accepted body media types; it defaults to application/json.
+	RequestContentTypes []string
+	// Response is a value of the success response body type. For streaming operations
+	// it is the type of each event.
+	Response interface{}
+	// Streaming marks a server-sent events endpoint: the response is text/event-stream
+	// and the operation is left out of the generated client.
+	Streaming bool
+	// Status is the success status code; it defaults to 200.
+	Status int
+	// Errors lists the error statuses the operation may return; their body is ErrorResponse.
+	Errors []int
+}
+
+// Param describes a query parameter.
+type Param struct {
+	Name        string
+	Type        string // "string", "integer" or "boolean"
+	Description string
+	Required    bool
+}
+
+// PathParams returns the names of the path parameters, in order.
+func (op Operation) PathParams() []string {
+	var names []string
+	for _, seg := range strings.Split(op.Path, "/") {
+		if strings.HasPrefix(seg, ":") {
+			names = append(names, seg[1:])
+		}
+	}
+	return names
+}
+
+// SuccessStatus returns the success status code of the operation.
+func (op Operation) SuccessStatus() int {
+	if op.Status == 0 {
+		return http.StatusOK
+	}
+	return op.Status
+}
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// Info is the OpenAPI info object.
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*OperationObject
+
+// OperationObject is the OpenAPI operation object.
+type OperationObject struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []ParameterObject    `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// ParameterObject is the OpenAPI parameter object.
+type ParameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the OpenAPI request body object.
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response is the OpenAPI response object.
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the OpenAPI media type object.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+var pathParamRe = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// OpenAPIPath converts a gin-style path (/scenarios/:id) into an OpenAPI path (/scenarios/{id}).
+func OpenAPIPath(path string) string {
+	return pathParamRe.ReplaceAllString(path, "{$1}")
+}
+
+// Build generates the document for ops. errorType is the body of every error response;
+// names overrides the component names of Go types (by default the Go type name is used).
+func Build(info Info, ops []Operation, errorType interface{}, names map[reflect.Type]string) *Document {
+	g := newSchemas(names)
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+	}
+	errSchema := g.For(reflect.TypeOf(errorType))
+
+	for _, op := range ops {
+		obj := &OperationObject{
+			OperationID: op.ID,
+			Summary:     op.Summary,
+			Description: op.Description,
+			Responses:   make(map[string]*Response),
+		}
+		if op.Tag != "" {
+			obj.Tags = []string{op.Tag}
+		}
+		for _, name := range op.PathParams() {
+			obj.Parameters = append(obj.Parameters, ParameterObject{
+				Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+		for _, q := range op.Query {
+			obj.Parameters = append(obj.Parameters, ParameterObject{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: q.Type},
+			})
+		}
+		if op.Request != nil {
+			types := op.RequestContentTypes
+			if len(types) == 0 {
+				types = []string{"application/json"}
+			}
+			schema := g.For(reflect.TypeOf(op.Request))
+			obj.RequestBody = &RequestBody{Content: make(map[string]*MediaType)}
+			for _, ct := range types {
+				obj.RequestBody.Content[ct] = &MediaType{Schema: schema}
+			}
+		}
+
+		status := op.SuccessStatus()
+		contentType := "application/json"
+		if op.Streaming {
+			contentType = "text/event-stream"
+		}
+		obj.Responses[strconv.Itoa(status)] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]*MediaType{contentType: {Schema: g.For(reflect.TypeOf(op.Response))}},
+		}
+		for _, code := range op.Errors {
+			obj.Responses[strconv.Itoa(code)] = &Response{
+				Description: http.StatusText(code),
+				Content:     map[string]*MediaType{"application/json": {Schema: errSchema}},
+			}
+		}
+
+		path := OpenAPIPath(op.Path)
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(op.Method)] = obj
+	}
+
+	doc.Components.Schemas = g.components
+	return doc
+}
+
+// RequestSchema returns the request body schema of the operation with the given ID.
+func (d *Document) RequestSchema(operationID string) (*Schema, bool) {
+	for _, item := range d.Paths {
+		for _, op := range *item {
+			if op.OperationID == operationID && op.RequestBody != nil {
+				for _, mt := range op.RequestBody.Content {
+					return mt.Schema, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// Resolve follows a $ref to its component schema.
+func (d *Document) Resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// OperationIDs returns all operation IDs in sorted order.
+func (d *Document) OperationIDs() []string {
+	var ids []string
+	for _, item := range d.Paths {
+		for _, op := range *item {
+			ids = append(ids, op.OperationID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
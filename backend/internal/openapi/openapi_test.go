@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	Name  string     `json:"name"`
+	Count int        `json:"count,omitempty"`
+	Tags  []string   `json:"tags"`
+	When  *time.Time `json:"when"`
+}
+
+type page struct {
+	Items []item `json:"items"`
+	Total int64  `json:"total"`
+}
+
+func testDoc() *Document {
+	return Build(Info{Title: "test", Version: "1"}, []Operation{
+		{ID: "createItem", Method: http.MethodPost, Path: "/items/:id", Request: item{}, Response: page{}, Errors: []int{http.StatusBadRequest}},
+		{ID: "watchItems", Method: http.MethodGet, Path: "/items/watch", Response: item{}, Streaming: true},
+	}, struct {
+		Message string `json:"message"`
+	}{}, nil)
+}
+
+func TestBuild(t *testing.T) {
+	doc := testDoc()
+
+	op := (*doc.Paths["/items/{id}"])["post"]
+	assert.Equal(t, "createItem", op.OperationID)
+	assert.Equal(t, "id", op.Parameters[0].Name)
+	assert.Contains(t, op.Responses, "200")
+	assert.Contains(t, op.Responses, "400")
+
+	s := doc.Components.Schemas["item"]
+	assert.Equal(t, "object", s.Type)
+	assert.ElementsMatch(t, []string{"name", "tags"}, s.Required)
+	assert.Equal(t, "date-time", s.Properties["when"].Format)
+	assert.True(t, s.Properties["when"].Nullable)
+	assert.Equal(t, "#/components/schemas/item", doc.Components.Schemas["page"].Properties["items"].Items.Ref)
+
+	watch := (*doc.Paths["/items/watch"])["get"]
+	assert.Contains(t, watch.Responses["200"].Content, "text/event-stream")
+}
+
+func TestValidate(t *testing.T) {
+	doc := testDoc()
+	schema, ok := doc.RequestSchema("createItem")
+	assert.True(t, ok)
+
+	assert.Nil(t, doc.Validate(schema, map[string]interface{}{"name": "a", "tags": []interface{}{"x"}, "count": 2.0}))
+
+	err := doc.Validate(schema, map[string]interface{}{"tags": []interface{}{}})
+	assert.Equal(t, "name: is required", err.Error())
+
+	err = doc.Validate(schema, map[string]interface{}{"name": "a", "tags": []interface{}{1.0}})
+	assert.Equal(t, "tags.0: must be a string", err.Error())
+
+	err = doc.Validate(schema, map[string]interface{}{"name": "a", "tags": nil, "count": 1.5})
+	assert.Equal(t, "count: must be an integer", err.Error())
+}
+
+func TestValidateQuery(t *testing.T) {
+	assert.Nil(t, ValidateQuery(Param{Name: "page", Type: "integer"}, "2"))
+	assert.NotNil(t, ValidateQuery(Param{Name: "page", Type: "integer"}, "two"))
+	assert.NotNil(t, ValidateQuery(Param{Name: "async", Type: "boolean"}, "maybe"))
+}
+
+func TestPathExpr(t *testing.T) {
+	assert.Equal(t, `"/api/scenarios/" + url.PathEscape(id) + "/actions/" + url.PathEscape(actionID)`,
+		pathExpr("/api/scenarios/:id/actions/:action_id"))
+	assert.Equal(t, `"/api/scenarios"`, pathExpr("/api/scenarios"))
+}
@@ -0,0 +1,139 @@
+package openapi
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the OpenAPI 3 schema object used by the playground.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemas builds schemas from Go types, collecting named struct types as reusable components.
+type schemas struct {
+	names      map[reflect.Type]string
+	components map[string]*Schema
+}
+
+func newSchemas(names map[reflect.Type]string) *schemas {
+	return &schemas{
+		names:      names,
+		components: make(map[string]*Schema),
+	}
+}
+
+// Name returns the component name of a named type: the configured override, or the Go type name.
+func (g *schemas) Name(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	return t.Name()
+}
+
+// For returns the schema of t. Named struct types are emitted once as components and referenced.
+func (g *schemas) For(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		s := g.For(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.Kind() == reflect.Int64 || t.Kind() == reflect.Uint64 {
+			return &Schema{Type: "integer", Format: "int64"}
+		}
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// encoding/json writes a nil slice or map as null.
+		return &Schema{Type: "array", Items: g.For(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.For(t.Elem()), Nullable: true}
+	case reflect.Interface:
+		// Any JSON value.
+		return &Schema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := g.Name(t)
+		if _, ok := g.components[name]; !ok {
+			// Reserve the name first so that recursive types terminate.
+			g.components[name] = &Schema{}
+			*g.components[name] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	default:
+		return &Schema{}
+	}
+}
+
+// structSchema describes the JSON encoding of a struct, following encoding/json's rules
+// for field names, omitempty and embedded structs.
+func (g *schemas) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(f.Type)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.For(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr && f.Type.Kind() != reflect.Interface {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
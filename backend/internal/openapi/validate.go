@@ -0,0 +1,146 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// ValidationError reports the first value that does not match its schema.
+type ValidationError struct {
+	Field  string // dot-separated path, empty for the root value
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into interface{})
+// against schema. $refs are resolved through d, which may be nil for self-contained schemas.
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate(schema, value, "")
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, field string) error {
+	if d != nil {
+		schema = d.Resolve(schema)
+	}
+	if schema == nil {
+		return nil
+	}
+	if value == nil {
+		if schema.Type == "" || schema.Nullable {
+			return nil
+		}
+		return &ValidationError{Field: field, Reason: "must not be null"}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(schema.Enum, value) {
+		return &ValidationError{Field: field, Reason: fmt.Sprintf("must be one of %v", schema.Enum)}
+	}
+
+	switch schema.Type {
+	case "":
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return typeError(field, "a string")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(field, "a boolean")
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return typeError(field, "a number")
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return typeError(field, "an integer")
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError(field, "an array")
+		}
+		for i, item := range items {
+			if err := d.validate(schema.Items, item, join(field, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(field, "an object")
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return &ValidationError{Field: join(field, name), Reason: "is required"}
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := schema.Properties[k]
+			if !ok {
+				prop = schema.AdditionalProperties
+			}
+			if err := d.validate(prop, obj[k], join(field, k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateQuery checks that a raw query parameter value parses as the parameter's type.
+func ValidateQuery(p Param, raw string) error {
+	var err error
+	switch p.Type {
+	case "integer":
+		_, err = strconv.Atoi(raw)
+	case "boolean":
+		_, err = strconv.ParseBool(raw)
+	}
+	if err != nil {
+		return &ValidationError{Field: p.Name, Reason: fmt.Sprintf("must be %s", article(p.Type))}
+	}
+	return nil
+}
+
+func typeError(field, want string) error {
+	return &ValidationError{Field: field, Reason: "must be " + want}
+}
+
+func article(typ string) string {
+	if typ == "integer" {
+		return "an integer"
+	}
+	return "a " + typ
+}
+
+func join(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
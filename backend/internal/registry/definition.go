@@ -0,0 +1,325 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is the content of a scenario definition file. It holds everything the
+// UI shows about a scenario; the behaviour comes from the Handlers it names.
+//
+// A definition is written either as YAML (*.scenario.yaml) or as Markdown
+// (*.scenario.md) whose YAML front matter holds the fields and whose "## Problem"
+// and "## Solution" sections provide the descriptions.
+type Definition struct {
+	ID                  string                        `yaml:"id"`
+	Name                string                        `yaml:"name"`
+	Category            string                        `yaml:"category"`
+	Tags                []string                      `yaml:"tags"`
+	ProblemDescription  string                        `yaml:"problem_description"`
+	SolutionDescription string                        `yaml:"solution_description"`
+	DeepDiveLink        string                        `yaml:"deep_dive_link"`
+	Handlers            string                        `yaml:"handlers"` // name passed to RegisterHandlers
+	Actions             []ActionDefinition            `yaml:"actions"`
+	Dashboard           []scenario.DashboardComponent `yaml:"dashboard"`
+}
+
+// ActionDefinition is an action of a definition bound to a Go handler function.
+type ActionDefinition struct {
+	scenario.Action `yaml:",inline"`
+	// Handler is the key of the function in Handlers.Actions; it defaults to the action ID.
+	Handler string `yaml:"handler"`
+}
+
+// ActionFunc runs one action of a declarative scenario.
+type ActionFunc func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+
+// Handlers is the Go code behind declarative scenarios. A scenario package registers
+// it under a name in init, and definition files bind to it by that name.
+type Handlers struct {
+	Initialize func() error                           // optional, run once however often the definition is reloaded
+	FetchState func() (map[string]interface{}, error) // optional
+	Resources  func() []snapshot.Resource             // optional, enables snapshots
+	// Fork optionally creates an isolated copy of the state the handlers work on and
+	// returns the handlers bound to it; see scenario.Forkable. It enables comparison mode.
+	Fork      func(ctx context.Context, name string) (Handlers, func(ctx context.Context) error, error)
+	Workloads []scenario.Workload // optional, offered to the load generator
+	Actions   map[string]ActionFunc
+}
+
+type registeredHandlers struct {
+	Handlers
+	initOnce sync.Once
+	initErr  error
+}
+
+// handlers holds the registered Handlers by name. It is guarded by lock.
+var handlers = make(map[string]*registeredHandlers)
+
+// RegisterHandlers makes h available to definition files under name.
+// Like Register, it panics if the name is already taken.
+func RegisterHandlers(name string, h Handlers) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, exists := handlers[name]; exists {
+		panic(fmt.Sprintf("handlers '%s' are already registered", name))
+	}
+	handlers[name] = &registeredHandlers{Handlers: h}
+}
+
+// ParseDefinition parses a definition file; the format is chosen by the extension of name.
+func ParseDefinition(name string, data []byte) (*Definition, error) {
+	def := &Definition{}
+	if strings.HasSuffix(name, ".md") {
+		front, body, err := splitFrontMatter(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := yaml.Unmarshal(front, def); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sections := markdownSections(body)
+		if def.ProblemDescription == "" {
+			def.ProblemDescription = sections["problem"]
+		}
+		if def.SolutionDescription == "" {
+			def.SolutionDescription = sections["solution"]
+		}
+	} else if err := yaml.Unmarshal(data, def); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if def.ID == "" || def.Name == "" {
+		return nil, fmt.Errorf("%s: id and name are required", name)
+	}
+	if def.Handlers == "" {
+		return nil, fmt.Errorf("%s: handlers is required", name)
+	}
+	seen := make(map[string]bool, len(def.Actions))
+	for i, a := range def.Actions {
+		if a.ID == "" {
+			return nil, fmt.Errorf("%s: action %d has no id", name, i+1)
+		}
+		if seen[a.ID] {
+			return nil, fmt.Errorf("%s: duplicate action %s", name, a.ID)
+		}
+		seen[a.ID] = true
+		if a.Role != "" {
+			if _, err := auth.ParseRole(a.Role); err != nil {
+				return nil, fmt.Errorf("%s: action %s: %w", name, a.ID, err)
+			}
+		}
+		if a.Handler == "" {
+			def.Actions[i].Handler = a.ID
+		}
+	}
+	return def, nil
+}
+
+// splitFrontMatter separates the YAML front matter, enclosed in "---" lines, from
+// the Markdown body.
+func splitFrontMatter(data []byte) (front, body []byte, err error) {
+	data = bytes.TrimLeft(data, "\ufeff\r\n")
+	const delim = "---"
+	if !bytes.HasPrefix(data, []byte(delim)) {
+		return nil, nil, fmt.Errorf("missing front matter")
+	}
+	rest := data[len(delim):]
+	end := bytes.Index(rest, []byte("\n"+delim))
+	if end < 0 {
+		return nil, nil, fmt.Errorf("unterminated front matter")
+	}
+	body = rest[end+len(delim)+1:]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	return rest[:end], body, nil
+}
+
+// markdownSections returns the text under each "## Heading", keyed by the lower-cased heading.
+func markdownSections(body []byte) map[string]string {
+	sections := make(map[string]string)
+	var heading string
+	var text strings.Builder
+	flush := func() {
+		if heading != "" {
+			sections[heading] = strings.TrimSpace(text.String())
+		}
+		text.Reset()
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, "## ") {
+			flush()
+			heading = strings.ToLower(strings.TrimSpace(line[3:]))
+			continue
+		}
+		text.WriteString(line)
+		text.WriteString("\n")
+	}
+	flush()
+	return sections
+}
+
+// isDefinitionFile reports whether name is a scenario definition file.
+func isDefinitionFile(name string) bool {
+	base := path.Base(name)
+	for _, ext := range []string{".scenario.yaml", ".scenario.yml", ".scenario.md"} {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// declarativeScenario implements scenario.Scenario from a Definition and its Handlers.
+type declarativeScenario struct {
+	def      *Definition
+	handlers *registeredHandlers
+	source   string // file the definition was loaded from
+}
+
+// Ensure declarativeScenario implements the scenario.Scenario, scenario.Tagged,
+// scenario.Forkable, scenario.WorkloadProvider and snapshot.Owner interfaces.
+var (
+	_ scenario.Scenario         = (*declarativeScenario)(nil)
+	_ scenario.Tagged           = (*declarativeScenario)(nil)
+	_ scenario.Forkable         = (*declarativeScenario)(nil)
+	_ scenario.WorkloadProvider = (*declarativeScenario)(nil)
+	_ snapshot.Owner            = (*declarativeScenario)(nil)
+)
+
+// newDeclarativeScenario binds def to its handlers. The caller must hold lock.
+func newDeclarativeScenario(def *Definition, source string) (*declarativeScenario, error) {
+	h, ok := handlers[def.Handlers]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown handlers %q", source, def.Handlers)
+	}
+	for _, a := range def.Actions {
+		if _, ok := h.Actions[a.Handler]; !ok {
+			return nil, fmt.Errorf("%s: action %s: handlers %q have no function %q", source, a.ID, def.Handlers, a.Handler)
+		}
+	}
+	return &declarativeScenario{def: def, handlers: h, source: source}, nil
+}
+
+func (s *declarativeScenario) ID() string                  { return s.def.ID }
+func (s *declarativeScenario) Name() string                { return s.def.Name }
+func (s *declarativeScenario) Category() string            { return s.def.Category }
+func (s *declarativeScenario) ProblemDescription() string  { return s.def.ProblemDescription }
+func (s *declarativeScenario) SolutionDescription() string { return s.def.SolutionDescription }
+func (s *declarativeScenario) DeepDiveLink() string        { return s.def.DeepDiveLink }
+func (s *declarativeScenario) Tags() []string              { return s.def.Tags }
+
+func (s *declarativeScenario) Actions() []scenario.Action {
+	actions := make([]scenario.Action, len(s.def.Actions))
+	for i, a := range s.def.Actions {
+		actions[i] = a.Action
+	}
+	return actions
+}
+
+func (s *declarativeScenario) DashboardComponents() []scenario.DashboardComponent {
+	return s.def.Dashboard
+}
+
+// Initialize runs the Initialize handler the first time any definition bound to the
+// handlers is initialized, so that reloading a definition does not set it up again.
+func (s *declarativeScenario) Initialize() error {
+	h := s.handlers
+	h.initOnce.Do(func() {
+		if h.Initialize != nil {
+			h.initErr = h.Initialize()
+		}
+	})
+	return h.initErr
+}
+
+func (s *declarativeScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	for _, a := range s.def.Actions {
+		if a.ID == actionID {
+			return s.handlers.Actions[a.Handler](ctx, params)
+		}
+	}
+	return nil, scenario.UnknownActionError(actionID)
+}
+
+func (s *declarativeScenario) FetchState() (map[string]interface{}, error) {
+	if s.handlers.FetchState == nil {
+		return map[string]interface{}{}, nil
+	}
+	return s.handlers.FetchState()
+}
+
+// SnapshotResources returns the resources of the handlers; none if they do not support snapshots.
+func (s *declarativeScenario) SnapshotResources() []snapshot.Resource {
+	if s.handlers.Resources == nil {
+		return nil
+	}
+	return s.handlers.Resources()
+}
+
+// Workloads returns the workloads of the handlers.
+func (s *declarativeScenario) Workloads() []scenario.Workload {
+	return s.handlers.Workloads
+}
+
+// Fork binds the definition to the handlers returned by the Fork handler. Scenarios
+// whose handlers have none cannot be forked.
+func (s *declarativeScenario) Fork(ctx context.Context, name string) (scenario.Scenario, func(ctx context.Context) error, error) {
+	if s.handlers.Fork == nil {
+		return nil, nil, scenario.InvalidParamsError("scenario %s cannot be forked", s.def.ID)
+	}
+	h, release, err := s.handlers.Fork(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, a := range s.def.Actions {
+		if _, ok := h.Actions[a.Handler]; !ok {
+			_ = release(ctx)
+			return nil, nil, fmt.Errorf("fork %s of scenario %s has no function %q", name, s.def.ID, a.Handler)
+		}
+	}
+	// The fork starts from existing state, so its Initialize handler is never run.
+	return &declarativeScenario{def: s.def, handlers: &registeredHandlers{Handlers: h}, source: s.source}, release, nil
+}
+
+// readDefinitions parses every definition file in fsys, keyed by path. Files that
+// fail to read or parse are returned in failed instead.
+func readDefinitions(fsys fs.FS) (defs map[string]*Definition, failed map[string]error, err error) {
+	defs = make(map[string]*Definition)
+	failed = make(map[string]error)
+	err = fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isDefinitionFile(p) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			failed[p] = err
+			return nil
+		}
+		def, err := ParseDefinition(p, data)
+		if err != nil {
+			failed[p] = err
+			return nil
+		}
+		defs[p] = def
+		return nil
+	})
+	return defs, failed, err
+}
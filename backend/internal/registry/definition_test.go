@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+const echoDefinition = `
+id: def_echo
+name: Echo
+category: Test
+handlers: def_echo
+actions:
+  - id: echo
+    name: Echo
+    params:
+      - name: msg
+        type: string
+        required: true
+  - id: shout
+    name: Shout
+    handler: upper
+dashboard:
+  - id: calls
+    name: Calls
+    type: key_value
+`
+
+const markdownDefinition = `---
+id: def_markdown
+name: Markdown
+handlers: def_echo
+deep_dive_link: https://example.com
+---
+
+# Markdown
+
+## Problem
+
+Something breaks.
+
+## Solution
+
+Fix it.
+`
+
+var initCalls int
+
+func init() {
+	RegisterHandlers("def_echo", Handlers{
+		Initialize: func() error {
+			initCalls++
+			return nil
+		},
+		Actions: map[string]ActionFunc{
+			"echo":  func(ctx context.Context, params map[string]interface{}) (interface{}, error) { return params["msg"], nil },
+			"upper": func(ctx context.Context, params map[string]interface{}) (interface{}, error) { return "ECHO", nil },
+		},
+	})
+}
+
+func TestParseDefinition(t *testing.T) {
+	def, err := ParseDefinition("echo.scenario.yaml", []byte(echoDefinition))
+	assert.NoError(t, err)
+	assert.Equal(t, "def_echo", def.ID)
+	assert.Equal(t, "echo", def.Actions[0].Handler)
+	assert.Equal(t, "upper", def.Actions[1].Handler)
+	assert.True(t, def.Actions[0].Params[0].Required)
+	assert.Equal(t, "key_value", def.Dashboard[0].Type)
+
+	def, err = ParseDefinition("markdown.scenario.md", []byte(markdownDefinition))
+	assert.NoError(t, err)
+	assert.Equal(t, "Something breaks.", def.ProblemDescription)
+	assert.Equal(t, "Fix it.", def.SolutionDescription)
+	assert.Equal(t, "https://example.com", def.DeepDiveLink)
+
+	_, err = ParseDefinition("bad.scenario.yaml", []byte("name: no id\nhandlers: x\n"))
+	assert.Error(t, err)
+	_, err = ParseDefinition("bad.scenario.md", []byte("no front matter"))
+	assert.Error(t, err)
+}
+
+func TestLoadDefinitions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"echo/echo.scenario.yaml":   {Data: []byte(echoDefinition)},
+		"echo/markdown.scenario.md": {Data: []byte(markdownDefinition)},
+		"echo/notes.md":             {Data: []byte("ignored")},
+	}
+	assert.NoError(t, LoadDefinitions(fsys))
+
+	s, ok := GetScenario("def_echo")
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Len(t, s.Actions(), 2)
+	out, err := s.ExecuteAction(context.Background(), "shout", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "ECHO", out)
+	_, err = s.ExecuteAction(context.Background(), "missing", nil)
+	assert.Error(t, err)
+
+	// Both definitions share the handlers, which are initialized once.
+	assert.NoError(t, s.Initialize())
+	md, _ := GetScenario("def_markdown")
+	assert.NoError(t, md.Initialize())
+	assert.Equal(t, 1, initCalls)
+
+	// A broken file keeps its previous version; a deleted file unregisters its scenario.
+	fsys["echo/echo.scenario.yaml"] = &fstest.MapFile{Data: []byte("id: [")}
+	delete(fsys, "echo/markdown.scenario.md")
+	assert.Error(t, LoadDefinitions(fsys))
+	_, ok = GetScenario("def_echo")
+	assert.True(t, ok)
+	_, ok = GetScenario("def_markdown")
+	assert.False(t, ok)
+
+	// Unknown handlers and IDs that are already taken are rejected.
+	fsys["echo/echo.scenario.yaml"] = &fstest.MapFile{Data: []byte(echoDefinition)}
+	fsys["echo/unbound.scenario.yaml"] = &fstest.MapFile{Data: []byte("id: def_unbound\nname: Unbound\nhandlers: nope\n")}
+	fsys["echo/zz_dup.scenario.yaml"] = &fstest.MapFile{Data: []byte("id: def_echo\nname: Duplicate\nhandlers: def_echo\n")}
+	err = LoadDefinitions(fsys)
+	assert.ErrorContains(t, err, `unknown handlers "nope"`)
+	assert.ErrorContains(t, err, "scenario with ID 'def_echo' is already registered")
+	_, ok = GetScenario("def_unbound")
+	assert.False(t, ok)
+	s, _ = GetScenario("def_echo")
+	assert.Equal(t, "Echo", s.Name())
+}
+
+func TestWatchDefinitions(t *testing.T) {
+	delay := reloadDelay
+	reloadDelay = 10 * time.Millisecond
+	defer func() { reloadDelay = delay }()
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, WatchDefinitions(ctx, dir))
+
+	def := []byte("id: def_watched\nname: Watched\nhandlers: def_echo\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "watched.scenario.yaml"), def, 0o644))
+	assert.Eventually(t, func() bool {
+		_, ok := GetScenario("def_watched")
+		return ok
+	}, 2*time.Second, 10*time.Millisecond)
+
+	renamed := []byte("id: def_watched\nname: Renamed\nhandlers: def_echo\n")
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "watched.scenario.yaml"), renamed, 0o644))
+	assert.Eventually(t, func() bool {
+		s, ok := GetScenario("def_watched")
+		return ok && s.Name() == "Renamed"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, os.Remove(filepath.Join(dir, "watched.scenario.yaml")))
+	assert.Eventually(t, func() bool {
+		_, ok := GetScenario("def_watched")
+		return !ok
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// counterHandlers counts the calls of its action in a map shared by the original and its forks.
+func counterHandlers(counts map[string]int, name string) Handlers {
+	return Handlers{
+		FetchState: func() (map[string]interface{}, error) {
+			return map[string]interface{}{"count": counts[name]}, nil
+		},
+		Fork: func(ctx context.Context, fork string) (Handlers, func(ctx context.Context) error, error) {
+			counts[fork] = counts[name]
+			return counterHandlers(counts, fork), func(ctx context.Context) error {
+				delete(counts, fork)
+				return nil
+			}, nil
+		},
+		Actions: map[string]ActionFunc{
+			"inc": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				counts[name]++
+				return counts[name], nil
+			},
+		},
+	}
+}
+
+var counts = map[string]int{}
+
+func init() {
+	RegisterHandlers("def_counter", counterHandlers(counts, "main"))
+}
+
+func TestForkDefinition(t *testing.T) {
+	fsys := fstest.MapFS{
+		"counter.scenario.yaml": {Data: []byte("id: def_counter\nname: Counter\nhandlers: def_counter\nactions:\n  - id: inc\n")},
+	}
+	assert.NoError(t, LoadDefinitions(fsys))
+	s, _ := GetScenario("def_counter")
+	counts["main"] = 1
+
+	fork, release, err := s.(scenario.Forkable).Fork(context.Background(), "f1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = fork.ExecuteAction(context.Background(), "inc", nil)
+	assert.NoError(t, err)
+	state, _ := fork.FetchState()
+	assert.Equal(t, 2, state["count"])
+	assert.Equal(t, 1, counts["main"], "the fork must not change the original")
+	assert.NoError(t, release(context.Background()))
+	assert.NotContains(t, counts, "f1")
+
+	// Handlers without a Fork function cannot be forked.
+	echo := &declarativeScenario{def: &Definition{ID: "def_echo"}, handlers: handlers["def_echo"]}
+	_, _, err = echo.Fork(context.Background(), "f2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay debounces bursts of file events, e.g. an editor writing a file in several steps.
+var reloadDelay = 200 * time.Millisecond
+
+// definitionSources maps the path of each loaded definition file to the ID of the
+// scenario it registered. It is guarded by lock.
+var definitionSources = make(map[string]string)
+
+// LoadDefinitions registers a scenario for every definition file in fsys, searched
+// recursively. Loading the same fsys again replaces the scenarios of changed files and
+// unregisters those whose file is gone. A file that fails to load keeps its previous
+// version, if any; all failures are returned together.
+func LoadDefinitions(fsys fs.FS) error {
+	_, err := loadDefinitions(fsys)
+	return err
+}
+
+// loadDefinitions implements LoadDefinitions and also returns the scenarios it (re)registered.
+func loadDefinitions(fsys fs.FS) ([]scenario.Scenario, error) {
+	defs, failed, err := readDefinitions(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario definitions: %w", err)
+	}
+	var errs []error
+	for _, err := range failed {
+		errs = append(errs, err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	for p, id := range definitionSources {
+		_, loaded := defs[p]
+		_, broken := failed[p]
+		if !loaded && !broken {
+			delete(scenarios, id)
+			delete(definitionSources, p)
+			slog.Info("Scenario definition removed", "scenario", id, "file", p)
+		}
+	}
+
+	paths := make([]string, 0, len(defs))
+	for p := range defs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var registered []scenario.Scenario
+	for _, p := range paths {
+		def := defs[p]
+		s, err := newDeclarativeScenario(def, p)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if existing, ok := scenarios[def.ID]; ok {
+			if d, ok := existing.(*declarativeScenario); !ok || d.source != p {
+				errs = append(errs, fmt.Errorf("%s: scenario with ID '%s' is already registered", p, def.ID))
+				continue
+			}
+		}
+		if old, ok := definitionSources[p]; ok && old != def.ID {
+			delete(scenarios, old)
+		}
+		scenarios[def.ID] = s
+		definitionSources[p] = def.ID
+		registered = append(registered, s)
+	}
+	return registered, errors.Join(errs...)
+}
+
+// WatchDefinitions loads the definition files in dir and reloads them whenever a file
+// under dir changes, until ctx is done. Reloaded scenarios are initialized; reload
+// failures are logged and leave the previous definitions in place.
+func WatchDefinitions(ctx context.Context, dir string) error {
+	if err := LoadDefinitions(os.DirFS(dir)); err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch scenario definitions: %w", err)
+	}
+	// fsnotify does not watch recursively, so every directory is added on its own.
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(p)
+	})
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch scenario definitions: %w", err)
+	}
+
+	go func() {
+		defer watcher.Close()
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&fsnotify.Create != 0 {
+					if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+						_ = watcher.Add(event.Name)
+					}
+				}
+				reload = time.After(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Error("Scenario definition watcher failed", "error", err)
+			case <-reload:
+				reload = nil
+				reloadDefinitions(dir)
+			}
+		}
+	}()
+	return nil
+}
+
+func reloadDefinitions(dir string) {
+	registered, err := loadDefinitions(os.DirFS(dir))
+	if err != nil {
+		slog.Error("Failed to reload scenario definitions", "dir", dir, "error", err)
+	}
+	for _, s := range registered {
+		if err := s.Initialize(); err != nil {
+			slog.Error("Failed to initialize scenario", "scenario", s.ID(), "error", err)
+			continue
+		}
+		slog.Info("Scenario definition reloaded", "scenario", s.ID())
+	}
+}
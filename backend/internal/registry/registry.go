@@ -2,18 +2,44 @@ package registry
 
 import (
 	"fmt"
+	"log/slog"
+	"sort"
 	"sync"
 
+	"SYS_DESIGN_PLAYGROUND/internal/config"
 	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 )
 
 var (
 	// scenarios is a thread-safe map to store all registered scenario implementations.
 	scenarios = make(map[string]scenario.Scenario)
-	// lock is used to protect access to the scenarios map.
+	// settings holds the configuration of each scenario, by ID.
+	settings = make(map[string]config.Scenario)
+	// lock is used to protect access to the scenarios and settings maps.
 	lock = &sync.RWMutex{}
 )
 
+// Category is a group of scenarios as shown in the navigator.
+type Category struct {
+	Name      string
+	Scenarios []scenario.Scenario
+}
+
+// Configure applies the scenario settings of cfg: which scenarios are enabled, their
+// order and extra tags. It should be called before InitializeAll.
+func Configure(cfg *config.Config) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	settings = make(map[string]config.Scenario, len(cfg.Scenarios))
+	for id, s := range cfg.Scenarios {
+		if _, ok := scenarios[id]; !ok {
+			slog.Warn("Configuration for unknown scenario", "scenario", id)
+		}
+		settings[id] = s
+	}
+}
+
 // Register adds a new scenario to the registry.
 // It will panic if a scenario with the same ID is already registered,
 // ensuring that all scenario IDs are unique at startup.
@@ -28,40 +54,100 @@ func Register(s scenario.Scenario) {
 	scenarios[id] = s
 }
 
-// GetScenario retrieves a single scenario from the registry by its ID.
+// GetScenario retrieves a single enabled scenario from the registry by its ID.
 // It returns the scenario and a boolean indicating if it was found.
 func GetScenario(id string) (scenario.Scenario, bool) {
 	lock.RLock()
 	defer lock.RUnlock()
 
 	s, ok := scenarios[id]
-	return s, ok
+	if !ok || !settings[id].IsEnabled() {
+		return nil, false
+	}
+	return s, true
 }
 
-// ListScenarios returns a slice of all registered scenarios.
+// ListScenarios returns all enabled scenarios, sorted by category, configured order and name.
 func ListScenarios() []scenario.Scenario {
 	lock.RLock()
 	defer lock.RUnlock()
 
-	list := make([]scenario.Scenario, 0, len(scenarios))
-	for _, s := range scenarios {
-		list = append(list, s)
+	return sortedScenarios()
+}
+
+// Categories returns the enabled scenarios grouped by category. Categories are sorted
+// by name and their scenarios are in the order of ListScenarios.
+func Categories() []Category {
+	var categories []Category
+	for _, s := range ListScenarios() {
+		if n := len(categories); n == 0 || categories[n-1].Name != s.Category() {
+			categories = append(categories, Category{Name: s.Category()})
+		}
+		c := &categories[len(categories)-1]
+		c.Scenarios = append(c.Scenarios, s)
 	}
-	return list
+	return categories
 }
 
-// InitializeAll initializes all registered scenarios.
+// Tags returns the tags a scenario declares (see scenario.Tagged) followed by those
+// added in the configuration, without duplicates.
+func Tags(s scenario.Scenario) []string {
+	var declared []string
+	if t, ok := s.(scenario.Tagged); ok {
+		declared = t.Tags()
+	}
+	lock.RLock()
+	configured := settings[s.ID()].Tags
+	lock.RUnlock()
+
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range append(append([]string{}, declared...), configured...) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// InitializeAll initializes all enabled scenarios, in the order of ListScenarios.
 // This should be called once at application startup.
 func InitializeAll() error {
 	lock.RLock()
 	defer lock.RUnlock()
 
-	fmt.Printf("Initializing %d scenarios...\n", len(scenarios))
-	for id, s := range scenarios {
+	list := sortedScenarios()
+	slog.Info("Initializing scenarios", "count", len(list), "disabled", len(scenarios)-len(list))
+	for _, s := range list {
 		if err := s.Initialize(); err != nil {
-			return fmt.Errorf("failed to initialize scenario '%s': %w", id, err)
+			return fmt.Errorf("failed to initialize scenario '%s': %w", s.ID(), err)
 		}
-		fmt.Printf(" - Scenario '%s' initialized successfully.\n", id)
+		slog.Info("Scenario initialized", "scenario", s.ID())
 	}
 	return nil
+}
+
+// sortedScenarios returns the enabled scenarios in navigator order. The caller must hold lock.
+func sortedScenarios() []scenario.Scenario {
+	list := make([]scenario.Scenario, 0, len(scenarios))
+	for id, s := range scenarios {
+		if settings[id].IsEnabled() {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Category() != b.Category() {
+			return a.Category() < b.Category()
+		}
+		if oa, ob := settings[a.ID()].Order, settings[b.ID()].Order; oa != ob {
+			return oa < ob
+		}
+		if a.Name() != b.Name() {
+			return a.Name() < b.Name()
+		}
+		return a.ID() < b.ID()
+	})
+	return list
 }
\ No newline at end of file
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeScenario provides the metadata used by the registry; the rest of the
+// interface is left to the nil embedded value.
+type fakeScenario struct {
+	scenario.Scenario
+	id, name, category string
+	tags               []string
+}
+
+func (s *fakeScenario) ID() string       { return s.id }
+func (s *fakeScenario) Name() string     { return s.name }
+func (s *fakeScenario) Category() string { return s.category }
+func (s *fakeScenario) Tags() []string   { return s.tags }
+
+func init() {
+	Register(&fakeScenario{id: "reg_b", name: "B", category: "zz Registry"})
+	Register(&fakeScenario{id: "reg_a", name: "A", category: "zz Registry", tags: []string{"lock"}})
+	Register(&fakeScenario{id: "reg_c", name: "C", category: "zz Registry"})
+	Register(&fakeScenario{id: "reg_other", name: "Other", category: "zy Registry"})
+}
+
+func TestOrderingAndConfiguration(t *testing.T) {
+	off := false
+	Configure(&config.Config{Scenarios: map[string]config.Scenario{
+		"reg_c": {Order: -1, Tags: []string{"lock", "beginner"}},
+		"reg_b": {Enabled: &off},
+	}})
+	defer Configure(&config.Config{})
+
+	var ids []string
+	for _, s := range ListScenarios() {
+		if s.Category() == "zz Registry" || s.Category() == "zy Registry" {
+			ids = append(ids, s.ID())
+		}
+	}
+	assert.Equal(t, []string{"reg_other", "reg_c", "reg_a"}, ids)
+
+	_, ok := GetScenario("reg_b")
+	assert.False(t, ok, "disabled scenarios are hidden")
+
+	categories := Categories()
+	last := categories[len(categories)-1]
+	assert.Equal(t, "zz Registry", last.Name)
+	assert.Len(t, last.Scenarios, 2)
+	assert.Equal(t, "zy Registry", categories[len(categories)-2].Name)
+
+	s, _ := GetScenario("reg_c")
+	assert.Equal(t, []string{"lock", "beginner"}, Tags(s))
+	s, _ = GetScenario("reg_a")
+	assert.Equal(t, []string{"lock"}, Tags(s))
+}
@@ -0,0 +1,143 @@
+package runbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// AssertionResult is the outcome of a single assertion.
+type AssertionResult struct {
+	Assertion
+	Passed bool        `json:"passed"`
+	Actual interface{} `json:"actual,omitempty"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+func (a Assertion) validate() error {
+	if a.Path == "" {
+		return errors.New("path is required")
+	}
+	ops := 0
+	if a.Equals != nil {
+		ops++
+	}
+	if a.NotEquals != nil {
+		ops++
+	}
+	if a.Contains != "" {
+		ops++
+	}
+	if a.Exists != nil {
+		ops++
+	}
+	if ops != 1 {
+		return errors.New("exactly one of equals, not_equals, contains or exists must be set")
+	}
+	return nil
+}
+
+// evaluate checks the assertion against a normalized state document.
+func (a Assertion) evaluate(state interface{}) AssertionResult {
+	res := AssertionResult{Assertion: a}
+	actual, found := lookup(state, a.Path)
+	res.Actual = actual
+
+	switch {
+	case a.Exists != nil:
+		res.Passed = found == *a.Exists
+		if !res.Passed {
+			res.Reason = fmt.Sprintf("expected exists=%t", *a.Exists)
+		}
+	case !found:
+		res.Reason = "path not found"
+	case a.Equals != nil:
+		res.Passed = equal(actual, a.Equals)
+		if !res.Passed {
+			res.Reason = fmt.Sprintf("expected %v", a.Equals)
+		}
+	case a.NotEquals != nil:
+		res.Passed = !equal(actual, a.NotEquals)
+		if !res.Passed {
+			res.Reason = fmt.Sprintf("expected a value other than %v", a.NotEquals)
+		}
+	case a.Contains != "":
+		res.Passed = strings.Contains(stringify(actual), a.Contains)
+		if !res.Passed {
+			res.Reason = fmt.Sprintf("expected to contain %q", a.Contains)
+		}
+	}
+	return res
+}
+
+// normalize converts a FetchState result into plain JSON values (maps, slices, float64, string, bool)
+// so that struct fields and YAML literals can be compared uniformly.
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// lookup walks a dot-separated path through nested maps and slices.
+// A string value holding a JSON document (e.g. a raw Redis value) is decoded on the way.
+func lookup(doc interface{}, path string) (interface{}, bool) {
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		if s, ok := cur.(string); ok {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(s), &decoded); err == nil {
+				cur = decoded
+			}
+		}
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			next, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func equal(actual, expected interface{}) bool {
+	exp, err := normalize(expected)
+	if err != nil {
+		return false
+	}
+	if reflect.DeepEqual(actual, exp) {
+		return true
+	}
+	// Numbers are frequently rendered as strings (e.g. DECIMAL columns), so fall back to a textual comparison.
+	return stringify(actual) == stringify(exp)
+}
+
+func stringify(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
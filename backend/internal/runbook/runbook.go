@@ -0,0 +1,158 @@
+package runbook
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Runbook is a scripted sequence of steps executed against a single scenario.
+// It replaces clicking actions by hand, and doubles as a regression test.
+type Runbook struct {
+	Name        string `yaml:"name" json:"name,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Scenario    string `yaml:"scenario" json:"scenario"`
+	Steps       []Step `yaml:"steps" json:"steps"`
+}
+
+// Step is one entry of a runbook. Exactly one of Action, Wait, Fault or Assert must be set.
+type Step struct {
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+
+	// Action runs a scenario action with the given params.
+	Action string                 `yaml:"action,omitempty" json:"action,omitempty"`
+	Params map[string]interface{} `yaml:"params,omitempty" json:"params,omitempty"`
+	// ExpectError marks an action that is supposed to fail, e.g. a "naive" action demonstrating a bug.
+	ExpectError bool `yaml:"expect_error,omitempty" json:"expect_error,omitempty"`
+
+	// Wait pauses the runbook, e.g. to let asynchronous processing catch up.
+	Wait Duration `yaml:"wait,omitempty" json:"wait,omitempty"`
+
+	// Fault toggles a fault-injection point (see pkg/fault).
+	Fault *FaultToggle `yaml:"fault,omitempty" json:"fault,omitempty"`
+
+	// Assert checks the scenario's FetchState output.
+	Assert []Assertion `yaml:"assert,omitempty" json:"assert,omitempty"`
+}
+
+// FaultToggle enables or disables a named fault point.
+type FaultToggle struct {
+	Name    string `yaml:"name" json:"name"`
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+}
+
+// Assertion checks a single value in the scenario state.
+// Path is a dot-separated path into the FetchState output, e.g. "mysql_record.price".
+// Exactly one operator must be set.
+type Assertion struct {
+	Path      string      `yaml:"path" json:"path"`
+	Equals    interface{} `yaml:"equals,omitempty" json:"equals,omitempty"`
+	NotEquals interface{} `yaml:"not_equals,omitempty" json:"not_equals,omitempty"`
+	Contains  string      `yaml:"contains,omitempty" json:"contains,omitempty"`
+	Exists    *bool       `yaml:"exists,omitempty" json:"exists,omitempty"`
+}
+
+// Duration is a time.Duration that is written as a string such as "500ms" in YAML and JSON.
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration string.
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", value.Value, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalText renders the duration as a string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText parses a duration string. It is used when a runbook is sent as JSON.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q: %w", text, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Kind returns the type of the step: "action", "wait", "fault" or "assert".
+func (s Step) Kind() string {
+	switch {
+	case s.Action != "":
+		return "action"
+	case s.Wait > 0:
+		return "wait"
+	case s.Fault != nil:
+		return "fault"
+	case len(s.Assert) > 0:
+		return "assert"
+	default:
+		return ""
+	}
+}
+
+// Parse decodes a runbook from YAML (JSON is accepted as well) and validates it.
+func Parse(data []byte) (*Runbook, error) {
+	var rb Runbook
+	if err := yaml.Unmarshal(data, &rb); err != nil {
+		return nil, fmt.Errorf("failed to parse runbook: %w", err)
+	}
+	if err := rb.Validate(); err != nil {
+		return nil, err
+	}
+	return &rb, nil
+}
+
+// Load reads and parses the runbook file at path.
+func Load(path string) (*Runbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runbook: %w", err)
+	}
+	return Parse(data)
+}
+
+// Validate checks that the runbook is well-formed.
+func (rb *Runbook) Validate() error {
+	if rb.Scenario == "" {
+		return errors.New("runbook: scenario is required")
+	}
+	if len(rb.Steps) == 0 {
+		return errors.New("runbook: at least one step is required")
+	}
+	for i, step := range rb.Steps {
+		set := 0
+		if step.Action != "" {
+			set++
+		}
+		if step.Wait > 0 {
+			set++
+		}
+		if step.Fault != nil {
+			set++
+		}
+		if len(step.Assert) > 0 {
+			set++
+		}
+		if set != 1 {
+			return fmt.Errorf("runbook: step %d must set exactly one of action, wait, fault or assert", i+1)
+		}
+		if step.Fault != nil && step.Fault.Name == "" {
+			return fmt.Errorf("runbook: step %d: fault name is required", i+1)
+		}
+		for j, a := range step.Assert {
+			if err := a.validate(); err != nil {
+				return fmt.Errorf("runbook: step %d, assertion %d: %w", i+1, j+1, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package runbook
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counterScenario is a minimal in-memory scenario used to exercise the runner.
+type counterScenario struct {
+	count int
+}
+
+func (s *counterScenario) ID() string                                         { return "counter" }
+func (s *counterScenario) Name() string                                       { return "Counter" }
+func (s *counterScenario) Category() string                                   { return "Test" }
+func (s *counterScenario) ProblemDescription() string                         { return "" }
+func (s *counterScenario) SolutionDescription() string                        { return "" }
+func (s *counterScenario) DeepDiveLink() string                               { return "" }
+func (s *counterScenario) Actions() []scenario.Action                         { return nil }
+func (s *counterScenario) DashboardComponents() []scenario.DashboardComponent { return nil }
+func (s *counterScenario) Initialize() error                                  { return nil }
+
+func (s *counterScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	switch actionID {
+	case "incr":
+		if fault.Enabled("counter.incr") {
+			return nil, errors.New("injected fault")
+		}
+		s.count++
+		return s.count, nil
+	case "reset":
+		s.count = 0
+		return "reset", nil
+	default:
+		return nil, errors.New("unknown action")
+	}
+}
+
+func (s *counterScenario) FetchState() (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"counter": map[string]interface{}{"value": s.count},
+		"raw":     `{"nested":{"ok":true}}`,
+	}, nil
+}
+
+func TestParse(t *testing.T) {
+	rb, err := Parse([]byte(`
+name: demo
+scenario: counter
+steps:
+  - action: incr
+  - wait: 10ms
+  - fault: {name: counter.incr, enabled: true}
+  - assert:
+      - path: counter.value
+        equals: 1
+`))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(rb.Steps))
+	assert.Equal(t, "action", rb.Steps[0].Kind())
+	assert.Equal(t, Duration(10*time.Millisecond), rb.Steps[1].Wait)
+	assert.Equal(t, "fault", rb.Steps[2].Kind())
+	assert.Equal(t, "assert", rb.Steps[3].Kind())
+
+	_, err = Parse([]byte(`scenario: counter`))
+	assert.NotNil(t, err)
+
+	_, err = Parse([]byte(`
+scenario: counter
+steps:
+  - action: incr
+    wait: 1s
+`))
+	assert.NotNil(t, err)
+
+	_, err = Parse([]byte(`
+scenario: counter
+steps:
+  - assert:
+      - path: counter.value
+`))
+	assert.NotNil(t, err)
+}
+
+func TestRunPasses(t *testing.T) {
+	rb, err := Parse([]byte(`
+name: counter goes up
+scenario: counter
+steps:
+  - action: reset
+  - action: incr
+  - action: incr
+  - assert:
+      - path: counter.value
+        equals: 2
+      - path: raw.nested.ok
+        equals: true
+      - path: counter.missing
+        exists: false
+`))
+	assert.Nil(t, err)
+
+	report := Run(context.Background(), rb, &counterScenario{})
+	assert.True(t, report.Passed)
+	assert.Equal(t, 4, len(report.Timeline))
+	assert.Equal(t, 2, report.Timeline[2].Result)
+	for _, a := range report.Timeline[3].Assertions {
+		assert.True(t, a.Passed, a.Path)
+	}
+}
+
+func TestRunStopsAtFirstFailure(t *testing.T) {
+	rb, err := Parse([]byte(`
+scenario: counter
+steps:
+  - action: incr
+  - assert:
+      - path: counter.value
+        equals: 5
+  - action: incr
+`))
+	assert.Nil(t, err)
+
+	s := &counterScenario{}
+	report := Run(context.Background(), rb, s)
+	assert.False(t, report.Passed)
+	assert.Equal(t, 2, len(report.Timeline))
+	assert.False(t, report.Timeline[1].Assertions[0].Passed)
+	assert.Equal(t, 1, s.count)
+}
+
+func TestRunFaultIsRestored(t *testing.T) {
+	rb, err := Parse([]byte(`
+scenario: counter
+steps:
+  - fault: {name: counter.incr, enabled: true}
+  - action: incr
+    expect_error: true
+`))
+	assert.Nil(t, err)
+
+	report := Run(context.Background(), rb, &counterScenario{})
+	assert.True(t, report.Passed)
+	assert.False(t, fault.Enabled("counter.incr"))
+}
+
+func TestRunExpectErrorFailsOnSuccess(t *testing.T) {
+	rb, err := Parse([]byte(`
+scenario: counter
+steps:
+  - action: incr
+    expect_error: true
+`))
+	assert.Nil(t, err)
+
+	report := Run(context.Background(), rb, &counterScenario{})
+	assert.False(t, report.Passed)
+}
+
+// TestBundledRunbooks makes sure every runbook shipped in the repository stays parseable.
+func TestBundledRunbooks(t *testing.T) {
+	files, err := filepath.Glob("../../runbooks/*.yaml")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+	for _, f := range files {
+		_, err := Load(f)
+		assert.Nil(t, err, f)
+	}
+}
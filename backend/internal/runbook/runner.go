@@ -0,0 +1,165 @@
+package runbook
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/executor"
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Report is the outcome of a runbook execution.
+type Report struct {
+	Runbook    string    `json:"runbook"`
+	Scenario   string    `json:"scenario"`
+	Passed     bool      `json:"passed"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   Duration  `json:"duration"`
+	Timeline   []Event   `json:"timeline"`
+}
+
+// Event records the execution of one step in the report timeline.
+type Event struct {
+	Step      int         `json:"step"`
+	Kind      string      `json:"kind"`
+	Name      string      `json:"name"`
+	StartedAt time.Time   `json:"started_at"`
+	Duration  Duration    `json:"duration"`
+	Passed    bool        `json:"passed"`
+	Result    interface{} `json:"result,omitempty"`
+	// ExecutionID links an action step to its entry in the action history.
+	ExecutionID string             `json:"execution_id,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	ErrorCode   scenario.ErrorCode `json:"error_code,omitempty"`
+	Assertions  []AssertionResult  `json:"assertions,omitempty"`
+}
+
+// Run executes the runbook step by step against s.
+// Execution stops at the first failing step; fault points toggled by the runbook are
+// restored before returning so that one runbook cannot leak faults into the next.
+func Run(ctx context.Context, rb *Runbook, s scenario.Scenario) *Report {
+	report := &Report{
+		Runbook:   rb.Name,
+		Scenario:  s.ID(),
+		Passed:    true,
+		StartedAt: time.Now(),
+	}
+
+	touched := make(map[string]bool)
+	defer func() {
+		for name, wasEnabled := range touched {
+			fault.Set(name, wasEnabled)
+		}
+	}()
+
+	for i, step := range rb.Steps {
+		event := Event{
+			Step:      i + 1,
+			Kind:      step.Kind(),
+			Name:      stepName(step),
+			StartedAt: time.Now(),
+			Passed:    true,
+		}
+
+		if err := ctx.Err(); err != nil {
+			event.Passed = false
+			event.Error = err.Error()
+		} else {
+			switch event.Kind {
+			case "action":
+				runAction(ctx, s, rb, step, &event)
+			case "wait":
+				runWait(ctx, step, &event)
+			case "fault":
+				if _, seen := touched[step.Fault.Name]; !seen {
+					touched[step.Fault.Name] = fault.Enabled(step.Fault.Name)
+				}
+				fault.Set(step.Fault.Name, step.Fault.Enabled)
+			case "assert":
+				runAssert(s, step, &event)
+			}
+		}
+
+		event.Duration = Duration(time.Since(event.StartedAt))
+		report.Timeline = append(report.Timeline, event)
+		if !event.Passed {
+			report.Passed = false
+			break
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	report.Duration = Duration(report.FinishedAt.Sub(report.StartedAt))
+	return report
+}
+
+func runAction(ctx context.Context, s scenario.Scenario, rb *Runbook, step Step, event *Event) {
+	rec, err := executor.Execute(ctx, s, step.Action, step.Params, executor.Caller{ID: "runbook:" + rb.Name})
+	event.Result = rec.Result
+	event.ExecutionID = rec.ID
+	if err != nil {
+		event.Error = err.Error()
+		event.ErrorCode = scenario.CodeOf(err)
+	}
+	switch {
+	case step.ExpectError && err == nil:
+		event.Passed = false
+		event.Error = "expected the action to fail, but it succeeded"
+	case !step.ExpectError && err != nil:
+		event.Passed = false
+	}
+}
+
+func runWait(ctx context.Context, step Step, event *Event) {
+	timer := time.NewTimer(time.Duration(step.Wait))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		event.Passed = false
+		event.Error = ctx.Err().Error()
+	}
+}
+
+func runAssert(s scenario.Scenario, step Step, event *Event) {
+	state, err := s.FetchState()
+	if err != nil {
+		event.Passed = false
+		event.Error = fmt.Sprintf("failed to fetch state: %v", err)
+		return
+	}
+	doc, err := normalize(state)
+	if err != nil {
+		event.Passed = false
+		event.Error = fmt.Sprintf("failed to normalize state: %v", err)
+		return
+	}
+	for _, a := range step.Assert {
+		res := a.evaluate(doc)
+		event.Assertions = append(event.Assertions, res)
+		if !res.Passed {
+			event.Passed = false
+		}
+	}
+}
+
+func stepName(step Step) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	switch step.Kind() {
+	case "action":
+		return step.Action
+	case "wait":
+		return "wait " + time.Duration(step.Wait).String()
+	case "fault":
+		if step.Fault.Enabled {
+			return "enable fault " + step.Fault.Name
+		}
+		return "disable fault " + step.Fault.Name
+	default:
+		return fmt.Sprintf("%d assertion(s)", len(step.Assert))
+	}
+}
@@ -0,0 +1,219 @@
+// Package server runs the playground: it loads the scenario definitions and the
+// configuration, initializes the scenarios and serves the API, and optionally the
+// built frontend, over HTTP. It is shared by cmd/server and the playground serve command.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/api"
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/config"
+	"SYS_DESIGN_PLAYGROUND/internal/history"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/scenarios"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// shutdownTimeout bounds how long Run waits for open requests once ctx is done.
+// Streams are cut off when it expires.
+const shutdownTimeout = 5 * time.Second
+
+// Options configures Run.
+type Options struct {
+	Addr string // listen address, e.g. ":8080"
+	// ConfigPath is the configuration file; see internal/config. A missing file is an
+	// error only if ConfigRequired is set.
+	ConfigPath     string
+	ConfigRequired bool
+	// ScenarioDir, if set, is read for scenario definition files, which are reloaded
+	// on change; otherwise the definitions embedded in the binary are used.
+	ScenarioDir string
+	// HistoryDSN, if set, persists the action history in MySQL; otherwise it is kept in memory.
+	HistoryDSN string
+	// TraceExporter and TraceFile configure tracing; see telemetry.SetupTracing.
+	TraceExporter string
+	TraceFile     string
+	// Scenarios, if set, enables only these scenarios, overriding the configuration file.
+	Scenarios []string
+	// Frontend holds the built UI, served for every path outside the API; nil serves the API only.
+	Frontend fs.FS
+}
+
+// OptionsFromEnv reads the options from the PLAYGROUND_* environment variables.
+func OptionsFromEnv() Options {
+	configPath, required := os.LookupEnv("PLAYGROUND_CONFIG")
+	if !required {
+		configPath = config.DefaultPath
+	}
+	return Options{
+		Addr:           ":" + envOr("PLAYGROUND_PORT", "8080"),
+		ConfigPath:     configPath,
+		ConfigRequired: required,
+		ScenarioDir:    os.Getenv("PLAYGROUND_SCENARIO_DIR"),
+		HistoryDSN:     os.Getenv("PLAYGROUND_HISTORY_DSN"),
+		TraceExporter:  os.Getenv("PLAYGROUND_TRACE_EXPORTER"),
+		TraceFile:      envOr("PLAYGROUND_TRACE_FILE", "traces.jsonl"),
+	}
+}
+
+// Run sets up the playground and serves it until ctx is done, then shuts down gracefully.
+func Run(ctx context.Context, opts Options) error {
+	cleanup, err := Setup(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	srv := &http.Server{Addr: opts.Addr, Handler: NewRouter(opts.Frontend)}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	slog.Info("Server listening", "addr", opts.Addr, "frontend", opts.Frontend != nil)
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("failed to run server: %w", err)
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return nil
+}
+
+// Setup loads the scenarios and the configuration and initializes everything the
+// router needs, without serving it. Definition files in opts.ScenarioDir are watched
+// until ctx is done. The returned cleanup flushes the traces.
+func Setup(ctx context.Context, opts Options) (cleanup func(), err error) {
+	// Load the scenario definition files, from disk if a directory is given.
+	if opts.ScenarioDir != "" {
+		if err := registry.WatchDefinitions(ctx, opts.ScenarioDir); err != nil {
+			return nil, fmt.Errorf("failed to load scenario definitions: %w", err)
+		}
+	} else if err := registry.LoadDefinitions(scenarios.Definitions); err != nil {
+		return nil, fmt.Errorf("failed to load scenario definitions: %w", err)
+	}
+
+	// Enable, disable and order scenarios as configured; see internal/config.
+	cfg, err := config.Load(opts.ConfigPath, opts.ConfigRequired)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if len(opts.Scenarios) > 0 {
+		onlyScenarios(cfg, opts.Scenarios)
+	}
+	registry.Configure(cfg)
+	if err := auth.Configure(cfg.Auth); err != nil {
+		return nil, fmt.Errorf("failed to configure authentication: %w", err)
+	}
+	if err := api.ConfigureRateLimit(cfg.RateLimit); err != nil {
+		return nil, fmt.Errorf("failed to configure rate limits: %w", err)
+	}
+	if err := api.ConfigureIdempotency(cfg.Idempotency); err != nil {
+		return nil, fmt.Errorf("failed to configure idempotency: %w", err)
+	}
+
+	// Initialize all registered scenarios
+	if err := registry.InitializeAll(); err != nil {
+		return nil, fmt.Errorf("failed to initialize scenarios: %w", err)
+	}
+
+	if opts.HistoryDSN != "" {
+		store, err := history.NewMySQLStore(opts.HistoryDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize history store: %w", err)
+		}
+		history.SetStore(store)
+	}
+
+	shutdownTracing, err := telemetry.SetupTracing(opts.TraceExporter, opts.TraceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	return func() { _ = shutdownTracing(context.Background()) }, nil
+}
+
+// NewRouter returns the HTTP handler of the playground: the API, /health, /metrics
+// and, if frontend is not nil, the UI.
+func NewRouter(frontend fs.FS) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.LoggerWithConfig(gin.LoggerConfig{Formatter: accessLogFormatter}), gin.Recovery())
+	router.Use(telemetry.Middleware())
+
+	// Setup API routes
+	api.SetupRouter(router)
+
+	// Simple health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "UP",
+		})
+	})
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	if frontend != nil {
+		router.NoRoute(StaticHandler(frontend))
+	}
+	return router
+}
+
+// accessLogFormatter formats the access log line as gin's default logger does, with
+// the token of the access_token query parameter redacted.
+func accessLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor, methodColor, resetColor = param.StatusCodeColor(), param.MethodColor(), param.ResetColor()
+	}
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		auth.RedactToken(param.Path),
+		param.ErrorMessage,
+	)
+}
+
+// onlyScenarios disables every registered scenario but those in ids.
+func onlyScenarios(cfg *config.Config, ids []string) {
+	if cfg.Scenarios == nil {
+		cfg.Scenarios = make(map[string]config.Scenario)
+	}
+	keep := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		keep[id] = true
+	}
+	disabled := false
+	for _, s := range registry.ListScenarios() {
+		if !keep[s.ID()] {
+			sc := cfg.Scenarios[s.ID()]
+			sc.Enabled = &disabled
+			cfg.Scenarios[s.ID()] = sc
+		}
+	}
+}
+
+// envOr returns the value of the environment variable key, or def if it is unset.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccessLogRedactsToken(t *testing.T) {
+	var buf bytes.Buffer
+	out := gin.DefaultWriter
+	defer func() { gin.DefaultWriter = out }()
+	gin.DefaultWriter = &buf
+	r := NewRouter(nil)
+
+	w := get(r, "/health?access_token=s3cret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, buf.String(), `"/health?access_token=REDACTED"`)
+	assert.NotContains(t, buf.String(), "s3cret")
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minGzipSize is the size below which files are not worth compressing on the fly.
+const minGzipSize = 1024
+
+// asset is a file of the frontend as sent in one content encoding.
+type asset struct {
+	body     []byte
+	encoding string // "" for the file itself
+	etag     string
+}
+
+// static serves the files of a built single-page app.
+type static struct {
+	fsys fs.FS
+	mu   sync.Mutex
+	// assets caches the files by name and encoding; the embedded files never change.
+	assets map[string]*asset
+}
+
+// StaticHandler serves the single-page app in fsys. It is meant for requests that
+// match no other route:
+//
+//   - an existing file is served as is, preferring a precompressed name.br or name.gz
+//     sibling the client accepts, or else gzipping text on the fly;
+//   - any other path without a file extension gets index.html, so that the app's
+//     client-side routes can be reloaded and linked to;
+//   - paths under /api/ and missing files get 404.
+//
+// Vite puts content-hashed files under assets/, which may be cached forever; every
+// other file, index.html in particular, is revalidated on each use.
+func StaticHandler(fsys fs.FS) gin.HandlerFunc {
+	s := &static{fsys: fsys, assets: make(map[string]*asset)}
+	return s.serve
+}
+
+func (s *static) serve(c *gin.Context) {
+	p := c.Request.URL.Path
+	if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || p == "/api" || strings.HasPrefix(p, "/api/") {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	if info, err := fs.Stat(s.fsys, name); err != nil || info.IsDir() {
+		if path.Ext(name) != "" {
+			c.String(http.StatusNotFound, "404 page not found")
+			return
+		}
+		name = "index.html"
+	}
+
+	a, err := s.asset(name, c.GetHeader("Accept-Encoding"))
+	if err != nil {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	ctype := mime.TypeByExtension(path.Ext(name))
+	if ctype == "" {
+		ctype = http.DetectContentType(a.body)
+	}
+	h := c.Writer.Header()
+	h.Set("Content-Type", ctype)
+	h.Set("ETag", a.etag)
+	h.Add("Vary", "Accept-Encoding")
+	if a.encoding != "" {
+		h.Set("Content-Encoding", a.encoding)
+	}
+	if strings.HasPrefix(name, "assets/") {
+		h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		h.Set("Cache-Control", "no-cache")
+	}
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(a.body))
+}
+
+// asset returns the file name in the best encoding the client accepts.
+func (s *static) asset(name, acceptEncoding string) (*asset, error) {
+	for _, enc := range []struct{ name, ext string }{{"br", ".br"}, {"gzip", ".gz"}} {
+		if !accepts(acceptEncoding, enc.name) {
+			continue
+		}
+		if a, err := s.load(name+enc.ext, enc.name, nil); err == nil {
+			return a, nil
+		}
+	}
+
+	plain, err := s.load(name, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if accepts(acceptEncoding, "gzip") && len(plain.body) >= minGzipSize && compressible(name) {
+		return s.load(name+".gz", "gzip", plain.body)
+	}
+	return plain, nil
+}
+
+// load returns the file key sent with the given encoding. If src is set, the body is
+// src compressed with gzip instead of the content of a file.
+func (s *static) load(key, encoding string, src []byte) (*asset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.assets[key]; ok {
+		return a, nil
+	}
+
+	var body []byte
+	if src != nil {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		_, _ = zw.Write(src)
+		_ = zw.Close()
+		body = buf.Bytes()
+	} else {
+		data, err := fs.ReadFile(s.fsys, key)
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+	sum := sha256.Sum256(body)
+	a := &asset{body: body, encoding: encoding, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+	s.assets[key] = a
+	return a, nil
+}
+
+// accepts reports whether an Accept-Encoding header allows the encoding.
+func accepts(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		q := strings.ReplaceAll(params, " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
+}
+
+// compressible reports whether a file is text that gzip shrinks.
+func compressible(name string) bool {
+	switch path.Ext(name) {
+	case ".html", ".js", ".mjs", ".css", ".json", ".map", ".svg", ".txt", ".xml", ".webmanifest":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var appJS = strings.Repeat("console.log('playground');\n", 100)
+
+func testFrontend() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":             {Data: []byte("<html>app</html>")},
+		"favicon.svg":            {Data: []byte("<svg/>")},
+		"assets/app-1a2b.js":     {Data: []byte(appJS)},
+		"assets/app-1a2b.css":    {Data: []byte(strings.Repeat("body{margin:0}\n", 100))},
+		"assets/app-1a2b.css.br": {Data: []byte("brotli")},
+	}
+}
+
+func get(r *gin.Engine, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticSPAFallback(t *testing.T) {
+	r := NewRouter(testFrontend())
+
+	for _, path := range []string{"/", "/index.html", "/scenarios/cache_inconsistency"} {
+		w := get(r, path)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+		assert.Equal(t, "<html>app</html>", w.Body.String(), path)
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"), path)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html", path)
+	}
+
+	// Missing files and API paths are not answered with the app.
+	assert.Equal(t, http.StatusNotFound, get(r, "/assets/missing.js").Code)
+	assert.Equal(t, http.StatusNotFound, get(r, "/api/nope").Code)
+
+	// The API and the other routes still work.
+	assert.Equal(t, http.StatusOK, get(r, "/health").Code)
+	assert.Equal(t, http.StatusOK, get(r, "/api/openapi.json").Code)
+}
+
+func TestStaticCaching(t *testing.T) {
+	r := NewRouter(testFrontend())
+
+	w := get(r, "/assets/app-1a2b.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, appJS, w.Body.String())
+	assert.Equal(t, "public, max-age=31536000, immutable", w.Header().Get("Cache-Control"))
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	w = get(r, "/assets/app-1a2b.js", "If-None-Match", etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestStaticCompression(t *testing.T) {
+	r := NewRouter(testFrontend())
+
+	// A precompressed file is preferred.
+	w := get(r, "/assets/app-1a2b.css", "Accept-Encoding", "gzip, br")
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "brotli", w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/css")
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+	// Otherwise text is gzipped on the fly.
+	w = get(r, "/assets/app-1a2b.js", "Accept-Encoding", "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	zr, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	assert.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	assert.NoError(t, err)
+	assert.Equal(t, appJS, string(body))
+	assert.NotEqual(t, get(r, "/assets/app-1a2b.js").Header().Get("ETag"), w.Header().Get("ETag"))
+
+	// Small files and refused encodings are sent as they are.
+	assert.Empty(t, get(r, "/favicon.svg", "Accept-Encoding", "gzip").Header().Get("Content-Encoding"))
+	assert.Empty(t, get(r, "/assets/app-1a2b.js", "Accept-Encoding", "gzip;q=0").Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,179 @@
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// resourceFunc adapts a name and a capture function to a Resource.
+type resourceFunc struct {
+	name    string
+	capture func(ctx context.Context) (func(ctx context.Context) error, error)
+}
+
+func (r resourceFunc) Name() string { return r.name }
+
+func (r resourceFunc) Capture(ctx context.Context) (func(ctx context.Context) error, error) {
+	return r.capture(ctx)
+}
+
+// Memory returns a resource for in-process state. capture copies the state and
+// returns a function that puts the copy back.
+func Memory(name string, capture func() (restore func())) Resource {
+	return resourceFunc{name: "memory:" + name, capture: func(context.Context) (func(context.Context) error, error) {
+		restore := capture()
+		return func(context.Context) error {
+			restore()
+			return nil
+		}, nil
+	}}
+}
+
+// MySQLTable returns a resource for all rows of a table. Restoring replaces the
+// table's rows in one transaction; the schema itself is not captured.
+//
+// Note that the restore is an ordinary write: it shows up in the binlog like any other.
+func MySQLTable(db *sql.DB, table string) Resource {
+	return resourceFunc{name: "mysql:" + table, capture: func(ctx context.Context) (func(ctx context.Context) error, error) {
+		columns, rows, err := selectAll(ctx, db, table)
+		if err != nil {
+			return nil, err
+		}
+		return func(ctx context.Context) error {
+			return replaceRows(ctx, db, table, columns, rows)
+		}, nil
+	}}
+}
+
+func selectAll(ctx context.Context, db *sql.DB, table string) ([]string, [][]interface{}, error) {
+	rs, err := db.QueryContext(ctx, "SELECT * FROM "+quoteIdent(table))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read table %s: %w", table, err)
+	}
+	defer rs.Close()
+
+	columns, err := rs.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var rows [][]interface{}
+	for rs.Next() {
+		// Raw bytes round-trip every column type unchanged; nil stands for NULL.
+		raw := make([][]byte, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range raw {
+			dest[i] = &raw[i]
+		}
+		if err := rs.Scan(dest...); err != nil {
+			return nil, nil, fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+		row := make([]interface{}, len(columns))
+		for i, v := range raw {
+			if v != nil {
+				row[i] = v
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, rs.Err()
+}
+
+func replaceRows(ctx context.Context, db *sql.DB, table string, columns []string, rows [][]interface{}) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM "+quoteIdent(table)); err != nil {
+		return fmt.Errorf("failed to clear table %s: %w", table, err)
+	}
+	if len(rows) > 0 {
+		quoted := make([]string, len(columns))
+		for i, c := range columns {
+			quoted[i] = quoteIdent(c)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(table), strings.Join(quoted, ", "), placeholders)
+		for _, row := range rows {
+			if _, err := tx.ExecContext(ctx, insert, row...); err != nil {
+				return fmt.Errorf("failed to restore table %s: %w", table, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// RedisKeys returns a resource for every key matching a glob pattern, e.g. "product:*".
+// Values are captured with DUMP, so any data type is supported, together with their
+// remaining TTL. Restoring deletes the keys that match the pattern now and recreates
+// the captured ones.
+func RedisKeys(client *redis.Client, pattern string) Resource {
+	return resourceFunc{name: "redis:" + pattern, capture: func(ctx context.Context) (func(ctx context.Context) error, error) {
+		keys, err := scanKeys(ctx, client, pattern)
+		if err != nil {
+			return nil, err
+		}
+		type dumped struct {
+			value string
+			ttl   time.Duration
+		}
+		values := make(map[string]dumped, len(keys))
+		for _, key := range keys {
+			value, err := client.Dump(ctx, key).Result()
+			if err == redis.Nil {
+				continue // expired since the scan
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to dump %s: %w", key, err)
+			}
+			ttl, err := client.PTTL(ctx, key).Result()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the TTL of %s: %w", key, err)
+			}
+			if ttl < 0 {
+				ttl = 0 // RESTORE treats 0 as no expiry
+			}
+			values[key] = dumped{value: value, ttl: ttl}
+		}
+
+		return func(ctx context.Context) error {
+			current, err := scanKeys(ctx, client, pattern)
+			if err != nil {
+				return err
+			}
+			if len(current) > 0 {
+				if err := client.Del(ctx, current...).Err(); err != nil {
+					return fmt.Errorf("failed to clear %s: %w", pattern, err)
+				}
+			}
+			for key, d := range values {
+				if err := client.RestoreReplace(ctx, key, d.ttl, d.value).Err(); err != nil {
+					return fmt.Errorf("failed to restore %s: %w", key, err)
+				}
+			}
+			return nil
+		}, nil
+	}}
+}
+
+func scanKeys(ctx context.Context, client *redis.Client, pattern string) ([]string, error) {
+	var keys []string
+	iter := client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", pattern, err)
+	}
+	return keys, nil
+}
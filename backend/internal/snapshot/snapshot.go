@@ -0,0 +1,136 @@
+// Package snapshot captures the state a scenario owns (MySQL tables, Redis keys and
+// in-process caches) under a name and restores it later, so that a scenario can be
+// rewound to a point such as "just before the bug" without redoing its setup actions.
+//
+// Snapshots are kept in memory and are lost when the server restarts.
+package snapshot
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+)
+
+// Resource is one piece of state owned by a scenario.
+type Resource interface {
+	// Name identifies the resource in snapshot listings, e.g. "mysql:products".
+	Name() string
+	// Capture records the current state and returns a function that puts it back.
+	Capture(ctx context.Context) (restore func(ctx context.Context) error, err error)
+}
+
+// Owner is implemented by scenarios that support snapshots. Resources are captured
+// and restored in the returned order; a scenario returning none does not support snapshots.
+type Owner interface {
+	SnapshotResources() []Resource
+}
+
+// Snapshot describes a stored snapshot.
+type Snapshot struct {
+	Name       string    `json:"name"`
+	ScenarioID string    `json:"scenario_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	Resources  []string  `json:"resources"`
+}
+
+type stored struct {
+	Snapshot
+	restores []func(ctx context.Context) error
+}
+
+var (
+	// snapshots holds the snapshots of each scenario by name.
+	snapshots = make(map[string]map[string]*stored)
+	lock      = &sync.RWMutex{}
+)
+
+// Take captures the resources of s under name, replacing any snapshot with the same name.
+func Take(ctx context.Context, s scenario.Scenario, name string) (*Snapshot, error) {
+	owner, err := ownerOf(s)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := owner.SnapshotResources()
+	if len(resources) == 0 {
+		return nil, scenario.InvalidParamsError("scenario %s does not support snapshots", s.ID())
+	}
+
+	snap := &stored{Snapshot: Snapshot{Name: name, ScenarioID: s.ID(), CreatedAt: time.Now(), Resources: []string{}}}
+	for _, r := range resources {
+		restore, err := r.Capture(ctx)
+		if err != nil {
+			return nil, scenario.DependencyError(err, "failed to capture %s", r.Name())
+		}
+		snap.Resources = append(snap.Resources, r.Name())
+		snap.restores = append(snap.restores, restore)
+	}
+
+	lock.Lock()
+	if snapshots[s.ID()] == nil {
+		snapshots[s.ID()] = make(map[string]*stored)
+	}
+	snapshots[s.ID()][name] = snap
+	lock.Unlock()
+
+	logs.For(s.ID()).InfoContext(ctx, "Snapshot taken", "snapshot", name, "resources", snap.Resources)
+	out := snap.Snapshot
+	return &out, nil
+}
+
+// Restore puts the resources of s back to the state captured under name.
+// A snapshot can be restored any number of times.
+func Restore(ctx context.Context, s scenario.Scenario, name string) (*Snapshot, error) {
+	if _, err := ownerOf(s); err != nil {
+		return nil, err
+	}
+
+	lock.RLock()
+	snap, ok := snapshots[s.ID()][name]
+	lock.RUnlock()
+	if !ok {
+		return nil, scenario.NotFoundError("snapshot %q of scenario %s not found", name, s.ID())
+	}
+
+	for i, restore := range snap.restores {
+		if err := restore(ctx); err != nil {
+			return nil, scenario.DependencyError(err, "failed to restore %s", snap.Resources[i])
+		}
+	}
+
+	logs.For(s.ID()).InfoContext(ctx, "Snapshot restored", "snapshot", name)
+	out := snap.Snapshot
+	return &out, nil
+}
+
+// List returns the snapshots of a scenario, oldest first.
+func List(scenarioID string) []Snapshot {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	list := make([]Snapshot, 0, len(snapshots[scenarioID]))
+	for _, snap := range snapshots[scenarioID] {
+		list = append(list, snap.Snapshot)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Reset drops all snapshots. It is used by tests.
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	snapshots = make(map[string]map[string]*stored)
+}
+
+func ownerOf(s scenario.Scenario) (Owner, error) {
+	owner, ok := s.(Owner)
+	if !ok {
+		return nil, scenario.InvalidParamsError("scenario %s does not support snapshots", s.ID())
+	}
+	return owner, nil
+}
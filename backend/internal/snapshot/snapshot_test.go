@@ -0,0 +1,77 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/stretchr/testify/assert"
+)
+
+// counterScenario owns a single in-memory counter. Methods not used by the
+// snapshot package are left to the nil embedded interface.
+type counterScenario struct {
+	scenario.Scenario
+	count       int
+	failCapture bool
+}
+
+func (s *counterScenario) ID() string { return "counter" }
+
+func (s *counterScenario) SnapshotResources() []Resource {
+	resources := []Resource{Memory("count", func() func() {
+		saved := s.count
+		return func() { s.count = saved }
+	})}
+	if s.failCapture {
+		resources = append(resources, resourceFunc{name: "broken", capture: func(context.Context) (func(context.Context) error, error) {
+			return nil, errors.New("unreachable")
+		}})
+	}
+	return resources
+}
+
+func TestTakeAndRestore(t *testing.T) {
+	Reset()
+	ctx := context.Background()
+	s := &counterScenario{count: 1}
+
+	snap, err := Take(ctx, s, "before")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"memory:count"}, snap.Resources)
+
+	// A snapshot can be restored repeatedly.
+	for i := 0; i < 2; i++ {
+		s.count = 42
+		_, err = Restore(ctx, s, "before")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, s.count)
+	}
+
+	// Taking a snapshot with the same name replaces it.
+	s.count = 2
+	_, err = Take(ctx, s, "before")
+	assert.NoError(t, err)
+	s.count = 3
+	_, err = Restore(ctx, s, "before")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.count)
+	assert.Len(t, List("counter"), 1)
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	Reset()
+	ctx := context.Background()
+
+	_, err := Restore(ctx, &counterScenario{}, "missing")
+	assert.Equal(t, scenario.CodeNotFound, scenario.CodeOf(err))
+
+	_, err = Take(ctx, &counterScenario{failCapture: true}, "broken")
+	assert.Equal(t, scenario.CodeDependencyUnavailable, scenario.CodeOf(err))
+	assert.Empty(t, List("counter"))
+
+	type plain struct{ scenario.Scenario }
+	_, err = Take(ctx, plain{&counterScenario{}}, "x")
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+}
@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const gormSpanKey = "telemetry:span"
+
+// GormPlugin returns a gorm plugin that wraps every statement in a client span.
+// Install it with db.Use(telemetry.GormPlugin()); statements must run with WithContext(ctx)
+// to become children of the current span.
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "playground:telemetry"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("telemetry:before_create", startGormSpan("create")),
+		cb.Create().After("gorm:create").Register("telemetry:after_create", endGormSpan),
+		cb.Query().Before("gorm:query").Register("telemetry:before_query", startGormSpan("query")),
+		cb.Query().After("gorm:query").Register("telemetry:after_query", endGormSpan),
+		cb.Update().Before("gorm:update").Register("telemetry:before_update", startGormSpan("update")),
+		cb.Update().After("gorm:update").Register("telemetry:after_update", endGormSpan),
+		cb.Delete().Before("gorm:delete").Register("telemetry:before_delete", startGormSpan("delete")),
+		cb.Delete().After("gorm:delete").Register("telemetry:after_delete", endGormSpan),
+		cb.Row().Before("gorm:row").Register("telemetry:before_row", startGormSpan("row")),
+		cb.Row().After("gorm:row").Register("telemetry:after_row", endGormSpan),
+		cb.Raw().Before("gorm:raw").Register("telemetry:before_raw", startGormSpan("raw")),
+		cb.Raw().After("gorm:raw").Register("telemetry:after_raw", endGormSpan),
+	)
+}
+
+func startGormSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx, span := Tracer().Start(db.Statement.Context, "gorm "+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attribute.String("db.system", "mysql"), attribute.String("db.operation", operation)))
+		db.Statement.Context = ctx
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func endGormSpan(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := v.(trace.Span)
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	EndSpan(span, err)
+}
@@ -0,0 +1,29 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartPublishSpan starts a producer span for a message sent to topic and injects the
+// trace context into carrier (the message headers/properties) so the consumer can continue it.
+func StartPublishSpan(ctx context.Context, system, topic string, carrier propagation.TextMapCarrier) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "publish "+topic,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attribute.String("messaging.system", system), attribute.String("messaging.destination.name", topic)))
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return ctx, span
+}
+
+// StartProcessSpan starts a consumer span for a received message, continuing the trace
+// whose context was injected into carrier by StartPublishSpan.
+func StartProcessSpan(ctx context.Context, system, topic string, carrier propagation.TextMapCarrier) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+	return Tracer().Start(ctx, "process "+topic,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attribute.String("messaging.system", system), attribute.String("messaging.destination.name", topic)))
+}
@@ -0,0 +1,98 @@
+package telemetry
+
+import (
+	"strconv"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "playground"
+
+// Prometheus metrics exposed at /metrics. They are registered with the default registry.
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "http", Name: "requests_total",
+		Help: "HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "http", Name: "request_duration_seconds",
+		Help:    "HTTP request latency by method and route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	actionExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "action", Name: "executions_total",
+		Help: "Scenario action executions by outcome; code is \"ok\" or the scenario error code.",
+	}, []string{"scenario", "action", "code"})
+
+	actionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "action", Name: "duration_seconds",
+		Help:    "Scenario action execution time.",
+		Buckets: []float64{.005, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"scenario", "action"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "cache", Name: "lookups_total",
+		Help: "Cache lookups by scenario, tier (e.g. local, redis) and result (hit or miss).",
+	}, []string{"scenario", "tier", "result"})
+
+	cdcLag = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Subsystem: "cdc", Name: "lag_seconds",
+		Help:    "Delay between a change being written to the binlog and its CDC event being processed.",
+		Buckets: []float64{.01, .05, .1, .5, 1, 2, 5, 10, 30, 60},
+	}, []string{"scenario"})
+
+	dbQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace, Subsystem: "db", Name: "queries_total",
+		Help: "Queries a scenario sent to its database behind a cache.",
+	}, []string{"scenario"})
+
+	queueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace, Subsystem: "queue", Name: "depth",
+		Help: "Number of messages waiting in an in-process queue.",
+	}, []string{"scenario", "queue"})
+)
+
+// ObserveRequest records one HTTP request.
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveAction records one scenario action execution and its outcome.
+func ObserveAction(scenarioID, actionID string, d time.Duration, err error) {
+	code := "ok"
+	if err != nil {
+		code = string(scenario.CodeOf(err))
+	}
+	actionExecutions.WithLabelValues(scenarioID, actionID, code).Inc()
+	actionDuration.WithLabelValues(scenarioID, actionID).Observe(d.Seconds())
+}
+
+// CacheLookup records a hit or miss on one cache tier.
+func CacheLookup(scenarioID, tier string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(scenarioID, tier, result).Inc()
+}
+
+// ObserveCDCLag records how far behind the database a processed CDC event was.
+func ObserveCDCLag(scenarioID string, lag time.Duration) {
+	cdcLag.WithLabelValues(scenarioID).Observe(lag.Seconds())
+}
+
+// ObserveDBQuery records one query that got past a cache to the database.
+func ObserveDBQuery(scenarioID string) {
+	dbQueries.WithLabelValues(scenarioID).Inc()
+}
+
+// SetQueueDepth reports the current length of an in-process queue.
+func SetQueueDepth(scenarioID, queue string, depth int) {
+	queueDepth.WithLabelValues(scenarioID, queue).Set(float64(depth))
+}
@@ -0,0 +1,79 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestMiddlewareRecordsRequestsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/things/:id", func(c *gin.Context) { c.Status(http.StatusTeapot) })
+
+	for _, path := range []string{"/things/1", "/things/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/things/:id", "418")))
+}
+
+func TestMessageSpansContinueTheRequestTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.POST("/publish", func(c *gin.Context) {
+		headers := propagation.MapCarrier{}
+		_, span := StartPublishSpan(c.Request.Context(), "test", "orders", headers)
+		span.End()
+
+		// The consumer only sees the message headers.
+		_, span = StartProcessSpan(context.Background(), "test", "orders", headers)
+		span.End()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/publish", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	traceID := spans[0].SpanContext().TraceID()
+	for _, s := range spans {
+		assert.Equal(t, traceID, s.SpanContext().TraceID(), s.Name())
+	}
+	assert.Equal(t, "process orders", spans[1].Name())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+}
+
+func TestSetupTracingToFile(t *testing.T) {
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	shutdown, err := SetupTracing(ExporterFile, path)
+	assert.Nil(t, err)
+	_, span := Tracer().Start(context.Background(), "offline")
+	span.End()
+	assert.Nil(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"Name":"offline"`)
+
+	_, err = SetupTracing("zipkin", "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,127 @@
+// Package apitypes holds the request and response types of the playground HTTP API.
+// They are the source of the OpenAPI document served at /api/openapi.json and are
+// shared by the server handlers and the generated client in pkg/client.
+package apitypes
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/auth"
+	"SYS_DESIGN_PLAYGROUND/internal/compare"
+	"SYS_DESIGN_PLAYGROUND/internal/history"
+	"SYS_DESIGN_PLAYGROUND/internal/jobs"
+	"SYS_DESIGN_PLAYGROUND/internal/load"
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/runbook"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+)
+
+// Types owned by other packages that appear on the wire. The aliases give them
+// stable names in the OpenAPI document and make them reachable from outside the module.
+type (
+	Action             = scenario.Action
+	ActionParam        = scenario.ActionParam
+	CompareRequest     = compare.Request
+	Comparison         = compare.Comparison
+	DashboardComponent = scenario.DashboardComponent
+	ErrorCode          = scenario.ErrorCode
+	HistoryRecord      = history.Record
+	Job                = jobs.Job
+	LoadReport         = load.Report
+	LoadSpec           = load.Spec
+	LogEntry           = logs.Entry
+	Principal          = auth.Principal
+	Runbook            = runbook.Runbook
+	RunbookReport      = runbook.Report
+	Snapshot           = snapshot.Snapshot
+	Workload           = scenario.Workload
+)
+
+// ScenarioSummary is an entry of GET /api/scenarios.
+type ScenarioSummary struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+}
+
+// Category groups the scenarios of one category in GET /api/categories.
+type Category struct {
+	Name      string            `json:"name"`
+	Scenarios []ScenarioSummary `json:"scenarios"`
+}
+
+// ScenarioDetail is the full configuration returned by GET /api/scenarios/:id.
+type ScenarioDetail struct {
+	ID                  string               `json:"id"`
+	Title               string               `json:"title"`
+	Category            string               `json:"category"`
+	ProblemDescription  string               `json:"problem_description"`
+	SolutionDescription string               `json:"solution_description"`
+	DeepDiveLink        string               `json:"deep_dive_link"`
+	Actions             []Action             `json:"actions"`
+	DashboardComponents []DashboardComponent `json:"dashboard_components"`
+	// Workloads lists what the load generator can run besides the actions.
+	Workloads []Workload `json:"workloads"`
+}
+
+// ExecuteActionRequest is the optional body of POST /api/scenarios/:id/actions/:action_id.
+type ExecuteActionRequest struct {
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// SnapshotRequest is the body of POST /api/scenarios/:id/snapshots and POST /api/scenarios/:id/restore.
+type SnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+// Action statuses reported in ActionResponse.Status.
+const (
+	ActionStatusSuccess      = "success"
+	ActionStatusDemonstrated = "demonstrated"
+	ActionStatusAccepted     = "accepted"
+)
+
+// ActionResponse is returned by POST /api/scenarios/:id/actions/:action_id.
+// Status is "success", "demonstrated" (an expected failure, see Error) or, for
+// asynchronous executions, "accepted" together with JobID and StatusURL.
+type ActionResponse struct {
+	Status      string      `json:"status"`
+	Message     string      `json:"message"`
+	Result      interface{} `json:"result,omitempty"`
+	ExecutionID string      `json:"execution_id,omitempty"`
+	JobID       string      `json:"job_id,omitempty"`
+	StatusURL   string      `json:"status_url,omitempty"`
+	Error       *ErrorBody  `json:"error,omitempty"`
+}
+
+// State is the dashboard state returned by GET /api/scenarios/:id/state,
+// keyed by dashboard component ID.
+type State map[string]interface{}
+
+// HistoryPage is returned by GET /api/scenarios/:id/history.
+type HistoryPage struct {
+	Items    []*HistoryRecord `json:"items"`
+	Page     int              `json:"page"`
+	PageSize int              `json:"page_size"`
+	Total    int              `json:"total"`
+}
+
+// WhoAmI is returned by GET /api/auth/whoami.
+type WhoAmI struct {
+	Principal
+	// AuthEnabled is false while the server runs without authentication; every caller is then an admin.
+	AuthEnabled bool `json:"auth_enabled"`
+}
+
+// ErrorBody is the stable JSON shape of every API error.
+type ErrorBody struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	TraceID string                 `json:"trace_id,omitempty"`
+}
+
+// ErrorResponse wraps an ErrorBody: {"error": {...}}.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
@@ -0,0 +1,115 @@
+// Package bloom implements a Bloom filter: a set that answers "possibly present" or
+// "definitely absent" in a fixed number of bits, whatever the size of its items.
+//
+// A filter sized for n items with a false positive rate p takes about 1.44·log2(1/p)
+// bits per item (under 10 bits for 1%), and k = log2(1/p) hashes per lookup. It never
+// answers absent for an added item. Items cannot be removed; a filter over a changing
+// set is rebuilt from the source of truth from time to time.
+package bloom
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Filter is a Bloom filter. It is safe for concurrent use.
+type Filter struct {
+	mu    sync.RWMutex
+	bits  []uint64
+	m     uint64 // number of bits
+	k     int    // number of hashes
+	count int    // items added
+}
+
+// New returns a filter for n items with a false positive rate of about p once they
+// are added. n below 1 is taken as 1, and p outside (0, 1) as 1%.
+func New(n int, p float64) *Filter {
+	if n < 1 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.01
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	return &Filter{bits: make([]uint64, (m+63)/64), m: m, k: max(k, 1)}
+}
+
+// locations returns the two hashes from which the k bit positions of item are derived
+// (Kirsch and Mitzenmacher: h1 + i·h2).
+func locations(item []byte) (uint64, uint64) {
+	h := fnv.New128a()
+	h.Write(item)
+	sum := h.Sum(nil)
+	return binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:]) | 1
+}
+
+// Add adds item to the filter.
+func (f *Filter) Add(item []byte) {
+	h1, h2 := locations(item)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// Test reports whether item may have been added. False means it was not.
+func (f *Filter) Test(item []byte) bool {
+	h1, h2 := locations(item)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for i := 0; i < f.k; i++ {
+		bit := (h1 + uint64(i)*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AddString adds s to the filter.
+func (f *Filter) AddString(s string) { f.Add([]byte(s)) }
+
+// TestString reports whether s may have been added.
+func (f *Filter) TestString(s string) bool { return f.Test([]byte(s)) }
+
+// AddInt adds the integer n to the filter.
+func (f *Filter) AddInt(n int64) { f.AddString(strconv.FormatInt(n, 10)) }
+
+// TestInt reports whether the integer n may have been added.
+func (f *Filter) TestInt(n int64) bool { return f.TestString(strconv.FormatInt(n, 10)) }
+
+// Count returns the number of items added, counting repeated items each time.
+func (f *Filter) Count() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.count
+}
+
+// Bits returns the size of the filter in bits.
+func (f *Filter) Bits() uint64 { return f.m }
+
+// Hashes returns the number of bit positions set per item.
+func (f *Filter) Hashes() int { return f.k }
+
+// FalsePositiveRate estimates the probability that Test answers true for an item that
+// was not added, given the items added so far.
+func (f *Filter) FalsePositiveRate() float64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return math.Pow(1-math.Exp(-float64(f.k)*float64(f.count)/float64(f.m)), float64(f.k))
+}
+
+// Reset removes every item.
+func (f *Filter) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.bits)
+	f.count = 0
+}
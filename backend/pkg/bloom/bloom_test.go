@@ -0,0 +1,45 @@
+package bloom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoFalseNegatives(t *testing.T) {
+	f := New(1000, 0.01)
+	for i := int64(0); i < 1000; i++ {
+		f.AddInt(i)
+	}
+	for i := int64(0); i < 1000; i++ {
+		assert.True(t, f.TestInt(i), i)
+	}
+	assert.Equal(t, 1000, f.Count())
+}
+
+func TestFalsePositiveRate(t *testing.T) {
+	f := New(1000, 0.01)
+	assert.Equal(t, uint64(9586), f.Bits())
+	assert.Equal(t, 7, f.Hashes())
+	for i := int64(0); i < 1000; i++ {
+		f.AddInt(i)
+	}
+	positives := 0
+	for i := int64(1000); i < 101000; i++ {
+		if f.TestInt(i) {
+			positives++
+		}
+	}
+	assert.InDelta(t, 0.01, float64(positives)/100000, 0.005)
+	assert.InDelta(t, 0.01, f.FalsePositiveRate(), 0.002)
+}
+
+func TestReset(t *testing.T) {
+	f := New(10, 0.01)
+	f.AddString("a")
+	assert.True(t, f.TestString("a"))
+	f.Reset()
+	assert.False(t, f.TestString("a"))
+	assert.Zero(t, f.Count())
+	assert.Zero(t, f.FalsePositiveRate())
+}
@@ -0,0 +1,279 @@
+// Code generated by cmd/apigen from the API operations; DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+)
+
+// WhoAmI calls GET /api/auth/whoami.
+// Get the caller's name and role, and whether authentication is enabled.
+func (c *Client) WhoAmI(ctx context.Context) (apitypes.WhoAmI, error) {
+	var out apitypes.WhoAmI
+	err := c.do(ctx, http.MethodGet, "/api/auth/whoami", nil, nil, &out)
+	return out, err
+}
+
+// ListScenariosParams holds the optional query parameters of ListScenarios.
+type ListScenariosParams struct {
+	// Tag maps to ?tag. Only return scenarios with this tag.
+	Tag string
+}
+
+func (p *ListScenariosParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Tag != "" {
+		q.Set("tag", p.Tag)
+	}
+	return q
+}
+
+// ListScenarios calls GET /api/scenarios.
+// List the enabled scenarios, sorted by category, configured order and name.
+func (c *Client) ListScenarios(ctx context.Context, params *ListScenariosParams) ([]apitypes.ScenarioSummary, error) {
+	var out []apitypes.ScenarioSummary
+	err := c.do(ctx, http.MethodGet, "/api/scenarios", params.values(), nil, &out)
+	return out, err
+}
+
+// ListCategories calls GET /api/categories.
+// List the enabled scenarios grouped by category, as shown in the navigator.
+func (c *Client) ListCategories(ctx context.Context) ([]apitypes.Category, error) {
+	var out []apitypes.Category
+	err := c.do(ctx, http.MethodGet, "/api/categories", nil, nil, &out)
+	return out, err
+}
+
+// GetScenario calls GET /api/scenarios/:id.
+// Get the full configuration of a scenario.
+func (c *Client) GetScenario(ctx context.Context, id string) (apitypes.ScenarioDetail, error) {
+	var out apitypes.ScenarioDetail
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// ExecuteActionParams holds the optional query parameters of ExecuteAction.
+type ExecuteActionParams struct {
+	// Async maps to ?async. Run the action as a background job.
+	Async bool
+}
+
+func (p *ExecuteActionParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Async {
+		q.Set("async", "true")
+	}
+	return q
+}
+
+// ExecuteAction calls POST /api/scenarios/:id/actions/:action_id.
+// Execute an action.
+func (c *Client) ExecuteAction(ctx context.Context, id string, actionID string, body apitypes.ExecuteActionRequest, params *ExecuteActionParams) (apitypes.ActionResponse, error) {
+	var out apitypes.ActionResponse
+	err := c.do(ctx, http.MethodPost, "/api/scenarios/"+url.PathEscape(id)+"/actions/"+url.PathEscape(actionID), params.values(), body, &out)
+	return out, err
+}
+
+// GetState calls GET /api/scenarios/:id/state.
+// Get the dashboard state of a scenario.
+func (c *Client) GetState(ctx context.Context, id string) (apitypes.State, error) {
+	var out apitypes.State
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/state", nil, nil, &out)
+	return out, err
+}
+
+// ListHistoryParams holds the optional query parameters of ListHistory.
+type ListHistoryParams struct {
+	// Action maps to ?action. Only return executions of this action.
+	Action string
+	// Page maps to ?page. 1-based page number.
+	Page int
+	// PageSize maps to ?page_size. Page size, at most 100.
+	PageSize int
+}
+
+func (p *ListHistoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Action != "" {
+		q.Set("action", p.Action)
+	}
+	if p.Page != 0 {
+		q.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.PageSize != 0 {
+		q.Set("page_size", strconv.Itoa(p.PageSize))
+	}
+	return q
+}
+
+// ListHistory calls GET /api/scenarios/:id/history.
+// List recorded action executions, newest first.
+func (c *Client) ListHistory(ctx context.Context, id string, params *ListHistoryParams) (apitypes.HistoryPage, error) {
+	var out apitypes.HistoryPage
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/history", params.values(), nil, &out)
+	return out, err
+}
+
+// ExportHistoryParams holds the optional query parameters of ExportHistory.
+type ExportHistoryParams struct {
+	// Action maps to ?action. Only return executions of this action.
+	Action string
+}
+
+func (p *ExportHistoryParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Action != "" {
+		q.Set("action", p.Action)
+	}
+	return q
+}
+
+// ExportHistory calls GET /api/scenarios/:id/history/export.
+// Export the full history of a scenario as a chronological timeline.
+func (c *Client) ExportHistory(ctx context.Context, id string, params *ExportHistoryParams) ([]*apitypes.HistoryRecord, error) {
+	var out []*apitypes.HistoryRecord
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/history/export", params.values(), nil, &out)
+	return out, err
+}
+
+// ListLogsParams holds the optional query parameters of ListLogs.
+type ListLogsParams struct {
+	// Level maps to ?level. Minimum level: debug, info, warn or error.
+	Level string
+	// Since maps to ?since. Only return entries at or after this RFC 3339 time.
+	Since string
+	// Until maps to ?until. Only return entries before this RFC 3339 time.
+	Until string
+	// Action maps to ?action. Only return entries logged by this action.
+	Action string
+	// Limit maps to ?limit. Only return the newest limit entries.
+	Limit int
+}
+
+func (p *ListLogsParams) values() url.Values {
+	q := url.Values{}
+	if p == nil {
+		return q
+	}
+	if p.Level != "" {
+		q.Set("level", p.Level)
+	}
+	if p.Since != "" {
+		q.Set("since", p.Since)
+	}
+	if p.Until != "" {
+		q.Set("until", p.Until)
+	}
+	if p.Action != "" {
+		q.Set("action", p.Action)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// ListLogs calls GET /api/scenarios/:id/logs.
+// Query the buffered structured logs of a scenario, oldest first.
+func (c *Client) ListLogs(ctx context.Context, id string, params *ListLogsParams) ([]apitypes.LogEntry, error) {
+	var out []apitypes.LogEntry
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/logs", params.values(), nil, &out)
+	return out, err
+}
+
+// ListSnapshots calls GET /api/scenarios/:id/snapshots.
+// List the snapshots of a scenario, oldest first.
+func (c *Client) ListSnapshots(ctx context.Context, id string) ([]apitypes.Snapshot, error) {
+	var out []apitypes.Snapshot
+	err := c.do(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/snapshots", nil, nil, &out)
+	return out, err
+}
+
+// CreateSnapshot calls POST /api/scenarios/:id/snapshots.
+// Capture the state owned by a scenario under a name.
+func (c *Client) CreateSnapshot(ctx context.Context, id string, body apitypes.SnapshotRequest) (apitypes.Snapshot, error) {
+	var out apitypes.Snapshot
+	err := c.do(ctx, http.MethodPost, "/api/scenarios/"+url.PathEscape(id)+"/snapshots", nil, body, &out)
+	return out, err
+}
+
+// RestoreSnapshot calls POST /api/scenarios/:id/restore.
+// Restore the state of a scenario from a named snapshot.
+func (c *Client) RestoreSnapshot(ctx context.Context, id string, body apitypes.SnapshotRequest) (apitypes.Snapshot, error) {
+	var out apitypes.Snapshot
+	err := c.do(ctx, http.MethodPost, "/api/scenarios/"+url.PathEscape(id)+"/restore", nil, body, &out)
+	return out, err
+}
+
+// CompareActions calls POST /api/scenarios/:id/compare.
+// Run the problem and the solution action side by side.
+func (c *Client) CompareActions(ctx context.Context, id string, body apitypes.CompareRequest) (apitypes.Comparison, error) {
+	var out apitypes.Comparison
+	err := c.do(ctx, http.MethodPost, "/api/scenarios/"+url.PathEscape(id)+"/compare", nil, body, &out)
+	return out, err
+}
+
+// StartLoad calls POST /api/scenarios/:id/load.
+// Start a load test of an action or workload.
+func (c *Client) StartLoad(ctx context.Context, id string, body apitypes.LoadSpec) (apitypes.Job, error) {
+	var out apitypes.Job
+	err := c.do(ctx, http.MethodPost, "/api/scenarios/"+url.PathEscape(id)+"/load", nil, body, &out)
+	return out, err
+}
+
+// GetLoad calls GET /api/load/:id.
+// Get the current report of a load test: throughput, error rate and latency histogram.
+func (c *Client) GetLoad(ctx context.Context, id string) (apitypes.LoadReport, error) {
+	var out apitypes.LoadReport
+	err := c.do(ctx, http.MethodGet, "/api/load/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// GetJob calls GET /api/jobs/:id.
+// Get the status, progress and result of an asynchronous execution.
+func (c *Client) GetJob(ctx context.Context, id string) (apitypes.Job, error) {
+	var out apitypes.Job
+	err := c.do(ctx, http.MethodGet, "/api/jobs/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// CancelJob calls DELETE /api/jobs/:id.
+// Cancel a running job.
+func (c *Client) CancelJob(ctx context.Context, id string) (apitypes.Job, error) {
+	var out apitypes.Job
+	err := c.do(ctx, http.MethodDelete, "/api/jobs/"+url.PathEscape(id), nil, nil, &out)
+	return out, err
+}
+
+// RunRunbook calls POST /api/runbooks/run.
+// Run a runbook and return its report.
+func (c *Client) RunRunbook(ctx context.Context, body apitypes.Runbook) (apitypes.RunbookReport, error) {
+	var out apitypes.RunbookReport
+	err := c.do(ctx, http.MethodPost, "/api/runbooks/run", nil, body, &out)
+	return out, err
+}
+
+// GetOpenAPI calls GET /api/openapi.json.
+// Get this OpenAPI document.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, nil, &out)
+	return out, err
+}
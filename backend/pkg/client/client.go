@@ -0,0 +1,119 @@
+// Package client is a typed Go client for the playground HTTP API. The methods in
+// client.gen.go are generated from the same operation table that produces the
+// OpenAPI document served at /api/openapi.json; run go generate after changing it.
+package client
+
+//go:generate go run ../../cmd/apigen -o client.gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+)
+
+// Client calls a playground server.
+type Client struct {
+	BaseURL    string // e.g. http://localhost:8080
+	HTTPClient *http.Client
+	// SessionID, if set, is sent as X-Session-ID so that the actions are grouped in the history.
+	SessionID string
+	// Token, if set, is sent as a bearer token; see the auth section of the server config.
+	Token string
+}
+
+// New returns a client for the server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// APIError is returned for any non-2xx response. It carries the server's error envelope.
+type APIError struct {
+	StatusCode int
+	apitypes.ErrorBody
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// send issues a request and returns the response of a 2xx status, or an *APIError.
+// The caller must close the response body.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.SessionID != "" {
+		req.Header.Set("X-Session-ID", c.SessionID)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var envelope apitypes.ErrorResponse
+	if json.Unmarshal(data, &envelope) == nil && envelope.Error.Code != "" {
+		apiErr.ErrorBody = envelope.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return nil, apiErr
+}
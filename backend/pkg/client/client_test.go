@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/api"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoScenario echoes the params of its only action.
+type echoScenario struct{}
+
+func (s *echoScenario) ID() string                  { return "client_echo" }
+func (s *echoScenario) Name() string                { return "Client Echo" }
+func (s *echoScenario) Category() string            { return "Test" }
+func (s *echoScenario) ProblemDescription() string  { return "" }
+func (s *echoScenario) SolutionDescription() string { return "" }
+func (s *echoScenario) DeepDiveLink() string        { return "" }
+func (s *echoScenario) Actions() []scenario.Action {
+	return []scenario.Action{{ID: "echo", Params: []scenario.ActionParam{{Name: "n", Type: "integer"}}}}
+}
+func (s *echoScenario) DashboardComponents() []scenario.DashboardComponent { return nil }
+func (s *echoScenario) Initialize() error                                  { return nil }
+func (s *echoScenario) FetchState() (map[string]interface{}, error) {
+	return map[string]interface{}{"ok": true}, nil
+}
+func (s *echoScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
+	return params, nil
+}
+
+func newTestClient(t *testing.T) *Client {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api.SetupRouter(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return New(srv.URL)
+}
+
+func init() {
+	registry.Register(&echoScenario{})
+}
+
+func TestClient(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	detail, err := c.GetScenario(ctx, "client_echo")
+	assert.Nil(t, err)
+	assert.Equal(t, "Client Echo", detail.Title)
+	assert.Equal(t, "n", detail.Actions[0].Params[0].Name)
+
+	resp, err := c.ExecuteAction(ctx, "client_echo", "echo", apitypes.ExecuteActionRequest{Params: map[string]interface{}{"n": 3}}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, apitypes.ActionStatusSuccess, resp.Status)
+	assert.Equal(t, map[string]interface{}{"n": 3.0}, resp.Result)
+
+	page, err := c.ListHistory(ctx, "client_echo", &ListHistoryParams{Action: "echo"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, page.Total)
+	assert.Equal(t, resp.ExecutionID, page.Items[0].ID)
+
+	state, err := c.GetState(ctx, "client_echo")
+	assert.Nil(t, err)
+	assert.Equal(t, true, state["ok"])
+}
+
+func TestClientErrors(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.GetScenario(ctx, "missing")
+	var apiErr *APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, scenario.CodeNotFound, apiErr.Code)
+
+	_, err = c.ExecuteAction(ctx, "client_echo", "echo", apitypes.ExecuteActionRequest{Params: map[string]interface{}{"n": "three"}}, nil)
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, scenario.CodeInvalidParams, apiErr.Code)
+}
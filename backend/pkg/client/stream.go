@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"SYS_DESIGN_PLAYGROUND/pkg/apitypes"
+)
+
+// StreamLogsParams holds the optional query parameters of StreamLogs.
+type StreamLogsParams struct {
+	Level  string // minimum level: debug, info, warn or error
+	Action string // only stream entries logged by this action
+	Tail   int    // number of buffered entries to replay first; 0 uses the server default
+}
+
+// StreamLogs calls GET /api/scenarios/:id/logs/stream and invokes fn for every log entry
+// until ctx is done, the server closes the stream or fn returns an error.
+// It returns nil when ctx is cancelled.
+func (c *Client) StreamLogs(ctx context.Context, id string, params *StreamLogsParams, fn func(apitypes.LogEntry) error) error {
+	q := url.Values{}
+	if params != nil {
+		if params.Level != "" {
+			q.Set("level", params.Level)
+		}
+		if params.Action != "" {
+			q.Set("action", params.Action)
+		}
+		if params.Tail != 0 {
+			q.Set("tail", strconv.Itoa(params.Tail))
+		}
+	}
+	resp, err := c.send(ctx, http.MethodGet, "/api/scenarios/"+url.PathEscape(id)+"/logs/stream", q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Each event is a block of "field:value" lines ended by an empty line;
+	// only the data lines matter here.
+	scanner := bufio.NewScanner(resp.Body)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			var entry apitypes.LogEntry
+			if err := json.Unmarshal([]byte(data.String()), &entry); err != nil {
+				return err
+			}
+			data.Reset()
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,99 @@
+package fault
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+var (
+	// enabled holds the names of all currently active fault points.
+	enabled = make(map[string]bool)
+	// lock is used to protect access to the enabled map.
+	lock = &sync.RWMutex{}
+)
+
+// Enable turns on the named fault point.
+// Scenarios consult Enabled at the places where a dependency can fail.
+func Enable(name string) {
+	lock.Lock()
+	defer lock.Unlock()
+	enabled[name] = true
+}
+
+// Disable turns off the named fault point.
+func Disable(name string) {
+	lock.Lock()
+	defer lock.Unlock()
+	delete(enabled, name)
+}
+
+// Set enables or disables the named fault point.
+func Set(name string, on bool) {
+	if on {
+		Enable(name)
+		return
+	}
+	Disable(name)
+}
+
+// Enabled reports whether the named fault point is active.
+func Enabled(name string) bool {
+	lock.RLock()
+	defer lock.RUnlock()
+	return enabled[name]
+}
+
+// overridesKey is the context key of the fault overrides set by WithOverrides.
+type overridesKey struct{}
+
+// WithOverrides returns a context in which the named fault points are on or off as
+// given, whatever their global state. Overrides only affect code that checks faults
+// with EnabledContext; they let a caller such as comparison mode inject faults into
+// its own actions without changing what everyone else sees.
+func WithOverrides(ctx context.Context, overrides map[string]bool) context.Context {
+	if len(overrides) == 0 {
+		return ctx
+	}
+	merged := make(map[string]bool)
+	if outer, ok := ctx.Value(overridesKey{}).(map[string]bool); ok {
+		for name, on := range outer {
+			merged[name] = on
+		}
+	}
+	for name, on := range overrides {
+		merged[name] = on
+	}
+	return context.WithValue(ctx, overridesKey{}, merged)
+}
+
+// EnabledContext reports whether the named fault point is active for ctx: an override
+// set by WithOverrides wins over the global state.
+func EnabledContext(ctx context.Context, name string) bool {
+	if overrides, ok := ctx.Value(overridesKey{}).(map[string]bool); ok {
+		if on, ok := overrides[name]; ok {
+			return on
+		}
+	}
+	return Enabled(name)
+}
+
+// List returns the names of all active fault points in sorted order.
+func List() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+
+	names := make([]string, 0, len(enabled))
+	for name := range enabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reset disables every fault point.
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	enabled = make(map[string]bool)
+}
@@ -0,0 +1,28 @@
+package fault
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnabledContext(t *testing.T) {
+	Reset()
+	defer Reset()
+	Enable("global")
+
+	ctx := context.Background()
+	assert.True(t, EnabledContext(ctx, "global"))
+	assert.False(t, EnabledContext(ctx, "scoped"))
+
+	ctx = WithOverrides(ctx, map[string]bool{"global": false, "scoped": true})
+	assert.False(t, EnabledContext(ctx, "global"))
+	assert.True(t, EnabledContext(ctx, "scoped"))
+	assert.False(t, Enabled("scoped"), "overrides must not leak into the global state")
+
+	// Inner overrides are merged with the outer ones.
+	inner := WithOverrides(ctx, map[string]bool{"global": true})
+	assert.True(t, EnabledContext(inner, "global"))
+	assert.True(t, EnabledContext(inner, "scoped"))
+}
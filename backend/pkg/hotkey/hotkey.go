@@ -0,0 +1,199 @@
+// Package hotkey detects hot keys: the few keys of a skewed workload that take a large
+// share of the requests, and overload the one cache node or shard that holds them.
+//
+// A Detector counts the accesses of each window of time in a Count-Min Sketch, which
+// takes fixed memory however many distinct keys there are, and keeps the keys whose
+// estimate reaches a threshold. A key is hot while it reaches the threshold in the
+// current window or did in the previous one, so that it does not flap at the boundary.
+// With a sample rate below one only a share of the accesses is counted, each for the
+// ones it stands for, which makes counting cheaper on the hot path at some accuracy.
+//
+// Callers mitigate the hot keys they are told about: serve them from a local cache,
+// spread them over replicas, or coalesce concurrent requests for them.
+package hotkey
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Config configures a Detector. Zero values take the defaults.
+type Config struct {
+	// Window is the period over which accesses are counted; one second by default.
+	Window time.Duration
+	// Threshold is the number of accesses in a window that makes a key hot; 100 by
+	// default.
+	Threshold uint64
+	// TopK bounds the number of hot keys kept per window, the most accessed first; 20 by
+	// default.
+	TopK int
+	// Width and Depth size the sketch; 2048 and 4 by default, which overestimates by
+	// at most 0.14% of the accesses of a window with a probability of 98%.
+	Width, Depth int
+	// SampleRate is the share of accesses counted, in (0, 1]; 1 by default.
+	SampleRate float64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = time.Second
+	}
+	if c.Threshold == 0 {
+		c.Threshold = 100
+	}
+	if c.TopK <= 0 {
+		c.TopK = 20
+	}
+	if c.Width <= 0 {
+		c.Width = 2048
+	}
+	if c.Depth <= 0 {
+		c.Depth = 4
+	}
+	if c.SampleRate <= 0 || c.SampleRate > 1 {
+		c.SampleRate = 1
+	}
+	return c
+}
+
+// Key is a hot key with the estimated number of its accesses in a window.
+type Key struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+}
+
+// Detector finds the hot keys of a stream of accesses. It is safe for concurrent use.
+type Detector struct {
+	cfg Config
+
+	mu       sync.Mutex
+	sketch   *Sketch
+	start    time.Time         // of the current window
+	current  map[string]uint64 // keys that reached the threshold in the current window
+	previous map[string]uint64 // and in the previous one
+}
+
+// NewDetector returns a detector configured by cfg.
+func NewDetector(cfg Config) *Detector {
+	cfg = cfg.withDefaults()
+	return &Detector{
+		cfg:      cfg,
+		sketch:   NewSketch(cfg.Width, cfg.Depth),
+		current:  make(map[string]uint64),
+		previous: make(map[string]uint64),
+	}
+}
+
+// Config returns the configuration of the detector, with the defaults applied.
+func (d *Detector) Config() Config {
+	return d.cfg
+}
+
+// rotate moves to the window holding now. It must be called with mu held.
+func (d *Detector) rotate(now time.Time) {
+	start := now.Truncate(d.cfg.Window)
+	if start.Equal(d.start) || start.Before(d.start) {
+		return
+	}
+	if start.Sub(d.start) == d.cfg.Window {
+		d.previous = d.current
+	} else {
+		// Windows without accesses passed.
+		d.previous = make(map[string]uint64)
+	}
+	d.current = make(map[string]uint64)
+	d.sketch.Reset()
+	d.start = start
+}
+
+// Observe counts an access to key at now and reports whether key is hot.
+func (d *Detector) Observe(key string, now time.Time) bool {
+	n := uint64(1)
+	sampled := true
+	if d.cfg.SampleRate < 1 {
+		sampled = rand.Float64() < d.cfg.SampleRate
+		n = uint64(1/d.cfg.SampleRate + 0.5)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotate(now)
+	if sampled {
+		if est := d.sketch.Add(key, n); est >= d.cfg.Threshold {
+			d.admit(key, est)
+		}
+	}
+	return d.isHot(key)
+}
+
+// admit records key with est accesses as hot in the current window, evicting the least
+// accessed key if there are more than TopK.
+func (d *Detector) admit(key string, est uint64) {
+	if _, ok := d.current[key]; ok || len(d.current) < d.cfg.TopK {
+		d.current[key] = est
+		return
+	}
+	coldest, least := "", est
+	for k, c := range d.current {
+		if c < least {
+			coldest, least = k, c
+		}
+	}
+	if coldest != "" {
+		delete(d.current, coldest)
+		d.current[key] = est
+	}
+}
+
+func (d *Detector) isHot(key string) bool {
+	if _, ok := d.current[key]; ok {
+		return true
+	}
+	_, ok := d.previous[key]
+	return ok
+}
+
+// IsHot reports whether key is hot at now.
+func (d *Detector) IsHot(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotate(now)
+	return d.isHot(key)
+}
+
+// Hot returns the hot keys at now, the most accessed first. The count of a key is that
+// of the current window, or of the previous one if it was higher.
+func (d *Detector) Hot(now time.Time) []Key {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rotate(now)
+	counts := make(map[string]uint64, len(d.current)+len(d.previous))
+	for k, c := range d.previous {
+		counts[k] = c
+	}
+	for k, c := range d.current {
+		counts[k] = max(counts[k], c)
+	}
+	keys := make([]Key, 0, len(counts))
+	for k, c := range counts {
+		keys = append(keys, Key{Key: k, Count: c})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	return keys
+}
+
+// Reset forgets every access.
+func (d *Detector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sketch.Reset()
+	d.start = time.Time{}
+	d.current = make(map[string]uint64)
+	d.previous = make(map[string]uint64)
+}
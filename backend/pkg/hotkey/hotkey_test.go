@@ -0,0 +1,100 @@
+package hotkey
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketchNeverUnderestimates(t *testing.T) {
+	s := NewSketch(64, 4)
+	truth := make(map[string]uint64)
+	for i := 0; i < 5000; i++ {
+		k := fmt.Sprintf("k%d", rand.Intn(500))
+		s.Add(k, 1)
+		truth[k]++
+	}
+	assert.Equal(t, uint64(5000), s.Total())
+	over := uint64(0)
+	for k, n := range truth {
+		est := s.Estimate(k)
+		assert.GreaterOrEqual(t, est, n, k)
+		over += est - n
+	}
+	// e/width of the total on average, far less than the counts themselves.
+	assert.Less(t, over/uint64(len(truth)), uint64(5000*3/64))
+
+	s.Reset()
+	assert.Zero(t, s.Estimate("k1"))
+	assert.Zero(t, s.Total())
+}
+
+func TestSketchExactWhenWide(t *testing.T) {
+	s := NewSketch(1<<16, 4)
+	assert.Equal(t, uint64(3), s.Add("a", 3))
+	assert.Equal(t, uint64(4), s.Add("a", 1))
+	assert.Equal(t, uint64(2), s.Add("b", 2))
+	assert.Equal(t, uint64(4), s.Estimate("a"))
+	assert.Zero(t, s.Estimate("c"))
+}
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(Config{Window: time.Second, Threshold: 10})
+	now := time.Unix(1000, 0)
+	for i := 0; i < 9; i++ {
+		assert.False(t, d.Observe("hot", now))
+		d.Observe(fmt.Sprintf("cold%d", i), now)
+	}
+	assert.True(t, d.Observe("hot", now))
+	assert.Equal(t, []Key{{Key: "hot", Count: 10}}, d.Hot(now))
+
+	// A key stays hot through the next window, then cools down.
+	next := now.Add(time.Second)
+	assert.True(t, d.IsHot("hot", next))
+	assert.Equal(t, []Key{{Key: "hot", Count: 10}}, d.Hot(next))
+	assert.False(t, d.IsHot("hot", next.Add(time.Second)))
+	assert.Empty(t, d.Hot(next.Add(time.Second)))
+
+	// Windows without accesses cool it down at once.
+	for i := 0; i < 10; i++ {
+		d.Observe("hot", now.Add(5*time.Second))
+	}
+	assert.True(t, d.IsHot("hot", now.Add(5*time.Second)))
+	assert.False(t, d.IsHot("hot", now.Add(7*time.Second)))
+
+	d.Reset()
+	assert.False(t, d.IsHot("hot", now.Add(7*time.Second)))
+}
+
+func TestDetectorTopK(t *testing.T) {
+	d := NewDetector(Config{Threshold: 1, TopK: 3})
+	now := time.Unix(1000, 0)
+	for i, k := range []string{"a", "b", "c", "d"} {
+		for n := 0; n <= i; n++ {
+			d.Observe(k, now)
+		}
+	}
+	// d evicts a, the least accessed.
+	assert.Equal(t, []Key{{Key: "d", Count: 4}, {Key: "c", Count: 3}, {Key: "b", Count: 2}}, d.Hot(now))
+	// e is not admitted in place of a more accessed key.
+	d.Observe("e", now)
+	assert.False(t, d.IsHot("e", now))
+}
+
+func TestDetectorZipf(t *testing.T) {
+	d := NewDetector(Config{Threshold: 500, SampleRate: 0.1})
+	now := time.Unix(1000, 0)
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.2, 1, 999)
+	for i := 0; i < 20000; i++ {
+		d.Observe(fmt.Sprintf("product:%d", z.Uint64()), now)
+	}
+	hot := d.Hot(now)
+	// Sampling one access in ten still finds the head of the distribution.
+	assert.NotEmpty(t, hot)
+	assert.LessOrEqual(t, len(hot), 10)
+	assert.Equal(t, "product:0", hot[0].Key)
+	assert.InDelta(t, 20000*0.23, float64(hot[0].Count), 20000*0.05)
+}
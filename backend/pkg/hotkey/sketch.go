@@ -0,0 +1,74 @@
+package hotkey
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// Sketch is a Count-Min Sketch: it estimates how often each key was added in a fixed
+// amount of memory, whatever the number of distinct keys. Estimates are never below the
+// true count, and exceed it by at most e/width of the total with probability
+// 1 - e^-depth. A Sketch is not safe for concurrent use.
+type Sketch struct {
+	width uint64
+	depth int
+	rows  [][]uint64
+	total uint64
+}
+
+// NewSketch returns a sketch of depth rows of width counters. Values below 1 are taken
+// as 1.
+func NewSketch(width, depth int) *Sketch {
+	width, depth = max(width, 1), max(depth, 1)
+	rows := make([][]uint64, depth)
+	for i := range rows {
+		rows[i] = make([]uint64, width)
+	}
+	return &Sketch{width: uint64(width), depth: depth, rows: rows}
+}
+
+// cells returns the counter of key in each row, from two hashes of key (Kirsch and
+// Mitzenmacher).
+func (s *Sketch) cells(key string, visit func(row int, col uint64)) {
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	sum := h.Sum(nil)
+	h1, h2 := binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:])|1
+	for i := 0; i < s.depth; i++ {
+		visit(i, (h1+uint64(i)*h2)%s.width)
+	}
+}
+
+// Add counts key n more times and returns its new estimate.
+func (s *Sketch) Add(key string, n uint64) uint64 {
+	est := uint64(math.MaxUint64)
+	s.cells(key, func(row int, col uint64) {
+		s.rows[row][col] += n
+		est = min(est, s.rows[row][col])
+	})
+	s.total += n
+	return est
+}
+
+// Estimate returns how often key was added, or more.
+func (s *Sketch) Estimate(key string) uint64 {
+	est := uint64(math.MaxUint64)
+	s.cells(key, func(row int, col uint64) {
+		est = min(est, s.rows[row][col])
+	})
+	return est
+}
+
+// Total returns the sum of the counts added.
+func (s *Sketch) Total() uint64 {
+	return s.total
+}
+
+// Reset sets every count to zero.
+func (s *Sketch) Reset() {
+	for _, row := range s.rows {
+		clear(row)
+	}
+	s.total = 0
+}
@@ -0,0 +1,93 @@
+// Package idempotency makes HTTP endpoints safe to retry with an Idempotency-Key
+// header.
+//
+// A client that times out cannot tell whether its request was lost or only its
+// response, so it sends the request again. If the request carries a key of its
+// choosing, Middleware runs the handler for the first request with the key only, stores
+// its response in a Store and answers every retry with the stored response. A request
+// that reuses a key with another method, path or body gets 422, since the key no longer
+// identifies one operation. While the first request is still running, a claim on the key
+// in the store keeps duplicates from running the handler as well: they wait for its
+// response, or get 409 once the wait runs out. A claim expires after a TTL, so that a
+// crashed server does not hold the key forever, and it leaves nothing behind.
+//
+// The stores are Memory for a single process, Redis, and MySQL.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrMismatch means the key was used before for a request with another fingerprint.
+	ErrMismatch = errors.New("idempotency: key reused with a different request")
+	// ErrInProgress means a request with the key is still running.
+	ErrInProgress = errors.New("idempotency: a request with this key is in progress")
+	// ErrInvalidKey means the key, with its scope, is longer than MaxKeyLen.
+	ErrInvalidKey = errors.New("idempotency: invalid key")
+)
+
+const (
+	// DefaultHeader is the request header that carries the key.
+	DefaultHeader = "Idempotency-Key"
+	// ReplayedHeader is set to "true" on responses that are replayed from the store.
+	ReplayedHeader = "Idempotent-Replayed"
+	// DefaultRetention is how long a response is kept when Config.Retention is zero.
+	DefaultRetention = 24 * time.Hour
+	// MaxKeyLen is the longest key accepted, in bytes, counting the scope and the colon
+	// that Middleware puts before it. It is the width of the key column of MySQLTable.
+	MaxKeyLen = 191
+	// DefaultClaimTTL is how long a key stays in flight when Config.ClaimTTL is zero.
+	DefaultClaimTTL = 30 * time.Second
+)
+
+// Response is a stored response, with the fingerprint of the request that produced it.
+type Response struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Store keeps the responses of completed requests by key, and claims on the keys of the
+// requests in flight. Implementations are safe for concurrent use.
+type Store interface {
+	// Get returns the response stored under key, or nil if there is none or the key is
+	// only claimed.
+	Get(ctx context.Context, key string) (*Response, error)
+	// Put stores resp under key for ttl, replacing any earlier response or claim.
+	Put(ctx context.Context, key string, resp *Response, ttl time.Duration) error
+	// Claim marks key as in flight for ttl on behalf of token, unless a response or an
+	// unexpired claim is stored under it. It reports whether it did.
+	Claim(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	// Unclaim removes the claim of token on key. A response, or the claim of another
+	// token, is left alone.
+	Unclaim(ctx context.Context, key, token string) error
+}
+
+// Fingerprint identifies a request by its method, its path and query, and its body.
+func Fingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + uri + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StatusOf returns the HTTP status of an error of Middleware.
+func StatusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrInProgress):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidKey):
+		return http.StatusBadRequest
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
@@ -0,0 +1,269 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRouter serves POST /orders, which counts its runs and answers with the run
+// number. A status query parameter sets the status of the response.
+func newTestRouter(cfg Config, runs *atomic.Int64, block <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/orders", Middleware(cfg), func(c *gin.Context) {
+		if block != nil {
+			<-block
+		}
+		n := runs.Add(1)
+		status := http.StatusCreated
+		if c.Query("status") == "500" {
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, gin.H{"run": n})
+	})
+	return r
+}
+
+func post(r http.Handler, key, uri, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, uri, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(DefaultHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareReplay(t *testing.T) {
+	var runs atomic.Int64
+	r := newTestRouter(Config{Store: NewMemory()}, &runs, nil)
+
+	first := post(r, "k1", "/orders", `{"amount":5}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(ReplayedHeader))
+
+	retry := post(r, "k1", "/orders", `{"amount":5}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(ReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, int64(1), runs.Load())
+
+	// Reusing the key for another request is rejected.
+	w := post(r, "k1", "/orders", `{"amount":6}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	w = post(r, "k1", "/orders?async=true", `{"amount":5}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, int64(1), runs.Load())
+
+	// Requests without a key are not deduplicated.
+	post(r, "", "/orders", `{"amount":5}`)
+	post(r, "", "/orders", `{"amount":5}`)
+	assert.Equal(t, int64(3), runs.Load())
+
+	w = post(r, strings.Repeat("k", MaxKeyLen+1), "/orders", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestMiddlewareServerError(t *testing.T) {
+	var runs atomic.Int64
+	r := newTestRouter(Config{Store: NewMemory()}, &runs, nil)
+
+	// A server error is not stored, so the retry runs the handler again.
+	assert.Equal(t, http.StatusInternalServerError, post(r, "k1", "/orders?status=500", "").Code)
+	w := post(r, "k1", "/orders?status=500", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, w.Header().Get(ReplayedHeader))
+	assert.Equal(t, int64(2), runs.Load())
+}
+
+// failingPut is a store that cannot store responses.
+type failingPut struct{ *Memory }
+
+func (failingPut) Put(context.Context, string, *Response, time.Duration) error {
+	return errors.New("store down")
+}
+
+func TestMiddlewarePutFailure(t *testing.T) {
+	var runs atomic.Int64
+	var logs bytes.Buffer
+	cfg := Config{Store: failingPut{NewMemory()}, ClaimTTL: 50 * time.Millisecond, Logger: slog.New(slog.NewTextHandler(&logs, nil))}
+	r := newTestRouter(cfg, &runs, nil)
+
+	assert.Equal(t, http.StatusCreated, post(r, "k1", "/orders", "").Code)
+	assert.Contains(t, logs.String(), "store down")
+	// The key stays in flight until its claim expires, then a retry runs again.
+	assert.Equal(t, http.StatusConflict, post(r, "k1", "/orders", "").Code)
+	assert.Eventually(t, func() bool {
+		return post(r, "k1", "/orders", "").Code == http.StatusCreated
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), runs.Load())
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	var runs atomic.Int64
+	cfg := Config{Store: NewMemory(), Scope: func(c *gin.Context) string { return c.GetHeader("X-Client") }}
+	r := newTestRouter(cfg, &runs, nil)
+
+	for _, client := range []string{"a", "b", "a"} {
+		req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+		req.Header.Set(DefaultHeader, "k1")
+		req.Header.Set("X-Client", client)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Equal(t, int64(2), runs.Load())
+
+	// The scope counts towards the length of the key.
+	req := httptest.NewRequest(http.MethodPost, "/orders", nil)
+	req.Header.Set(DefaultHeader, strings.Repeat("k", MaxKeyLen-1))
+	req.Header.Set("X-Client", "a")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, int64(2), runs.Load())
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	var runs atomic.Int64
+	block := make(chan struct{})
+	r := newTestRouter(Config{Store: NewMemory()}, &runs, block)
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = post(r, "k1", "/orders", "")
+	}()
+	// A duplicate of the running request is turned away instead of running as well.
+	assert.Eventually(t, func() bool {
+		w := post(r, "k1", "/orders", "")
+		return w.Code == http.StatusConflict && w.Header().Get("Retry-After") == "1"
+	}, time.Second, 5*time.Millisecond)
+
+	close(block)
+	wg.Wait()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, post(r, "k1", "/orders", "").Code)
+	assert.Equal(t, int64(1), runs.Load())
+}
+
+func TestMiddlewareClaimExpiry(t *testing.T) {
+	var runs atomic.Int64
+	store := NewMemory()
+	r := newTestRouter(Config{Store: store, ClaimTTL: 50 * time.Millisecond}, &runs, nil)
+
+	// The claim of a server that crashed mid-request holds the key until it expires.
+	ok, err := store.Claim(context.Background(), "k1", "crashed", 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusConflict, post(r, "k1", "/orders", "").Code)
+	assert.Eventually(t, func() bool {
+		return post(r, "k1", "/orders", "").Code == http.StatusCreated
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), runs.Load())
+}
+
+func TestMiddlewareWait(t *testing.T) {
+	var runs atomic.Int64
+	block := make(chan struct{})
+	r := newTestRouter(Config{Store: NewMemory(), Wait: time.Second}, &runs, block)
+
+	// Concurrent duplicates wait for the first request and get its response.
+	var wg sync.WaitGroup
+	responses := make([]*httptest.ResponseRecorder, 5)
+	for i := range responses {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = post(r, "k1", "/orders", "")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(block)
+	wg.Wait()
+
+	replayed := 0
+	for _, w := range responses {
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"run":1}`, w.Body.String())
+		if w.Header().Get(ReplayedHeader) == "true" {
+			replayed++
+		}
+	}
+	assert.Equal(t, 4, replayed)
+	assert.Equal(t, int64(1), runs.Load())
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	assert.NoError(t, m.Put(ctx, "k1", &Response{Status: 200, Body: []byte("ok")}, time.Hour))
+	assert.NoError(t, m.Put(ctx, "k2", &Response{Status: 200}, -time.Second))
+
+	resp, err := m.Get(ctx, "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("ok"), resp.Body)
+	resp, err = m.Get(ctx, "k2")
+	assert.NoError(t, err)
+	assert.Nil(t, resp)
+
+	m.Clear()
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestMemoryClaim(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	ok, err := m.Claim(ctx, "k1", "a", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = m.Claim(ctx, "k1", "b", time.Hour)
+	assert.False(t, ok)
+	// A claimed key has no response yet.
+	resp, err := m.Get(ctx, "k1")
+	assert.NoError(t, err)
+	assert.Nil(t, resp)
+
+	// Only the holder of a claim removes it.
+	assert.NoError(t, m.Unclaim(ctx, "k1", "b"))
+	ok, _ = m.Claim(ctx, "k1", "b", time.Hour)
+	assert.False(t, ok)
+	assert.NoError(t, m.Unclaim(ctx, "k1", "a"))
+	ok, _ = m.Claim(ctx, "k1", "b", time.Hour)
+	assert.True(t, ok)
+
+	// A response replaces the claim and is not removed with it.
+	assert.NoError(t, m.Put(ctx, "k1", &Response{Status: 200}, time.Hour))
+	assert.NoError(t, m.Unclaim(ctx, "k1", "b"))
+	resp, _ = m.Get(ctx, "k1")
+	assert.NotNil(t, resp)
+	ok, _ = m.Claim(ctx, "k1", "c", time.Hour)
+	assert.False(t, ok)
+
+	// An expired claim gives way.
+	ok, _ = m.Claim(ctx, "k2", "a", -time.Second)
+	assert.True(t, ok)
+	ok, _ = m.Claim(ctx, "k2", "b", time.Hour)
+	assert.True(t, ok)
+}
+
+func TestStatusOf(t *testing.T) {
+	assert.Equal(t, http.StatusUnprocessableEntity, StatusOf(ErrMismatch))
+	assert.Equal(t, http.StatusConflict, StatusOf(ErrInProgress))
+	assert.Equal(t, http.StatusBadRequest, StatusOf(ErrInvalidKey))
+	assert.Equal(t, http.StatusServiceUnavailable, StatusOf(context.DeadlineExceeded))
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is a Store within the process, for a single server and for tests.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	swept   time.Time
+}
+
+// memoryEntry is a response, or the claim of token if resp is nil.
+type memoryEntry struct {
+	resp    *Response
+	token   string
+	expires time.Time
+}
+
+// NewMemory returns an empty store.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) (*Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || e.resp == nil || !time.Now().Before(e.expires) {
+		return nil, nil
+	}
+	resp := *e.resp
+	return &resp, nil
+}
+
+func (m *Memory) Put(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweep(now)
+	stored := *resp
+	m.entries[key] = memoryEntry{resp: &stored, expires: now.Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Claim(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	m.sweep(now)
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return false, nil
+	}
+	m.entries[key] = memoryEntry{token: token, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *Memory) Unclaim(ctx context.Context, key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok && e.resp == nil && e.token == token {
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// sweep drops the expired entries now and then.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) <= time.Minute {
+		return
+	}
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.swept = now
+}
+
+// Len returns the number of stored responses and claims, including expired ones not
+// dropped yet.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Clear drops all responses and claims.
+func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = make(map[string]memoryEntry)
+}
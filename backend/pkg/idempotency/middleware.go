@@ -0,0 +1,210 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// claimRetryInterval is the pause between the attempts of a duplicate to claim a key.
+const claimRetryInterval = 10 * time.Millisecond
+
+// Config configures Middleware.
+type Config struct {
+	// Store keeps the responses. It is required.
+	Store Store
+	// Header carries the key. Empty means DefaultHeader.
+	Header string
+	// Scope returns the namespace of the keys of a request, typically the caller, so
+	// that two clients that happen to pick the same key do not see each other's
+	// responses. Nil puts all keys in one namespace.
+	Scope func(c *gin.Context) string
+	// Retention is how long a response is replayed. Zero means DefaultRetention.
+	Retention time.Duration
+	// ClaimTTL is how long a key stays in flight once its request has claimed it. It
+	// should exceed the longest run of the handler: a duplicate that arrives after it
+	// runs the handler again. Zero means DefaultClaimTTL.
+	ClaimTTL time.Duration
+	// Wait is how long a duplicate waits for the request in progress to finish before
+	// it gets ErrInProgress. Zero answers it at once.
+	Wait time.Duration
+	// OnError writes the response of a request that is not passed on, for ErrMismatch,
+	// ErrInProgress, ErrInvalidKey and store failures. Nil writes the status of
+	// StatusOf with a JSON body {"error": "..."}.
+	OnError func(c *gin.Context, err error)
+	// Logger reports the responses that could not be stored. Nil means slog.Default().
+	Logger *slog.Logger
+}
+
+// Middleware returns Gin middleware that runs the handlers of a request with a key at
+// most once per key and replays the stored response to every retry, with the
+// ReplayedHeader set. Requests without a key pass through.
+//
+// Only responses below 500 are stored: after a server error the client may retry and
+// have the handler run again. An ErrInProgress response carries a Retry-After header.
+func Middleware(cfg Config) gin.HandlerFunc {
+	if cfg.Header == "" {
+		cfg.Header = DefaultHeader
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = DefaultRetention
+	}
+	if cfg.ClaimTTL <= 0 {
+		cfg.ClaimTTL = DefaultClaimTTL
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(c *gin.Context, err error) {
+			c.JSON(StatusOf(err), gin.H{"error": err.Error()})
+		}
+	}
+	return func(c *gin.Context) {
+		key := c.GetHeader(cfg.Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if err := cfg.handle(c, key); err != nil {
+			if errors.Is(err, ErrInProgress) {
+				c.Header("Retry-After", "1")
+			}
+			cfg.OnError(c, err)
+			c.Abort()
+		}
+	}
+}
+
+// handle runs the rest of the chain for the first request with key, or replays its
+// response. It returns the errors that OnError has to answer.
+func (cfg Config) handle(c *gin.Context, key string) error {
+	if cfg.Scope != nil {
+		key = cfg.Scope(c) + ":" + key
+	}
+	if len(key) > MaxKeyLen {
+		return fmt.Errorf("%w: longer than %d bytes with its scope", ErrInvalidKey, MaxKeyLen)
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return fmt.Errorf("idempotency: read body: %w", err)
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	fingerprint := Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+	ctx := c.Request.Context()
+
+	token, err := cfg.claim(c, key, fingerprint)
+	if token == "" || err != nil {
+		return err
+	}
+	// A response below 500 replaces the claim, or keeps it until it expires if it cannot
+	// be stored. After a server error the claim is dropped, so that a retry runs the
+	// handler again.
+	stored := false
+	defer func() {
+		if !stored {
+			_ = cfg.Store.Unclaim(context.WithoutCancel(ctx), key, token)
+		}
+	}()
+
+	rec := &recorder{ResponseWriter: c.Writer}
+	c.Writer = rec
+	c.Next()
+	c.Writer = rec.ResponseWriter
+	if rec.Status() >= http.StatusInternalServerError {
+		return nil
+	}
+	resp := &Response{
+		Fingerprint: fingerprint,
+		Status:      rec.Status(),
+		ContentType: rec.Header().Get("Content-Type"),
+		Body:        rec.body.Bytes(),
+	}
+	stored = true
+	// The response has been sent. If it cannot be stored, the claim turns retries away
+	// with 409 until it expires, so that they do not run the handler again at once.
+	if err := cfg.Store.Put(context.WithoutCancel(ctx), key, resp, cfg.Retention); err != nil {
+		cfg.Logger.Error("Failed to store idempotent response", "key", key, "claim_ttl", cfg.ClaimTTL, "error", err)
+	}
+	return nil
+}
+
+// claim claims key for the request, waiting up to cfg.Wait while another request holds
+// it. It returns the token of the claim, or "" if it replayed the stored response
+// instead.
+func (cfg Config) claim(c *gin.Context, key, fingerprint string) (string, error) {
+	ctx := c.Request.Context()
+	token := newToken()
+	deadline := time.Now().Add(cfg.Wait)
+	for {
+		if done, err := cfg.replay(c, key, fingerprint); done || err != nil {
+			return "", err
+		}
+		ok, err := cfg.Store.Claim(ctx, key, token, cfg.ClaimTTL)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return token, nil
+		}
+		if !time.Now().Before(deadline) {
+			return "", ErrInProgress
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(claimRetryInterval):
+		}
+	}
+}
+
+// replay answers the request with the response stored under key, if there is one. It
+// reports whether it did.
+func (cfg Config) replay(c *gin.Context, key, fingerprint string) (bool, error) {
+	resp, err := cfg.Store.Get(c.Request.Context(), key)
+	if err != nil {
+		return false, err
+	}
+	if resp == nil {
+		return false, nil
+	}
+	if resp.Fingerprint != fingerprint {
+		return false, ErrMismatch
+	}
+	c.Header(ReplayedHeader, "true")
+	c.Data(resp.Status, resp.ContentType, resp.Body)
+	c.Abort()
+	return true, nil
+}
+
+// newToken returns a random token that identifies a claim.
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// recorder keeps a copy of the response body.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,131 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MySQLTable is the DDL of the table that MySQL keeps the responses in. CreateMySQLTable
+// creates it.
+const MySQLTable = `CREATE TABLE IF NOT EXISTS idempotency_record
+(
+    idem_key     VARCHAR(191)  NOT NULL COMMENT 'Idempotency key, with the scope of the client',
+    claim_token  CHAR(32)      NOT NULL DEFAULT '' COMMENT 'Token of the request in flight, empty once its response is stored',
+    fingerprint  CHAR(64)      NOT NULL COMMENT 'SHA-256 of the method, path and body of the request',
+    status_code  INT           NOT NULL COMMENT 'HTTP status of the response',
+    content_type VARCHAR(128)  NOT NULL DEFAULT '' COMMENT 'Content type of the response',
+    body         MEDIUMBLOB    NOT NULL COMMENT 'Response body',
+    expires_at   DATETIME(3)   NOT NULL COMMENT 'End of the retention period, or of the claim',
+    created_at   TIMESTAMP(3)  NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Creation time',
+    PRIMARY KEY (idem_key),
+    KEY idx_expires_at (expires_at)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='Responses of idempotent requests'`
+
+// CreateMySQLTable creates the idempotency_record table if it does not exist.
+func CreateMySQLTable(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, MySQLTable); err != nil {
+		return fmt.Errorf("idempotency: create table: %w", err)
+	}
+	return nil
+}
+
+// MySQL is a Store in the idempotency_record table. A claim is a row with the token of
+// the request in flight and no response, replaced by the response once it is stored.
+// Expired rows are ignored and replaced, but stay in the table until Purge deletes them;
+// the store does not purge by itself, so its owner runs PurgeEvery.
+type MySQL struct {
+	db *sql.DB
+}
+
+// NewMySQL returns a store in db. The idempotency_record table must exist.
+func NewMySQL(db *sql.DB) *MySQL {
+	return &MySQL{db: db}
+}
+
+func (m *MySQL) Get(ctx context.Context, key string) (*Response, error) {
+	var resp Response
+	err := m.db.QueryRowContext(ctx, "SELECT fingerprint, status_code, content_type, body FROM idempotency_record WHERE idem_key = ? AND claim_token = '' AND expires_at > ?",
+		key, time.Now()).Scan(&resp.Fingerprint, &resp.Status, &resp.ContentType, &resp.Body)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: get %s: %w", key, err)
+	}
+	return &resp, nil
+}
+
+func (m *MySQL) Put(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	_, err := m.db.ExecContext(ctx, "INSERT INTO idempotency_record (idem_key, fingerprint, status_code, content_type, body, expires_at) VALUES (?, ?, ?, ?, ?, ?) "+
+		"ON DUPLICATE KEY UPDATE claim_token = '', fingerprint = VALUES(fingerprint), status_code = VALUES(status_code), content_type = VALUES(content_type), body = VALUES(body), expires_at = VALUES(expires_at)",
+		key, resp.Fingerprint, resp.Status, resp.ContentType, resp.Body, time.Now().Add(ttl))
+	if err != nil {
+		return fmt.Errorf("idempotency: put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (m *MySQL) Claim(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// An expired row, a response or a claim, gives way to the new claim.
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE idem_key = ? AND expires_at <= ?", key, now); err != nil {
+		return false, fmt.Errorf("idempotency: claim %s: %w", key, err)
+	}
+	res, err := m.db.ExecContext(ctx, "INSERT IGNORE INTO idempotency_record (idem_key, claim_token, fingerprint, status_code, body, expires_at) VALUES (?, ?, '', 0, '', ?)",
+		key, token, now.Add(ttl))
+	if err != nil {
+		return false, fmt.Errorf("idempotency: claim %s: %w", key, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("idempotency: claim %s: %w", key, err)
+	}
+	return n == 1, nil
+}
+
+func (m *MySQL) Unclaim(ctx context.Context, key, token string) error {
+	if _, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE idem_key = ? AND claim_token = ?", key, token); err != nil {
+		return fmt.Errorf("idempotency: unclaim %s: %w", key, err)
+	}
+	return nil
+}
+
+// Purge deletes the expired rows and returns how many it deleted.
+func (m *MySQL) Purge(ctx context.Context) (int64, error) {
+	res, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("idempotency: purge: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// PurgeEvery runs Purge every interval until ctx is done. It blocks, so run it in a
+// goroutine of its own. The errors of Purge are passed to onError, if it is not nil.
+func (m *MySQL) PurgeEvery(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Purge(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// DeletePrefix deletes the rows whose keys start with prefix and returns how many it
+// deleted.
+func (m *MySQL) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	res, err := m.db.ExecContext(ctx, "DELETE FROM idempotency_record WHERE LEFT(idem_key, CHAR_LENGTH(?)) = ?", prefix, prefix)
+	if err != nil {
+		return 0, fmt.Errorf("idempotency: delete %s: %w", prefix, err)
+	}
+	return res.RowsAffected()
+}
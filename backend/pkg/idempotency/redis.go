@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// claimPrefix starts the value of a claimed key in Redis, followed by the token of the
+// claim. A response is a JSON object, so it never starts with it.
+const claimPrefix = "claim:"
+
+// unclaimScript deletes a key only if it still holds the claim of the caller's token.
+var unclaimScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Redis is a Store in Redis, shared by the servers behind a load balancer. Each
+// response is a JSON string under the prefixed key, expiring with its TTL. A claim is
+// the string "claim:" and its token under the same key, set with SET NX PX.
+type Redis struct {
+	client redis.Cmdable
+	prefix string
+}
+
+// NewRedis returns a store that keeps its responses under prefix.
+func NewRedis(client redis.Cmdable, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) (*Response, error) {
+	data, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: get %s: %w", key, err)
+	}
+	if bytes.HasPrefix(data, []byte(claimPrefix)) {
+		return nil, nil
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("idempotency: decode %s: %w", key, err)
+	}
+	return &resp, nil
+}
+
+func (r *Redis) Put(ctx context.Context, key string, resp *Response, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("idempotency: encode %s: %w", key, err)
+	}
+	if err := r.client.Set(ctx, r.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("idempotency: put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Redis) Claim(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.prefix+key, claimPrefix+token, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("idempotency: claim %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+func (r *Redis) Unclaim(ctx context.Context, key, token string) error {
+	if err := unclaimScript.Run(ctx, r.client, []string{r.prefix + key}, claimPrefix+token).Err(); err != nil {
+		return fmt.Errorf("idempotency: unclaim %s: %w", key, err)
+	}
+	return nil
+}
+
+// DeletePrefix removes the responses and claims whose keys start with prefix and returns how many
+// it removed.
+func (r *Redis) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var deleted int64
+	iter := r.client.Scan(ctx, 0, r.prefix+prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		n, err := r.client.Del(ctx, iter.Val()).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("idempotency: delete %s: %w", iter.Val(), err)
+		}
+		deleted += n
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, fmt.Errorf("idempotency: scan %s: %w", r.prefix+prefix, err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,138 @@
+// Package lock provides distributed locks with leases, fencing tokens and automatic
+// lease renewal.
+//
+// A Locker grants a Lease on a key. The lease expires after its TTL unless it is
+// refreshed, so that a crashed holder cannot keep the lock forever; Watch refreshes it
+// in the background. Because a lease can expire while its holder still believes it owns
+// the lock (a long GC pause, a network partition), every lease carries a fencing token
+// that is larger than that of every earlier lease on the key. Storage that remembers the
+// largest token it has seen can reject the writes of a holder whose lease has passed.
+//
+// The backends are Memory for a single process, Redis for a single Redis node, Redlock
+// for a quorum of independent Redis nodes, and MySQLGetLock and MySQLRowLock for MySQL.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrNotAcquired means the lock stayed taken until the acquire timeout passed.
+	ErrNotAcquired = errors.New("lock: not acquired")
+	// ErrNotHeld means a lease could not be refreshed or released because it has
+	// expired, possibly with the lock taken by someone else since.
+	ErrNotHeld = errors.New("lock: not held")
+)
+
+const (
+	// DefaultTTL is the lease length used when Options.TTL is zero.
+	DefaultTTL = 10 * time.Second
+	// DefaultRetryInterval is the pause between attempts used when Options.RetryInterval is zero.
+	DefaultRetryInterval = 10 * time.Millisecond
+)
+
+// Locker grants leases on keys. Implementations are safe for concurrent use.
+type Locker interface {
+	// Acquire takes the lock on key, trying again until it is free, opts.Timeout
+	// passes (ErrNotAcquired) or ctx is done.
+	Acquire(ctx context.Context, key string, opts Options) (*Lease, error)
+	// Refresh extends the lease by its TTL. It returns ErrNotHeld if the lease has
+	// already expired.
+	Refresh(ctx context.Context, l *Lease) error
+	// Release gives up the lease. It returns ErrNotHeld if the lease had already
+	// expired; the lock is never taken away from a newer holder.
+	Release(ctx context.Context, l *Lease) error
+}
+
+// Options control how a lock is acquired.
+type Options struct {
+	// TTL is how long the lease lasts unless it is refreshed. Zero means DefaultTTL.
+	TTL time.Duration
+	// Timeout is how long to wait for a taken lock. Zero makes a single attempt.
+	Timeout time.Duration
+	// RetryInterval is the pause between attempts. Zero means DefaultRetryInterval.
+	RetryInterval time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.TTL <= 0 {
+		o.TTL = DefaultTTL
+	}
+	if o.RetryInterval <= 0 {
+		o.RetryInterval = DefaultRetryInterval
+	}
+	return o
+}
+
+// Lease is a held lock.
+type Lease struct {
+	// Key is the locked key.
+	Key string
+	// Token identifies the holder; only it can refresh or release the lease.
+	Token string
+	// Fence is the fencing token: larger than that of every earlier lease on Key.
+	Fence int64
+	// TTL is the lease length, by which Refresh extends it.
+	TTL time.Duration
+	// Retries counts the attempts that found the lock taken before it was acquired.
+	Retries int
+
+	expires atomic.Int64 // unix nanoseconds
+}
+
+func newLease(key string, fence int64, ttl time.Duration, token string) *Lease {
+	l := &Lease{Key: key, Token: token, Fence: fence, TTL: ttl}
+	l.extend(time.Now(), ttl)
+	return l
+}
+
+// Expires returns when the lease ends unless it is refreshed, as measured by the
+// holder's clock. The backend may consider it over a little earlier.
+func (l *Lease) Expires() time.Time {
+	return time.Unix(0, l.expires.Load())
+}
+
+// extend moves the end of the lease to start+d.
+func (l *Lease) extend(start time.Time, d time.Duration) {
+	l.expires.Store(start.Add(d).UnixNano())
+}
+
+// newToken returns a random holder token.
+func newToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// retry calls try until it reports the lock acquired, returns an error, the timeout
+// of opts passes or ctx is done. It returns the number of attempts that found the
+// lock taken.
+func retry(ctx context.Context, key string, opts Options, try func() (bool, error)) (retries int, err error) {
+	deadline := time.Now().Add(opts.Timeout)
+	for {
+		ok, err := try()
+		if err != nil {
+			return retries, err
+		}
+		if ok {
+			return retries, nil
+		}
+		if !time.Now().Before(deadline) {
+			return retries, fmt.Errorf("%w: %s within %s", ErrNotAcquired, key, opts.Timeout)
+		}
+		retries++
+		timer := time.NewTimer(opts.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
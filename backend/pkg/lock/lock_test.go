@@ -0,0 +1,116 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+
+	a, err := m.Acquire(ctx, "k", Options{TTL: 30 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(1), a.Fence)
+
+	_, err = m.Acquire(ctx, "k", Options{})
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	// b waits for a's lease to run out and gets a larger fencing token.
+	b, err := m.Acquire(ctx, "k", Options{TTL: time.Second, Timeout: time.Second, RetryInterval: 5 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(2), b.Fence)
+	assert.Positive(t, b.Retries)
+
+	// a's lease has passed: it can neither refresh nor release b's lock.
+	assert.ErrorIs(t, m.Refresh(ctx, a), ErrNotHeld)
+	assert.ErrorIs(t, m.Release(ctx, a), ErrNotHeld)
+	_, err = m.Acquire(ctx, "k", Options{})
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	assert.NoError(t, m.Refresh(ctx, b))
+	assert.NoError(t, m.Release(ctx, b))
+	c, err := m.Acquire(ctx, "k", Options{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), c.Fence)
+	}
+}
+
+func TestMutualExclusion(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	var inside, violations, lastFence int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				err := Do(ctx, m, "k", Options{Timeout: time.Second, RetryInterval: time.Millisecond}, func(ctx context.Context, l *Lease) error {
+					if atomic.AddInt64(&inside, 1) != 1 {
+						atomic.AddInt64(&violations, 1)
+					}
+					if l.Fence <= atomic.LoadInt64(&lastFence) {
+						atomic.AddInt64(&violations, 1)
+					}
+					atomic.StoreInt64(&lastFence, l.Fence)
+					atomic.AddInt64(&inside, -1)
+					return nil
+				})
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Zero(t, violations)
+	assert.Equal(t, int64(80), lastFence)
+}
+
+func TestWatchdog(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	l, err := m.Acquire(ctx, "k", Options{TTL: 30 * time.Millisecond})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w := Watch(ctx, m, l)
+	time.Sleep(100 * time.Millisecond)
+	// The watchdog kept the lease alive for several TTLs.
+	_, err = m.Acquire(ctx, "k", Options{})
+	assert.ErrorIs(t, err, ErrNotAcquired)
+	assert.NoError(t, w.Err())
+	w.Stop()
+	assert.NoError(t, m.Release(ctx, l))
+}
+
+// stealing is a Locker whose leases are taken away at the first refresh.
+type stealing struct {
+	*Memory
+}
+
+func (s stealing) Refresh(ctx context.Context, l *Lease) error {
+	return ErrNotHeld
+}
+
+func TestDoLost(t *testing.T) {
+	ctx := context.Background()
+	err := Do(ctx, stealing{NewMemory()}, "k", Options{TTL: 15 * time.Millisecond}, func(ctx context.Context, l *Lease) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return errors.New("context not canceled")
+		}
+	})
+	assert.ErrorIs(t, err, ErrNotHeld)
+}
@@ -0,0 +1,77 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Memory is a Locker for the goroutines of a single process.
+type Memory struct {
+	mu     sync.Mutex
+	held   map[string]memoryLease
+	fences map[string]int64
+}
+
+type memoryLease struct {
+	token   string
+	expires time.Time
+}
+
+// NewMemory returns an in-memory Locker.
+func NewMemory() *Memory {
+	return &Memory{held: make(map[string]memoryLease), fences: make(map[string]int64)}
+}
+
+func (m *Memory) Acquire(ctx context.Context, key string, opts Options) (*Lease, error) {
+	opts = opts.withDefaults()
+	token := newToken()
+	var fence int64
+	retries, err := retry(ctx, key, opts, func() (bool, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		now := time.Now()
+		if h, ok := m.held[key]; ok && now.Before(h.expires) {
+			return false, nil
+		}
+		m.fences[key]++
+		fence = m.fences[key]
+		m.held[key] = memoryLease{token: token, expires: now.Add(opts.TTL)}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := newLease(key, fence, opts.TTL, token)
+	l.Retries = retries
+	return l, nil
+}
+
+func (m *Memory) Refresh(ctx context.Context, l *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	h, ok := m.held[l.Key]
+	if !ok || h.token != l.Token || !now.Before(h.expires) {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	h.expires = now.Add(l.TTL)
+	m.held[l.Key] = h
+	l.extend(now, l.TTL)
+	return nil
+}
+
+func (m *Memory) Release(ctx context.Context, l *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.held[l.Key]
+	if !ok || h.token != l.Token {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	delete(m.held, l.Key)
+	if !time.Now().Before(h.expires) {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	return nil
+}
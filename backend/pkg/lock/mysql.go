@@ -0,0 +1,235 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MySQLTable is the DDL of the table that holds the fence counters of both MySQL
+// lockers and the leases of MySQLRowLock. CreateMySQLTable creates it.
+const MySQLTable = `CREATE TABLE IF NOT EXISTS distributed_lock
+(
+    name       VARCHAR(191) NOT NULL COMMENT 'Lock key',
+    owner      VARCHAR(64)  NOT NULL DEFAULT '' COMMENT 'Token of the lease holder, empty if free',
+    fence      BIGINT       NOT NULL DEFAULT 0 COMMENT 'Fencing token of the latest lease',
+    expires_at DATETIME(3)  NOT NULL DEFAULT '1970-01-01 00:00:01' COMMENT 'End of the lease',
+    PRIMARY KEY (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='Distributed locks'`
+
+// CreateMySQLTable creates the distributed_lock table if it does not exist.
+func CreateMySQLTable(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, MySQLTable); err != nil {
+		return fmt.Errorf("lock: create table: %w", err)
+	}
+	return nil
+}
+
+// execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// nextFence increments the fence counter of key and returns it. LAST_INSERT_ID(expr)
+// makes the new value the insert ID of the statement.
+func nextFence(ctx context.Context, exec execer, key string) (int64, error) {
+	res, err := exec.ExecContext(ctx, "INSERT INTO distributed_lock (name, fence) VALUES (?, LAST_INSERT_ID(1)) "+
+		"ON DUPLICATE KEY UPDATE fence = LAST_INSERT_ID(fence + 1)", key)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// MySQLGetLock is a Locker on the named locks of MySQL (GET_LOCK). A named lock belongs
+// to a connection, so each lease keeps one connection of the pool until it is released,
+// and the lock ends when the connection does: MySQL itself never expires it. Refresh
+// checks that the connection still holds the lock.
+type MySQLGetLock struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	conns map[string]*sql.Conn // by lease token
+}
+
+// NewMySQLGetLock returns a Locker on the named locks of db. The distributed_lock table
+// must exist for the fence counters.
+func NewMySQLGetLock(db *sql.DB) *MySQLGetLock {
+	return &MySQLGetLock{db: db, conns: make(map[string]*sql.Conn)}
+}
+
+func (m *MySQLGetLock) Acquire(ctx context.Context, key string, opts Options) (*Lease, error) {
+	opts = opts.withDefaults()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lock: acquire %s: %w", key, err)
+	}
+	retries, err := retry(ctx, key, opts, func() (bool, error) {
+		var got sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", key).Scan(&got); err != nil {
+			return false, fmt.Errorf("lock: acquire %s: %w", key, err)
+		}
+		return got.Valid && got.Int64 == 1, nil
+	})
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	fence, err := nextFence(ctx, conn, key)
+	if err != nil {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "DO RELEASE_LOCK(?)", key)
+		_ = conn.Close()
+		return nil, fmt.Errorf("lock: fence %s: %w", key, err)
+	}
+
+	l := newLease(key, fence, opts.TTL, newToken())
+	l.Retries = retries
+	m.mu.Lock()
+	m.conns[l.Token] = conn
+	m.mu.Unlock()
+	return l, nil
+}
+
+func (m *MySQLGetLock) conn(l *Lease) (*sql.Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	conn, ok := m.conns[l.Token]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	return conn, nil
+}
+
+func (m *MySQLGetLock) Refresh(ctx context.Context, l *Lease) error {
+	conn, err := m.conn(l)
+	if err != nil {
+		return err
+	}
+	var held sql.NullBool
+	if err := conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.Key).Scan(&held); err != nil {
+		if errors.Is(err, sql.ErrConnDone) {
+			return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+		}
+		return fmt.Errorf("lock: refresh %s: %w", l.Key, err)
+	}
+	if !held.Valid || !held.Bool {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	l.extend(time.Now(), l.TTL)
+	return nil
+}
+
+func (m *MySQLGetLock) Release(ctx context.Context, l *Lease) error {
+	conn, err := m.conn(l)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	delete(m.conns, l.Token)
+	m.mu.Unlock()
+	defer conn.Close()
+
+	var released sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", l.Key).Scan(&released); err != nil {
+		return fmt.Errorf("lock: release %s: %w", l.Key, err)
+	}
+	if !released.Valid || released.Int64 != 1 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	return nil
+}
+
+// MySQLRowLock is a Locker that keeps leases as rows of the distributed_lock table. A
+// lease is taken under the row lock of SELECT ... FOR UPDATE when the row is free or
+// its lease has expired, so it expires like a Redis lock, by the database clock.
+type MySQLRowLock struct {
+	db *sql.DB
+}
+
+// NewMySQLRowLock returns a Locker on the distributed_lock table of db.
+func NewMySQLRowLock(db *sql.DB) *MySQLRowLock {
+	return &MySQLRowLock{db: db}
+}
+
+func (m *MySQLRowLock) Acquire(ctx context.Context, key string, opts Options) (*Lease, error) {
+	opts = opts.withDefaults()
+	token := newToken()
+	var fence int64
+	var start time.Time
+	retries, err := retry(ctx, key, opts, func() (bool, error) {
+		start = time.Now()
+		ok, err := m.tryAcquire(ctx, key, token, opts.TTL, &fence)
+		if err != nil {
+			return false, fmt.Errorf("lock: acquire %s: %w", key, err)
+		}
+		return ok, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := newLease(key, fence, opts.TTL, token)
+	l.extend(start, opts.TTL)
+	l.Retries = retries
+	return l, nil
+}
+
+func (m *MySQLRowLock) tryAcquire(ctx context.Context, key, token string, ttl time.Duration, fence *int64) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "INSERT IGNORE INTO distributed_lock (name) VALUES (?)", key); err != nil {
+		return false, err
+	}
+	var owner string
+	var live bool
+	err = tx.QueryRowContext(ctx, "SELECT owner, expires_at > NOW(3) FROM distributed_lock WHERE name = ? FOR UPDATE", key).
+		Scan(&owner, &live)
+	if err != nil {
+		return false, err
+	}
+	if owner != "" && live {
+		return false, nil
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE distributed_lock SET owner = ?, fence = LAST_INSERT_ID(fence + 1), "+
+		"expires_at = NOW(3) + INTERVAL ? MICROSECOND WHERE name = ?", token, ttl.Microseconds(), key)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT LAST_INSERT_ID()").Scan(fence); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (m *MySQLRowLock) Refresh(ctx context.Context, l *Lease) error {
+	start := time.Now()
+	res, err := m.db.ExecContext(ctx, "UPDATE distributed_lock SET expires_at = NOW(3) + INTERVAL ? MICROSECOND "+
+		"WHERE name = ? AND owner = ? AND expires_at > NOW(3)", l.TTL.Microseconds(), l.Key, l.Token)
+	if err != nil {
+		return fmt.Errorf("lock: refresh %s: %w", l.Key, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	l.extend(start, l.TTL)
+	return nil
+}
+
+func (m *MySQLRowLock) Release(ctx context.Context, l *Lease) error {
+	res, err := m.db.ExecContext(ctx, "UPDATE distributed_lock SET owner = '', expires_at = NOW(3) "+
+		"WHERE name = ? AND owner = ? AND expires_at > NOW(3)", l.Key, l.Token)
+	if err != nil {
+		return fmt.Errorf("lock: release %s: %w", l.Key, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// acquireScript sets the lock if it is free and then increments its fence counter.
+	// It returns the new fencing token, or 0 if the lock is taken.
+	acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+	// refreshScript extends the lock only if it still holds the caller's token.
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+	// releaseScript deletes the lock only if it still holds the caller's token. A holder
+	// whose lease expired must not delete the lock that someone else has taken since.
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+)
+
+// Redis is a Locker on a single Redis node. A lock is a key set with SET key token NX
+// PX ttl; its fencing tokens come from a counter at the key with ":fence" appended.
+//
+// If the node fails over to a replica that missed the write, two holders can hold the
+// lock at once; use Redlock or fencing where that matters.
+type Redis struct {
+	client redis.Scripter
+}
+
+// NewRedis returns a Locker on the Redis node of client.
+func NewRedis(client redis.Scripter) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Acquire(ctx context.Context, key string, opts Options) (*Lease, error) {
+	opts = opts.withDefaults()
+	token := newToken()
+	var fence int64
+	var start time.Time
+	retries, err := retry(ctx, key, opts, func() (bool, error) {
+		start = time.Now()
+		n, err := acquireScript.Run(ctx, r.client, []string{key, fenceKey(key)}, token, opts.TTL.Milliseconds()).Int64()
+		if err != nil {
+			return false, fmt.Errorf("lock: acquire %s: %w", key, err)
+		}
+		fence = n
+		return n > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := newLease(key, fence, opts.TTL, token)
+	l.extend(start, opts.TTL)
+	l.Retries = retries
+	return l, nil
+}
+
+func (r *Redis) Refresh(ctx context.Context, l *Lease) error {
+	start := time.Now()
+	n, err := refreshScript.Run(ctx, r.client, []string{l.Key}, l.Token, l.TTL.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("lock: refresh %s: %w", l.Key, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	l.extend(start, l.TTL)
+	return nil
+}
+
+func (r *Redis) Release(ctx context.Context, l *Lease) error {
+	n, err := releaseScript.Run(ctx, r.client, []string{l.Key}, l.Token).Int64()
+	if err != nil {
+		return fmt.Errorf("lock: release %s: %w", l.Key, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+	}
+	return nil
+}
+
+// fenceKey returns the key of the fence counter of the lock at key.
+func fenceKey(key string) string {
+	return key + ":fence"
+}
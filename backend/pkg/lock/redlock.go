@@ -0,0 +1,143 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// clockDriftFactor is the share of the TTL that Redlock gives up for clock drift
+// between the nodes.
+const clockDriftFactor = 0.01
+
+// raiseScript raises the fence counter to ARGV[1] unless it is already higher.
+var raiseScript = redis.NewScript(`
+local cur = tonumber(redis.call("GET", KEYS[1]) or "0")
+if cur < tonumber(ARGV[1]) then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// Redlock is a Locker on N independent Redis nodes that holds a lock once a majority of
+// them granted it within its TTL, so that it survives the failure of a minority.
+//
+// The fencing token of a lease is the largest counter value of the granting nodes,
+// which then raise their counters to it. Any two majorities share a node, so a later
+// lease always sees a larger value.
+type Redlock struct {
+	nodes []redis.Scripter
+}
+
+// NewRedlock returns a Locker on the given nodes, which should be an odd number of
+// independent Redis servers.
+func NewRedlock(nodes ...redis.Scripter) *Redlock {
+	return &Redlock{nodes: nodes}
+}
+
+func (r *Redlock) quorum() int {
+	return len(r.nodes)/2 + 1
+}
+
+// validity returns how long a lease of ttl granted by calls that started at start
+// remains safe to use.
+func validity(ttl time.Duration, start time.Time) time.Duration {
+	return ttl - time.Since(start) - time.Duration(float64(ttl)*clockDriftFactor)
+}
+
+// each runs fn on every node with a timeout of a tenth of ttl, so that a dead node does
+// not use up the lease. It returns the indexes of the nodes on which fn returned true,
+// and the last error.
+func (r *Redlock) each(ctx context.Context, ttl time.Duration, fn func(ctx context.Context, node redis.Scripter) (bool, error)) (ok []int, failed int, err error) {
+	for i, node := range r.nodes {
+		nctx, cancel := context.WithTimeout(ctx, ttl/10)
+		granted, nerr := fn(nctx, node)
+		cancel()
+		switch {
+		case nerr != nil:
+			failed++
+			err = nerr
+		case granted:
+			ok = append(ok, i)
+		}
+	}
+	return ok, failed, err
+}
+
+func (r *Redlock) Acquire(ctx context.Context, key string, opts Options) (*Lease, error) {
+	opts = opts.withDefaults()
+	token := newToken()
+	var l *Lease
+	retries, err := retry(ctx, key, opts, func() (bool, error) {
+		start := time.Now()
+		var fence int64
+		granted, failed, err := r.each(ctx, opts.TTL, func(ctx context.Context, node redis.Scripter) (bool, error) {
+			n, err := acquireScript.Run(ctx, node, []string{key, fenceKey(key)}, token, opts.TTL.Milliseconds()).Int64()
+			fence = max(fence, n)
+			return n > 0, err
+		})
+		if len(granted) >= r.quorum() {
+			raised := 0
+			for _, i := range granted {
+				if raiseScript.Run(ctx, r.nodes[i], []string{fenceKey(key)}, fence).Err() == nil {
+					raised++
+				}
+			}
+			if left := validity(opts.TTL, start); raised >= r.quorum() && left > 0 {
+				l = newLease(key, fence, opts.TTL, token)
+				l.extend(time.Now(), left)
+				return true, nil
+			}
+		}
+		// Give back what was granted, so that the nodes are free for the next attempt.
+		r.release(context.WithoutCancel(ctx), key, token, opts.TTL)
+		if len(r.nodes)-failed < r.quorum() {
+			return false, fmt.Errorf("lock: acquire %s: %d of %d nodes failed: %w", key, failed, len(r.nodes), err)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.Retries = retries
+	return l, nil
+}
+
+func (r *Redlock) Refresh(ctx context.Context, l *Lease) error {
+	start := time.Now()
+	refreshed, failed, err := r.each(ctx, l.TTL, func(ctx context.Context, node redis.Scripter) (bool, error) {
+		n, err := refreshScript.Run(ctx, node, []string{l.Key}, l.Token, l.TTL.Milliseconds()).Int64()
+		return n == 1, err
+	})
+	left := validity(l.TTL, start)
+	if len(refreshed) >= r.quorum() && left > 0 {
+		l.extend(time.Now(), left)
+		return nil
+	}
+	if len(r.nodes)-failed < r.quorum() {
+		return fmt.Errorf("lock: refresh %s: %d of %d nodes failed: %w", l.Key, failed, len(r.nodes), err)
+	}
+	return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+}
+
+func (r *Redlock) Release(ctx context.Context, l *Lease) error {
+	released, failed, err := r.release(ctx, l.Key, l.Token, l.TTL)
+	if released >= r.quorum() {
+		return nil
+	}
+	if len(r.nodes)-failed < r.quorum() {
+		return fmt.Errorf("lock: release %s: %d of %d nodes failed: %w", l.Key, failed, len(r.nodes), err)
+	}
+	return fmt.Errorf("%w: %s", ErrNotHeld, l.Key)
+}
+
+// release deletes the lock on every node that still holds token.
+func (r *Redlock) release(ctx context.Context, key, token string, ttl time.Duration) (released, failed int, err error) {
+	ok, failed, err := r.each(ctx, ttl, func(ctx context.Context, node redis.Scripter) (bool, error) {
+		n, err := releaseScript.Run(ctx, node, []string{key}, token).Int64()
+		return n == 1, err
+	})
+	return len(ok), failed, err
+}
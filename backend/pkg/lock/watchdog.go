@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Watchdog renews a lease in the background for as long as its holder works.
+type Watchdog struct {
+	locker Locker
+	lease  *Lease
+
+	lost chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	once sync.Once
+	err  error
+}
+
+// Watch starts a watchdog that refreshes l every third of its TTL until Stop is called.
+// A refresh that fails with an error other than ErrNotHeld is tried again at the next
+// tick. Once the lease is known to be lost, or has run out while refreshes kept
+// failing, the watchdog closes Lost and stops.
+func Watch(ctx context.Context, locker Locker, l *Lease) *Watchdog {
+	w := &Watchdog{
+		locker: locker,
+		lease:  l,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run(context.WithoutCancel(ctx))
+	return w
+}
+
+func (w *Watchdog) run(ctx context.Context) {
+	defer close(w.done)
+	ticker := time.NewTicker(max(w.lease.TTL/3, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		err := w.locker.Refresh(ctx, w.lease)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrNotHeld) || !time.Now().Before(w.lease.Expires()) {
+			w.err = err
+			close(w.lost)
+			return
+		}
+	}
+}
+
+// Lost returns a channel that is closed when the lease has been lost.
+func (w *Watchdog) Lost() <-chan struct{} {
+	return w.lost
+}
+
+// Err returns why the lease was lost, or nil if it has not been.
+func (w *Watchdog) Err() error {
+	select {
+	case <-w.lost:
+		return w.err
+	default:
+		return nil
+	}
+}
+
+// Stop stops refreshing the lease and waits for a refresh in progress to end.
+func (w *Watchdog) Stop() {
+	w.once.Do(func() { close(w.stop) })
+	<-w.done
+}
+
+// Do acquires the lock on key, runs fn while a watchdog keeps the lease alive and
+// releases the lock. The context passed to fn is canceled if the lease is lost; fn
+// should check it, and use the fencing token of the lease, before each write. Do
+// returns the error of fn, else why the lease was lost, else the error of releasing it.
+func Do(ctx context.Context, locker Locker, key string, opts Options, fn func(ctx context.Context, l *Lease) error) error {
+	l, err := locker.Acquire(ctx, key, opts)
+	if err != nil {
+		return err
+	}
+	w := Watch(ctx, locker, l)
+	fnCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-w.Lost():
+			cancel()
+		case <-fnCtx.Done():
+		}
+	}()
+
+	err = fn(fnCtx, l)
+	cancel()
+	w.Stop()
+	releaseErr := locker.Release(context.WithoutCancel(ctx), l)
+	if lostErr := w.Err(); lostErr != nil && (err == nil || errors.Is(err, context.Canceled)) {
+		return lostErr
+	}
+	if err == nil {
+		err = releaseErr
+	}
+	return err
+}
@@ -0,0 +1,160 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInProgress is returned by DedupStore.Begin while another consumer processes a
+// message with the same key. The message is then delivered again later.
+var ErrInProgress = errors.New("mq: message is being processed")
+
+// KeyFunc returns the idempotency key of a message. An empty key turns deduplication
+// off for the message.
+type KeyFunc func(msg *Message) string
+
+// ByID keys messages by Message.ID, which survives redelivery and republishing.
+func ByID(msg *Message) string { return msg.ID }
+
+// ByKey keys messages by Message.Key, the business key. It also catches a message
+// published twice under different IDs, but only suits one message per business key.
+func ByKey(msg *Message) string { return msg.Key }
+
+// DedupStore records which messages a consumer has processed.
+type DedupStore interface {
+	// Begin claims key for processing. It returns false if key has been processed
+	// already, and ErrInProgress if it is claimed by another consumer.
+	Begin(ctx context.Context, key string) (bool, error)
+	// Done marks key processed.
+	Done(ctx context.Context, key string) error
+	// Abort releases the claim on key, so that the message can be processed again.
+	Abort(ctx context.Context, key string) error
+}
+
+// Dedup is consumer middleware that runs a handler at most once per idempotency key.
+//
+// The claim and the side effects of the handler are not atomic: if the consumer dies
+// after the side effects and before Done, the claim expires and the message is
+// processed again. Handlers whose side effects are writes to MySQL should rather
+// record the key in the same transaction, with MarkConsumed.
+type Dedup struct {
+	Store DedupStore
+	// Key returns the idempotency key of a message. Nil means ByID.
+	Key KeyFunc
+	// OnDuplicate, if set, is called for every message dropped as a duplicate.
+	OnDuplicate func(ctx context.Context, msg *Message)
+}
+
+// Wrap returns a handler that passes h the messages whose key has not been processed.
+func (d Dedup) Wrap(h Handler) Handler {
+	keyOf := d.Key
+	if keyOf == nil {
+		keyOf = ByID
+	}
+	return func(ctx context.Context, msg *Message) error {
+		key := keyOf(msg)
+		if key == "" {
+			return h(ctx, msg)
+		}
+		first, err := d.Store.Begin(ctx, key)
+		if err != nil {
+			return fmt.Errorf("dedup %s: %w", key, err)
+		}
+		if !first {
+			if d.OnDuplicate != nil {
+				d.OnDuplicate(ctx, msg)
+			}
+			return nil
+		}
+		if err := h(ctx, msg); err != nil {
+			if abortErr := d.Store.Abort(ctx, key); abortErr != nil {
+				return errors.Join(err, fmt.Errorf("dedup %s: %w", key, abortErr))
+			}
+			return err
+		}
+		if err := d.Store.Done(ctx, key); err != nil {
+			return fmt.Errorf("dedup %s: %w", key, err)
+		}
+		return nil
+	}
+}
+
+// MemoryDedup is a DedupStore within the process. It suits consumers whose side
+// effects live in the same process, such as a local cache in broadcast mode.
+type MemoryDedup struct {
+	retention time.Duration
+
+	mu   sync.Mutex
+	keys map[string]memoryDedupEntry
+}
+
+type memoryDedupEntry struct {
+	done    bool
+	expires time.Time
+}
+
+// NewMemoryDedup returns a store that remembers processed keys for retention. Zero
+// means forever.
+func NewMemoryDedup(retention time.Duration) *MemoryDedup {
+	return &MemoryDedup{retention: retention, keys: make(map[string]memoryDedupEntry)}
+}
+
+func (m *MemoryDedup) Begin(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if e, ok := m.keys[key]; ok && (e.expires.IsZero() || now.Before(e.expires)) {
+		if e.done {
+			return false, nil
+		}
+		return false, ErrInProgress
+	}
+	m.keys[key] = memoryDedupEntry{}
+	return true, nil
+}
+
+func (m *MemoryDedup) Done(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e := memoryDedupEntry{done: true}
+	if m.retention > 0 {
+		e.expires = time.Now().Add(m.retention)
+		m.purge()
+	}
+	m.keys[key] = e
+	return nil
+}
+
+func (m *MemoryDedup) Abort(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, key)
+	return nil
+}
+
+// Len returns the number of keys the store remembers.
+func (m *MemoryDedup) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.keys)
+}
+
+// Clear forgets all keys.
+func (m *MemoryDedup) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = make(map[string]memoryDedupEntry)
+}
+
+// purge drops the expired keys. The caller must hold m.mu.
+func (m *MemoryDedup) purge() {
+	now := time.Now()
+	for k, e := range m.keys {
+		if e.done && !e.expires.IsZero() && now.After(e.expires) {
+			delete(m.keys, k)
+		}
+	}
+}
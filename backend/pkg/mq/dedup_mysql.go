@@ -0,0 +1,48 @@
+package mq
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DedupTable is the DDL of the table that MarkConsumed records processed messages in.
+// CreateDedupTable creates it.
+const DedupTable = `CREATE TABLE IF NOT EXISTS consumed_message
+(
+    consumer_group VARCHAR(128) NOT NULL COMMENT 'Consumer group that processed the message',
+    message_key    VARCHAR(128) NOT NULL COMMENT 'Idempotency key of the message',
+    created_at     TIMESTAMP(3) NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Processing time',
+    PRIMARY KEY (consumer_group, message_key)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='Messages processed by consumer groups'`
+
+// Execer is implemented by *sql.DB, *sql.Tx and the connection of a gorm transaction
+// (gorm.DB.Statement.ConnPool).
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateDedupTable creates the consumed_message table if it does not exist.
+func CreateDedupTable(ctx context.Context, db Execer) error {
+	if _, err := db.ExecContext(ctx, DedupTable); err != nil {
+		return fmt.Errorf("mq: create table: %w", err)
+	}
+	return nil
+}
+
+// MarkConsumed records in tx that group processed key. It returns false if the key was
+// recorded before: the message is a duplicate, and the caller should skip its business
+// write. Called in the transaction of that write, the record and the write commit or
+// roll back together, so that no crash can separate them.
+func MarkConsumed(ctx context.Context, tx Execer, group, key string) (bool, error) {
+	res, err := tx.ExecContext(ctx, "INSERT IGNORE INTO consumed_message (consumer_group, message_key) VALUES (?, ?)", group, key)
+	if err != nil {
+		return false, fmt.Errorf("mq: mark %s consumed: %w", key, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("mq: mark %s consumed: %w", key, err)
+	}
+	return n == 1, nil
+}
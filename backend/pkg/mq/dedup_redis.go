@@ -0,0 +1,56 @@
+package mq
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	dedupProcessing = "processing"
+	dedupDone       = "done"
+)
+
+// RedisDedup is a DedupStore in Redis, shared by the consumers of a group. Begin claims
+// a key with SET NX and a short TTL, so that the claim of a consumer that died expires;
+// Done replaces it with a marker kept for the retention period.
+type RedisDedup struct {
+	client    redis.Cmdable
+	prefix    string
+	claimTTL  time.Duration
+	retention time.Duration
+}
+
+// NewRedisDedup returns a store that keeps its keys under prefix. A claim lasts for
+// claimTTL, which must exceed the time a handler takes, and a processed key is
+// remembered for retention.
+func NewRedisDedup(client redis.Cmdable, prefix string, claimTTL, retention time.Duration) *RedisDedup {
+	return &RedisDedup{client: client, prefix: prefix, claimTTL: claimTTL, retention: retention}
+}
+
+func (r *RedisDedup) Begin(ctx context.Context, key string) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.prefix+key, dedupProcessing, r.claimTTL).Result()
+	if err != nil || ok {
+		return ok, err
+	}
+	state, err := r.client.Get(ctx, r.prefix+key).Result()
+	switch {
+	case err == redis.Nil:
+		// The claim expired in between; let the redelivery take it.
+		return false, ErrInProgress
+	case err != nil:
+		return false, err
+	case state == dedupDone:
+		return false, nil
+	}
+	return false, ErrInProgress
+}
+
+func (r *RedisDedup) Done(ctx context.Context, key string) error {
+	return r.client.Set(ctx, r.prefix+key, dedupDone, r.retention).Err()
+}
+
+func (r *RedisDedup) Abort(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
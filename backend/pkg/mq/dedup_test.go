@@ -0,0 +1,112 @@
+package mq
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedup(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedup(0)
+	var runs, duplicates int
+	fail := true
+	h := Dedup{
+		Store:       store,
+		OnDuplicate: func(ctx context.Context, msg *Message) { duplicates++ },
+	}.Wrap(func(ctx context.Context, msg *Message) error {
+		runs++
+		if msg.Key == "flaky" && fail {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	msg := &Message{ID: "m1", Key: "p1"}
+	assert.NoError(t, h(ctx, msg))
+	assert.NoError(t, h(ctx, msg))
+	assert.NoError(t, h(ctx, &Message{ID: "m1", Key: "p2"}))
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, 2, duplicates)
+
+	// A failed handler releases its claim, so the redelivery runs it again.
+	flaky := &Message{ID: "m2", Key: "flaky"}
+	assert.Error(t, h(ctx, flaky))
+	fail = false
+	assert.NoError(t, h(ctx, flaky))
+	assert.Equal(t, 3, runs)
+
+	// Messages without a key are not deduplicated.
+	assert.NoError(t, h(ctx, &Message{}))
+	assert.NoError(t, h(ctx, &Message{}))
+	assert.Equal(t, 5, runs)
+
+	// A claimed key is in progress for other consumers.
+	first, err := store.Begin(ctx, "m3")
+	assert.True(t, first)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, h(ctx, &Message{ID: "m3"}), ErrInProgress)
+}
+
+func TestDedupByKey(t *testing.T) {
+	ctx := context.Background()
+	runs := 0
+	h := Dedup{Store: NewMemoryDedup(0), Key: ByKey}.Wrap(func(ctx context.Context, msg *Message) error {
+		runs++
+		return nil
+	})
+	assert.NoError(t, h(ctx, &Message{ID: "m1", Key: "p1"}))
+	assert.NoError(t, h(ctx, &Message{ID: "m2", Key: "p1"}))
+	assert.Equal(t, 1, runs)
+}
+
+func TestMemoryDedupRetention(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDedup(10 * time.Millisecond)
+	first, _ := store.Begin(ctx, "k")
+	assert.True(t, first)
+	assert.NoError(t, store.Done(ctx, "k"))
+	first, _ = store.Begin(ctx, "k")
+	assert.False(t, first)
+
+	time.Sleep(20 * time.Millisecond)
+	first, _ = store.Begin(ctx, "k")
+	assert.True(t, first)
+	assert.NoError(t, store.Done(ctx, "other"))
+	assert.Equal(t, 2, store.Len())
+	store.Clear()
+	assert.Zero(t, store.Len())
+}
+
+// fakeExecer answers every statement with the given number of affected rows.
+type fakeExecer struct {
+	rows  int64
+	query string
+}
+
+func (f *fakeExecer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	f.query = query
+	return driverResult(f.rows), nil
+}
+
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+func TestMarkConsumed(t *testing.T) {
+	ctx := context.Background()
+	first, err := MarkConsumed(ctx, &fakeExecer{rows: 1}, "billing", "m1")
+	assert.NoError(t, err)
+	assert.True(t, first)
+
+	exec := &fakeExecer{rows: 0}
+	first, err = MarkConsumed(ctx, exec, "billing", "m1")
+	assert.NoError(t, err)
+	assert.False(t, first)
+	assert.Contains(t, exec.query, "INSERT IGNORE INTO consumed_message")
+}
@@ -0,0 +1,262 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+)
+
+var errClosed = errors.New("mq: broker closed")
+
+// MemoryOptions configure a Memory broker.
+type MemoryOptions struct {
+	// RedeliveryDelay is how long a failed message waits before it is delivered again.
+	// Zero means 100ms.
+	RedeliveryDelay time.Duration
+	// MaxDeliveries is how often a message is delivered before it is given up on and
+	// counted as dead. Zero means 16, like RocketMQ's default retries.
+	MaxDeliveries int
+	// TxCheckInterval is how often the half messages of transactional producers are
+	// checked back, and how old an unresolved one must be to be checked. Zero means 1s.
+	TxCheckInterval time.Duration
+	// MaxTxChecks is how often a half message is checked back before it is rolled back.
+	// Zero means 15, like RocketMQ.
+	MaxTxChecks int
+}
+
+// MemoryStats counts the messages that went through a Memory broker.
+type MemoryStats struct {
+	Published   int64 `json:"published"`
+	Delivered   int64 `json:"delivered"`   // handler calls, redeliveries included
+	Redelivered int64 `json:"redelivered"` // deliveries after a failed one
+	Dead        int64 `json:"dead"`        // messages given up on after MaxDeliveries
+
+	HalfSent    int64 `json:"half_sent"`    // half messages of transactional producers
+	HalfPending int   `json:"half_pending"` // half messages neither committed nor rolled back yet
+	Committed   int64 `json:"committed"`
+	RolledBack  int64 `json:"rolled_back"`
+	TxChecks    int64 `json:"tx_checks"` // check-backs of unresolved half messages
+}
+
+// Memory is a Broker within the process. Each consumer group has a queue worked by
+// one goroutine, so that a group sees the messages of a topic in publish order,
+// except for redeliveries. SetDown simulates an outage, SetDropCommits lost commits of
+// transactional messages.
+type Memory struct {
+	opts MemoryOptions
+
+	mu        sync.Mutex
+	subs      map[string][]*subscription // by topic
+	half      map[string]*halfMessage    // by message ID
+	closed    bool
+	done      chan struct{}
+	down      atomic.Bool
+	dropEnds  atomic.Bool
+	checkOnce sync.Once
+
+	published, delivered, redelivered, dead   atomic.Int64
+	halfSent, committed, rolledBack, txChecks atomic.Int64
+	wg                                        sync.WaitGroup
+}
+
+// NewMemory returns an in-memory broker.
+func NewMemory(opts MemoryOptions) *Memory {
+	if opts.RedeliveryDelay <= 0 {
+		opts.RedeliveryDelay = 100 * time.Millisecond
+	}
+	if opts.MaxDeliveries <= 0 {
+		opts.MaxDeliveries = 16
+	}
+	if opts.TxCheckInterval <= 0 {
+		opts.TxCheckInterval = time.Second
+	}
+	if opts.MaxTxChecks <= 0 {
+		opts.MaxTxChecks = 15
+	}
+	return &Memory{
+		opts: opts,
+		subs: make(map[string][]*subscription),
+		half: make(map[string]*halfMessage),
+		done: make(chan struct{}),
+	}
+}
+
+// SetDown makes Publish fail with ErrUnavailable while down is true. Messages already
+// published are still delivered.
+func (m *Memory) SetDown(down bool) {
+	m.down.Store(down)
+}
+
+// Down reports whether the broker simulates an outage.
+func (m *Memory) Down() bool {
+	return m.down.Load()
+}
+
+// Stats returns the message counts so far.
+func (m *Memory) Stats() MemoryStats {
+	m.mu.Lock()
+	pending := len(m.half)
+	m.mu.Unlock()
+	return MemoryStats{
+		Published:   m.published.Load(),
+		Delivered:   m.delivered.Load(),
+		Redelivered: m.redelivered.Load(),
+		Dead:        m.dead.Load(),
+		HalfSent:    m.halfSent.Load(),
+		HalfPending: pending,
+		Committed:   m.committed.Load(),
+		RolledBack:  m.rolledBack.Load(),
+		TxChecks:    m.txChecks.Load(),
+	}
+}
+
+func (m *Memory) Publish(ctx context.Context, msg *Message) (err error) {
+	if msg.ID == "" {
+		msg.ID = NewID()
+	}
+	_, span := telemetry.StartPublishSpan(ctx, "memory", msg.Topic, carrier{msg})
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if m.down.Load() {
+		return fmt.Errorf("publish to %s: %w", msg.Topic, ErrUnavailable)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return fmt.Errorf("publish to %s: %w", msg.Topic, errClosed)
+	}
+	m.deliver(msg)
+	return nil
+}
+
+// deliver queues msg for the groups subscribed to its topic. The caller must hold m.mu.
+func (m *Memory) deliver(msg *Message) {
+	m.published.Add(1)
+	for _, sub := range m.subs[msg.Topic] {
+		sub.push(copyMessage(msg))
+	}
+}
+
+func (m *Memory) Subscribe(topic, group string, h Handler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return errClosed
+	}
+	for _, sub := range m.subs[topic] {
+		if sub.group == group {
+			return fmt.Errorf("mq: group %s already subscribed to %s", group, topic)
+		}
+	}
+	sub := &subscription{group: group, handler: h, notify: make(chan struct{}, 1), stop: make(chan struct{})}
+	m.subs[topic] = append(m.subs[topic], sub)
+	m.wg.Add(1)
+	go m.consume(sub)
+	return nil
+}
+
+// Close stops the consumers and waits for the handlers in progress to return.
+// Messages not yet delivered are dropped.
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.done)
+	for _, subs := range m.subs {
+		for _, sub := range subs {
+			close(sub.stop)
+		}
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+	return nil
+}
+
+func (m *Memory) consume(sub *subscription) {
+	defer m.wg.Done()
+	for {
+		msg, ok := sub.pop()
+		if !ok {
+			select {
+			case <-sub.stop:
+				return
+			case <-sub.notify:
+				continue
+			}
+		}
+		msg.Deliveries++
+		m.delivered.Add(1)
+		if msg.Deliveries > 1 {
+			m.redelivered.Add(1)
+		}
+
+		ctx, span := telemetry.StartProcessSpan(context.Background(), "memory", msg.Topic, carrier{msg})
+		err := sub.handler(ctx, msg)
+		telemetry.EndSpan(span, err)
+		if err == nil {
+			continue
+		}
+		if msg.Deliveries >= m.opts.MaxDeliveries {
+			m.dead.Add(1)
+			continue
+		}
+		time.AfterFunc(m.opts.RedeliveryDelay, func() { sub.push(msg) })
+	}
+}
+
+// subscription is the queue of a consumer group on a topic.
+type subscription struct {
+	group   string
+	handler Handler
+
+	mu      sync.Mutex
+	pending []*Message
+	notify  chan struct{}
+	stop    chan struct{}
+}
+
+func (s *subscription) push(msg *Message) {
+	s.mu.Lock()
+	s.pending = append(s.pending, msg)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscription) pop() (*Message, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+		return nil, false
+	default:
+	}
+	if len(s.pending) == 0 {
+		return nil, false
+	}
+	msg := s.pending[0]
+	s.pending[0] = nil
+	s.pending = s.pending[1:]
+	return msg, true
+}
+
+func copyMessage(msg *Message) *Message {
+	c := *msg
+	c.Body = append([]byte(nil), msg.Body...)
+	c.Properties = make(map[string]string, len(msg.Properties))
+	for k, v := range msg.Properties {
+		c.Properties[k] = v
+	}
+	c.Deliveries = 0
+	return &c
+}
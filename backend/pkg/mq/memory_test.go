@@ -0,0 +1,100 @@
+package mq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder collects the messages a handler received.
+type recorder struct {
+	mu   sync.Mutex
+	msgs []*Message
+	got  chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{got: make(chan struct{}, 100)}
+}
+
+func (r *recorder) handle(ctx context.Context, msg *Message) error {
+	r.mu.Lock()
+	r.msgs = append(r.msgs, msg)
+	r.mu.Unlock()
+	r.got <- struct{}{}
+	return nil
+}
+
+func (r *recorder) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.got:
+		case <-time.After(time.Second):
+			t.Fatalf("received %d of %d messages", i, n)
+		}
+	}
+}
+
+func TestMemoryDelivery(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(MemoryOptions{})
+	defer m.Close()
+
+	a, b := newRecorder(), newRecorder()
+	assert.NoError(t, m.Subscribe("products", "indexer", a.handle))
+	assert.NoError(t, m.Subscribe("products", "mailer", b.handle))
+	assert.Error(t, m.Subscribe("products", "mailer", b.handle))
+
+	for _, key := range []string{"p1", "p2", "p3"} {
+		assert.NoError(t, m.Publish(ctx, &Message{Topic: "products", Key: key, Body: []byte(key)}))
+	}
+	assert.NoError(t, m.Publish(ctx, &Message{Topic: "orders", Key: "o1"}))
+	a.wait(t, 3)
+	b.wait(t, 3)
+
+	// Every group sees every message in publish order, with the same ID.
+	for i, key := range []string{"p1", "p2", "p3"} {
+		assert.Equal(t, key, a.msgs[i].Key)
+		assert.Equal(t, 1, a.msgs[i].Deliveries)
+		assert.NotEmpty(t, a.msgs[i].ID)
+		assert.Equal(t, a.msgs[i].ID, b.msgs[i].ID)
+	}
+	assert.Equal(t, int64(4), m.Stats().Published)
+
+	m.SetDown(true)
+	assert.ErrorIs(t, m.Publish(ctx, &Message{Topic: "products"}), ErrUnavailable)
+	m.SetDown(false)
+	assert.NoError(t, m.Publish(ctx, &Message{Topic: "products"}))
+}
+
+func TestMemoryRedelivery(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(MemoryOptions{RedeliveryDelay: time.Millisecond, MaxDeliveries: 3})
+	defer m.Close()
+
+	r := newRecorder()
+	assert.NoError(t, m.Subscribe("t", "g", func(ctx context.Context, msg *Message) error {
+		_ = r.handle(ctx, msg)
+		if msg.Key == "poison" || msg.Deliveries < 2 {
+			return errors.New("handler failed")
+		}
+		return nil
+	}))
+	assert.NoError(t, m.Publish(ctx, &Message{Topic: "t", Key: "flaky"}))
+	assert.NoError(t, m.Publish(ctx, &Message{Topic: "t", Key: "poison"}))
+	r.wait(t, 5)
+
+	deliveries := map[string]int{}
+	r.mu.Lock()
+	for _, msg := range r.msgs {
+		deliveries[msg.Key] = msg.Deliveries
+	}
+	r.mu.Unlock()
+	assert.Equal(t, map[string]int{"flaky": 2, "poison": 3}, deliveries)
+	assert.Eventually(t, func() bool { return m.Stats().Dead == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int64(3), m.Stats().Redelivered)
+}
@@ -0,0 +1,137 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+)
+
+// halfMessage is a transactional message waiting for its commit or rollback.
+type halfMessage struct {
+	msg      *Message
+	producer *MemoryTx
+	sent     time.Time
+	checks   int // touched by the check-back goroutine only
+}
+
+// MemoryTx is a TxProducer on a Memory broker.
+type MemoryTx struct {
+	m      *Memory
+	check  TxChecker
+	closed atomic.Bool
+}
+
+// NewTxProducer returns a transactional producer on m. The broker calls check for the
+// half messages of the producer that stay unresolved for MemoryOptions.TxCheckInterval.
+func (m *Memory) NewTxProducer(check TxChecker) *MemoryTx {
+	m.checkOnce.Do(func() {
+		m.wg.Add(1)
+		go m.checkBack()
+	})
+	return &MemoryTx{m: m, check: check}
+}
+
+// SetDropCommits makes the broker lose the commits and rollbacks that producers send
+// while drop is true, as if each producer crashed right after its local transaction.
+// The half messages are then resolved by checking back with their producer.
+func (m *Memory) SetDropCommits(drop bool) {
+	m.dropEnds.Store(drop)
+}
+
+func (p *MemoryTx) PublishTx(ctx context.Context, msg *Message, local LocalTx) (state TxState, err error) {
+	m := p.m
+	if msg.ID == "" {
+		msg.ID = NewID()
+	}
+	ctx, span := telemetry.StartPublishSpan(ctx, "memory", msg.Topic, carrier{msg})
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	if m.down.Load() {
+		return 0, fmt.Errorf("publish to %s: %w", msg.Topic, ErrUnavailable)
+	}
+	if p.closed.Load() {
+		return 0, fmt.Errorf("publish to %s: %w", msg.Topic, errClosed)
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return 0, fmt.Errorf("publish to %s: %w", msg.Topic, errClosed)
+	}
+	m.half[msg.ID] = &halfMessage{msg: copyMessage(msg), producer: p, sent: time.Now()}
+	m.mu.Unlock()
+	m.halfSent.Add(1)
+
+	state = local(ctx, msg)
+	if !m.dropEnds.Load() {
+		m.endTx(msg.ID, state)
+	}
+	return state, nil
+}
+
+// Close stops the producer. Its unresolved half messages are no longer checked back.
+func (p *MemoryTx) Close() error {
+	p.closed.Store(true)
+	return nil
+}
+
+// endTx delivers or discards the half message id. TxUnknown leaves it pending.
+func (m *Memory) endTx(id string, state TxState) {
+	if state != TxCommit && state != TxRollback {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.half[id]
+	if !ok {
+		return
+	}
+	delete(m.half, id)
+	if state == TxRollback {
+		m.rolledBack.Add(1)
+		return
+	}
+	m.committed.Add(1)
+	if !m.closed {
+		m.deliver(h.msg)
+	}
+}
+
+// checkBack periodically resolves the half messages that have been pending for longer
+// than the check interval, like the transaction check of a RocketMQ broker.
+func (m *Memory) checkBack() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.opts.TxCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			m.checkHalfMessages()
+		}
+	}
+}
+
+func (m *Memory) checkHalfMessages() {
+	m.mu.Lock()
+	var due []*halfMessage
+	for _, h := range m.half {
+		if time.Since(h.sent) >= m.opts.TxCheckInterval && !h.producer.closed.Load() {
+			due = append(due, h)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, h := range due {
+		h.checks++
+		m.txChecks.Add(1)
+		state := h.producer.check(context.Background(), copyMessage(h.msg))
+		if state == TxUnknown && h.checks >= m.opts.MaxTxChecks {
+			state = TxRollback
+		}
+		m.endTx(h.msg.ID, state)
+	}
+}
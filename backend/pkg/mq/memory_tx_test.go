@@ -0,0 +1,78 @@
+package mq
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryTx(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(MemoryOptions{TxCheckInterval: 5 * time.Millisecond, MaxTxChecks: 3})
+	defer m.Close()
+	r := newRecorder()
+	assert.NoError(t, m.Subscribe("t", "g", r.handle))
+
+	// The checker commits the messages keyed "committed" and never resolves the others.
+	var mu sync.Mutex
+	checked := map[string]int{}
+	p := m.NewTxProducer(func(ctx context.Context, msg *Message) TxState {
+		mu.Lock()
+		checked[msg.Key]++
+		mu.Unlock()
+		if msg.Key == "committed" {
+			return TxCommit
+		}
+		return TxUnknown
+	})
+	defer p.Close()
+
+	publish := func(key string, state TxState) {
+		got, err := p.PublishTx(ctx, &Message{Topic: "t", Key: key}, func(ctx context.Context, msg *Message) TxState {
+			return state
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, state, got)
+	}
+	publish("commit", TxCommit)
+	publish("rollback", TxRollback)
+	r.wait(t, 1)
+	assert.Equal(t, "commit", r.msgs[0].Key)
+
+	// A producer that crashed after its local transaction leaves the message to the check.
+	publish("committed", TxUnknown)
+	r.wait(t, 1)
+	assert.Equal(t, "committed", r.msgs[1].Key)
+
+	// So does a lost commit.
+	m.SetDropCommits(true)
+	publish("committed", TxCommit)
+	m.SetDropCommits(false)
+	r.wait(t, 1)
+
+	// A message that stays unknown is rolled back after MaxTxChecks.
+	publish("lost", TxUnknown)
+	assert.Eventually(t, func() bool { return m.Stats().HalfPending == 0 }, time.Second, time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, 3, checked["lost"])
+	assert.Zero(t, checked["commit"])
+	mu.Unlock()
+
+	stats := m.Stats()
+	assert.Equal(t, int64(5), stats.HalfSent)
+	assert.Equal(t, int64(3), stats.Committed)
+	assert.Equal(t, int64(2), stats.RolledBack)
+	assert.Equal(t, int64(3), stats.Published)
+
+	m.SetDown(true)
+	ran := false
+	_, err := p.PublishTx(ctx, &Message{Topic: "t"}, func(ctx context.Context, msg *Message) TxState {
+		ran = true
+		return TxCommit
+	})
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.False(t, ran)
+}
@@ -0,0 +1,121 @@
+// Package mq is a small message bus abstraction over RocketMQ and an in-memory broker
+// that stands in for it in scenarios and tests.
+//
+// Delivery is at least once: a message whose handler fails is delivered again, and a
+// publish whose acknowledgement was lost may be repeated by the producer. Consumers
+// should therefore be idempotent, keyed by Message.ID or a business key.
+package mq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrUnavailable is returned by Publish when the broker cannot be reached.
+var ErrUnavailable = errors.New("mq: broker unavailable")
+
+// Message is a message on a topic.
+type Message struct {
+	// ID identifies the message across redeliveries and republishing. Publish sets it
+	// if it is empty.
+	ID    string
+	Topic string
+	// Key is the business key, e.g. a product code.
+	Key        string
+	Tag        string
+	Body       []byte
+	Properties map[string]string
+	// Deliveries counts how often the message has been handed to the consumer group,
+	// starting at 1. It is set by the broker.
+	Deliveries int
+}
+
+// Handler processes a message. If it returns an error the message is delivered again.
+type Handler func(ctx context.Context, msg *Message) error
+
+// Broker publishes messages and delivers them to subscribed consumer groups. Every
+// group receives every message of the topics it subscribes to.
+type Broker interface {
+	Publish(ctx context.Context, msg *Message) error
+	// Subscribe starts delivering the messages of topic to h on behalf of group.
+	Subscribe(topic, group string, h Handler) error
+	// Close stops delivering and releases the broker's resources.
+	Close() error
+}
+
+// TxState is the outcome of the local transaction of a transactional message.
+type TxState int
+
+const (
+	// TxCommit delivers the message.
+	TxCommit TxState = iota + 1
+	// TxRollback discards the message.
+	TxRollback
+	// TxUnknown leaves the message pending until a TxChecker resolves it.
+	TxUnknown
+)
+
+func (s TxState) String() string {
+	switch s {
+	case TxCommit:
+		return "commit"
+	case TxRollback:
+		return "rollback"
+	case TxUnknown:
+		return "unknown"
+	}
+	return fmt.Sprintf("TxState(%d)", int(s))
+}
+
+// LocalTx runs the local transaction of a transactional message and reports its outcome.
+type LocalTx func(ctx context.Context, msg *Message) TxState
+
+// TxChecker looks up the outcome of the local transaction of a message that was neither
+// committed nor rolled back, e.g. because the producer crashed or its commit was lost.
+// The broker calls it periodically until it returns TxCommit or TxRollback.
+type TxChecker func(ctx context.Context, msg *Message) TxState
+
+// TxProducer publishes transactional messages in two phases. It sends msg as a half
+// message that consumers do not see yet, then runs the local transaction, then commits
+// or rolls back the message according to its outcome.
+type TxProducer interface {
+	// PublishTx sends msg as a half message and runs local. It returns the state that
+	// local reported, or an error if the half message could not be sent, in which case
+	// local does not run.
+	PublishTx(ctx context.Context, msg *Message, local LocalTx) (TxState, error)
+	Close() error
+}
+
+// NewID returns a random message ID.
+func NewID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// carrier exposes the properties of a message as an OpenTelemetry TextMapCarrier.
+type carrier struct {
+	msg *Message
+}
+
+func (c carrier) Get(key string) string {
+	return c.msg.Properties[key]
+}
+
+func (c carrier) Set(key, value string) {
+	if c.msg.Properties == nil {
+		c.msg.Properties = make(map[string]string)
+	}
+	c.msg.Properties[key] = value
+}
+
+func (c carrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Properties))
+	for k := range c.msg.Properties {
+		keys = append(keys, k)
+	}
+	return keys
+}
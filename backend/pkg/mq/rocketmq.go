@@ -0,0 +1,152 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+
+	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/apache/rocketmq-client-go/v2/consumer"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/apache/rocketmq-client-go/v2/producer"
+)
+
+// idProperty carries Message.ID. RocketMQ assigns every send a new MsgId, so the ID
+// that stays the same when a message is published again travels as a property.
+const idProperty = "PLAYGROUND_MSG_ID"
+
+// RocketMQ is a Broker on a RocketMQ cluster. Its consumer groups consume in
+// clustering mode: each message goes to one consumer of each group.
+type RocketMQ struct {
+	nameservers []string
+	producer    rocketmq.Producer
+
+	mu        sync.Mutex
+	consumers []rocketmq.PushConsumer
+}
+
+// NewRocketMQ starts a producer in group on the cluster of nameservers.
+func NewRocketMQ(nameservers []string, group string) (*RocketMQ, error) {
+	p, err := rocketmq.NewProducer(
+		producer.WithNameServer(nameservers),
+		producer.WithRetry(2),
+		producer.WithGroupName(group),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start producer: %w", err)
+	}
+	return &RocketMQ{nameservers: nameservers, producer: p}, nil
+}
+
+func (r *RocketMQ) Publish(ctx context.Context, msg *Message) (err error) {
+	if msg.ID == "" {
+		msg.ID = NewID()
+	}
+	ctx, span := telemetry.StartPublishSpan(ctx, "rocketmq", msg.Topic, carrier{msg})
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	res, err := r.producer.SendSync(ctx, toRocketMQ(msg))
+	if err != nil {
+		return fmt.Errorf("publish to %s: %w: %v", msg.Topic, ErrUnavailable, err)
+	}
+	if res.Status != primitive.SendOK {
+		return fmt.Errorf("publish to %s: send status %d", msg.Topic, res.Status)
+	}
+	return nil
+}
+
+func (r *RocketMQ) Subscribe(topic, group string, h Handler) error {
+	c, err := rocketmq.NewPushConsumer(
+		consumer.WithNameServer(r.nameservers),
+		consumer.WithConsumerModel(consumer.Clustering),
+		consumer.WithGroupName(group),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+	err = c.Subscribe(topic, consumer.MessageSelector{}, func(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
+		for _, ext := range msgs {
+			msg := fromRocketMQ(ext)
+			ctx, span := telemetry.StartProcessSpan(ctx, "rocketmq", topic, carrier{msg})
+			err := h(ctx, msg)
+			telemetry.EndSpan(span, err)
+			if err != nil {
+				// The whole batch is delivered again; handlers must be idempotent.
+				return consumer.ConsumeRetryLater, nil
+			}
+		}
+		return consumer.ConsumeSuccess, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+	if err := c.Start(); err != nil {
+		return fmt.Errorf("failed to start consumer: %w", err)
+	}
+	r.mu.Lock()
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *RocketMQ) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var firstErr error
+	for _, c := range r.consumers {
+		if err := c.Shutdown(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	r.consumers = nil
+	if err := r.producer.Shutdown(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+func toRocketMQ(msg *Message) *primitive.Message {
+	rm := primitive.NewMessage(msg.Topic, msg.Body)
+	if msg.Key != "" {
+		rm.WithKeys([]string{msg.Key})
+	}
+	if msg.Tag != "" {
+		rm.WithTag(msg.Tag)
+	}
+	for k, v := range msg.Properties {
+		rm.WithProperty(k, v)
+	}
+	rm.WithProperty(idProperty, msg.ID)
+	return rm
+}
+
+func fromRocketMQ(ext *primitive.MessageExt) *Message {
+	msg := fromRocketMQMessage(&ext.Message)
+	if msg.ID == "" {
+		msg.ID = ext.MsgId
+	}
+	msg.Deliveries = int(ext.ReconsumeTimes) + 1
+	return msg
+}
+
+func fromRocketMQMessage(rm *primitive.Message) *Message {
+	msg := &Message{
+		ID:         rm.GetProperty(idProperty),
+		Topic:      rm.Topic,
+		Key:        rm.GetKeys(),
+		Tag:        rm.GetTags(),
+		Body:       rm.Body,
+		Properties: make(map[string]string),
+	}
+	for k, v := range rm.GetProperties() {
+		if k != idProperty {
+			msg.Properties[k] = v
+		}
+	}
+	return msg
+}
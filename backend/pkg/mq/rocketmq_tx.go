@@ -0,0 +1,106 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+
+	"github.com/apache/rocketmq-client-go/v2"
+	"github.com/apache/rocketmq-client-go/v2/primitive"
+	"github.com/apache/rocketmq-client-go/v2/producer"
+)
+
+// RocketMQTx is a TxProducer on a RocketMQ cluster. The broker asks the producer group
+// about half messages that stay unresolved, and the producer answers with its TxChecker.
+type RocketMQTx struct {
+	producer rocketmq.TransactionProducer
+	check    TxChecker
+
+	// calls holds the local transaction of every PublishTx in progress by message ID,
+	// since the client runs it through a listener that only sees the message.
+	calls sync.Map
+}
+
+// localCall is a local transaction waiting to be run by ExecuteLocalTransaction.
+type localCall struct {
+	ctx   context.Context
+	local LocalTx
+}
+
+// NewRocketMQTx starts a transactional producer in group on the cluster of nameservers.
+func NewRocketMQTx(nameservers []string, group string, check TxChecker) (*RocketMQTx, error) {
+	t := &RocketMQTx{check: check}
+	p, err := rocketmq.NewTransactionProducer(t,
+		producer.WithNameServer(nameservers),
+		producer.WithRetry(2),
+		producer.WithGroupName(group),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transaction producer: %w", err)
+	}
+	if err := p.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start transaction producer: %w", err)
+	}
+	t.producer = p
+	return t, nil
+}
+
+func (t *RocketMQTx) PublishTx(ctx context.Context, msg *Message, local LocalTx) (state TxState, err error) {
+	if msg.ID == "" {
+		msg.ID = NewID()
+	}
+	ctx, span := telemetry.StartPublishSpan(ctx, "rocketmq", msg.Topic, carrier{msg})
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	t.calls.Store(msg.ID, localCall{ctx: ctx, local: local})
+	defer t.calls.Delete(msg.ID)
+	res, err := t.producer.SendMessageInTransaction(ctx, toRocketMQ(msg))
+	if err != nil {
+		return 0, fmt.Errorf("publish to %s: %w: %v", msg.Topic, ErrUnavailable, err)
+	}
+	if res.Status != primitive.SendOK {
+		return 0, fmt.Errorf("publish to %s: send status %d", msg.Topic, res.Status)
+	}
+	return fromRocketMQState(res.State), nil
+}
+
+func (t *RocketMQTx) Close() error {
+	return t.producer.Shutdown()
+}
+
+// ExecuteLocalTransaction implements primitive.TransactionListener.
+func (t *RocketMQTx) ExecuteLocalTransaction(rm *primitive.Message) primitive.LocalTransactionState {
+	v, ok := t.calls.Load(rm.GetProperty(idProperty))
+	if !ok {
+		return primitive.UnknowState
+	}
+	call := v.(localCall)
+	return toRocketMQState(call.local(call.ctx, fromRocketMQMessage(rm)))
+}
+
+// CheckLocalTransaction implements primitive.TransactionListener.
+func (t *RocketMQTx) CheckLocalTransaction(ext *primitive.MessageExt) primitive.LocalTransactionState {
+	return toRocketMQState(t.check(context.Background(), fromRocketMQ(ext)))
+}
+
+func toRocketMQState(s TxState) primitive.LocalTransactionState {
+	switch s {
+	case TxCommit:
+		return primitive.CommitMessageState
+	case TxRollback:
+		return primitive.RollbackMessageState
+	}
+	return primitive.UnknowState
+}
+
+func fromRocketMQState(s primitive.LocalTransactionState) TxState {
+	switch s {
+	case primitive.CommitMessageState:
+		return TxCommit
+	case primitive.RollbackMessageState:
+		return TxRollback
+	}
+	return TxUnknown
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Local is a Limiter within the process. Each server enforces its own limit, so N
+// servers together allow N times the limit; use Redis to share one.
+type Local struct {
+	cfg Config
+
+	mu     sync.Mutex
+	states map[string]state
+	swept  time.Time
+}
+
+// state is the state of one key under one algorithm.
+type state interface {
+	allow(cfg Config, now time.Time) Result
+	// idle reports whether the state no longer affects any decision from now on, so
+	// that it can be dropped.
+	idle(cfg Config, now time.Time) bool
+}
+
+// NewLocal returns a limiter in the process.
+func NewLocal(cfg Config) (*Local, error) {
+	cfg, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &Local{cfg: cfg, states: make(map[string]state)}, nil
+}
+
+func (l *Local) Config() Config {
+	return l.cfg
+}
+
+func (l *Local) Allow(ctx context.Context, key string, now time.Time) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Drop the states of idle clients now and then.
+	if now.Sub(l.swept) > time.Minute {
+		for k, s := range l.states {
+			if s.idle(l.cfg, now) {
+				delete(l.states, k)
+			}
+		}
+		l.swept = now
+	}
+	s, ok := l.states[key]
+	if !ok {
+		s = newState(l.cfg.Algorithm)
+		l.states[key] = s
+	}
+	return s.allow(l.cfg, now), nil
+}
+
+// Reset forgets every client.
+func (l *Local) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.states = make(map[string]state)
+}
+
+func newState(a Algorithm) state {
+	switch a {
+	case FixedWindow:
+		return &fixedWindow{}
+	case SlidingLog:
+		return &slidingLog{}
+	case SlidingWindow:
+		return &slidingWindow{}
+	case TokenBucket:
+		return &tokenBucket{}
+	default:
+		return &leakyBucket{}
+	}
+}
+
+// fixedWindow counts the requests of the window that starts at start.
+type fixedWindow struct {
+	start time.Time
+	count int
+}
+
+func (s *fixedWindow) allow(cfg Config, now time.Time) Result {
+	if start := now.Truncate(cfg.Window); !start.Equal(s.start) {
+		s.start, s.count = start, 0
+	}
+	if s.count >= cfg.Limit {
+		return Result{RetryAfter: s.start.Add(cfg.Window).Sub(now)}
+	}
+	s.count++
+	return Result{Allowed: true, Remaining: cfg.Limit - s.count}
+}
+
+func (s *fixedWindow) idle(cfg Config, now time.Time) bool {
+	return !now.Before(s.start.Add(cfg.Window))
+}
+
+// slidingLog keeps the times of the accepted requests of the last window, oldest first.
+type slidingLog struct {
+	log []time.Time
+}
+
+func (s *slidingLog) allow(cfg Config, now time.Time) Result {
+	cutoff := now.Add(-cfg.Window)
+	i := 0
+	for i < len(s.log) && !s.log[i].After(cutoff) {
+		i++
+	}
+	s.log = s.log[i:]
+	if len(s.log) >= cfg.Limit {
+		return Result{RetryAfter: s.log[0].Add(cfg.Window).Sub(now)}
+	}
+	s.log = append(s.log, now)
+	return Result{Allowed: true, Remaining: cfg.Limit - len(s.log)}
+}
+
+func (s *slidingLog) idle(cfg Config, now time.Time) bool {
+	return len(s.log) == 0 || !s.log[len(s.log)-1].After(now.Add(-cfg.Window))
+}
+
+// slidingWindow counts the requests of the current fixed window and the one before it.
+type slidingWindow struct {
+	start      time.Time
+	curr, prev int
+}
+
+func (s *slidingWindow) allow(cfg Config, now time.Time) Result {
+	if start := now.Truncate(cfg.Window); !start.Equal(s.start) {
+		if start.Sub(s.start) == cfg.Window {
+			s.prev = s.curr
+		} else {
+			s.prev = 0
+		}
+		s.start, s.curr = start, 0
+	}
+	// The previous window still overlaps the last Window by this much.
+	weight := float64(s.start.Add(cfg.Window).Sub(now)) / float64(cfg.Window)
+	estimate := float64(s.prev)*weight + float64(s.curr)
+	if estimate+1 > float64(cfg.Limit) {
+		return Result{RetryAfter: slidingRetry(cfg, s.start, now, s.prev, s.curr)}
+	}
+	s.curr++
+	return Result{Allowed: true, Remaining: int(float64(cfg.Limit) - estimate - 1)}
+}
+
+// slidingRetry is how long until the estimate of a sliding window drops enough for one
+// more request: when the previous window has slid out far enough, or else when the
+// current window ends.
+func slidingRetry(cfg Config, start, now time.Time, prev, curr int) time.Duration {
+	end := start.Add(cfg.Window)
+	if curr+1 > cfg.Limit || prev == 0 {
+		return end.Sub(now)
+	}
+	weight := float64(cfg.Limit-curr-1) / float64(prev)
+	at := end.Add(-time.Duration(weight * float64(cfg.Window)))
+	return max(at.Sub(now), time.Millisecond)
+}
+
+func (s *slidingWindow) idle(cfg Config, now time.Time) bool {
+	return !now.Before(s.start.Add(2 * cfg.Window))
+}
+
+// tokenBucket holds the tokens left at last; a zero last means a full bucket.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// perNanosecond is the steady rate of cfg.
+func perNanosecond(cfg Config) float64 {
+	return float64(cfg.Limit) / float64(cfg.Window)
+}
+
+func (s *tokenBucket) level(cfg Config, now time.Time) float64 {
+	if s.last.IsZero() {
+		return float64(cfg.Burst)
+	}
+	return math.Min(float64(cfg.Burst), s.tokens+float64(now.Sub(s.last))*perNanosecond(cfg))
+}
+
+func (s *tokenBucket) allow(cfg Config, now time.Time) Result {
+	s.tokens, s.last = s.level(cfg, now), now
+	if s.tokens < 1 {
+		return Result{RetryAfter: time.Duration(math.Ceil((1 - s.tokens) / perNanosecond(cfg)))}
+	}
+	s.tokens--
+	return Result{Allowed: true, Remaining: int(s.tokens)}
+}
+
+func (s *tokenBucket) idle(cfg Config, now time.Time) bool {
+	return s.level(cfg, now) >= float64(cfg.Burst)
+}
+
+// leakyBucket is the time at which the queue is empty: the last request in it is served
+// an interval before.
+type leakyBucket struct {
+	next time.Time
+}
+
+func (s *leakyBucket) allow(cfg Config, now time.Time) Result {
+	interval := cfg.interval()
+	start := now
+	if s.next.After(now) {
+		start = s.next
+	}
+	wait := start.Sub(now)
+	// Burst requests fit in the queue: the one served now and Burst-1 waiting.
+	maxWait := time.Duration(cfg.Burst-1) * interval
+	if wait > maxWait {
+		return Result{RetryAfter: wait - maxWait}
+	}
+	s.next = start.Add(interval)
+	return Result{Allowed: true, Remaining: int((maxWait - wait) / interval), Delay: wait}
+}
+
+func (s *leakyBucket) idle(cfg Config, now time.Time) bool {
+	return !s.next.After(now)
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Rule limits the requests of the client that Key returns. An empty key skips the rule.
+type Rule struct {
+	Limiter Limiter
+	Key     func(c *gin.Context) string
+}
+
+// ByIP keys requests by client IP.
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByHeader keys requests by the value of a header; requests without it are not limited.
+func ByHeader(name string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		return c.GetHeader(name)
+	}
+}
+
+// MiddlewareConfig configures Middleware.
+type MiddlewareConfig struct {
+	// Rules are checked in order; a request must pass all of them.
+	Rules []Rule
+	// Skip exempts a request from every rule, e.g. one of an admin.
+	Skip func(c *gin.Context) bool
+	// OnLimited answers a rejected request. By default it sets Retry-After and answers
+	// 429 with a JSON error.
+	OnLimited func(c *gin.Context, res Result)
+	// OnError is called when a limiter fails, e.g. because Redis is unreachable. By
+	// default the request is allowed: an outage of the limiter does not take the API
+	// down with it.
+	OnError func(c *gin.Context, err error)
+}
+
+// Middleware returns Gin middleware that applies the rules of cfg. It sets
+// X-RateLimit-Limit and X-RateLimit-Remaining from the last rule, and holds requests
+// that a leaky bucket queued for their delay.
+func Middleware(cfg MiddlewareConfig) gin.HandlerFunc {
+	if cfg.OnLimited == nil {
+		cfg.OnLimited = func(c *gin.Context, res Result) {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests"})
+		}
+	}
+	return func(c *gin.Context) {
+		if cfg.Skip != nil && cfg.Skip(c) {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		var delay time.Duration
+		for _, rule := range cfg.Rules {
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+			res, err := rule.Limiter.Allow(c.Request.Context(), key, now)
+			if err != nil {
+				if cfg.OnError != nil {
+					cfg.OnError(c, err)
+					if c.IsAborted() {
+						return
+					}
+				}
+				continue
+			}
+			c.Header("X-RateLimit-Limit", strconv.Itoa(rule.Limiter.Config().Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			if !res.Allowed {
+				c.Header("Retry-After", strconv.Itoa(RetryAfterSeconds(res)))
+				cfg.OnLimited(c, res)
+				c.Abort()
+				return
+			}
+			delay = max(delay, res.Delay)
+		}
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RetryAfterSeconds returns the RetryAfter of res in whole seconds, rounded up, as
+// Retry-After carries it.
+func RetryAfterSeconds(res Result) int {
+	return int(math.Ceil(res.RetryAfter.Seconds()))
+}
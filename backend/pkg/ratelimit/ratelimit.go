@@ -0,0 +1,100 @@
+// Package ratelimit limits how often a client may send requests, with five algorithms
+// that differ in how they treat bursts:
+//
+//   - FixedWindow counts the requests in each window of fixed boundaries. It is cheap,
+//     but lets twice the limit through around a boundary: a full window's worth just
+//     before it and another just after.
+//   - SlidingLog keeps the time of every accepted request and counts those in the last
+//     window. It is exact, and costs memory per request.
+//   - SlidingWindow estimates the count of the last window from the counts of the
+//     current and the previous fixed window, weighting the previous one by how much of
+//     it still overlaps. It is nearly exact at the cost of two counters.
+//   - TokenBucket refills a bucket of Burst tokens at Limit per Window; a request takes
+//     a token. It allows bursts of up to Burst and then the steady rate.
+//   - LeakyBucket queues up to Burst requests and lets them out evenly at Limit per
+//     Window. Accepted requests are given a Delay, which smooths bursts into a constant
+//     rate instead of passing them through.
+//
+// Every algorithm is implemented in the process (NewLocal) and as a Redis Lua script
+// (NewRedis), which enforces one limit across all servers. Middleware applies limiters
+// to Gin routes.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Algorithm names a rate limiting algorithm.
+type Algorithm string
+
+const (
+	FixedWindow   Algorithm = "fixed_window"
+	SlidingLog    Algorithm = "sliding_log"
+	SlidingWindow Algorithm = "sliding_window"
+	TokenBucket   Algorithm = "token_bucket"
+	LeakyBucket   Algorithm = "leaky_bucket"
+)
+
+// Algorithms lists every algorithm.
+var Algorithms = []Algorithm{FixedWindow, SlidingLog, SlidingWindow, TokenBucket, LeakyBucket}
+
+// ErrInvalidConfig means a Config names an unknown algorithm or has no limit or window.
+var ErrInvalidConfig = errors.New("ratelimit: invalid config")
+
+// Config configures a limiter.
+type Config struct {
+	Algorithm Algorithm
+	// Limit is the number of requests allowed per Window. For the buckets it sets the
+	// rate: Limit / Window.
+	Limit  int
+	Window time.Duration
+	// Burst is the capacity of the token bucket, or the length of the queue of the
+	// leaky bucket. Zero means Limit. The window algorithms ignore it.
+	Burst int
+}
+
+func (c Config) validate() (Config, error) {
+	if c.Limit <= 0 || c.Window <= 0 {
+		return c, fmt.Errorf("%w: limit and window must be positive", ErrInvalidConfig)
+	}
+	if c.Burst <= 0 {
+		c.Burst = c.Limit
+	}
+	for _, a := range Algorithms {
+		if c.Algorithm == a {
+			return c, nil
+		}
+	}
+	return c, fmt.Errorf("%w: unknown algorithm %q", ErrInvalidConfig, c.Algorithm)
+}
+
+// interval is the time between two requests at the steady rate.
+func (c Config) interval() time.Duration {
+	return c.Window / time.Duration(c.Limit)
+}
+
+// Result is the decision on a request.
+type Result struct {
+	Allowed bool
+	// Remaining is how many more requests would be allowed right now.
+	Remaining int
+	// RetryAfter is how long a rejected client has to wait before a request can be
+	// allowed.
+	RetryAfter time.Duration
+	// Delay is how long an allowed request has to wait in the queue of a leaky bucket
+	// before it is served; zero for the other algorithms.
+	Delay time.Duration
+}
+
+// Limiter decides whether requests are allowed.
+type Limiter interface {
+	// Allow decides on one request of the client key at now, and counts it if it is
+	// allowed. Callers pass time.Now(); a simulation may pass the times of its own clock,
+	// as long as they do not go backwards for a key.
+	Allow(ctx context.Context, key string, now time.Time) (Result, error)
+	// Config returns the configuration of the limiter.
+	Config() Config
+}
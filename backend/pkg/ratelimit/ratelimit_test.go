@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// send sends n requests of key at at, a millisecond apart, and returns how many were
+// allowed.
+func send(t *testing.T, l Limiter, key string, at time.Duration, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		res, err := l.Allow(context.Background(), key, epoch.Add(at+time.Duration(i)*time.Millisecond))
+		assert.NoError(t, err)
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func newTestLimiter(t *testing.T, a Algorithm, burst int) *Local {
+	l, err := NewLocal(Config{Algorithm: a, Limit: 10, Window: time.Second, Burst: burst})
+	assert.NoError(t, err)
+	return l
+}
+
+func TestBoundaryBurst(t *testing.T) {
+	// 10 requests just before the boundary of a window and 10 just after it.
+	want := map[Algorithm]int{
+		FixedWindow:   20, // a full window's worth on each side
+		SlidingLog:    10,
+		SlidingWindow: 10,
+		TokenBucket:   10, // the bucket refills by one token in 100ms only
+		LeakyBucket:   10,
+	}
+	for _, a := range Algorithms {
+		l := newTestLimiter(t, a, 0)
+		got := send(t, l, "a", 950*time.Millisecond, 10) + send(t, l, "a", 1010*time.Millisecond, 10)
+		assert.Equal(t, want[a], got, a)
+	}
+}
+
+func TestSteadyRate(t *testing.T) {
+	// Twice the rate for three windows: every algorithm lets the limit through.
+	for _, a := range Algorithms {
+		l := newTestLimiter(t, a, 1)
+		allowed := 0
+		for i := 0; i < 60; i++ {
+			allowed += send(t, l, "a", time.Duration(i)*50*time.Millisecond, 1)
+		}
+		assert.InDelta(t, 30, allowed, 2, a)
+	}
+}
+
+func TestRetryAfterAndDelay(t *testing.T) {
+	l := newTestLimiter(t, FixedWindow, 0)
+	send(t, l, "a", 200*time.Millisecond, 10)
+	res, _ := l.Allow(context.Background(), "a", epoch.Add(400*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 600*time.Millisecond, res.RetryAfter)
+
+	l = newTestLimiter(t, SlidingLog, 0)
+	send(t, l, "a", 200*time.Millisecond, 10)
+	res, _ = l.Allow(context.Background(), "a", epoch.Add(400*time.Millisecond))
+	assert.Equal(t, 800*time.Millisecond, res.RetryAfter)
+
+	l = newTestLimiter(t, TokenBucket, 2)
+	assert.Equal(t, 2, send(t, l, "a", 0, 3))
+	res, _ = l.Allow(context.Background(), "a", epoch.Add(2*time.Millisecond))
+	assert.Equal(t, 98*time.Millisecond, res.RetryAfter.Round(time.Millisecond))
+	// Other clients have buckets of their own.
+	assert.Equal(t, 1, send(t, l, "b", 0, 1))
+
+	// The leaky bucket queues a burst and serves it at the steady rate.
+	l = newTestLimiter(t, LeakyBucket, 3)
+	for i, delay := range []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond} {
+		res, _ = l.Allow(context.Background(), "a", epoch)
+		assert.True(t, res.Allowed, i)
+		assert.Equal(t, delay, res.Delay, i)
+	}
+	res, _ = l.Allow(context.Background(), "a", epoch)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 100*time.Millisecond, res.RetryAfter)
+}
+
+func TestSlidingWindowWeighsPreviousWindow(t *testing.T) {
+	l := newTestLimiter(t, SlidingWindow, 0)
+	assert.Equal(t, 10, send(t, l, "a", 0, 10))
+	// 30% into the next window, the previous one still counts for 7.
+	assert.Equal(t, 3, send(t, l, "a", 1300*time.Millisecond, 5))
+	res, _ := l.Allow(context.Background(), "a", epoch.Add(1310*time.Millisecond))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 90*time.Millisecond, res.RetryAfter.Round(time.Millisecond))
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, cfg := range []Config{
+		{Algorithm: "gcra", Limit: 1, Window: time.Second},
+		{Algorithm: TokenBucket, Window: time.Second},
+		{Algorithm: TokenBucket, Limit: 1},
+	} {
+		_, err := NewLocal(cfg)
+		assert.ErrorIs(t, err, ErrInvalidConfig, "%v", cfg)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	byIP, _ := NewLocal(Config{Algorithm: FixedWindow, Limit: 1, Window: time.Minute})
+	bySession, _ := NewLocal(Config{Algorithm: TokenBucket, Limit: 60, Window: time.Minute, Burst: 1})
+	r := gin.New()
+	r.Use(Middleware(MiddlewareConfig{
+		Rules: []Rule{{Limiter: bySession, Key: ByHeader("X-Session-ID")}, {Limiter: byIP, Key: ByIP}},
+		Skip:  func(c *gin.Context) bool { return c.GetHeader("X-Admin") != "" },
+	}))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	get := func(header, value string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	w = get("", "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, get("X-Admin", "1").Code)
+	// The session rule runs first and rejects before the IP rule counts the request.
+	byIP.Reset()
+	assert.Equal(t, http.StatusOK, get("X-Session-ID", "s1").Code)
+	w = get("X-Session-ID", "s1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
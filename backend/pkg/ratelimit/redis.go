@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The scripts take the key of a client in KEYS[1] and now, limit, window and burst as
+// ARGV[1..4], with times in milliseconds. They return {allowed, remaining,
+// retry_after_ms, delay_ms}. The whole decision runs in one script, so concurrent
+// servers cannot both take the last slot.
+var scripts = map[Algorithm]*redis.Script{
+	// A hash of the start of the current window and its count.
+	FixedWindow: redis.NewScript(`
+local now, limit, window = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local start = now - now % window
+local h = redis.call("HMGET", KEYS[1], "start", "count")
+local count = 0
+if tonumber(h[1]) == start then
+	count = tonumber(h[2])
+end
+if count >= limit then
+	return {0, 0, start + window - now, 0}
+end
+redis.call("HSET", KEYS[1], "start", start, "count", count + 1)
+redis.call("PEXPIRE", KEYS[1], window)
+return {1, limit - count - 1, 0, 0}
+`),
+	// A sorted set of the accepted requests, scored by time; ARGV[5] is a unique member.
+	SlidingLog: redis.NewScript(`
+local now, limit, window = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+if count >= limit then
+	local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+	return {0, 0, tonumber(oldest[2]) + window - now, 0}
+end
+redis.call("ZADD", KEYS[1], now, ARGV[5])
+redis.call("PEXPIRE", KEYS[1], window)
+return {1, limit - count - 1, 0, 0}
+`),
+	// A hash of the start of the current window and the counts of it and the one before.
+	SlidingWindow: redis.NewScript(`
+local now, limit, window = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local start = now - now % window
+local h = redis.call("HMGET", KEYS[1], "start", "curr", "prev")
+local curr, prev = tonumber(h[2]) or 0, tonumber(h[3]) or 0
+local last = tonumber(h[1])
+if last ~= start then
+	if last == start - window then prev = curr else prev = 0 end
+	curr = 0
+end
+local estimate = prev * (start + window - now) / window + curr
+if estimate + 1 > limit then
+	local retry = start + window - now
+	if curr + 1 <= limit and prev > 0 then
+		retry = math.max(1, math.ceil(start + window - window * (limit - curr - 1) / prev - now))
+	end
+	return {0, 0, retry, 0}
+end
+redis.call("HSET", KEYS[1], "start", start, "curr", curr + 1, "prev", prev)
+redis.call("PEXPIRE", KEYS[1], 2 * window)
+return {1, math.floor(limit - estimate - 1), 0, 0}
+`),
+	// A hash of the tokens left and the time they were counted.
+	TokenBucket: redis.NewScript(`
+local now, limit, window, burst = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local rate = limit / window
+local h = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = burst
+if h[1] then
+	tokens = math.min(burst, tonumber(h[1]) + math.max(0, now - tonumber(h[2])) * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate))
+if allowed == 0 then
+	return {0, 0, math.ceil((1 - tokens) / rate), 0}
+end
+return {1, math.floor(tokens), 0, 0}
+`),
+	// The time at which the queue is empty.
+	LeakyBucket: redis.NewScript(`
+local now, limit, window, burst = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local interval = window / limit
+local start = math.max(now, tonumber(redis.call("GET", KEYS[1]) or "0"))
+local wait = start - now
+local maxWait = (burst - 1) * interval
+if wait > maxWait then
+	return {0, 0, math.ceil(wait - maxWait), 0}
+end
+redis.call("SET", KEYS[1], tostring(start + interval), "PX", math.ceil(wait + interval))
+return {1, math.floor((maxWait - wait) / interval), 0, math.floor(wait)}
+`),
+}
+
+// Redis is a Limiter in Redis, shared by every server that uses the same keys.
+type Redis struct {
+	client redis.Scripter
+	prefix string
+	cfg    Config
+	seq    atomic.Int64
+}
+
+// NewRedis returns a limiter that keeps the state of client key at prefix+key.
+func NewRedis(client redis.Scripter, prefix string, cfg Config) (*Redis, error) {
+	cfg, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{client: client, prefix: prefix, cfg: cfg}, nil
+}
+
+func (r *Redis) Config() Config {
+	return r.cfg
+}
+
+func (r *Redis) Allow(ctx context.Context, key string, now time.Time) (Result, error) {
+	ms := now.UnixMilli()
+	member := strconv.FormatInt(now.UnixNano(), 10) + "-" + strconv.FormatInt(r.seq.Add(1), 10)
+	vals, err := scripts[r.cfg.Algorithm].Run(ctx, r.client, []string{r.prefix + key},
+		ms, r.cfg.Limit, r.cfg.Window.Milliseconds(), r.cfg.Burst, member).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: %s %s: %w", r.cfg.Algorithm, key, err)
+	}
+	if len(vals) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: %s %s: unexpected reply %v", r.cfg.Algorithm, key, vals)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+		Delay:      time.Duration(vals[3]) * time.Millisecond,
+	}, nil
+}
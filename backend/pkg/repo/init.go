@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
 	"SYS_DESIGN_PLAYGROUND/pkg/repo/config"
 	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
 
@@ -21,5 +22,8 @@ func Init() {
 	if err != nil {
 		panic(err)
 	}
+	if err = DB.Use(telemetry.GormPlugin()); err != nil {
+		panic(err)
+	}
 	query.SetDefault(DB)
 }
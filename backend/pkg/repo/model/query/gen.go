@@ -17,6 +17,8 @@ import (
 
 var (
 	Q                      = new(Query)
+	OutboxMessage          *outboxMessage
+	PaymentOrder           *paymentOrder
 	ProductCost            *productCost
 	User                   *user
 	WebProduct             *webProduct
@@ -25,6 +27,8 @@ var (
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
+	OutboxMessage = &Q.OutboxMessage
+	PaymentOrder = &Q.PaymentOrder
 	ProductCost = &Q.ProductCost
 	User = &Q.User
 	WebProduct = &Q.WebProduct
@@ -34,6 +38,8 @@ func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
 		db:                     db,
+		OutboxMessage:          newOutboxMessage(db, opts...),
+		PaymentOrder:           newPaymentOrder(db, opts...),
 		ProductCost:            newProductCost(db, opts...),
 		User:                   newUser(db, opts...),
 		WebProduct:             newWebProduct(db, opts...),
@@ -44,6 +50,8 @@ func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 type Query struct {
 	db *gorm.DB
 
+	OutboxMessage          outboxMessage
+	PaymentOrder           paymentOrder
 	ProductCost            productCost
 	User                   user
 	WebProduct             webProduct
@@ -55,6 +63,8 @@ func (q *Query) Available() bool { return q.db != nil }
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
 		db:                     db,
+		OutboxMessage:          q.OutboxMessage.clone(db),
+		PaymentOrder:           q.PaymentOrder.clone(db),
 		ProductCost:            q.ProductCost.clone(db),
 		User:                   q.User.clone(db),
 		WebProduct:             q.WebProduct.clone(db),
@@ -73,6 +83,8 @@ func (q *Query) WriteDB() *Query {
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
 		db:                     db,
+		OutboxMessage:          q.OutboxMessage.replaceDB(db),
+		PaymentOrder:           q.PaymentOrder.replaceDB(db),
 		ProductCost:            q.ProductCost.replaceDB(db),
 		User:                   q.User.replaceDB(db),
 		WebProduct:             q.WebProduct.replaceDB(db),
@@ -81,6 +93,8 @@ func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 }
 
 type queryCtx struct {
+	OutboxMessage          IOutboxMessageDo
+	PaymentOrder           IPaymentOrderDo
 	ProductCost            IProductCostDo
 	User                   IUserDo
 	WebProduct             IWebProductDo
@@ -89,6 +103,8 @@ type queryCtx struct {
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
+		OutboxMessage:          q.OutboxMessage.WithContext(ctx),
+		PaymentOrder:           q.PaymentOrder.WithContext(ctx),
 		ProductCost:            q.ProductCost.WithContext(ctx),
 		User:                   q.User.WithContext(ctx),
 		WebProduct:             q.WebProduct.WithContext(ctx),
@@ -0,0 +1,19 @@
+CREATE TABLE `outbox_message`
+(
+    `id`              BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Primary key ID',
+    `message_id`      VARCHAR(64)     NOT NULL DEFAULT '' COMMENT 'Unique message ID, used by consumers to drop duplicates',
+    `topic`           VARCHAR(128)    NOT NULL DEFAULT '' COMMENT 'Destination topic',
+    `message_key`     VARCHAR(128)    NOT NULL DEFAULT '' COMMENT 'Business key, e.g. the product code',
+    `payload`         JSON            NOT NULL COMMENT 'Message body',
+    `status`          TINYINT         NOT NULL DEFAULT 0 COMMENT 'Status: 0=pending, 1=sent, 2=failed',
+    `attempts`        INT             NOT NULL DEFAULT 0 COMMENT 'Publish attempts so far',
+    `last_error`      VARCHAR(512)    NOT NULL DEFAULT '' COMMENT 'Error of the last failed attempt',
+    `next_attempt_at` TIMESTAMP(3)    NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Earliest time of the next attempt',
+    `created_at`      TIMESTAMP(3)    NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Creation time',
+    `updated_at`      TIMESTAMP(3)    NULL     DEFAULT NULL ON UPDATE CURRENT_TIMESTAMP(3) COMMENT 'Update time',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_message_id` (`message_id`),
+    KEY `idx_status_next_attempt` (`status`, `next_attempt_at`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_unicode_ci COMMENT ='Transactional outbox of messages to publish';
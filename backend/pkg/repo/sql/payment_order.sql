@@ -0,0 +1,15 @@
+CREATE TABLE `payment_order`
+(
+    `id`              BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT 'Primary key ID',
+    `order_no`        VARCHAR(64)     NOT NULL DEFAULT '' COMMENT 'Order number assigned by the server',
+    `checkout_id`     VARCHAR(64)     NOT NULL DEFAULT '' COMMENT 'Checkout the client placed the order for, shared by its retries',
+    `user_id`         BIGINT          NOT NULL DEFAULT 0 COMMENT 'Charged user ID',
+    `amount`          BIGINT          NOT NULL DEFAULT 0 COMMENT 'Charged amount in cents',
+    `idempotency_key` VARCHAR(128)    NOT NULL DEFAULT '' COMMENT 'Idempotency-Key header of the request, empty if none',
+    `created_at`      TIMESTAMP(3)    NOT NULL DEFAULT CURRENT_TIMESTAMP(3) COMMENT 'Creation time',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_order_no` (`order_no`),
+    KEY `idx_checkout_id` (`checkout_id`)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4
+  COLLATE = utf8mb4_unicode_ci COMMENT ='Orders charged by the idempotent API scenario';
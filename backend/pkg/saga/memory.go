@@ -0,0 +1,107 @@
+package saga
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Memory is a Store within the process, for tests. Its sagas do not survive a restart.
+type Memory struct {
+	mu        sync.Mutex
+	instances map[string]*Instance
+	logs      map[string][]Entry
+}
+
+// NewMemory returns an empty store.
+func NewMemory() *Memory {
+	return &Memory{instances: make(map[string]*Instance), logs: make(map[string][]Entry)}
+}
+
+func (m *Memory) Create(ctx context.Context, inst *Instance) error {
+	return m.Update(ctx, inst)
+}
+
+func (m *Memory) Update(ctx context.Context, inst *Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[inst.ID] = cloneInstance(inst)
+	return nil
+}
+
+func (m *Memory) Get(ctx context.Context, id string) (*Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	inst, ok := m.instances[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneInstance(inst), nil
+}
+
+func (m *Memory) Append(ctx context.Context, e Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logs[e.SagaID] = append(m.logs[e.SagaID], e)
+	return nil
+}
+
+func (m *Memory) Log(ctx context.Context, id string) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Entry(nil), m.logs[id]...), nil
+}
+
+func (m *Memory) Unfinished(ctx context.Context) ([]*Instance, error) {
+	all := m.sorted()
+	var out []*Instance
+	for _, inst := range all {
+		if !inst.Status.Finished() {
+			out = append(out, inst)
+		}
+	}
+	return out, nil
+}
+
+func (m *Memory) Recent(ctx context.Context, limit int) ([]*Instance, error) {
+	all := m.sorted()
+	var out []*Instance
+	for i := len(all) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, all[i])
+	}
+	return out, nil
+}
+
+// Clear deletes every saga and its log.
+func (m *Memory) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances = make(map[string]*Instance)
+	m.logs = make(map[string][]Entry)
+}
+
+// sorted returns copies of the instances, oldest first.
+func (m *Memory) sorted() []*Instance {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	all := make([]*Instance, 0, len(m.instances))
+	for _, inst := range m.instances {
+		all = append(all, cloneInstance(inst))
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.Before(all[j].CreatedAt)
+		}
+		return all[i].ID < all[j].ID
+	})
+	return all
+}
+
+func cloneInstance(inst *Instance) *Instance {
+	c := *inst
+	c.Data = make(Data, len(inst.Data))
+	for k, v := range inst.Data {
+		c.Data[k] = v
+	}
+	return &c
+}
@@ -0,0 +1,188 @@
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// MySQLTables are the DDL of the tables that MySQL keeps the sagas and their log in.
+// CreateMySQLTables creates them.
+var MySQLTables = []string{`CREATE TABLE IF NOT EXISTS saga_instance
+(
+    id         CHAR(36)     NOT NULL COMMENT 'Saga ID',
+    saga       VARCHAR(64)  NOT NULL COMMENT 'Name of the saga definition',
+    status     VARCHAR(16)  NOT NULL COMMENT 'running, completed, compensating, compensated or failed',
+    done       INT          NOT NULL DEFAULT 0 COMMENT 'Steps completed and not compensated',
+    data       JSON         NOT NULL COMMENT 'Data shared by the steps',
+    error      VARCHAR(1024) NOT NULL DEFAULT '' COMMENT 'Error that made the saga compensate or fail',
+    created_at DATETIME(3)  NOT NULL COMMENT 'Start time',
+    updated_at DATETIME(3)  NOT NULL COMMENT 'Time of the last transition',
+    PRIMARY KEY (id),
+    KEY idx_status (status),
+    KEY idx_created_at (created_at)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='Saga instances'`,
+	`CREATE TABLE IF NOT EXISTS saga_log
+(
+    id         BIGINT        NOT NULL AUTO_INCREMENT COMMENT 'Primary key ID',
+    saga_id    CHAR(36)      NOT NULL COMMENT 'Saga ID',
+    step       VARCHAR(64)   NOT NULL COMMENT 'Step name',
+    phase      VARCHAR(16)   NOT NULL COMMENT 'action or compensate',
+    attempt    INT           NOT NULL DEFAULT 1 COMMENT 'Attempt of the compensation',
+    ok         TINYINT(1)    NOT NULL COMMENT 'Whether it succeeded',
+    error      VARCHAR(1024) NOT NULL DEFAULT '' COMMENT 'Error of a failed attempt',
+    created_at DATETIME(3)   NOT NULL COMMENT 'Time of the attempt',
+    PRIMARY KEY (id),
+    KEY idx_saga_id (saga_id)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='Log of the steps and compensations of sagas'`,
+}
+
+// maxErrorLen is the size of the error columns.
+const maxErrorLen = 1024
+
+// CreateMySQLTables creates the saga_instance and saga_log tables if they do not exist.
+func CreateMySQLTables(ctx context.Context, db *sql.DB) error {
+	for _, ddl := range MySQLTables {
+		if _, err := db.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("saga: create table: %w", err)
+		}
+	}
+	return nil
+}
+
+// MySQL is a Store in the saga_instance and saga_log tables.
+type MySQL struct {
+	db *sql.DB
+}
+
+// NewMySQL returns a store in db. The tables must exist.
+func NewMySQL(db *sql.DB) *MySQL {
+	return &MySQL{db: db}
+}
+
+func (m *MySQL) Create(ctx context.Context, inst *Instance) error {
+	data, err := json.Marshal(inst.Data)
+	if err != nil {
+		return err
+	}
+	_, err = m.db.ExecContext(ctx, "INSERT INTO saga_instance (id, saga, status, done, data, error, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		inst.ID, inst.Saga, inst.Status, inst.Done, data, truncate(inst.Error), inst.CreatedAt, inst.UpdatedAt)
+	return err
+}
+
+func (m *MySQL) Update(ctx context.Context, inst *Instance) error {
+	data, err := json.Marshal(inst.Data)
+	if err != nil {
+		return err
+	}
+	res, err := m.db.ExecContext(ctx, "UPDATE saga_instance SET status = ?, done = ?, data = ?, error = ?, updated_at = ? WHERE id = ?",
+		inst.Status, inst.Done, data, truncate(inst.Error), inst.UpdatedAt, inst.ID)
+	if err != nil {
+		return err
+	}
+	// A row that did not change also counts zero rows; only a missing row is an error.
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		if _, err := m.Get(ctx, inst.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const instanceColumns = "id, saga, status, done, data, error, created_at, updated_at"
+
+func (m *MySQL) Get(ctx context.Context, id string) (*Instance, error) {
+	inst, err := scanInstance(m.db.QueryRowContext(ctx, "SELECT "+instanceColumns+" FROM saga_instance WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("saga: get %s: %w", id, err)
+	}
+	return inst, nil
+}
+
+func (m *MySQL) Append(ctx context.Context, e Entry) error {
+	_, err := m.db.ExecContext(ctx, "INSERT INTO saga_log (saga_id, step, phase, attempt, ok, error, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		e.SagaID, e.Step, e.Phase, e.Attempt, e.OK, truncate(e.Error), e.At)
+	return err
+}
+
+func (m *MySQL) Log(ctx context.Context, id string) ([]Entry, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT saga_id, step, phase, attempt, ok, error, created_at FROM saga_log WHERE saga_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, fmt.Errorf("saga: log %s: %w", id, err)
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.SagaID, &e.Step, &e.Phase, &e.Attempt, &e.OK, &e.Error, &e.At); err != nil {
+			return nil, fmt.Errorf("saga: log %s: %w", id, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (m *MySQL) Unfinished(ctx context.Context) ([]*Instance, error) {
+	return m.query(ctx, "SELECT "+instanceColumns+" FROM saga_instance WHERE status IN (?, ?, ?) ORDER BY created_at, id",
+		StatusRunning, StatusCompensating, StatusFailed)
+}
+
+func (m *MySQL) Recent(ctx context.Context, limit int) ([]*Instance, error) {
+	return m.query(ctx, "SELECT "+instanceColumns+" FROM saga_instance ORDER BY created_at DESC, id DESC LIMIT ?", limit)
+}
+
+// DeleteSaga deletes the instances of the named saga and their log, and returns how
+// many instances it deleted.
+func (m *MySQL) DeleteSaga(ctx context.Context, name string) (int64, error) {
+	if _, err := m.db.ExecContext(ctx, "DELETE l FROM saga_log l JOIN saga_instance i ON i.id = l.saga_id WHERE i.saga = ?", name); err != nil {
+		return 0, fmt.Errorf("saga: delete %s: %w", name, err)
+	}
+	res, err := m.db.ExecContext(ctx, "DELETE FROM saga_instance WHERE saga = ?", name)
+	if err != nil {
+		return 0, fmt.Errorf("saga: delete %s: %w", name, err)
+	}
+	return res.RowsAffected()
+}
+
+func (m *MySQL) query(ctx context.Context, query string, args ...interface{}) ([]*Instance, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("saga: list: %w", err)
+	}
+	defer rows.Close()
+	var out []*Instance
+	for rows.Next() {
+		inst, err := scanInstance(rows)
+		if err != nil {
+			return nil, fmt.Errorf("saga: list: %w", err)
+		}
+		out = append(out, inst)
+	}
+	return out, rows.Err()
+}
+
+func scanInstance(row interface{ Scan(...interface{}) error }) (*Instance, error) {
+	var inst Instance
+	var data []byte
+	if err := row.Scan(&inst.ID, &inst.Saga, &inst.Status, &inst.Done, &data, &inst.Error, &inst.CreatedAt, &inst.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &inst.Data); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLen {
+		return s[:maxErrorLen]
+	}
+	return s
+}
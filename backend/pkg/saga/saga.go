@@ -0,0 +1,387 @@
+// Package saga runs business transactions that span several services as orchestrated
+// sagas.
+//
+// A saga is a sequence of steps, each a local transaction in one service, with a
+// compensating action that semantically undoes it. The Orchestrator runs the steps in
+// order and records every transition in a Store before moving on. If a step fails, it
+// runs the compensations of the steps that succeeded, newest first, so that the saga
+// ends either completed or compensated. A compensation that keeps failing leaves the
+// saga failed, for an operator to look at and Resume.
+//
+// Because the state is persisted, a saga interrupted by a crash or a shutdown can be
+// picked up where it stopped: Recover resumes every saga that is still running or
+// compensating. Steps must therefore be idempotent, since a step that committed just
+// before a crash runs again; so must compensations, which may run more than once.
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownSaga means no definition with the name is registered.
+	ErrUnknownSaga = errors.New("saga: unknown saga")
+	// ErrNotFound means the store holds no saga with the ID.
+	ErrNotFound = errors.New("saga: not found")
+	// ErrBusy means the saga is being run by this orchestrator already.
+	ErrBusy = errors.New("saga: already running")
+	// ErrInterrupted means the context was done before the saga finished. Its state is
+	// in the store, and Resume carries on from there.
+	ErrInterrupted = errors.New("saga: interrupted")
+)
+
+// Status is the state of a saga instance.
+type Status string
+
+const (
+	// StatusRunning means the steps are running.
+	StatusRunning Status = "running"
+	// StatusCompleted means every step succeeded.
+	StatusCompleted Status = "completed"
+	// StatusCompensating means a step failed and the completed steps are being undone.
+	StatusCompensating Status = "compensating"
+	// StatusCompensated means a step failed and every completed step was undone.
+	StatusCompensated Status = "compensated"
+	// StatusFailed means a compensation failed after its retries. The saga is left
+	// partially applied until it is resumed.
+	StatusFailed Status = "failed"
+)
+
+// Finished reports whether a saga with the status has nothing left to run.
+func (s Status) Finished() bool {
+	return s == StatusCompleted || s == StatusCompensated
+}
+
+// Data is the state that the steps of a saga share, such as the IDs of the rows they
+// created. Steps add to it; it is stored after every step, so compensations and resumed
+// sagas see what the earlier steps recorded.
+type Data map[string]string
+
+// Step is one local transaction of a saga.
+type Step struct {
+	Name string
+	// Action performs the step. It must be idempotent.
+	Action func(ctx context.Context, data Data) error
+	// Compensate undoes a completed Action. It must be idempotent. A nil Compensate
+	// means the step has nothing to undo.
+	Compensate func(ctx context.Context, data Data) error
+}
+
+// Definition is a named sequence of steps.
+type Definition struct {
+	Name  string
+	Steps []Step
+}
+
+// Instance is the persisted state of one run of a saga.
+type Instance struct {
+	ID     string `json:"id"`
+	Saga   string `json:"saga"`
+	Status Status `json:"status"`
+	// Done is the number of steps whose actions have completed and that have not been
+	// compensated. While running, step Done runs next; while compensating, step Done-1
+	// is compensated next.
+	Done      int       `json:"done"`
+	Data      Data      `json:"data"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Phase tells an action from a compensation in the log.
+type Phase string
+
+const (
+	PhaseAction     Phase = "action"
+	PhaseCompensate Phase = "compensate"
+)
+
+// Entry is a line of the saga log.
+type Entry struct {
+	SagaID  string    `json:"saga_id"`
+	Step    string    `json:"step"`
+	Phase   Phase     `json:"phase"`
+	Attempt int       `json:"attempt"`
+	OK      bool      `json:"ok"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Store persists saga instances and their log.
+type Store interface {
+	// Create stores a new instance.
+	Create(ctx context.Context, inst *Instance) error
+	// Update stores the state of an existing instance.
+	Update(ctx context.Context, inst *Instance) error
+	// Get returns the instance with the ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Instance, error)
+	// Append adds an entry to the log of a saga.
+	Append(ctx context.Context, e Entry) error
+	// Log returns the entries of a saga, oldest first.
+	Log(ctx context.Context, id string) ([]Entry, error)
+	// Unfinished returns the instances that are running, compensating or failed,
+	// oldest first.
+	Unfinished(ctx context.Context) ([]*Instance, error)
+	// Recent returns up to limit instances, newest first.
+	Recent(ctx context.Context, limit int) ([]*Instance, error)
+}
+
+// Config configures an Orchestrator.
+type Config struct {
+	Store Store
+	// Retries is how often a failed compensation is retried before the saga is marked
+	// failed. Zero means 3.
+	Retries int
+	// Backoff is the wait before the first retry of a compensation; it doubles with
+	// every retry. Zero means 100ms.
+	Backoff time.Duration
+}
+
+// Orchestrator runs the sagas registered with it and records their progress in the
+// store.
+type Orchestrator struct {
+	store   Store
+	retries int
+	backoff time.Duration
+
+	mu          sync.Mutex
+	definitions map[string]Definition
+	running     map[string]bool // IDs of the sagas being run
+}
+
+// New returns an orchestrator over cfg.Store.
+func New(cfg Config) *Orchestrator {
+	o := &Orchestrator{
+		store:       cfg.Store,
+		retries:     cfg.Retries,
+		backoff:     cfg.Backoff,
+		definitions: make(map[string]Definition),
+		running:     make(map[string]bool),
+	}
+	if o.retries == 0 {
+		o.retries = 3
+	}
+	if o.backoff == 0 {
+		o.backoff = 100 * time.Millisecond
+	}
+	return o
+}
+
+// Register adds def, replacing any definition of the same name.
+func (o *Orchestrator) Register(def Definition) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.definitions[def.Name] = def
+}
+
+// Start runs a new instance of the named saga with data and returns its final state.
+// The error is nil only if the saga completed. If a step failed, the saga is
+// compensated and the error is that of the step; the instance tells whether the
+// compensation succeeded.
+func (o *Orchestrator) Start(ctx context.Context, name string, data Data) (*Instance, error) {
+	def, err := o.definition(name)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = Data{}
+	}
+	now := time.Now()
+	inst := &Instance{ID: uuid.New().String(), Saga: name, Status: StatusRunning, Data: data, CreatedAt: now, UpdatedAt: now}
+	if err := o.store.Create(ctx, inst); err != nil {
+		return nil, fmt.Errorf("saga: create %s: %w", name, err)
+	}
+	o.mu.Lock()
+	o.running[inst.ID] = true
+	o.mu.Unlock()
+	defer o.release(inst.ID)
+	return inst, o.run(ctx, def, inst)
+}
+
+// Resume carries on with the saga with the ID from its stored state: a running saga
+// runs its remaining steps, a compensating or failed one retries its compensations. A
+// finished saga is returned as it is.
+func (o *Orchestrator) Resume(ctx context.Context, id string) (*Instance, error) {
+	o.mu.Lock()
+	if o.running[id] {
+		o.mu.Unlock()
+		return nil, ErrBusy
+	}
+	o.running[id] = true
+	o.mu.Unlock()
+	defer o.release(id)
+
+	inst, err := o.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inst.Status.Finished() {
+		return inst, nil
+	}
+	def, err := o.definition(inst.Saga)
+	if err != nil {
+		return inst, err
+	}
+	if inst.Status == StatusFailed {
+		inst.Status = StatusCompensating
+		if err := o.save(ctx, inst); err != nil {
+			return inst, err
+		}
+	}
+	return inst, o.run(ctx, def, inst)
+}
+
+// Recover resumes every unfinished saga that is not failed, as a process does after a
+// restart, and returns how many it resumed. Failed sagas wait for an explicit Resume,
+// and sagas without a registered definition are skipped.
+func (o *Orchestrator) Recover(ctx context.Context) (int, error) {
+	unfinished, err := o.store.Unfinished(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("saga: recover: %w", err)
+	}
+	resumed := 0
+	for _, inst := range unfinished {
+		if inst.Status == StatusFailed {
+			continue
+		}
+		// A saga that fails again is compensated or marked failed by Resume; only an
+		// interruption stops the recovery.
+		_, err := o.Resume(ctx, inst.ID)
+		switch {
+		case errors.Is(err, ErrBusy), errors.Is(err, ErrUnknownSaga):
+			continue
+		case errors.Is(err, ErrInterrupted):
+			return resumed, err
+		}
+		resumed++
+	}
+	return resumed, nil
+}
+
+func (o *Orchestrator) definition(name string) (Definition, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	def, ok := o.definitions[name]
+	if !ok {
+		return Definition{}, fmt.Errorf("%w: %s", ErrUnknownSaga, name)
+	}
+	return def, nil
+}
+
+func (o *Orchestrator) release(id string) {
+	o.mu.Lock()
+	delete(o.running, id)
+	o.mu.Unlock()
+}
+
+// run drives inst forward from its stored state until it finishes, fails or ctx is done.
+func (o *Orchestrator) run(ctx context.Context, def Definition, inst *Instance) error {
+	var stepErr error
+	for inst.Status == StatusRunning && inst.Done < len(def.Steps) {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %s before step %d: %v", ErrInterrupted, inst.ID, inst.Done+1, ctx.Err())
+		}
+		step := def.Steps[inst.Done]
+		err := step.Action(ctx, inst.Data)
+		if err := o.log(ctx, inst, step.Name, PhaseAction, 1, err); err != nil {
+			return err
+		}
+		if err != nil {
+			// The step is a local transaction: it failed as a whole, so only the steps
+			// before it are undone.
+			stepErr = fmt.Errorf("saga: step %s: %w", step.Name, err)
+			inst.Status, inst.Error = StatusCompensating, stepErr.Error()
+		} else {
+			inst.Done++
+		}
+		if err := o.save(ctx, inst); err != nil {
+			return err
+		}
+	}
+	if inst.Status == StatusRunning {
+		inst.Status = StatusCompleted
+		return o.save(ctx, inst)
+	}
+	if stepErr == nil {
+		// Resumed while compensating: the step error is only in the stored message.
+		stepErr = errors.New(inst.Error)
+	}
+
+	for inst.Done > 0 {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %s while compensating: %v", ErrInterrupted, inst.ID, ctx.Err())
+		}
+		step := def.Steps[inst.Done-1]
+		if step.Compensate != nil {
+			if err := o.compensate(ctx, inst, step); err != nil {
+				inst.Status = StatusFailed
+				inst.Error = fmt.Sprintf("%s; compensation of %s: %v", stepErr, step.Name, err)
+				if err := o.save(ctx, inst); err != nil {
+					return err
+				}
+				return fmt.Errorf("%w; compensation of %s failed: %v", stepErr, step.Name, err)
+			}
+		}
+		inst.Done--
+		if err := o.save(ctx, inst); err != nil {
+			return err
+		}
+	}
+	inst.Status = StatusCompensated
+	if err := o.save(ctx, inst); err != nil {
+		return err
+	}
+	return stepErr
+}
+
+// compensate runs the compensation of step, retrying it with exponential backoff.
+func (o *Orchestrator) compensate(ctx context.Context, inst *Instance, step Step) error {
+	backoff := o.backoff
+	var err error
+	for attempt := 1; attempt <= o.retries+1; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err = step.Compensate(ctx, inst.Data)
+		if logErr := o.log(ctx, inst, step.Name, PhaseCompensate, attempt, err); logErr != nil {
+			return logErr
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// log appends the outcome of an action or compensation to the saga log.
+func (o *Orchestrator) log(ctx context.Context, inst *Instance, step string, phase Phase, attempt int, err error) error {
+	e := Entry{SagaID: inst.ID, Step: step, Phase: phase, Attempt: attempt, OK: err == nil, At: time.Now()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := o.store.Append(context.WithoutCancel(ctx), e); err != nil {
+		return fmt.Errorf("saga: log %s: %w", inst.ID, err)
+	}
+	return nil
+}
+
+// save stores the state of inst. It is not cancelled with ctx, so that the outcome of a
+// step that ran is never lost.
+func (o *Orchestrator) save(ctx context.Context, inst *Instance) error {
+	inst.UpdatedAt = time.Now()
+	if err := o.store.Update(context.WithoutCancel(ctx), inst); err != nil {
+		return fmt.Errorf("saga: update %s: %w", inst.ID, err)
+	}
+	return nil
+}
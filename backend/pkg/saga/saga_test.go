@@ -0,0 +1,152 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder builds steps that record what ran and fail on demand.
+type recorder struct {
+	calls       []string
+	failAction  map[string]bool
+	failUndo    map[string]int // failures left per compensation
+	cancelAfter string
+	cancel      context.CancelFunc
+}
+
+func newRecorder() *recorder {
+	return &recorder{failAction: map[string]bool{}, failUndo: map[string]int{}}
+}
+
+func (r *recorder) step(name string) Step {
+	return Step{
+		Name: name,
+		Action: func(ctx context.Context, data Data) error {
+			r.calls = append(r.calls, name)
+			if r.failAction[name] {
+				return fmt.Errorf("%s is down", name)
+			}
+			data[name] = "done"
+			if r.cancelAfter == name {
+				r.cancel()
+			}
+			return nil
+		},
+		Compensate: func(ctx context.Context, data Data) error {
+			r.calls = append(r.calls, "undo "+name)
+			if r.failUndo[name] > 0 {
+				r.failUndo[name]--
+				return errors.New("undo failed")
+			}
+			data[name] = "undone"
+			return nil
+		},
+	}
+}
+
+func newTestOrchestrator(r *recorder) (*Orchestrator, *Memory) {
+	store := NewMemory()
+	o := New(Config{Store: store, Retries: 2, Backoff: time.Millisecond})
+	o.Register(Definition{Name: "product", Steps: []Step{r.step("create"), r.step("reserve"), r.step("notify")}})
+	return o, store
+}
+
+func TestSagaCompletes(t *testing.T) {
+	r := newRecorder()
+	o, store := newTestOrchestrator(r)
+
+	inst, err := o.Start(context.Background(), "product", Data{"code": "p-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCompleted, inst.Status)
+	assert.Equal(t, 3, inst.Done)
+	assert.Equal(t, []string{"create", "reserve", "notify"}, r.calls)
+
+	stored, err := store.Get(context.Background(), inst.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, Data{"code": "p-1", "create": "done", "reserve": "done", "notify": "done"}, stored.Data)
+	log, _ := store.Log(context.Background(), inst.ID)
+	assert.Len(t, log, 3)
+
+	_, err = o.Start(context.Background(), "order", nil)
+	assert.ErrorIs(t, err, ErrUnknownSaga)
+}
+
+func TestSagaCompensatesInReverse(t *testing.T) {
+	r := newRecorder()
+	r.failAction["notify"] = true
+	r.failUndo["reserve"] = 1
+	o, store := newTestOrchestrator(r)
+
+	inst, err := o.Start(context.Background(), "product", nil)
+	assert.ErrorContains(t, err, "notify is down")
+	assert.Equal(t, StatusCompensated, inst.Status)
+	assert.Equal(t, 0, inst.Done)
+	// The failed step is not compensated; the others are, newest first, with a retry.
+	assert.Equal(t, []string{"create", "reserve", "notify", "undo reserve", "undo reserve", "undo create"}, r.calls)
+
+	log, _ := store.Log(context.Background(), inst.ID)
+	var lines []string
+	for _, e := range log {
+		lines = append(lines, fmt.Sprintf("%s %s %d %v", e.Phase, e.Step, e.Attempt, e.OK))
+	}
+	assert.Equal(t, []string{
+		"action create 1 true", "action reserve 1 true", "action notify 1 false",
+		"compensate reserve 1 false", "compensate reserve 2 true", "compensate create 1 true",
+	}, lines)
+}
+
+func TestSagaFailsAndResumes(t *testing.T) {
+	r := newRecorder()
+	r.failAction["reserve"] = true
+	r.failUndo["create"] = 5
+	o, store := newTestOrchestrator(r)
+
+	inst, err := o.Start(context.Background(), "product", nil)
+	assert.ErrorContains(t, err, "compensation of create failed")
+	assert.Equal(t, StatusFailed, inst.Status)
+	assert.Equal(t, 1, inst.Done)
+	// Recover leaves failed sagas to an operator.
+	n, err := o.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	r.failUndo["create"] = 0
+	inst, err = o.Resume(context.Background(), inst.ID)
+	assert.ErrorContains(t, err, "reserve is down")
+	assert.Equal(t, StatusCompensated, inst.Status)
+	unfinished, _ := store.Unfinished(context.Background())
+	assert.Empty(t, unfinished)
+}
+
+func TestSagaRecoversAfterInterruption(t *testing.T) {
+	r := newRecorder()
+	o, store := newTestOrchestrator(r)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancelAfter, r.cancel = "create", cancel
+
+	inst, err := o.Start(ctx, "product", nil)
+	assert.ErrorIs(t, err, ErrInterrupted)
+	stored, _ := store.Get(context.Background(), inst.ID)
+	assert.Equal(t, StatusRunning, stored.Status)
+	assert.Equal(t, 1, stored.Done)
+
+	// Another orchestrator over the same store, as after a restart.
+	restarted, _ := newTestOrchestrator(r)
+	restarted.store = store
+	n, err := restarted.Recover(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"create", "reserve", "notify"}, r.calls)
+	stored, _ = store.Get(context.Background(), inst.ID)
+	assert.Equal(t, StatusCompleted, stored.Status)
+
+	recent, _ := store.Recent(context.Background(), 10)
+	assert.Len(t, recent, 1)
+	_, err = o.Resume(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,128 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies an error returned by a scenario so the API can map it to an HTTP status.
+type ErrorCode string
+
+const (
+	// CodeNotFound means the requested scenario, action or resource does not exist.
+	CodeNotFound ErrorCode = "not_found"
+	// CodeInvalidParams means the action parameters were missing or malformed.
+	CodeInvalidParams ErrorCode = "invalid_params"
+	// CodeDependencyUnavailable means MySQL, Redis, the message bus etc. could not be reached or failed.
+	CodeDependencyUnavailable ErrorCode = "dependency_unavailable"
+	// CodeConflict means the target is in a state that does not allow the request, e.g. a finished job.
+	CodeConflict ErrorCode = "conflict"
+	// CodeUnauthorized means the request carried no valid credentials but needs some.
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodeForbidden means the caller's role does not allow the request.
+	CodeForbidden ErrorCode = "forbidden"
+	// CodeUnprocessable means the request is well-formed but cannot be applied, e.g. an
+	// Idempotency-Key reused for a different request.
+	CodeUnprocessable ErrorCode = "unprocessable"
+	// CodeRateLimited means the caller sent too many requests; see the retry_after detail.
+	CodeRateLimited ErrorCode = "rate_limited"
+	// CodeDemonstrated marks an expected failure: the action failed on purpose to demonstrate the problem.
+	CodeDemonstrated ErrorCode = "demonstrated"
+	// CodeInternal is used for any error that is not a *Error.
+	CodeInternal ErrorCode = "internal"
+)
+
+// Error is a typed error returned by scenarios and the API layer.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Details map[string]interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetail attaches a key/value pair that is returned to the client in the error details.
+func (e *Error) WithDetail(key string, value interface{}) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// NotFoundError returns an error with CodeNotFound.
+func NotFoundError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnknownActionError is returned by ExecuteAction for an action ID the scenario does not define.
+func UnknownActionError(actionID string) *Error {
+	return NotFoundError("unknown action: %s", actionID).WithDetail("action_id", actionID)
+}
+
+// InvalidParamsError returns an error with CodeInvalidParams.
+func InvalidParamsError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// DependencyError wraps a failure of an external dependency with CodeDependencyUnavailable.
+func DependencyError(err error, format string, args ...interface{}) *Error {
+	return &Error{Code: CodeDependencyUnavailable, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// ConflictError returns an error with CodeConflict.
+func ConflictError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnauthorizedError returns an error with CodeUnauthorized.
+func UnauthorizedError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// ForbiddenError returns an error with CodeForbidden.
+func ForbiddenError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnprocessableError returns an error with CodeUnprocessable.
+func UnprocessableError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeUnprocessable, Message: fmt.Sprintf(format, args...)}
+}
+
+// RateLimitedError returns an error with CodeRateLimited.
+func RateLimitedError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeRateLimited, Message: fmt.Sprintf(format, args...)}
+}
+
+// DemonstratedError marks a deliberate failure that demonstrates the scenario's problem.
+func DemonstratedError(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeDemonstrated, Message: fmt.Sprintf(format, args...)}
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or CodeInternal.
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// IsDemonstrated reports whether err is an expected, demonstrative failure.
+func IsDemonstrated(err error) bool {
+	return CodeOf(err) == CodeDemonstrated
+}
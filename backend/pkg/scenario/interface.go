@@ -1,10 +1,29 @@
 package scenario
 
+import "context"
+
 // Action represents a user-triggerable event in a scenario.
 type Action struct {
 	ID          string `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	// Kind marks the action as the "problem" or the "solution" of the scenario. Comparison
+	// mode runs the two side by side by default; other actions leave it empty.
+	Kind string `json:"kind,omitempty"`
+	// Role is the least role allowed to run the action once authentication is enabled:
+	// "viewer", "operator" (the default) or "admin".
+	Role string `json:"role,omitempty"`
+	// Params declares the parameters the action accepts. The API validates requests against them.
+	Params []ActionParam `json:"params,omitempty"`
+}
+
+// ActionParam describes one parameter of an action.
+type ActionParam struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // "string", "integer", "number" or "boolean"
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"` // documented only; the action applies it
 }
 
 // DashboardComponent defines a piece of state to be visualized on the frontend.
@@ -32,10 +51,35 @@ type Scenario interface {
 	Initialize() error
 
 	// ExecuteAction runs a specific action defined by the scenario.
-	// It takes an actionID and optional parameters.
-	ExecuteAction(actionID string, params map[string]interface{}) (interface{}, error)
+	// It takes an actionID and optional parameters. Long-running actions must stop when ctx
+	// is cancelled and may report their progress with ReportProgress(ctx, ...).
+	ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error)
 
 	// FetchState retrieves the current state of all dashboard components for the scenario.
 	// The keys of the returned map should match the IDs of the DashboardComponents.
 	FetchState() (map[string]interface{}, error)
-}
\ No newline at end of file
+}
+
+// Action kinds, see Action.Kind.
+const (
+	KindProblem  = "problem"
+	KindSolution = "solution"
+)
+
+// Forkable is optionally implemented by scenarios that can run isolated copies of
+// themselves, so that comparison mode can show the problem and the solution side by side.
+//
+// Fork returns a scenario that starts from the current state of the receiver but owns
+// separate keys and rows, so that actions on it leave the original and other forks
+// alone. name is unique among live forks and consists of lowercase letters, digits and
+// underscores, so it can be used in table names and keys. release removes the state of
+// the fork once it is no longer needed.
+type Forkable interface {
+	Fork(ctx context.Context, name string) (fork Scenario, release func(ctx context.Context) error, err error)
+}
+
+// Tagged is optionally implemented by scenarios to label themselves for the navigator,
+// e.g. "cache" or "cdc".
+type Tagged interface {
+	Tags() []string
+}
@@ -0,0 +1,23 @@
+package scenario
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx that carries the given logger.
+// The action executor attaches one tagged with the scenario, action, session and trace ID.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// Logger returns the logger carried by ctx, or slog.Default() if there is none.
+// Actions should log through it so that their output shows up in the scenario's log stream.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,25 @@
+package scenario
+
+import "context"
+
+// ProgressReporter receives progress updates from a running action.
+// Percent is in the range [0, 100]; message is a short human-readable line of partial output.
+type ProgressReporter interface {
+	Report(percent int, message string)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that carries the given reporter.
+// The action executor attaches one for asynchronous executions.
+func WithProgress(ctx context.Context, r ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, r)
+}
+
+// ReportProgress reports progress to the reporter carried by ctx, if any.
+// It is safe to call from any action; synchronous executions simply ignore the updates.
+func ReportProgress(ctx context.Context, percent int, message string) {
+	if r, ok := ctx.Value(progressKey{}).(ProgressReporter); ok {
+		r.Report(percent, message)
+	}
+}
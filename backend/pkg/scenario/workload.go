@@ -0,0 +1,21 @@
+package scenario
+
+import "context"
+
+// Workload is a request pattern a scenario offers to the load generator besides its
+// actions, e.g. "read the product through the cache". Problems such as a thundering
+// herd or lock contention only show up when many of them run concurrently.
+type Workload struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Params declares the parameters the workload accepts, like Action.Params.
+	Params []ActionParam `json:"params,omitempty"`
+	// Run performs one request. It is called concurrently and must stop when ctx is cancelled.
+	Run func(ctx context.Context, params map[string]interface{}) error `json:"-"`
+}
+
+// WorkloadProvider is optionally implemented by scenarios that offer workloads.
+type WorkloadProvider interface {
+	Workloads() []Workload
+}
@@ -0,0 +1,15 @@
+// Code generated by cmd/scenariogen; DO NOT EDIT.
+
+package all
+
+import (
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/cache_failures"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/cache_inconsistency"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/distributed_lock"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/hot_keys"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/idempotent_api"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/product_saga"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/rate_limiter"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/trans_msg"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/xdc_cache_sync"
+)
@@ -0,0 +1,5 @@
+// Package all imports every scenario package so that their init functions register
+// them. The server imports it once instead of listing each scenario.
+package all
+
+//go:generate go run ../../cmd/scenariogen -root .. -o all.gen.go
@@ -0,0 +1,373 @@
+package cachefailures
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/bloom"
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in cache_failures.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario()
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "cache_failures"
+
+	// codePrefix marks the products of the scenario in web_product, and keyPrefix its
+	// keys in Redis.
+	codePrefix = "cf-"
+	keyPrefix  = "cache_failures:"
+	// products is the number of products seeded.
+	products = 100
+
+	defaultTTLMs       = 5000
+	defaultDBLatencyMs = 20
+	// liveSeconds is the number of seconds of live DB queries shown on the dashboard.
+	liveSeconds = 10
+)
+
+// logger is used by the background rebuilds, which outlive the request that started them.
+var logger = logs.For(scenarioID)
+
+// CacheFailuresScenario holds the handlers of the scenario on the read-side failures of
+// a cache in front of web_product: penetration, breakdown and avalanche. The actions run
+// each failure for a moment with and without its mitigation and record the queries that
+// reached MySQL; the workload reads with the mitigations chosen by configure.
+type CacheFailuresScenario struct {
+	catalog productcache.Catalog
+	cache   productcache.Cache
+	locker  lock.Locker
+	// filter holds the IDs of the seeded products.
+	filter *bloom.Filter
+
+	mu   sync.Mutex
+	ids  []int64 // seeded products
+	opts options // of the workload
+	live *stats  // of the workload since the last configure
+	// liveQueries counts the queries of the workload per second.
+	liveQueries *meter
+	runs        map[string]map[string]*run // last run by failure and mitigation
+	last        *run
+}
+
+func newScenario() *CacheFailuresScenario {
+	return &CacheFailuresScenario{
+		locker:      lock.NewMemory(),
+		filter:      bloom.New(products, 0.01),
+		opts:        defaultOptions(),
+		live:        &stats{},
+		liveQueries: newMeter(time.Second),
+		runs:        make(map[string]map[string]*run),
+	}
+}
+
+func defaultOptions() options {
+	return options{Guard: guardNone, Rebuild: rebuildNone, TTLMs: defaultTTLMs, DBLatencyMs: defaultDBLatencyMs}
+}
+
+func (s *CacheFailuresScenario) handlers() registry.Handlers {
+	experiment := func(failure, mitigation string) registry.ActionFunc {
+		return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return s.runExperiment(ctx, failure, mitigation)
+		}
+	}
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Workloads: []scenario.Workload{{
+			ID:          "read_products",
+			Name:        "Read Products",
+			Description: "Reads a product through the cache with the mitigations chosen by Configure Reads.",
+			Params: []scenario.ActionParam{
+				{Name: "missing_pct", Type: "integer", Description: "Percentage of reads for products that do not exist", Default: 0},
+				{Name: "hot_pct", Type: "integer", Description: "Percentage of reads for the first product", Default: 0},
+			},
+			Run: s.readProducts,
+		}},
+		Actions: map[string]registry.ActionFunc{
+			"penetration_naive": experiment(failurePenetration, mitigationNone),
+			"penetration_guarded": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				guard, err := choice(params, "guard", guardBloom, guardNullCache, guardBloom)
+				if err != nil {
+					return nil, err
+				}
+				return s.runExperiment(ctx, failurePenetration, guard)
+			},
+			"breakdown_naive": experiment(failureBreakdown, mitigationNone),
+			"breakdown_guarded": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				rebuild, err := choice(params, "rebuild", rebuildMutex, rebuildMutex, rebuildLogical)
+				if err != nil {
+					return nil, err
+				}
+				return s.runExperiment(ctx, failureBreakdown, rebuild)
+			},
+			"avalanche_naive":  experiment(failureAvalanche, mitigationNone),
+			"avalanche_jitter": experiment(failureAvalanche, mitigationJitter),
+			"configure":        s.configure,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database and Redis and seeds the products.
+func (s *CacheFailuresScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.WebProduct{})
+	if err != nil {
+		return err
+	}
+	s.catalog = productcache.NewMySQLCatalog(db, codePrefix)
+
+	ctx := context.Background()
+	redisClient, err := infra.OpenRedis(ctx)
+	if err != nil {
+		return err
+	}
+	s.cache = productcache.NewRedisCache(redisClient, keyPrefix)
+	s.locker = lock.NewRedis(redisClient)
+	return s.seed(ctx)
+}
+
+// seed creates the products that are missing and fills the Bloom filter with their IDs.
+func (s *CacheFailuresScenario) seed(ctx context.Context) error {
+	ids, err := s.catalog.Seed(ctx, products)
+	if err != nil {
+		return fmt.Errorf("failed to seed products: %w", err)
+	}
+	s.filter.Reset()
+	for _, id := range ids {
+		s.filter.AddInt(id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = ids
+	return nil
+}
+
+// runExperiment runs failure with mitigation and reports the failure as demonstrated
+// when the database took the load that the mitigation would have kept away.
+func (s *CacheFailuresScenario) runExperiment(ctx context.Context, failure, mitigation string) (interface{}, error) {
+	s.mu.Lock()
+	seeded := len(s.ids)
+	s.mu.Unlock()
+	if seeded == 0 {
+		return nil, scenario.UnprocessableError("no products are seeded; reset the scenario")
+	}
+	res, err := s.experiment(ctx, failure, mitigation)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "%s experiment failed", failure)
+	}
+	log := scenario.Logger(ctx)
+	log.Info("Ran experiment", "failure", failure, "mitigation", mitigation, "requests", res.Requests,
+		"db_queries", res.DBQueries, "peak_db_qps", res.PeakDBQPS, "peak_concurrent_queries", res.PeakConcurrent)
+	if mitigation != mitigationNone {
+		return res, nil
+	}
+	switch {
+	case failure == failurePenetration && 2*res.DBQueries > res.Requests:
+		log.Warn("Lookups of absent products went through to MySQL", "db_queries", res.DBQueries)
+		return nil, scenario.DemonstratedError("%d of %d lookups of absent products reached MySQL", res.DBQueries, res.Requests)
+	case failure == failureBreakdown && res.PeakConcurrent > 1:
+		log.Warn("Readers rebuilt the hot key at once", "peak_concurrent_queries", res.PeakConcurrent)
+		return nil, scenario.DemonstratedError("%d readers queried MySQL for the hot key at once when it expired (peak %d QPS)", res.PeakConcurrent, res.PeakDBQPS)
+	case failure == failureAvalanche && res.PeakDBQPS/int(time.Second/bucket) >= seeded/4:
+		log.Warn("Keys cached together expired together", "peak_db_qps", res.PeakDBQPS)
+		return nil, scenario.DemonstratedError("the %d keys cached with the same TTL expired together: MySQL peaked at %d QPS", seeded, res.PeakDBQPS)
+	}
+	return res, nil
+}
+
+// record keeps res for the dashboard.
+func (s *CacheFailuresScenario) record(res *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runs[res.Failure] == nil {
+		s.runs[res.Failure] = make(map[string]*run)
+	}
+	s.runs[res.Failure][res.Mitigation] = res
+	s.last = res
+}
+
+// readProducts reads one product through the cache with the mitigations of the workload.
+func (s *CacheFailuresScenario) readProducts(ctx context.Context, params map[string]interface{}) error {
+	missing, err := intParam(params, "missing_pct", 0, 0, 100)
+	if err != nil {
+		return err
+	}
+	hot, err := intParam(params, "hot_pct", 0, 0, 100)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	ids := s.ids
+	r := &reader{
+		cache:   s.cache,
+		catalog: s.catalog,
+		locker:  s.locker,
+		filter:  s.filter,
+		opts:    s.opts,
+		prefix:  keyPrefix + "live:",
+		stats:   s.live,
+		meters:  []*meter{s.liveQueries},
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return scenario.UnprocessableError("no products are seeded; reset the scenario")
+	}
+
+	var id int64
+	switch n := rand.Intn(100); {
+	case n < missing:
+		// IDs past the seeded ones, as an attacker would try.
+		id = ids[len(ids)-1] + 1 + rand.Int63n(1<<40)
+	case n < missing+hot:
+		id = ids[0]
+	default:
+		id = ids[rand.Intn(len(ids))]
+	}
+	if _, err := r.get(ctx, id); err != nil {
+		return scenario.DependencyError(err, "failed to read product %d", id)
+	}
+	return nil
+}
+
+// configure sets the mitigations of the workload and clears its counters. The queries
+// per second are kept, to compare them before and after.
+func (s *CacheFailuresScenario) configure(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	o := s.opts
+	s.mu.Unlock()
+	var err error
+	if o.Guard, err = choice(params, "guard", o.Guard, guards...); err != nil {
+		return nil, err
+	}
+	if o.Rebuild, err = choice(params, "rebuild", o.Rebuild, rebuilds...); err != nil {
+		return nil, err
+	}
+	if v, ok := params["jitter"].(bool); ok {
+		o.Jitter = v
+	}
+	if o.TTLMs, err = intParam(params, "ttl_ms", o.TTLMs, 100, 3600000); err != nil {
+		return nil, err
+	}
+	if o.DBLatencyMs, err = intParam(params, "db_latency_ms", o.DBLatencyMs, 0, 5000); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.opts, s.live = o, &stats{}
+	s.mu.Unlock()
+	scenario.Logger(ctx).Info("Configured reads", "guard", o.Guard, "rebuild", o.Rebuild, "jitter", o.Jitter, "ttl_ms", o.TTLMs, "db_latency_ms", o.DBLatencyMs)
+	return o, nil
+}
+
+func (s *CacheFailuresScenario) FetchState() (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := s.liveQueries.series(time.Now().Add(-(liveSeconds-1)*time.Second), liveSeconds)
+	parts := make([]string, len(counts))
+	for i, n := range counts {
+		parts[i] = strconv.Itoa(n)
+	}
+	experiments := make(map[string]interface{}, len(s.runs))
+	for failure, byMitigation := range s.runs {
+		m := make(map[string]interface{}, len(byMitigation))
+		for mitigation, r := range byMitigation {
+			m[mitigation] = map[string]interface{}{
+				"requests":                r.Requests,
+				"db_queries":              r.DBQueries,
+				"peak_db_qps":             r.PeakDBQPS,
+				"peak_concurrent_queries": r.PeakConcurrent,
+				"db_queries_per_100ms":    r.Timeline,
+			}
+		}
+		experiments[failure] = m
+	}
+	state := map[string]interface{}{
+		"live": map[string]interface{}{
+			"db_qps_last_10s": strings.Join(parts, " "),
+			"stats":           s.live.snapshot(),
+		},
+		"experiments": experiments,
+		"config":      s.opts,
+		"bloom_filter": map[string]interface{}{
+			"items":               s.filter.Count(),
+			"bits":                s.filter.Bits(),
+			"hashes":              s.filter.Hashes(),
+			"false_positive_rate": s.filter.FalsePositiveRate(),
+		},
+		"last_run": "no run yet",
+	}
+	if s.last != nil {
+		state["last_run"] = *s.last
+	}
+	return state, nil
+}
+
+// resetState clears the cache and the records, restores the default mitigations and
+// seeds the products again.
+func (s *CacheFailuresScenario) resetState(ctx context.Context) error {
+	if err := s.cache.Clear(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to clear the cache")
+	}
+	if err := s.catalog.Reset(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to delete products")
+	}
+	if err := s.seed(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to seed products")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts, s.live, s.liveQueries = defaultOptions(), &stats{}, newMeter(time.Second)
+	s.runs, s.last = make(map[string]map[string]*run), nil
+	return nil
+}
+
+// choice returns the string param name, which must be one of allowed, or def if it is
+// absent.
+func choice(params map[string]interface{}, name, def string, allowed ...string) (string, error) {
+	v, _ := params[name].(string)
+	if v == "" {
+		return def, nil
+	}
+	for _, a := range allowed {
+		if v == a {
+			return v, nil
+		}
+	}
+	return "", scenario.InvalidParamsError("%s must be one of %s", name, strings.Join(allowed, ", ")).WithDetail("field", name)
+}
+
+// intParam returns the integer param name, or def if it is absent.
+func intParam(params map[string]interface{}, name string, def, min, max int) (int, error) {
+	v := def
+	switch p := params[name].(type) {
+	case nil:
+	case float64:
+		v = int(p)
+	case int:
+		v = p
+	default:
+		return 0, scenario.InvalidParamsError("%s must be a number", name).WithDetail("field", name)
+	}
+	if v < min || v > max {
+		return 0, scenario.InvalidParamsError("%s must be between %d and %d", name, min, max).WithDetail("field", name)
+	}
+	return v, nil
+}
@@ -0,0 +1,99 @@
+package cachefailures
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScenario(t *testing.T) *CacheFailuresScenario {
+	s := newScenario()
+	s.catalog = productcache.NewMemoryCatalog(codePrefix)
+	s.cache = productcache.NewMemoryCache(0)
+	assert.NoError(t, s.resetState(context.Background()))
+	return s
+}
+
+func TestPenetration(t *testing.T) {
+	s := newTestScenario(t)
+	_, err := s.runExperiment(context.Background(), failurePenetration, mitigationNone)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	naive := s.runs[failurePenetration][mitigationNone]
+	assert.Equal(t, naive.Requests, naive.DBQueries)
+
+	// A cached null answers the next lookups of an absent product.
+	out, err := s.runExperiment(context.Background(), failurePenetration, guardNullCache)
+	assert.NoError(t, err)
+	res := out.(*run)
+	assert.Less(t, res.DBQueries, naive.DBQueries/2)
+	assert.Positive(t, res.Stats["null_hits"])
+
+	// The Bloom filter answers them without the cache, but for false positives.
+	out, err = s.runExperiment(context.Background(), failurePenetration, guardBloom)
+	assert.NoError(t, err)
+	res = out.(*run)
+	assert.LessOrEqual(t, res.DBQueries, int64(res.Requests/20))
+	assert.Positive(t, res.Stats["bloom_rejected"])
+}
+
+func TestBreakdown(t *testing.T) {
+	s := newTestScenario(t)
+	_, err := s.runExperiment(context.Background(), failureBreakdown, mitigationNone)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	assert.Greater(t, s.runs[failureBreakdown][mitigationNone].PeakConcurrent, int64(1))
+
+	for _, rebuild := range []string{rebuildMutex, rebuildLogical} {
+		out, err := s.runExperiment(context.Background(), failureBreakdown, rebuild)
+		assert.NoError(t, err, rebuild)
+		res := out.(*run)
+		assert.Equal(t, int64(1), res.PeakConcurrent, rebuild)
+		assert.Zero(t, res.Stats["errors"], rebuild)
+		// The key expires every 300ms or so over the second of the run.
+		assert.LessOrEqual(t, res.DBQueries, int64(5), rebuild)
+	}
+	assert.Positive(t, s.runs[failureBreakdown][rebuildMutex].Stats["lock_waits"])
+	assert.Positive(t, s.runs[failureBreakdown][rebuildLogical].Stats["stale_served"])
+	assert.Zero(t, s.runs[failureBreakdown][rebuildLogical].Stats["lock_waits"])
+}
+
+func TestAvalanche(t *testing.T) {
+	s := newTestScenario(t)
+	_, err := s.runExperiment(context.Background(), failureAvalanche, mitigationNone)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	out, err := s.runExperiment(context.Background(), failureAvalanche, mitigationJitter)
+	assert.NoError(t, err)
+	assert.Less(t, out.(*run).PeakDBQPS, s.runs[failureAvalanche][mitigationNone].PeakDBQPS)
+}
+
+func TestReadProducts(t *testing.T) {
+	s := newTestScenario(t)
+	ctx := context.Background()
+	_, err := s.configure(ctx, map[string]interface{}{"guard": guardBloom, "db_latency_ms": float64(0)})
+	assert.NoError(t, err)
+	// Only the first read of the hot product reaches the database.
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.readProducts(ctx, map[string]interface{}{"hot_pct": float64(100)}))
+	}
+	stats := s.live.snapshot()
+	assert.Equal(t, int64(1), stats["db_queries"])
+	assert.Equal(t, int64(9), stats["cache_hits"])
+	// Absent products are stopped by the Bloom filter, but for false positives.
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.readProducts(ctx, map[string]interface{}{"missing_pct": float64(100)}))
+	}
+	stats = s.live.snapshot()
+	assert.Equal(t, int64(20), stats["requests"])
+	assert.GreaterOrEqual(t, stats["bloom_rejected"], int64(8))
+
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	live := state["live"].(map[string]interface{})
+	assert.Len(t, strings.Fields(live["db_qps_last_10s"].(string)), liveSeconds)
+
+	_, err = s.configure(ctx, map[string]interface{}{"rebuild": "eventually"})
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+}
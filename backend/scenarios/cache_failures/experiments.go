@@ -0,0 +1,176 @@
+package cachefailures
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	failurePenetration = "penetration"
+	failureBreakdown   = "breakdown"
+	failureAvalanche   = "avalanche"
+
+	mitigationNone   = "none"
+	mitigationJitter = "jitter"
+
+	// workers read concurrently during an experiment, pausing think between requests.
+	workers = 20
+	think   = 5 * time.Millisecond
+	// bucket is the resolution of the DB query timeline of an experiment.
+	bucket = 100 * time.Millisecond
+
+	// missingKeys is the number of distinct absent products looked up by penetration.
+	missingKeys = 50
+	// hotTTL is the TTL of the hot key of breakdown, and avalancheTTL that of the keys
+	// warmed up together by avalanche: short, so that they expire during the run.
+	hotTTL       = 300 * time.Millisecond
+	avalancheTTL = 500 * time.Millisecond
+)
+
+// durations of the experiments.
+var durations = map[string]time.Duration{
+	failurePenetration: 500 * time.Millisecond,
+	failureBreakdown:   time.Second,
+	failureAvalanche:   1200 * time.Millisecond,
+}
+
+// run is the outcome of an experiment.
+type run struct {
+	Failure    string `json:"failure"`
+	Mitigation string `json:"mitigation"`
+	Requests   int64  `json:"requests"`
+	DBQueries  int64  `json:"db_queries"`
+	// PeakDBQPS is the busiest tenth of a second, as queries per second.
+	PeakDBQPS int `json:"peak_db_qps"`
+	// PeakConcurrent is the most queries in flight at once.
+	PeakConcurrent int64 `json:"peak_concurrent_queries"`
+	// Timeline counts the queries per tenth of a second.
+	Timeline string           `json:"db_queries_per_100ms"`
+	Stats    map[string]int64 `json:"stats"`
+}
+
+// experimentOptions returns the options of a reader facing failure with mitigation. The
+// database latency is the configured one.
+func experimentOptions(failure, mitigation string, latencyMs int) options {
+	o := options{Guard: guardNone, Rebuild: rebuildNone, TTLMs: defaultTTLMs, DBLatencyMs: latencyMs}
+	switch failure {
+	case failurePenetration:
+		if mitigation != mitigationNone {
+			o.Guard = mitigation
+		}
+	case failureBreakdown:
+		o.TTLMs = int(hotTTL / time.Millisecond)
+		if mitigation != mitigationNone {
+			o.Rebuild = mitigation
+		}
+	case failureAvalanche:
+		o.TTLMs = int(avalancheTTL / time.Millisecond)
+		o.Jitter = mitigation == mitigationJitter
+	}
+	return o
+}
+
+// experiment has workers read products for a while, the way that provokes failure, with
+// mitigation in place, and records the queries that reached the database.
+//
+//	penetration  lookups of absent products
+//	breakdown    every reader on one hot key, which expires
+//	avalanche    reads of all products, cached together with the same TTL
+func (s *CacheFailuresScenario) experiment(ctx context.Context, failure, mitigation string) (*run, error) {
+	s.mu.Lock()
+	ids, latency := s.ids, s.opts.DBLatencyMs
+	s.mu.Unlock()
+
+	m := newMeter(bucket)
+	r := &reader{
+		cache:   s.cache,
+		catalog: s.catalog,
+		locker:  s.locker,
+		filter:  s.filter,
+		opts:    experimentOptions(failure, mitigation, latency),
+		// Every run has keys of its own, so that it starts with a cold cache.
+		prefix: keyPrefix + "run:" + uuid.New().String()[:8] + ":",
+		stats:  &stats{},
+		meters: []*meter{m},
+	}
+
+	var next func() int64
+	var warm []int64
+	switch failure {
+	case failurePenetration:
+		var top int64
+		for _, id := range ids {
+			top = max(top, id)
+		}
+		first := top + 1 + rand.Int63n(1<<40)
+		next = func() int64 { return first + rand.Int63n(missingKeys) }
+	case failureBreakdown:
+		warm = ids[:1]
+		next = func() int64 { return ids[0] }
+	default:
+		warm = ids
+		next = func() int64 { return ids[rand.Intn(len(ids))] }
+	}
+	// Warming up is not part of the measure.
+	for _, id := range warm {
+		p, err := s.catalog.Product(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.store(ctx, id, p); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	duration := durations[failure]
+	drive(ctx, r, duration, next)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	counts := m.series(start, int(duration/bucket)+1)
+	parts := make([]string, len(counts))
+	peak := 0
+	for i, n := range counts {
+		parts[i] = strconv.Itoa(n)
+		peak = max(peak, n)
+	}
+	res := &run{
+		Failure:        failure,
+		Mitigation:     mitigation,
+		Requests:       r.stats.requests.Load(),
+		DBQueries:      r.stats.dbQueries.Load(),
+		PeakDBQPS:      peak * int(time.Second/bucket),
+		PeakConcurrent: r.stats.peakInflight.Load(),
+		Timeline:       strings.Join(parts, " "),
+		Stats:          r.stats.snapshot(),
+	}
+	s.record(res)
+	return res, nil
+}
+
+// drive has workers read the products returned by next through r until duration has
+// passed. Reads in flight at the end are finished.
+func drive(ctx context.Context, r *reader, duration time.Duration, next func() int64) {
+	deadline := time.Now().Add(duration)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil && time.Now().Before(deadline) {
+				// Errors are counted by the reader.
+				_, _ = r.get(ctx, next())
+				_ = sleep(ctx, think)
+			}
+		}()
+	}
+	wg.Wait()
+}
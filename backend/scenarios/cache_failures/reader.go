@@ -0,0 +1,335 @@
+package cachefailures
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/bloom"
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	guardNone      = "none"
+	guardNullCache = "null_cache"
+	guardBloom     = "bloom"
+
+	rebuildNone    = "none"
+	rebuildMutex   = "mutex"
+	rebuildLogical = "logical"
+
+	// nullTTL is how long an absent product is remembered with null caching. A product
+	// created meanwhile stays invisible that long, so it is kept short.
+	nullTTL = 30 * time.Second
+	// logicalGrace is how long an entry with a logical expiry is kept in the cache past
+	// it, so that the keys nobody reads any more go away.
+	logicalGrace = time.Minute
+	// lockTTL bounds how long a crashed rebuild holds the lock of its key.
+	lockTTL = 5 * time.Second
+	// rebuildWait is how long a reader waits for the rebuild of another before it fails,
+	// and rebuildPoll how often it looks at the cache meanwhile.
+	rebuildWait = 2 * time.Second
+	rebuildPoll = 10 * time.Millisecond
+)
+
+var guards = []string{guardNone, guardNullCache, guardBloom}
+var rebuilds = []string{rebuildNone, rebuildMutex, rebuildLogical}
+
+// options select the mitigations of a reader.
+type options struct {
+	// Guard protects the database from lookups of absent products: none, null_cache or
+	// bloom.
+	Guard string `json:"guard"`
+	// Rebuild is how a missing or expired entry is rebuilt: none (by every reader that
+	// misses), mutex (by the one reader holding the lock of the key) or logical (in the
+	// background, while readers are served the expired entry).
+	Rebuild string `json:"rebuild"`
+	// Jitter adds a random extra of up to the TTL to the TTL of every entry.
+	Jitter bool `json:"jitter"`
+	TTLMs  int  `json:"ttl_ms"`
+	// DBLatencyMs is added to every query, standing for a database under load.
+	DBLatencyMs int `json:"db_latency_ms"`
+}
+
+func (o options) ttl() time.Duration {
+	ttl := time.Duration(o.TTLMs) * time.Millisecond
+	if o.Jitter && ttl > 0 {
+		ttl += time.Duration(rand.Int63n(int64(ttl)))
+	}
+	return ttl
+}
+
+// entry is a cached product. Product is nil for a product that does not exist; ExpireAt
+// (unix milliseconds) is set when the entry expires logically instead of in the cache.
+type entry struct {
+	Product  *productcache.Product `json:"p"`
+	ExpireAt int64                 `json:"e,omitempty"`
+}
+
+// stats count what a reader did.
+type stats struct {
+	requests      atomic.Int64
+	hits          atomic.Int64
+	misses        atomic.Int64
+	nullHits      atomic.Int64 // absent products answered by a cached null
+	bloomRejected atomic.Int64 // absent products answered by the Bloom filter
+	staleServed   atomic.Int64 // logically expired entries served during their rebuild
+	lockWaits     atomic.Int64 // readers that waited for the rebuild of another
+	errors        atomic.Int64
+	dbQueries     atomic.Int64
+	inflight      atomic.Int64
+	peakInflight  atomic.Int64 // most queries at the same time
+}
+
+func (s *stats) snapshot() map[string]int64 {
+	return map[string]int64{
+		"requests":                s.requests.Load(),
+		"cache_hits":              s.hits.Load(),
+		"cache_misses":            s.misses.Load(),
+		"null_hits":               s.nullHits.Load(),
+		"bloom_rejected":          s.bloomRejected.Load(),
+		"stale_served":            s.staleServed.Load(),
+		"lock_waits":              s.lockWaits.Load(),
+		"errors":                  s.errors.Load(),
+		"db_queries":              s.dbQueries.Load(),
+		"peak_concurrent_queries": s.peakInflight.Load(),
+	}
+}
+
+// meter counts events per bucket of time.
+type meter struct {
+	bucket time.Duration
+
+	mu     sync.Mutex
+	counts map[int64]int // by bucket number since the Unix epoch
+}
+
+func newMeter(bucket time.Duration) *meter {
+	return &meter{bucket: bucket, counts: make(map[int64]int)}
+}
+
+func (m *meter) add(at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b := at.UnixNano() / int64(m.bucket)
+	m.counts[b]++
+	// Keep a bounded history.
+	if len(m.counts) > 600 {
+		for k := range m.counts {
+			if k < b-300 {
+				delete(m.counts, k)
+			}
+		}
+	}
+}
+
+// series returns the counts of the n buckets from the one holding from.
+func (m *meter) series(from time.Time, n int) []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	first := from.UnixNano() / int64(m.bucket)
+	out := make([]int, n)
+	for i := range out {
+		out[i] = m.counts[first+int64(i)]
+	}
+	return out
+}
+
+// reader reads products through the cache with the mitigations of opts. Every query of
+// the catalog is counted by stats and meters.
+type reader struct {
+	cache   productcache.Cache
+	catalog productcache.Catalog
+	locker  lock.Locker
+	filter  *bloom.Filter
+	opts    options
+	// prefix is the prefix of the cache and lock keys of the reader.
+	prefix string
+	stats  *stats
+	meters []*meter
+}
+
+func (r *reader) key(id int64) string {
+	return r.prefix + "product:" + strconv.FormatInt(id, 10)
+}
+
+func (r *reader) lockKey(id int64) string {
+	return r.prefix + "lock:" + strconv.FormatInt(id, 10)
+}
+
+// get returns the product with id, or nil if there is none.
+func (r *reader) get(ctx context.Context, id int64) (*productcache.Product, error) {
+	r.stats.requests.Add(1)
+	p, err := r.read(ctx, id)
+	if err != nil {
+		r.stats.errors.Add(1)
+	}
+	return p, err
+}
+
+func (r *reader) read(ctx context.Context, id int64) (*productcache.Product, error) {
+	if r.opts.Guard == guardBloom && !r.filter.TestInt(id) {
+		r.stats.bloomRejected.Add(1)
+		return nil, nil
+	}
+	e, ok, err := r.lookup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if e.Product == nil {
+			r.stats.nullHits.Add(1)
+			return nil, nil
+		}
+		if e.ExpireAt != 0 && time.Now().UnixMilli() >= e.ExpireAt {
+			r.stats.staleServed.Add(1)
+			r.rebuildInBackground(ctx, id)
+			return e.Product, nil
+		}
+		r.stats.hits.Add(1)
+		return e.Product, nil
+	}
+	r.stats.misses.Add(1)
+	if r.opts.Rebuild == rebuildNone {
+		return r.rebuild(ctx, id)
+	}
+	return r.rebuildLocked(ctx, id)
+}
+
+// lookup returns the cached entry of id, if any.
+func (r *reader) lookup(ctx context.Context, id int64) (entry, bool, error) {
+	var e entry
+	v, ok, err := r.cache.Get(ctx, r.key(id))
+	if err != nil || !ok {
+		return e, false, err
+	}
+	if err := json.Unmarshal([]byte(v), &e); err != nil {
+		return e, false, fmt.Errorf("corrupt cache entry %s: %w", r.key(id), err)
+	}
+	return e, true, nil
+}
+
+// rebuild loads the product from the catalog and caches it.
+func (r *reader) rebuild(ctx context.Context, id int64) (*productcache.Product, error) {
+	p, err := r.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return p, r.store(ctx, id, p)
+}
+
+// store caches p as the product with id: nil only with null caching.
+func (r *reader) store(ctx context.Context, id int64, p *productcache.Product) error {
+	if p == nil {
+		if r.opts.Guard != guardNullCache {
+			return nil
+		}
+		return r.set(ctx, id, entry{}, nullTTL)
+	}
+	ttl := r.opts.ttl()
+	if r.opts.Rebuild == rebuildLogical {
+		return r.set(ctx, id, entry{Product: p, ExpireAt: time.Now().Add(ttl).UnixMilli()}, ttl+logicalGrace)
+	}
+	return r.set(ctx, id, entry{Product: p}, ttl)
+}
+
+func (r *reader) set(ctx context.Context, id int64, e entry, ttl time.Duration) error {
+	v, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return r.cache.Set(ctx, r.key(id), string(v), ttl)
+}
+
+// rebuildLocked rebuilds the entry of id holding the lock of the key, so that one reader
+// queries the catalog while the others wait for the entry to appear.
+func (r *reader) rebuildLocked(ctx context.Context, id int64) (*productcache.Product, error) {
+	deadline := time.Now().Add(rebuildWait)
+	waited := false
+	for {
+		lease, err := r.locker.Acquire(ctx, r.lockKey(id), lock.Options{TTL: lockTTL})
+		if err == nil {
+			defer r.locker.Release(context.WithoutCancel(ctx), lease)
+			// The holder before may have rebuilt the entry since the miss.
+			if e, ok, err := r.lookup(ctx, id); err != nil || ok {
+				return e.Product, err
+			}
+			return r.rebuild(ctx, id)
+		}
+		if !errors.Is(err, lock.ErrNotAcquired) {
+			return nil, err
+		}
+		if !waited {
+			r.stats.lockWaits.Add(1)
+			waited = true
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("product %d was not rebuilt within %s", id, rebuildWait)
+		}
+		if err := sleep(ctx, rebuildPoll); err != nil {
+			return nil, err
+		}
+		if e, ok, err := r.lookup(ctx, id); err != nil || ok {
+			return e.Product, err
+		}
+	}
+}
+
+// rebuildInBackground rebuilds the logically expired entry of id, unless another reader
+// already does.
+func (r *reader) rebuildInBackground(ctx context.Context, id int64) {
+	ctx = context.WithoutCancel(ctx)
+	lease, err := r.locker.Acquire(ctx, r.lockKey(id), lock.Options{TTL: lockTTL})
+	if err != nil {
+		return
+	}
+	go func() {
+		defer r.locker.Release(ctx, lease)
+		if _, err := r.rebuild(ctx, id); err != nil {
+			logger.Warn("Failed to rebuild an expired entry", "id", id, "error", err)
+		}
+	}()
+}
+
+// load queries the catalog for the product with id.
+func (r *reader) load(ctx context.Context, id int64) (*productcache.Product, error) {
+	n := r.stats.inflight.Add(1)
+	defer r.stats.inflight.Add(-1)
+	for {
+		peak := r.stats.peakInflight.Load()
+		if n <= peak || r.stats.peakInflight.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	r.stats.dbQueries.Add(1)
+	now := time.Now()
+	for _, m := range r.meters {
+		m.add(now)
+	}
+	telemetry.ObserveDBQuery(scenarioID)
+	if err := sleep(ctx, time.Duration(r.opts.DBLatencyMs)*time.Millisecond); err != nil {
+		return nil, err
+	}
+	return r.catalog.Product(ctx, id)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
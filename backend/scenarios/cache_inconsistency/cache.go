@@ -2,30 +2,36 @@ package cache_inconsistency
 
 import (
 	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
 	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
 )
 
-// Ensure CacheInconsistencyScenario implements the scenario.Scenario interface.
-var _ scenario.Scenario = (*CacheInconsistencyScenario)(nil)
-
-// init registers the scenario with the central registry.
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in cache_inconsistency.scenario.md, which binds to them by name.
 func init() {
-	registry.Register(&CacheInconsistencyScenario{})
+	s := &CacheInconsistencyScenario{table: "products", cacheKey: fmt.Sprintf("product:%d", productID)}
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers("cache_inconsistency", h)
 }
 
 const (
 	productID   = 101
 	productName = "Laptop"
+
+	// faultCacheDelete makes the cache invalidation in update_with_fix fail,
+	// showing that the TTL still bounds how long stale data can live.
+	faultCacheDelete = "cache_inconsistency.cache_delete"
 )
 
 var (
@@ -41,46 +47,39 @@ type product struct {
 	Price float64 `json:"price"`
 }
 
-// CacheInconsistencyScenario demonstrates the problem of write-through cache inconsistency.
-type CacheInconsistencyScenario struct{}
-
-func (s *CacheInconsistencyScenario) ID() string {
-	return "cache_inconsistency"
-}
-
-func (s *CacheInconsistencyScenario) Name() string {
-	return "DB/Cache Write Inconsistency"
-}
-
-func (s *CacheInconsistencyScenario) Category() string {
-	return "Distributed Systems"
+// CacheInconsistencyScenario holds the handlers of the scenario demonstrating
+// write-through cache inconsistency. Forks work on their own table and cache key.
+type CacheInconsistencyScenario struct {
+	table    string // MySQL table holding the product
+	cacheKey string // Redis key caching the product
 }
 
-func (s *CacheInconsistencyScenario) ProblemDescription() string {
-	return "When updating data, the write to the primary database succeeds, but the subsequent write to the cache fails. This leaves stale data in the cache. Future reads will hit the cache and serve incorrect, outdated information, leading to data inconsistency between the DB and cache."
-}
-
-func (s *CacheInconsistencyScenario) SolutionDescription() string {
-	return "A common and robust solution is the 'Cache-Aside' pattern combined with a 'Write-Through' strategy where the cache key is deleted instead of updated. On write, you update the database and then invalidate the cache by deleting the corresponding key. If the deletion fails, the stale cache entry will eventually be removed when its Time-To-Live (TTL) expires. On read, if the data is not in the cache (a 'cache miss'), you fetch it from the database, store it in the cache with a TTL, and then return it."
-}
-
-func (s *CacheInconsistencyScenario) DeepDiveLink() string {
-	return "https://redis.io/docs/latest/develop/get-started/patterns/cache-aside/"
-}
-
-func (s *CacheInconsistencyScenario) Actions() []scenario.Action {
-	return []scenario.Action{
-		{ID: "update_naive", Name: "Update Price (Problematic)", Description: "Updates the DB, then attempts to update the cache, but the cache update will fail."},
-		{ID: "update_with_fix", Name: "Update Price (Solution)", Description: "Updates the DB, then invalidates the cache by deleting the key."},
-		{ID: "reset", Name: "Reset State", Description: "Resets the product price and clears the cache."},
-	}
-}
-
-func (s *CacheInconsistencyScenario) DashboardComponents() []scenario.DashboardComponent {
-	return []scenario.DashboardComponent{
-		{ID: "mysql_record", Name: "MySQL Product Record", Type: "key_value"},
-		{ID: "redis_key", Name: "Redis Cache Key", Type: "key_value"},
-		{ID: "logs", Name: "Live Logs", Type: "log_stream"},
+// handlers returns the handlers bound to the table and cache key of s.
+func (s *CacheInconsistencyScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Resources:  s.SnapshotResources,
+		Fork:       s.fork,
+		Workloads: []scenario.Workload{{
+			ID:          "read_product",
+			Name:        "Read Product",
+			Description: "Reads the product cache-aside: from Redis, or from MySQL on a miss, filling the cache.",
+			Run: func(ctx context.Context, params map[string]interface{}) error {
+				_, err := s.readProduct(ctx)
+				return err
+			},
+		}},
+		Actions: map[string]registry.ActionFunc{
+			"update_naive": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.updateNaive(ctx, priceFrom(params, 99.99))
+			},
+			"update_with_fix": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.updateWithFix(ctx, priceFrom(params, 129.99))
+			},
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
 	}
 }
 
@@ -100,40 +99,27 @@ func (s *CacheInconsistencyScenario) Initialize() error {
 	redisClient = redis.NewClient(&redis.Options{
 		Addr: "redis:6379",
 	})
+	redisClient.AddHook(telemetry.RedisHook())
 	if _, err = redisClient.Ping(ctx).Result(); err != nil {
 		return fmt.Errorf("failed to connect to redis: %w", err)
 	}
 
 	// Setup schema and initial data
-	return s.resetState()
-}
-
-func (s *CacheInconsistencyScenario) ExecuteAction(actionID string, params map[string]interface{}) (interface{}, error) {
-	switch actionID {
-	case "update_naive":
-		return s.updateNaive()
-	case "update_with_fix":
-		return s.updateWithFix()
-	case "reset":
-		return "State reset", s.resetState()
-	default:
-		return nil, fmt.Errorf("unknown action: %s", actionID)
-	}
+	return s.resetState(ctx)
 }
 
 func (s *CacheInconsistencyScenario) FetchState() (map[string]interface{}, error) {
 	// Fetch from DB
 	var p product
-	err := db.QueryRow("SELECT id, name, price FROM products WHERE id = ?", productID).Scan(&p.ID, &p.Name, &p.Price)
+	err := db.QueryRow("SELECT id, name, price FROM "+s.table+" WHERE id = ?", productID).Scan(&p.ID, &p.Name, &p.Price)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from db: %w", err)
+		return nil, scenario.DependencyError(err, "failed to fetch from db")
 	}
 
 	// Fetch from Redis
-	cacheKey := fmt.Sprintf("product:%d", productID)
-	val, err := redisClient.Get(ctx, cacheKey).Result()
+	val, err := redisClient.Get(ctx, s.cacheKey).Result()
 	if err != nil && err != redis.Nil {
-		return nil, fmt.Errorf("failed to fetch from redis: %w", err)
+		return nil, scenario.DependencyError(err, "failed to fetch from redis")
 	}
 	if err == redis.Nil {
 		val = "null (cache miss)"
@@ -145,75 +131,179 @@ func (s *CacheInconsistencyScenario) FetchState() (map[string]interface{}, error
 	}, nil
 }
 
+// SnapshotResources returns the product table and the product cache key.
+func (s *CacheInconsistencyScenario) SnapshotResources() []snapshot.Resource {
+	return []snapshot.Resource{
+		snapshot.MySQLTable(db, s.table),
+		snapshot.RedisKeys(redisClient, s.cacheKey),
+	}
+}
+
+// fork copies the product table and cache key under names derived from name, so that
+// comparison mode can run both updates from the same starting point.
+func (s *CacheInconsistencyScenario) fork(ctx context.Context, name string) (registry.Handlers, func(ctx context.Context) error, error) {
+	f := &CacheInconsistencyScenario{table: s.table + "_" + name, cacheKey: name + ":" + s.cacheKey}
+	if _, err := db.ExecContext(ctx, "CREATE TABLE "+f.table+" LIKE "+s.table); err != nil {
+		return registry.Handlers{}, nil, scenario.DependencyError(err, "failed to create table %s", f.table)
+	}
+	release := func(ctx context.Context) error {
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+f.table); err != nil {
+			return scenario.DependencyError(err, "failed to drop table %s", f.table)
+		}
+		if err := redisClient.Del(ctx, f.cacheKey).Err(); err != nil {
+			return scenario.DependencyError(err, "failed to delete cache key %s", f.cacheKey)
+		}
+		return nil
+	}
+
+	if err := s.copyTo(ctx, f); err != nil {
+		_ = release(ctx)
+		return registry.Handlers{}, nil, err
+	}
+	return f.handlers(), release, nil
+}
+
+// copyTo copies the product row and the cached product, with its TTL, to f.
+func (s *CacheInconsistencyScenario) copyTo(ctx context.Context, f *CacheInconsistencyScenario) error {
+	if _, err := db.ExecContext(ctx, "INSERT INTO "+f.table+" SELECT * FROM "+s.table); err != nil {
+		return scenario.DependencyError(err, "failed to copy table %s", s.table)
+	}
+	val, err := redisClient.Get(ctx, s.cacheKey).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return scenario.DependencyError(err, "failed to read cache key %s", s.cacheKey)
+	}
+	ttl, err := redisClient.PTTL(ctx, s.cacheKey).Result()
+	if err != nil {
+		return scenario.DependencyError(err, "failed to read TTL of cache key %s", s.cacheKey)
+	}
+	if ttl < 0 {
+		ttl = 0 // no expiry
+	}
+	if err := redisClient.Set(ctx, f.cacheKey, val, ttl).Err(); err != nil {
+		return scenario.DependencyError(err, "failed to copy cache key %s", s.cacheKey)
+	}
+	return nil
+}
+
+// priceFrom returns the "price" parameter, or def if it was not given.
+// The API has already validated that it is a number.
+func priceFrom(params map[string]interface{}, def float64) float64 {
+	if price, ok := params["price"].(float64); ok {
+		return price
+	}
+	return def
+}
+
 // updateNaive demonstrates the problem: DB write succeeds, cache write fails.
-func (s *CacheInconsistencyScenario) updateNaive() (string, error) {
-	log.Println("Executing naive update...")
-	newPrice := 99.99
+func (s *CacheInconsistencyScenario) updateNaive(ctx context.Context, newPrice float64) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Executing naive update")
 	// 1. Update database
-	_, err := db.Exec("UPDATE products SET price = ? WHERE id = ?", newPrice, productID)
+	_, err := db.ExecContext(ctx, "UPDATE "+s.table+" SET price = ? WHERE id = ?", newPrice, productID)
 	if err != nil {
-		log.Printf("ERROR: Failed to update database: %v", err)
-		return "Failed to update database", err
+		logger.Error("Failed to update database", "error", err)
+		return "Failed to update database", scenario.DependencyError(err, "failed to update database")
 	}
-	log.Printf("SUCCESS: Database updated. Price set to %.2f", newPrice)
+	logger.Info("Database updated", "price", newPrice)
 
 	// 2. Simulate a failure to update cache
-	log.Println("ATTEMPT: Updating cache...")
-	log.Println("ERROR: Cache update failed!")
-	return "DB updated, but cache update failed, causing inconsistency.", errors.New("simulated cache update failure")
+	logger.Info("Updating cache", "key", s.cacheKey)
+	logger.Error("Cache update failed")
+	return "DB updated, but cache update failed, causing inconsistency.", scenario.DemonstratedError("simulated cache update failure")
 }
 
 // updateWithFix demonstrates the solution: update DB, then invalidate cache.
-func (s *CacheInconsistencyScenario) updateWithFix() (string, error) {
-	log.Println("Executing solution update...")
-	newPrice := 129.99
+func (s *CacheInconsistencyScenario) updateWithFix(ctx context.Context, newPrice float64) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Executing solution update")
 	// 1. Update database
-	_, err := db.Exec("UPDATE products SET price = ? WHERE id = ?", newPrice, productID)
+	_, err := db.ExecContext(ctx, "UPDATE "+s.table+" SET price = ? WHERE id = ?", newPrice, productID)
 	if err != nil {
-		log.Printf("ERROR: Failed to update database: %v", err)
-		return "Failed to update database", err
+		logger.Error("Failed to update database", "error", err)
+		return "Failed to update database", scenario.DependencyError(err, "failed to update database")
 	}
-	log.Printf("SUCCESS: Database updated. Price set to %.2f", newPrice)
+	logger.Info("Database updated", "price", newPrice)
 
 	// 2. Invalidate cache by deleting the key
-	log.Println("ATTEMPT: Invalidating cache by deleting key...")
-	cacheKey := fmt.Sprintf("product:%d", productID)
-	if err := redisClient.Del(ctx, cacheKey).Err(); err != nil {
-		log.Printf("ERROR: Failed to invalidate cache: %v", err)
+	logger.Info("Invalidating cache by deleting key", "key", s.cacheKey)
+	if err := deleteCacheKey(ctx, s.cacheKey); err != nil {
+		logger.Error("Failed to invalidate cache", "key", s.cacheKey, "error", err)
 		// Even if this fails, the TTL will eventually save us.
 		return "DB updated, but failed to invalidate cache.", err
 	}
-	log.Println("SUCCESS: Cache invalidated.")
+	logger.Info("Cache invalidated", "key", s.cacheKey)
 	return "DB updated and cache invalidated successfully.", nil
 }
 
+// readProduct reads the product cache-aside. Under load it shows how long readers
+// keep getting the stale price after a naive update.
+func (s *CacheInconsistencyScenario) readProduct(ctx context.Context) (product, error) {
+	var p product
+	val, err := redisClient.Get(ctx, s.cacheKey).Result()
+	if err == nil {
+		if err := json.Unmarshal([]byte(val), &p); err != nil {
+			return p, fmt.Errorf("failed to decode cached product: %w", err)
+		}
+		return p, nil
+	}
+	if err != redis.Nil {
+		return p, scenario.DependencyError(err, "failed to read cache key %s", s.cacheKey)
+	}
+
+	err = db.QueryRowContext(ctx, "SELECT id, name, price FROM "+s.table+" WHERE id = ?", productID).Scan(&p.ID, &p.Name, &p.Price)
+	if err != nil {
+		return p, scenario.DependencyError(err, "failed to read product")
+	}
+	pJSON, _ := json.Marshal(p)
+	if err := redisClient.Set(ctx, s.cacheKey, pJSON, 10*time.Minute).Err(); err != nil {
+		return p, scenario.DependencyError(err, "failed to fill cache key %s", s.cacheKey)
+	}
+	return p, nil
+}
+
+// deleteCacheKey removes a key from Redis, unless the cache delete fault is injected.
+func deleteCacheKey(ctx context.Context, key string) error {
+	if fault.EnabledContext(ctx, faultCacheDelete) {
+		return scenario.DemonstratedError("injected fault: cache delete failed")
+	}
+	if err := redisClient.Del(ctx, key).Err(); err != nil {
+		return scenario.DependencyError(err, "failed to delete cache key %s", key)
+	}
+	return nil
+}
+
 // resetState sets up the initial database table and data.
-func (s *CacheInconsistencyScenario) resetState() error {
+func (s *CacheInconsistencyScenario) resetState(ctx context.Context) error {
 	// Create table
-	_, err := db.Exec(`
-		CREATE TABLE IF NOT EXISTS products (
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS `+s.table+` (
 			id INT PRIMARY KEY,
 			name VARCHAR(255),
 			price DECIMAL(10, 2)
 		)
 	`)
 	if err != nil {
-		return err
+		return scenario.DependencyError(err, "failed to create %s table", s.table)
 	}
 
 	// Reset or insert data
 	initialPrice := 79.99
-	_, err = db.Exec(`
-		INSERT INTO products (id, name, price) VALUES (?, ?, ?)
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO `+s.table+` (id, name, price) VALUES (?, ?, ?)
 		ON DUPLICATE KEY UPDATE name = ?, price = ?
 	`, productID, productName, initialPrice, productName, initialPrice)
 	if err != nil {
-		return err
+		return scenario.DependencyError(err, "failed to reset product")
 	}
 
 	// Prime the cache
 	p := product{ID: productID, Name: productName, Price: initialPrice}
 	pJSON, _ := json.Marshal(p)
-	cacheKey := fmt.Sprintf("product:%d", productID)
-	return redisClient.Set(ctx, cacheKey, pJSON, 10*time.Minute).Err()
+	if err := redisClient.Set(ctx, s.cacheKey, pJSON, 10*time.Minute).Err(); err != nil {
+		return scenario.DependencyError(err, "failed to prime cache")
+	}
+	return nil
 }
\ No newline at end of file
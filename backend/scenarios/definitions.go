@@ -0,0 +1,11 @@
+// Package scenarios embeds the scenario definition files kept next to the scenario
+// packages (*.scenario.yaml, *.scenario.md), so that the server binary carries them.
+// Their Go handlers are registered by the scenario packages themselves.
+package scenarios
+
+import "embed"
+
+// Definitions holds every definition file, under its package directory.
+//
+//go:embed */*.scenario.*
+var Definitions embed.FS
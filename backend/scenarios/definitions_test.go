@@ -0,0 +1,93 @@
+package scenarios
+
+import (
+	"testing"
+
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	_ "SYS_DESIGN_PLAYGROUND/scenarios/all"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDefinitions checks that every embedded definition parses and binds to registered handlers.
+func TestDefinitions(t *testing.T) {
+	assert.NoError(t, registry.LoadDefinitions(Definitions))
+
+	s, ok := registry.GetScenario("cache_failures")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 8)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, "bloom", s.Actions()[1].Params[0].Default)
+		assert.Equal(t, "mutex", s.Actions()[3].Params[0].Default)
+		assert.Equal(t, false, s.Actions()[6].Params[2].Default)
+		assert.Equal(t, "admin", s.Actions()[7].Role)
+	}
+
+	s, ok = registry.GetScenario("cache_inconsistency")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 3)
+		assert.NotEmpty(t, s.ProblemDescription())
+		assert.NotEmpty(t, s.SolutionDescription())
+		assert.Equal(t, 129.99, s.Actions()[1].Params[0].Default)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+	}
+
+	s, ok = registry.GetScenario("distributed_lock")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 10)
+		// The increment_pessimistic parameters are a YAML alias of the increment_naive ones.
+		assert.Equal(t, s.Actions()[0].Params, s.Actions()[2].Params)
+		assert.Equal(t, "admin", s.Actions()[9].Role)
+	}
+
+	s, ok = registry.GetScenario("hot_keys")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 4)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, true, s.Actions()[1].Params[0].Default)
+		assert.Equal(t, false, s.Actions()[2].Params[0].Default)
+		assert.Equal(t, "admin", s.Actions()[3].Role)
+	}
+
+	s, ok = registry.GetScenario("idempotent_api")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 6)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, "redis", s.Actions()[1].Params[4].Default)
+		assert.Equal(t, "admin", s.Actions()[5].Role)
+	}
+
+	s, ok = registry.GetScenario("product_saga")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 4)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, "notify", s.Actions()[1].Params[2].Default)
+		assert.Equal(t, false, s.Actions()[1].Params[4].Default)
+		assert.Equal(t, "admin", s.Actions()[3].Role)
+	}
+
+	s, ok = registry.GetScenario("rate_limiter")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 4)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, "boundary", s.Actions()[1].Params[0].Default)
+		assert.Equal(t, 1000, s.Actions()[2].Params[2].Default)
+		assert.Equal(t, "admin", s.Actions()[3].Role)
+	}
+
+	s, ok = registry.GetScenario("trans_msg")
+	if assert.True(t, ok) {
+		assert.Len(t, s.Actions(), 8)
+		assert.Equal(t, scenario.KindProblem, s.Actions()[0].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[1].Kind)
+		assert.Equal(t, scenario.KindSolution, s.Actions()[2].Kind)
+		assert.Equal(t, true, s.Actions()[0].Params[1].Default)
+		assert.Equal(t, "admin", s.Actions()[7].Role)
+	}
+}
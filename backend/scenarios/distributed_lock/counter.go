@@ -0,0 +1,331 @@
+package distributedlock
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Strategies of a counter run.
+const (
+	strategyNaive       = "naive"
+	strategyOptimistic  = "optimistic"
+	strategyPessimistic = "pessimistic"
+	strategyRedisLock   = "redis_lock"
+	strategyMySQLLock   = "mysql_lock"
+)
+
+const (
+	maxWorkers    = 50
+	maxIncrements = 100
+	lockTTL       = 2 * time.Second
+
+	// lockRetryInterval is how long a worker waits before trying a taken lock again.
+	lockRetryInterval = 2 * time.Millisecond
+)
+
+// counterParams are the parameters shared by the increment actions.
+type counterParams struct {
+	Workers     int           // concurrent workers
+	Increments  int           // increments per worker
+	Work        time.Duration // pause between reading and writing the counter, widening the race window
+	MaxRetries  int           // optimistic: attempts per increment before giving up
+	LockTimeout time.Duration // redis_lock, mysql_lock: how long to wait for the lock
+}
+
+func parseCounterParams(params map[string]interface{}) (counterParams, error) {
+	p := counterParams{
+		Workers:     intParam(params, "workers", 10),
+		Increments:  intParam(params, "increments", 10),
+		Work:        time.Duration(intParam(params, "work_ms", 5)) * time.Millisecond,
+		MaxRetries:  intParam(params, "max_retries", 100),
+		LockTimeout: time.Duration(intParam(params, "lock_timeout_ms", 5000)) * time.Millisecond,
+	}
+	switch {
+	case p.Workers < 1 || p.Workers > maxWorkers:
+		return p, scenario.InvalidParamsError("workers must be between 1 and %d", maxWorkers).WithDetail("field", "workers")
+	case p.Increments < 1 || p.Increments > maxIncrements:
+		return p, scenario.InvalidParamsError("increments must be between 1 and %d", maxIncrements).WithDetail("field", "increments")
+	case p.Work < 0 || p.Work > time.Second:
+		return p, scenario.InvalidParamsError("work_ms must be between 0 and 1000").WithDetail("field", "work_ms")
+	case p.MaxRetries < 1:
+		return p, scenario.InvalidParamsError("max_retries must be positive").WithDetail("field", "max_retries")
+	case p.LockTimeout <= 0:
+		return p, scenario.InvalidParamsError("lock_timeout_ms must be positive").WithDetail("field", "lock_timeout_ms")
+	}
+	return p, nil
+}
+
+// intParam returns an integer parameter, or def if it was not given.
+// The API has already validated that it is an integer.
+func intParam(params map[string]interface{}, name string, def int) int {
+	switch v := params[name].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return def
+}
+
+// runStats describes a counter run; the contention dashboard component shows the last one.
+type runStats struct {
+	Strategy   string `json:"strategy"`
+	Workers    int    `json:"workers"`
+	Increments int    `json:"increments"` // requested in total
+	Applied    int64  `json:"applied"`    // by which the counter actually grew
+	Lost       int64  `json:"lost"`
+	DurationMs int64  `json:"duration_ms"`
+	// Retries counts optimistic updates that found the version changed, and lock
+	// acquisitions that found the lock taken.
+	Retries int64 `json:"retries"`
+	// Failed counts increments given up on: out of retries, or lock wait timed out.
+	Failed      int64   `json:"failed"`
+	LockWaitAvg float64 `json:"lock_wait_avg_ms"`
+	LockWaitMax float64 `json:"lock_wait_max_ms"`
+}
+
+// runCounter runs increments of the counter from concurrent workers with the strategy
+// and reports how many were lost.
+func (s *DistributedLockScenario) runCounter(ctx context.Context, strategy string, params map[string]interface{}) (*runStats, error) {
+	p, err := parseCounterParams(params)
+	if err != nil {
+		return nil, err
+	}
+	logger := scenario.Logger(ctx)
+	logger.Info("Starting counter run", "strategy", strategy, "workers", p.Workers, "increments", p.Increments)
+
+	before, err := s.counter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	total := p.Workers * p.Increments
+	var done, retries, failed, waitTotal, waitMax int64 // waits in microseconds
+	var firstErr error
+	var errOnce sync.Once
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < p.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < p.Increments && ctx.Err() == nil; i++ {
+				r, wait, err := s.increment(ctx, strategy, p)
+				atomic.AddInt64(&retries, int64(r))
+				if wait > 0 {
+					us := int64(wait / time.Microsecond)
+					atomic.AddInt64(&waitTotal, us)
+					for {
+						cur := atomic.LoadInt64(&waitMax)
+						if us <= cur || atomic.CompareAndSwapInt64(&waitMax, cur, us) {
+							break
+						}
+					}
+				}
+				if err != nil {
+					if scenario.CodeOf(err) != scenario.CodeConflict {
+						errOnce.Do(func() { firstErr = err })
+						return
+					}
+					atomic.AddInt64(&failed, 1)
+				}
+				if n := atomic.AddInt64(&done, 1); n%int64(p.Workers) == 0 {
+					scenario.ReportProgress(ctx, int(n*100/int64(total)), fmt.Sprintf("%d/%d increments", n, total))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	s.expected += done - failed
+	s.mu.Unlock()
+
+	after, err := s.counter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &runStats{
+		Strategy:   strategy,
+		Workers:    p.Workers,
+		Increments: total,
+		Applied:    after - before,
+		Lost:       done - failed - (after - before),
+		DurationMs: time.Since(start).Milliseconds(),
+		Retries:    retries,
+		Failed:     failed,
+	}
+	if done > 0 {
+		stats.LockWaitAvg = float64(waitTotal) / float64(done) / 1000
+		stats.LockWaitMax = float64(waitMax) / 1000
+	}
+	s.mu.Lock()
+	s.lastRun = stats
+	s.mu.Unlock()
+
+	if firstErr != nil {
+		logger.Error("Counter run failed", "strategy", strategy, "error", firstErr)
+		return stats, firstErr
+	}
+	if ctx.Err() != nil {
+		return stats, ctx.Err()
+	}
+	logger.Info("Counter run finished", "strategy", strategy, "applied", stats.Applied, "lost", stats.Lost, "retries", stats.Retries, "failed", stats.Failed)
+	if stats.Lost > 0 {
+		logger.Warn("Updates were lost", "lost", stats.Lost)
+		return stats, scenario.DemonstratedError("%d of %d increments were lost to concurrent read-modify-write cycles", stats.Lost, done-failed)
+	}
+	return stats, nil
+}
+
+// increment adds one to the counter with the strategy. It returns how often it had to
+// retry and how long it waited for a lock. An increment given up on is a conflict error.
+func (s *DistributedLockScenario) increment(ctx context.Context, strategy string, p counterParams) (retries int, wait time.Duration, err error) {
+	switch strategy {
+	case strategyNaive:
+		return 0, 0, s.incrementNaive(ctx, p)
+	case strategyOptimistic:
+		retries, err = s.incrementOptimistic(ctx, p)
+		return retries, 0, err
+	case strategyPessimistic:
+		wait, err = s.incrementPessimistic(ctx, p)
+		return 0, wait, err
+	case strategyRedisLock:
+		return s.incrementLocked(ctx, redisLocker, p)
+	case strategyMySQLLock:
+		return s.incrementLocked(ctx, mysqlLocker, p)
+	}
+	return 0, 0, fmt.Errorf("unknown strategy %s", strategy)
+}
+
+// incrementNaive reads the counter and writes it back plus one. Workers that read the
+// same value overwrite each other's increment: a lost update.
+func (s *DistributedLockScenario) incrementNaive(ctx context.Context, p counterParams) error {
+	row, err := s.product(ctx, q)
+	if err != nil {
+		return err
+	}
+	n, err := counterOf(row)
+	if err != nil {
+		return err
+	}
+	time.Sleep(p.Work)
+	_, err = q.WebProduct.WithContext(ctx).Where(q.WebProduct.ID.Eq(row.ID)).
+		UpdateSimple(q.WebProduct.Extra.Value(counterExtra(n + 1)))
+	if err != nil {
+		return scenario.DependencyError(err, "failed to write counter")
+	}
+	return nil
+}
+
+// incrementOptimistic writes the counter only if the version it read is still current,
+// bumping the version. If another worker got there first, it reads again and retries.
+func (s *DistributedLockScenario) incrementOptimistic(ctx context.Context, p counterParams) (retries int, err error) {
+	for attempt := 0; attempt < p.MaxRetries; attempt++ {
+		row, err := s.product(ctx, q)
+		if err != nil {
+			return retries, err
+		}
+		n, err := counterOf(row)
+		if err != nil {
+			return retries, err
+		}
+		time.Sleep(p.Work)
+		info, err := q.WebProduct.WithContext(ctx).
+			Where(q.WebProduct.ID.Eq(row.ID), q.WebProduct.Version.Eq(row.Version)).
+			UpdateSimple(q.WebProduct.Extra.Value(counterExtra(n+1)), q.WebProduct.Version.Add(1))
+		if err != nil {
+			return retries, scenario.DependencyError(err, "failed to write counter")
+		}
+		if info.RowsAffected == 1 {
+			return retries, nil
+		}
+		retries++
+		// Back off a little so that the workers do not retry in lockstep.
+		time.Sleep(time.Duration(rand.Int63n(int64(time.Millisecond) * int64(retries%5+1))))
+	}
+	return retries, scenario.ConflictError("gave up after %d attempts", p.MaxRetries)
+}
+
+// incrementPessimistic locks the row with SELECT ... FOR UPDATE for the whole
+// read-modify-write cycle, so that the other workers wait for the transaction to end.
+func (s *DistributedLockScenario) incrementPessimistic(ctx context.Context, p counterParams) (wait time.Duration, err error) {
+	err = q.Transaction(func(tx *query.Query) error {
+		start := time.Now()
+		row, err := tx.WebProduct.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(tx.WebProduct.Code.Eq(s.productCode)).First()
+		wait = time.Since(start)
+		if err != nil {
+			return dbError(err, "failed to lock product %s", s.productCode)
+		}
+		n, err := counterOf(row)
+		if err != nil {
+			return err
+		}
+		time.Sleep(p.Work)
+		_, err = tx.WebProduct.WithContext(ctx).Where(tx.WebProduct.ID.Eq(row.ID)).
+			UpdateSimple(tx.WebProduct.Extra.Value(counterExtra(n+1)), tx.WebProduct.Version.Add(1))
+		if err != nil {
+			return scenario.DependencyError(err, "failed to write counter")
+		}
+		return nil
+	})
+	return wait, err
+}
+
+// incrementLocked runs the read-modify-write cycle while holding the lock of the
+// counter in locker, whose watchdog renews the lease should the cycle outlast it.
+// The counter row itself is not locked at all.
+func (s *DistributedLockScenario) incrementLocked(ctx context.Context, locker lock.Locker, p counterParams) (retries int, wait time.Duration, err error) {
+	start := time.Now()
+	err = lock.Do(ctx, locker, s.lockKey, lock.Options{TTL: lockTTL, Timeout: p.LockTimeout, RetryInterval: lockRetryInterval},
+		func(ctx context.Context, l *lock.Lease) error {
+			retries, wait = l.Retries, time.Since(start)
+			return s.incrementNaive(ctx, p)
+		})
+	if wait == 0 {
+		wait = time.Since(start)
+	}
+	return retries, wait, lockError(err, s.lockKey)
+}
+
+// counter returns the current value of the counter.
+func (s *DistributedLockScenario) counter(ctx context.Context) (int64, error) {
+	row, err := s.product(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return counterOf(row)
+}
+
+// counterDoc is the content of the extra column of the counter row.
+type counterDoc struct {
+	Counter int64 `json:"counter"`
+}
+
+// counterOf reads the counter from the extra column of a product row.
+func counterOf(p *model.WebProduct) (int64, error) {
+	var doc counterDoc
+	if err := json.Unmarshal([]byte(p.Extra), &doc); err != nil {
+		return 0, fmt.Errorf("failed to decode counter: %w", err)
+	}
+	return doc.Counter, nil
+}
+
+// counterExtra returns the extra column of a product row holding the counter n.
+func counterExtra(n int64) string {
+	data, _ := json.Marshal(counterDoc{Counter: n})
+	return string(data)
+}
@@ -0,0 +1,63 @@
+package distributedlock
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCounterParams(t *testing.T) {
+	p, err := parseCounterParams(map[string]interface{}{"workers": float64(20), "work_ms": 0})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, p.Workers)
+	assert.Equal(t, 10, p.Increments)
+	assert.Equal(t, time.Duration(0), p.Work)
+	assert.Equal(t, 5*time.Second, p.LockTimeout)
+
+	for _, params := range []map[string]interface{}{
+		{"workers": 0},
+		{"workers": maxWorkers + 1},
+		{"increments": float64(maxIncrements + 1)},
+		{"work_ms": -1},
+		{"max_retries": 0},
+	} {
+		_, err := parseCounterParams(params)
+		assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err), "%v", params)
+	}
+}
+
+func TestCounterExtra(t *testing.T) {
+	n, err := counterOf(&model.WebProduct{Extra: counterExtra(42)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), n)
+
+	_, err = counterOf(&model.WebProduct{Extra: "not json"})
+	assert.Error(t, err)
+}
+
+func TestNewScenario(t *testing.T) {
+	s := newScenario("")
+	assert.Equal(t, productCode, s.productCode)
+	assert.Equal(t, "lock:"+productCode, s.lockKey)
+
+	f := newScenario("fork1")
+	assert.Equal(t, productCode+"_fork1", f.productCode)
+	assert.Equal(t, userCode+"_fork1", f.userCode)
+	assert.Equal(t, "lock:"+productCode+"_fork1", f.lockKey)
+	assert.Equal(t, "lock:"+recordCode+"_fork1", f.recordKey)
+}
+
+func TestLockError(t *testing.T) {
+	assert.NoError(t, lockError(nil, "k"))
+	assert.Equal(t, scenario.CodeConflict, scenario.CodeOf(lockError(fmt.Errorf("%w: k", lock.ErrNotAcquired), "k")))
+	assert.Equal(t, scenario.CodeConflict, scenario.CodeOf(lockError(lock.ErrNotHeld, "k")))
+	assert.Equal(t, scenario.CodeDependencyUnavailable, scenario.CodeOf(lockError(errors.New("connection refused"), "k")))
+	demo := scenario.DemonstratedError("demo")
+	assert.Equal(t, demo, lockError(demo, "k"))
+}
@@ -1,5 +1,351 @@
 package distributedlock
 
-// 乐观锁
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
 
-// 悲观锁
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in distributed_lock.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario("")
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers("distributed_lock", h)
+}
+
+const (
+	productCode = "LOCK_DEMO_COUNTER"
+	recordCode  = "LOCK_DEMO_RECORD"
+	userCode    = "LOCK_DEMO_USER"
+
+	initialUserName   = "Acme Ads"
+	initialUserRegion = "US"
+)
+
+var (
+	q           *query.Query
+	sqlDB       *sql.DB
+	redisClient *redis.Client
+
+	redisLocker lock.Locker
+	mysqlLocker lock.Locker
+)
+
+// DistributedLockScenario holds the handlers of the scenario comparing ways to keep
+// concurrent read-modify-write cycles from losing updates. Its state is a counter kept
+// in the extra column of a web_product row, a record written under a fenced lock in
+// another, and a user row, all found by code; forks work on rows and lock keys of
+// their own.
+type DistributedLockScenario struct {
+	productCode string
+	recordCode  string
+	userCode    string
+	lockKey     string // key of the distributed lock of the counter
+	recordKey   string // key of the distributed lock of the record
+
+	mu        sync.Mutex
+	expected  int64     // increments requested since the last reset
+	lastRun   *runStats // statistics of the last counter run
+	lastEdit  *editStats
+	lastPause *pauseStats
+}
+
+func newScenario(suffix string) *DistributedLockScenario {
+	s := &DistributedLockScenario{productCode: productCode, recordCode: recordCode, userCode: userCode}
+	if suffix != "" {
+		s.productCode += "_" + suffix
+		s.recordCode += "_" + suffix
+		s.userCode += "_" + suffix
+	}
+	s.lockKey = "lock:" + s.productCode
+	s.recordKey = "lock:" + s.recordCode
+	return s
+}
+
+// handlers returns the handlers bound to the rows and lock key of s.
+func (s *DistributedLockScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Fork:       s.fork,
+		Actions: map[string]registry.ActionFunc{
+			"increment_naive": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runCounter(ctx, strategyNaive, params)
+			},
+			"increment_optimistic": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runCounter(ctx, strategyOptimistic, params)
+			},
+			"increment_pessimistic": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runCounter(ctx, strategyPessimistic, params)
+			},
+			"increment_redis_lock": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runCounter(ctx, strategyRedisLock, params)
+			},
+			"increment_mysql_lock": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runCounter(ctx, strategyMySQLLock, params)
+			},
+			"edit_user_naive": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.editUser(ctx, false, params)
+			},
+			"edit_user_optimistic": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.editUser(ctx, true, params)
+			},
+			"gc_pause_unfenced": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.gcPause(ctx, false, params)
+			},
+			"gc_pause_fenced": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.gcPause(ctx, true, params)
+			},
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database and Redis, and sets up the initial state.
+func (s *DistributedLockScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.WebProduct{}, &model.User{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err = db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return fmt.Errorf("failed to ping mysql: %w", err)
+	}
+	if err := lock.CreateMySQLTable(context.Background(), sqlDB); err != nil {
+		return err
+	}
+	q = query.Use(db)
+
+	redisClient, err = infra.OpenRedis(context.Background())
+	if err != nil {
+		return err
+	}
+
+	redisLocker = lock.NewRedis(redisClient)
+	mysqlLocker = lock.NewMySQLGetLock(sqlDB)
+
+	return s.resetState(context.Background())
+}
+
+func (s *DistributedLockScenario) FetchState() (map[string]interface{}, error) {
+	ctx := context.Background()
+	p, err := s.product(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := counterOf(p)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.record(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := recordOf(r)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.user(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := map[string]interface{}{
+		"counter": map[string]interface{}{
+			"value":    counter,
+			"version":  p.Version,
+			"expected": s.expected,
+			"lost":     s.expected - counter,
+			"correct":  s.expected == counter,
+		},
+		"contention": "no run yet",
+		"user_record": map[string]interface{}{
+			"name":    u.Name,
+			"region":  u.Region,
+			"version": u.Version,
+		},
+		"last_edit": "no edit yet",
+		"fenced_record": map[string]interface{}{
+			"content":    doc.Content,
+			"writer":     doc.Writer,
+			"last_fence": r.Version,
+		},
+		"last_pause": "no run yet",
+	}
+	if s.lastRun != nil {
+		state["contention"] = *s.lastRun
+	}
+	if s.lastEdit != nil {
+		state["last_edit"] = *s.lastEdit
+	}
+	if s.lastPause != nil {
+		state["last_pause"] = *s.lastPause
+	}
+	return state, nil
+}
+
+// product returns the counter row of s.
+func (s *DistributedLockScenario) product(ctx context.Context, tx *query.Query) (*model.WebProduct, error) {
+	p, err := tx.WebProduct.WithContext(ctx).Where(tx.WebProduct.Code.Eq(s.productCode)).First()
+	if err != nil {
+		return nil, dbError(err, "failed to read product %s", s.productCode)
+	}
+	return p, nil
+}
+
+// record returns the row that the GC pause demo writes under the record lock.
+func (s *DistributedLockScenario) record(ctx context.Context) (*model.WebProduct, error) {
+	r, err := q.WebProduct.WithContext(ctx).Where(q.WebProduct.Code.Eq(s.recordCode)).First()
+	if err != nil {
+		return nil, dbError(err, "failed to read record %s", s.recordCode)
+	}
+	return r, nil
+}
+
+// user returns the user row of s.
+func (s *DistributedLockScenario) user(ctx context.Context, tx *query.Query) (*model.User, error) {
+	u, err := tx.User.WithContext(ctx).Where(tx.User.Code.Eq(s.userCode)).First()
+	if err != nil {
+		return nil, dbError(err, "failed to read user %s", s.userCode)
+	}
+	return u, nil
+}
+
+// fork creates the rows of a scenario named after name with the state of s, so that
+// comparison mode can run two strategies from the same starting point.
+func (s *DistributedLockScenario) fork(ctx context.Context, name string) (registry.Handlers, func(ctx context.Context) error, error) {
+	f := newScenario(name)
+	release := func(ctx context.Context) error {
+		if _, err := q.WebProduct.WithContext(ctx).Unscoped().Where(q.WebProduct.Code.In(f.productCode, f.recordCode)).Delete(); err != nil {
+			return scenario.DependencyError(err, "failed to delete products of %s", name)
+		}
+		if _, err := q.User.WithContext(ctx).Unscoped().Where(q.User.Code.Eq(f.userCode)).Delete(); err != nil {
+			return scenario.DependencyError(err, "failed to delete user %s", f.userCode)
+		}
+		if err := redisClient.Del(ctx, f.lockKey, f.lockKey+":fence", f.recordKey, f.recordKey+":fence").Err(); err != nil {
+			return scenario.DependencyError(err, "failed to delete locks of %s", name)
+		}
+		return nil
+	}
+
+	p, err := s.product(ctx, q)
+	if err != nil {
+		return registry.Handlers{}, nil, err
+	}
+	r, err := s.record(ctx)
+	if err != nil {
+		return registry.Handlers{}, nil, err
+	}
+	u, err := s.user(ctx, q)
+	if err != nil {
+		return registry.Handlers{}, nil, err
+	}
+	s.mu.Lock()
+	f.expected = s.expected
+	s.mu.Unlock()
+
+	fp := &model.WebProduct{Code: f.productCode, Name: p.Name, Mode: p.Mode, Extra: p.Extra, Version: p.Version}
+	fu := &model.User{Code: f.userCode, Name: u.Name, Type: u.Type, Region: u.Region, Email: name + "." + u.Email, Phone: u.Phone, Extra: u.Extra, Version: u.Version}
+	fr := &model.WebProduct{Code: f.recordCode, Name: r.Name, Mode: r.Mode, Extra: r.Extra, Version: r.Version}
+	if err := q.WebProduct.WithContext(ctx).Create([]*model.WebProduct{fp, fr}...); err != nil {
+		_ = release(ctx)
+		return registry.Handlers{}, nil, scenario.DependencyError(err, "failed to copy products of %s", s.productCode)
+	}
+	if err := q.User.WithContext(ctx).Create(fu); err != nil {
+		_ = release(ctx)
+		return registry.Handlers{}, nil, scenario.DependencyError(err, "failed to copy user %s", s.userCode)
+	}
+	return f.handlers(), release, nil
+}
+
+// resetState puts the counter back to zero, empties the record and restores the user
+// to its initial name and region. The fence counters of the locks are kept, so that
+// fencing tokens keep growing across resets.
+func (s *DistributedLockScenario) resetState(ctx context.Context) error {
+	if err := redisClient.Del(ctx, s.lockKey, s.recordKey).Err(); err != nil {
+		return scenario.DependencyError(err, "failed to delete locks of %s", s.productCode)
+	}
+
+	p := &model.WebProduct{Code: s.productCode, Name: "Distributed lock demo counter", Extra: counterExtra(0)}
+	if existing, err := s.product(ctx, q); err == nil {
+		p.ID = existing.ID
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+	if err := q.WebProduct.WithContext(ctx).Save(p); err != nil {
+		return scenario.DependencyError(err, "failed to reset product %s", s.productCode)
+	}
+
+	r := &model.WebProduct{Code: s.recordCode, Name: "Distributed lock demo record", Extra: recordExtra(recordDoc{})}
+	if existing, err := s.record(ctx); err == nil {
+		r.ID = existing.ID
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+	if err := q.WebProduct.WithContext(ctx).Save(r); err != nil {
+		return scenario.DependencyError(err, "failed to reset record %s", s.recordCode)
+	}
+
+	u := &model.User{Code: s.userCode, Name: initialUserName, Region: initialUserRegion, Email: "lock-demo@example.com", Phone: "555-0100", Extra: "{}"}
+	if s.userCode != userCode {
+		u.Email = s.userCode + "@example.com"
+	}
+	if existing, err := s.user(ctx, q); err == nil {
+		u.ID = existing.ID
+	} else if !errors.Is(err, errNotFound) {
+		return err
+	}
+	if err := q.User.WithContext(ctx).Save(u); err != nil {
+		return scenario.DependencyError(err, "failed to reset user %s", s.userCode)
+	}
+
+	s.mu.Lock()
+	s.expected, s.lastRun, s.lastEdit, s.lastPause = 0, nil, nil, nil
+	s.mu.Unlock()
+	return nil
+}
+
+// errNotFound marks a missing row; dbError wraps it so that callers can tell it apart.
+var errNotFound = errors.New("row not found")
+
+// dbError turns a database error into a scenario error.
+func dbError(err error, format string, args ...interface{}) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), errNotFound)
+	}
+	return scenario.DependencyError(err, format, args...)
+}
+
+// lockError turns an error of pkg/lock into a scenario error.
+func lockError(err error, key string) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, lock.ErrNotAcquired):
+		return scenario.ConflictError("lock %s not acquired in time", key)
+	case errors.Is(err, lock.ErrNotHeld):
+		return scenario.ConflictError("lease on lock %s was lost", key)
+	case scenario.CodeOf(err) != scenario.CodeInternal, errors.Is(err, context.Canceled):
+		return err
+	}
+	return scenario.DependencyError(err, "lock %s failed", key)
+}
@@ -0,0 +1,151 @@
+package distributedlock
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/lock"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// pauseStats describes a run of the GC pause demo.
+type pauseStats struct {
+	Fenced  bool  `json:"fenced"`
+	TTLMs   int64 `json:"ttl_ms"`
+	PauseMs int64 `json:"pause_ms"`
+	FenceA  int64 `json:"fence_a"`
+	FenceB  int64 `json:"fence_b,omitempty"`
+	// AAccepted and BAccepted tell whether the record took the writes of the clients.
+	AAccepted bool `json:"a_write_accepted"`
+	BAccepted bool `json:"b_write_accepted"`
+	// Corrupted means that client A overwrote client B's write after its lease expired.
+	Corrupted bool     `json:"corrupted"`
+	Timeline  []string `json:"timeline"`
+}
+
+// gcPause lets client A take the record lock and then stop, as in a stop-the-world GC
+// pause, for longer than its lease. Its watchdog stops with it, so the lease expires and
+// client B takes the lock and writes the record. When A resumes it still believes it
+// holds the lock and writes too.
+//
+// Without fencing the record takes A's stale write over B's. With fencing the record
+// only takes writes whose fencing token is at least the largest it has seen, so it
+// rejects A's older token.
+func (s *DistributedLockScenario) gcPause(ctx context.Context, fenced bool, params map[string]interface{}) (*pauseStats, error) {
+	ttl := time.Duration(intParam(params, "ttl_ms", 500)) * time.Millisecond
+	pause := time.Duration(intParam(params, "pause_ms", 1500)) * time.Millisecond
+	switch {
+	case ttl < 50*time.Millisecond || ttl > 10*time.Second:
+		return nil, scenario.InvalidParamsError("ttl_ms must be between 50 and 10000").WithDetail("field", "ttl_ms")
+	case pause < 0 || pause > 30*time.Second:
+		return nil, scenario.InvalidParamsError("pause_ms must be between 0 and 30000").WithDetail("field", "pause_ms")
+	}
+
+	logger := scenario.Logger(ctx)
+	stats := &pauseStats{Fenced: fenced, TTLMs: ttl.Milliseconds(), PauseMs: pause.Milliseconds()}
+	start := time.Now()
+	step := func(format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		stats.Timeline = append(stats.Timeline, fmt.Sprintf("+%dms %s", time.Since(start).Milliseconds(), msg))
+		logger.Info(msg)
+	}
+
+	a, err := redisLocker.Acquire(ctx, s.recordKey, lock.Options{TTL: ttl})
+	if err != nil {
+		return nil, lockError(err, s.recordKey)
+	}
+	stats.FenceA = a.Fence
+	step("Client A acquired the lock with fencing token %d and pauses for %s", a.Fence, pause)
+	resume := time.Now().Add(pause)
+
+	// Client B waits for the lock while A is paused.
+	b, err := redisLocker.Acquire(ctx, s.recordKey, lock.Options{TTL: ttl, Timeout: pause, RetryInterval: 10 * time.Millisecond})
+	switch {
+	case err == nil:
+		stats.FenceB = b.Fence
+		step("Client A's lease expired; client B acquired the lock with fencing token %d", b.Fence)
+		if stats.BAccepted, err = s.writeRecord(ctx, "B", b.Fence, fenced); err != nil {
+			return nil, err
+		}
+		step("Client B wrote the record (accepted: %t)", stats.BAccepted)
+		if err := redisLocker.Release(ctx, b); err != nil {
+			return nil, lockError(err, s.recordKey)
+		}
+		step("Client B released the lock")
+	case errors.Is(err, lock.ErrNotAcquired):
+		step("Client B could not acquire the lock while client A was paused")
+	default:
+		return nil, lockError(err, s.recordKey)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(time.Until(resume)):
+	}
+	step("Client A resumed and writes the record with fencing token %d", a.Fence)
+	if stats.AAccepted, err = s.writeRecord(ctx, "A", a.Fence, fenced); err != nil {
+		return nil, err
+	}
+	if stats.AAccepted {
+		step("The record took client A's write")
+	} else {
+		step("The record rejected client A's write: token %d is older than the last one it took", a.Fence)
+	}
+	if err := redisLocker.Release(ctx, a); errors.Is(err, lock.ErrNotHeld) {
+		step("Client A's release found its lease gone")
+	} else if err != nil {
+		return nil, lockError(err, s.recordKey)
+	}
+
+	stats.Corrupted = stats.AAccepted && stats.BAccepted
+	s.mu.Lock()
+	s.lastPause = stats
+	s.mu.Unlock()
+
+	if stats.Corrupted {
+		logger.Warn("Stale lock holder overwrote the record", "fence_a", stats.FenceA, "fence_b", stats.FenceB)
+		return stats, scenario.DemonstratedError("client A wrote after its lease expired and overwrote client B's write")
+	}
+	return stats, nil
+}
+
+// recordDoc is the content of the extra column of the record row.
+type recordDoc struct {
+	Content string `json:"content"`
+	Writer  string `json:"writer"`
+}
+
+// writeRecord writes the record as client writer. The record keeps the fencing token of
+// the last write in its version column; a fenced write only lands if its token is not
+// older than that, an unfenced one always does. It reports whether the write landed.
+func (s *DistributedLockScenario) writeRecord(ctx context.Context, writer string, fence int64, fenced bool) (bool, error) {
+	doc := recordDoc{Writer: writer, Content: fmt.Sprintf("written by client %s at %s", writer, time.Now().Format("15:04:05.000"))}
+	do := q.WebProduct.WithContext(ctx).Where(q.WebProduct.Code.Eq(s.recordCode))
+	if fenced {
+		do = do.Where(q.WebProduct.Version.Lte(int32(fence)))
+	}
+	info, err := do.UpdateSimple(q.WebProduct.Extra.Value(recordExtra(doc)), q.WebProduct.Version.Value(int32(fence)))
+	if err != nil {
+		return false, scenario.DependencyError(err, "failed to write record %s", s.recordCode)
+	}
+	return info.RowsAffected == 1, nil
+}
+
+// recordOf reads the record from the extra column of its row.
+func recordOf(r *model.WebProduct) (recordDoc, error) {
+	var doc recordDoc
+	if err := json.Unmarshal([]byte(r.Extra), &doc); err != nil {
+		return doc, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return doc, nil
+}
+
+// recordExtra returns the extra column of the record row holding doc.
+func recordExtra(doc recordDoc) string {
+	data, _ := json.Marshal(doc)
+	return string(data)
+}
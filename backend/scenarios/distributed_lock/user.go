@@ -0,0 +1,146 @@
+package distributedlock
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"sync"
+	"time"
+)
+
+// editStats describes the last pair of concurrent user edits.
+type editStats struct {
+	Strategy string `json:"strategy"`
+	// Edits lists what each editor changed, and whether it survived.
+	Edits   []edit `json:"edits"`
+	Retries int    `json:"retries"`
+	Lost    int    `json:"lost"`
+}
+
+type edit struct {
+	Editor  string `json:"editor"`
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Applied bool   `json:"applied"`
+}
+
+// editUser lets two editors change different fields of the user at the same time, as
+// two admins submitting the same form would. Both load the user first; the region
+// editor saves first, the name editor second.
+//
+// Without a version check each save writes the whole row, so the name editor puts
+// the region it loaded back and the first edit is lost. With the version check the
+// name editor's save matches no row, so it loads the user again and reapplies its change.
+func (s *DistributedLockScenario) editUser(ctx context.Context, checkVersion bool, params map[string]interface{}) (*editStats, error) {
+	logger := scenario.Logger(ctx)
+	region, _ := params["region"].(string)
+	if region == "" {
+		region = "EU"
+	}
+	name, _ := params["name"].(string)
+	if name == "" {
+		name = "Acme Advertising Ltd"
+	}
+	strategy := strategyNaive
+	if checkVersion {
+		strategy = strategyOptimistic
+	}
+	stats := &editStats{Strategy: strategy, Edits: []edit{
+		{Editor: "alice", Field: "region", Value: region},
+		{Editor: "bob", Field: "name", Value: name},
+	}}
+	apply := []func(u *model.User){
+		func(u *model.User) { u.Region = region },
+		func(u *model.User) { u.Name = name },
+	}
+
+	// Both editors load the user before either saves.
+	var loaded sync.WaitGroup
+	loaded.Add(len(apply))
+	errs := make([]error, len(apply))
+	retries := make([]int, len(apply))
+	var wg sync.WaitGroup
+	for i := range apply {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u, err := s.user(ctx, q)
+			loaded.Done()
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			loaded.Wait()
+			time.Sleep(time.Duration(i) * 20 * time.Millisecond) // alice saves first
+			apply[i](u)
+			logger.Info("Saving user", "editor", stats.Edits[i].Editor, "field", stats.Edits[i].Field, "version", u.Version)
+			if checkVersion {
+				retries[i], errs[i] = s.saveUserChecked(ctx, u, apply[i])
+			} else {
+				errs[i] = s.saveUser(ctx, u)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := s.user(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	stats.Edits[0].Applied = u.Region == region
+	stats.Edits[1].Applied = u.Name == name
+	for i := range stats.Edits {
+		stats.Retries += retries[i]
+		if !stats.Edits[i].Applied {
+			stats.Lost++
+			logger.Warn("Edit lost", "editor", stats.Edits[i].Editor, "field", stats.Edits[i].Field)
+		}
+	}
+	s.mu.Lock()
+	s.lastEdit = stats
+	s.mu.Unlock()
+
+	if stats.Lost > 0 {
+		return stats, scenario.DemonstratedError("%d of %d edits were overwritten by a concurrent save", stats.Lost, len(stats.Edits))
+	}
+	return stats, nil
+}
+
+// saveUser writes the whole user row, whatever happened to it since it was loaded.
+func (s *DistributedLockScenario) saveUser(ctx context.Context, u *model.User) error {
+	_, err := q.User.WithContext(ctx).Where(q.User.ID.Eq(u.ID)).
+		UpdateSimple(q.User.Name.Value(u.Name), q.User.Region.Value(u.Region))
+	if err != nil {
+		return scenario.DependencyError(err, "failed to save user")
+	}
+	return nil
+}
+
+// saveUserChecked writes the user row only if its version is still the one loaded,
+// bumping the version. On a conflict it loads the user again, reapplies the change
+// with apply and retries.
+func (s *DistributedLockScenario) saveUserChecked(ctx context.Context, u *model.User, apply func(u *model.User)) (retries int, err error) {
+	const maxAttempts = 5
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		info, err := q.User.WithContext(ctx).Where(q.User.ID.Eq(u.ID), q.User.Version.Eq(u.Version)).
+			UpdateSimple(q.User.Name.Value(u.Name), q.User.Region.Value(u.Region), q.User.Version.Add(1))
+		if err != nil {
+			return retries, scenario.DependencyError(err, "failed to save user")
+		}
+		if info.RowsAffected == 1 {
+			return retries, nil
+		}
+		scenario.Logger(ctx).Info("User changed since it was loaded, reloading", "version", u.Version)
+		retries++
+		if u, err = s.user(ctx, q); err != nil {
+			return retries, err
+		}
+		apply(u)
+	}
+	return retries, scenario.ConflictError("user still changing after %d attempts", maxAttempts)
+}
@@ -0,0 +1,370 @@
+package hotkeys
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/hotkey"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	tierLocal = "local"
+	tierRedis = "redis"
+	tierMySQL = "mysql"
+
+	// shards is the number of nodes of the simulated Redis Cluster.
+	shards = 3
+	// replicaTTL is the TTL of the replicas of a hot key. They are not updated with the
+	// key, so they expire soon to bound how stale they get.
+	replicaTTL = 5 * time.Second
+)
+
+// options are the mitigations of a cacheManager, and the settings of its detector.
+type options struct {
+	LocalPromotion bool `json:"local_promotion"`
+	Replication    bool `json:"replication"`
+	Coalescing     bool `json:"coalescing"`
+	// Threshold is the number of accesses per second that makes a key hot.
+	Threshold int `json:"threshold"`
+	// LocalTTLMs is the TTL of the hot keys promoted to the local cache.
+	LocalTTLMs int `json:"local_ttl_ms"`
+	// Replicas is the number of copies of each hot key in Redis.
+	Replicas int `json:"replicas"`
+}
+
+// mitigations names the mitigations of o, or "none".
+func (o options) mitigations() string {
+	var names []string
+	if o.LocalPromotion {
+		names = append(names, "local_promotion")
+	}
+	if o.Replication {
+		names = append(names, "replication")
+	}
+	if o.Coalescing {
+		names = append(names, "coalescing")
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "+")
+}
+
+// productKey is the key of a product in Redis, and replicaKey that of its i-th replica:
+// the key with a suffix, which hashes it to another slot and so, likely, another shard.
+func productKey(id int64) string {
+	return keyPrefix + "product:" + strconv.FormatInt(id, 10)
+}
+
+func replicaKey(key string, i int) string {
+	return key + ":r" + strconv.Itoa(i)
+}
+
+// localCache is the in-process cache of a server. It holds the hot keys promoted to it,
+// each for a short TTL.
+type localCache struct {
+	mu      sync.RWMutex
+	entries map[string]localEntry
+}
+
+type localEntry struct {
+	value    string
+	expireAt time.Time
+}
+
+func newLocalCache() *localCache {
+	return &localCache{entries: make(map[string]localEntry)}
+}
+
+func (c *localCache) get(key string, now time.Time) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expireAt) {
+		return "", false
+	}
+	return e.value, true
+}
+
+func (c *localCache) set(key, value string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = localEntry{value: value, expireAt: time.Now().Add(ttl)}
+}
+
+// size returns the number of entries that have not expired.
+func (c *localCache) size(now time.Time) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	n := 0
+	for _, e := range c.entries {
+		if now.Before(e.expireAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// load counts the work of each tier, and of each Redis shard and key.
+type load struct {
+	requests     atomic.Int64
+	localHits    atomic.Int64
+	redisReads   atomic.Int64
+	redisHits    atomic.Int64
+	redisWrites  atomic.Int64
+	replicaReads atomic.Int64
+	coalesced    atomic.Int64
+	promotions   atomic.Int64
+	dbQueries    atomic.Int64
+	errors       atomic.Int64
+	shards       [shards]atomic.Int64 // Redis commands
+
+	mu   sync.Mutex
+	keys map[string]int64 // Redis reads
+}
+
+func newLoad() *load {
+	return &load{keys: make(map[string]int64)}
+}
+
+func (l *load) redisCommand(key string, read bool) {
+	l.shards[shard(key, shards)].Add(1)
+	if !read {
+		l.redisWrites.Add(1)
+		return
+	}
+	l.redisReads.Add(1)
+	l.mu.Lock()
+	l.keys[key]++
+	l.mu.Unlock()
+}
+
+func (l *load) snapshot() map[string]int64 {
+	return map[string]int64{
+		"requests":      l.requests.Load(),
+		"local_hits":    l.localHits.Load(),
+		"redis_reads":   l.redisReads.Load(),
+		"redis_hits":    l.redisHits.Load(),
+		"redis_writes":  l.redisWrites.Load(),
+		"replica_reads": l.replicaReads.Load(),
+		"coalesced":     l.coalesced.Load(),
+		"promotions":    l.promotions.Load(),
+		"db_queries":    l.dbQueries.Load(),
+		"errors":        l.errors.Load(),
+	}
+}
+
+// tiers returns the requests served by each tier.
+func (l *load) tiers() map[string]int64 {
+	return map[string]int64{
+		tierLocal: l.localHits.Load(),
+		tierRedis: l.redisReads.Load(),
+		tierMySQL: l.dbQueries.Load(),
+	}
+}
+
+// shardLoad returns the Redis commands served by each shard.
+func (l *load) shardLoad() map[string]int64 {
+	out := make(map[string]int64, shards)
+	for i := range l.shards {
+		out[fmt.Sprintf("shard_%d", i)] = l.shards[i].Load()
+	}
+	return out
+}
+
+// topKey returns the key read the most from Redis and the number of its reads.
+func (l *load) topKey() (string, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	top, most := "", int64(0)
+	for k, n := range l.keys {
+		if n > most || n == most && k < top {
+			top, most = k, n
+		}
+	}
+	return top, most
+}
+
+// cacheManager reads products through the local cache, Redis and MySQL, like the
+// CacheManager of xdc_cache_sync, and counts every access in a hot-key detector. The
+// keys found hot are mitigated as opts says: promoted to the local cache, read from one
+// of their replicas, or read once for all the concurrent requests.
+type cacheManager struct {
+	cache    productcache.Cache
+	catalog  productcache.Catalog
+	local    *localCache
+	detector *hotkey.Detector
+	opts     options
+	load     *load
+	group    singleflight.Group
+}
+
+func newCacheManager(c productcache.Cache, cat productcache.Catalog, opts options) *cacheManager {
+	return &cacheManager{
+		cache:    c,
+		catalog:  cat,
+		local:    newLocalCache(),
+		detector: hotkey.NewDetector(hotkey.Config{Window: time.Second, Threshold: uint64(opts.Threshold)}),
+		opts:     opts,
+		load:     newLoad(),
+	}
+}
+
+// get returns the product id.
+func (m *cacheManager) get(ctx context.Context, id int64) (*productcache.Product, error) {
+	m.load.requests.Add(1)
+	v, err := m.value(ctx, id)
+	if err != nil {
+		m.load.errors.Add(1)
+		return nil, err
+	}
+	var p productcache.Product
+	if err := json.Unmarshal([]byte(v), &p); err != nil {
+		m.load.errors.Add(1)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (m *cacheManager) value(ctx context.Context, id int64) (string, error) {
+	key := productKey(id)
+	now := time.Now()
+	hot := m.detector.Observe(key, now)
+	if m.opts.LocalPromotion {
+		if v, ok := m.local.get(key, now); ok {
+			telemetry.CacheLookup(scenarioID, tierLocal, true)
+			m.load.localHits.Add(1)
+			return v, nil
+		}
+		telemetry.CacheLookup(scenarioID, tierLocal, false)
+	}
+
+	var v string
+	var err error
+	if hot && m.opts.Coalescing {
+		// Only the caller whose function runs reads Redis; the others wait for its value.
+		ran := false
+		var out interface{}
+		out, err, _ = m.group.Do(key, func() (interface{}, error) {
+			ran = true
+			return m.fromRedis(ctx, id, key, hot)
+		})
+		if !ran {
+			m.load.coalesced.Add(1)
+		}
+		v, _ = out.(string)
+	} else {
+		v, err = m.fromRedis(ctx, id, key, hot)
+	}
+	if err != nil {
+		return "", err
+	}
+	if hot && m.opts.LocalPromotion {
+		m.local.set(key, v, time.Duration(m.opts.LocalTTLMs)*time.Millisecond)
+		m.load.promotions.Add(1)
+	}
+	return v, nil
+}
+
+// fromRedis reads key, or one of its replicas at random if it is hot and replicated,
+// and fills what is missing from MySQL.
+func (m *cacheManager) fromRedis(ctx context.Context, id int64, key string, hot bool) (string, error) {
+	readKey := key
+	if hot && m.opts.Replication {
+		readKey = replicaKey(key, rand.Intn(m.opts.Replicas))
+		m.load.replicaReads.Add(1)
+	}
+	v, ok, err := m.read(ctx, readKey)
+	if err != nil || ok {
+		return v, err
+	}
+	if readKey != key {
+		// A missing replica is copied from the key.
+		if v, ok, err = m.read(ctx, key); err != nil {
+			return "", err
+		}
+	}
+	if !ok {
+		if v, err = m.fromMySQL(ctx, id); err != nil {
+			return "", err
+		}
+		if err := m.write(ctx, key, v, 0); err != nil {
+			return "", err
+		}
+	}
+	if readKey != key {
+		if err := m.write(ctx, readKey, v, replicaTTL); err != nil {
+			return "", err
+		}
+	}
+	return v, nil
+}
+
+func (m *cacheManager) read(ctx context.Context, key string) (string, bool, error) {
+	m.load.redisCommand(key, true)
+	v, ok, err := m.cache.Get(ctx, key)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	telemetry.CacheLookup(scenarioID, tierRedis, ok)
+	if ok {
+		m.load.redisHits.Add(1)
+	}
+	return v, ok, nil
+}
+
+func (m *cacheManager) write(ctx context.Context, key, value string, ttl time.Duration) error {
+	m.load.redisCommand(key, false)
+	if err := m.cache.Set(ctx, key, value, ttl); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+func (m *cacheManager) fromMySQL(ctx context.Context, id int64) (string, error) {
+	m.load.dbQueries.Add(1)
+	telemetry.ObserveDBQuery(scenarioID)
+	p, err := m.catalog.Product(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to load product %d: %w", id, err)
+	}
+	if p == nil {
+		return "", fmt.Errorf("product %d does not exist", id)
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// warm caches the products with ids in Redis, outside of the load.
+func warm(ctx context.Context, c productcache.Cache, cat productcache.Catalog, ids []int64) error {
+	for _, id := range ids {
+		p, err := cat.Product(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to load product %d: %w", id, err)
+		}
+		if p == nil {
+			continue
+		}
+		b, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if err := c.Set(ctx, productKey(id), string(b), 0); err != nil {
+			return fmt.Errorf("failed to cache product %d: %w", id, err)
+		}
+	}
+	return nil
+}
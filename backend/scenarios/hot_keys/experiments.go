@@ -0,0 +1,140 @@
+package hotkeys
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/hotkey"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// workers read concurrently during an experiment for runDuration, pausing think
+	// between requests.
+	workers     = 20
+	runDuration = time.Second
+	think       = time.Millisecond
+)
+
+// zipf draws ranks in [0, n) with the probability of rank k proportional to 1/(k+1)^s:
+// the larger s, the more the first ranks take. It is safe for concurrent use.
+type zipf struct {
+	cdf []float64
+}
+
+func newZipf(n int, s float64) *zipf {
+	cdf := make([]float64, n)
+	sum := 0.0
+	for k := 0; k < n; k++ {
+		sum += 1 / math.Pow(float64(k+1), s)
+		cdf[k] = sum
+	}
+	for k := range cdf {
+		cdf[k] /= sum
+	}
+	return &zipf{cdf: cdf}
+}
+
+func (z *zipf) next() int {
+	return min(sort.SearchFloat64s(z.cdf, rand.Float64()), len(z.cdf)-1)
+}
+
+// run is the outcome of an experiment.
+type run struct {
+	Mitigations string  `json:"mitigations"`
+	Skew        float64 `json:"skew"`
+	Requests    int64   `json:"requests"`
+	// Tiers counts the requests served by the local cache, Redis and MySQL.
+	Tiers map[string]int64 `json:"tiers"`
+	// Shards counts the Redis commands served by each shard.
+	Shards map[string]int64 `json:"shards"`
+	// HottestShardPct is the share of the Redis commands served by the busiest shard.
+	HottestShardPct int `json:"hottest_shard_pct"`
+	// TopKey is the key read the most from Redis, and TopKeyPct its share of the reads.
+	TopKey    string           `json:"top_redis_key"`
+	TopKeyPct int              `json:"top_redis_key_pct"`
+	HotKeys   []hotkey.Key     `json:"hot_keys"`
+	Stats     map[string]int64 `json:"stats"`
+}
+
+// experiment has workers read the products with ids, ranked by a Zipf distribution of
+// exponent skew, through a fresh cacheManager with opts, and records the load of each
+// tier and shard.
+func experiment(ctx context.Context, c productcache.Cache, cat productcache.Catalog, ids []int64, opts options, skew float64) (*run, error) {
+	if err := warm(ctx, c, cat, ids); err != nil {
+		return nil, err
+	}
+	m := newCacheManager(c, cat, opts)
+	z := newZipf(len(ids), skew)
+	// The reads run under ctx, so that none is cut short at the deadline; stop ends
+	// the run early on the first error.
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+	deadline := time.Now().Add(runDuration)
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for stop.Err() == nil && time.Now().Before(deadline) {
+				if _, err := m.get(ctx, ids[z.next()]); err != nil {
+					once.Do(func() { firstErr = err; cancel() })
+					return
+				}
+				if !sleep(stop, think) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return m.result(skew), nil
+}
+
+// result summarizes the load of m.
+func (m *cacheManager) result(skew float64) *run {
+	res := &run{
+		Mitigations: m.opts.mitigations(),
+		Skew:        skew,
+		Requests:    m.load.requests.Load(),
+		Tiers:       m.load.tiers(),
+		Shards:      m.load.shardLoad(),
+		HotKeys:     m.detector.Hot(time.Now()),
+		Stats:       m.load.snapshot(),
+	}
+	var commands, busiest int64
+	for _, n := range res.Shards {
+		commands += n
+		busiest = max(busiest, n)
+	}
+	if commands > 0 {
+		res.HottestShardPct = int(100 * busiest / commands)
+	}
+	top, n := m.load.topKey()
+	res.TopKey = top
+	if reads := m.load.redisReads.Load(); reads > 0 {
+		res.TopKeyPct = int(100 * n / reads)
+	}
+	return res
+}
+
+// sleep waits for d and reports whether ctx was still live by then.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,327 @@
+package hotkeys
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/hotkey"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in hot_keys.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario()
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "hot_keys"
+
+	// codePrefix marks the products of the scenario in web_product, and keyPrefix its
+	// keys in Redis.
+	codePrefix = "hk-"
+	keyPrefix  = "hot_keys:"
+	// products is the number of products seeded.
+	products = 200
+
+	// defaultSkew is the Zipf exponent of the reads, in hundredths.
+	defaultSkew       = 120
+	defaultThreshold  = 100
+	defaultLocalTTLMs = 500
+	defaultReplicas   = 8
+	// demonstratedPct is the share of the Redis reads taken by one key from which the
+	// skewed reads count as a hot key.
+	demonstratedPct = 10
+)
+
+// HotKeysScenario holds the handlers of the scenario on hot keys: a skewed read workload
+// concentrates on a few products, whose Redis shard takes most of the load. Every access
+// goes through a Count-Min Sketch detector (pkg/hotkey), and the keys it finds hot are
+// promoted to a local cache, replicated across slots or read once for concurrent
+// requests.
+type HotKeysScenario struct {
+	catalog productcache.Catalog
+	cache   productcache.Cache
+
+	mu    sync.Mutex
+	ids   []int64 // seeded products, the hottest first
+	opts  options // of the workload
+	live  *cacheManager
+	zipfs map[int]*zipf   // of the workload, by skew
+	runs  map[string]*run // last run by mitigations
+	last  *run
+}
+
+func newScenario() *HotKeysScenario {
+	return &HotKeysScenario{
+		opts:  defaultOptions(),
+		zipfs: make(map[int]*zipf),
+		runs:  make(map[string]*run),
+	}
+}
+
+func defaultOptions() options {
+	return options{Threshold: defaultThreshold, LocalTTLMs: defaultLocalTTLMs, Replicas: defaultReplicas}
+}
+
+func (s *HotKeysScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Workloads: []scenario.Workload{{
+			ID:          "read_products",
+			Name:        "Read Products",
+			Description: "Reads a product ranked by a Zipf distribution through the caches, with the mitigations chosen by Configure Reads.",
+			Params: []scenario.ActionParam{
+				{Name: "skew", Type: "integer", Description: "Zipf exponent in hundredths; the higher, the more the first products take", Default: defaultSkew},
+			},
+			Run: s.readProducts,
+		}},
+		Actions: map[string]registry.ActionFunc{
+			"skewed_reads": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runExperiment(ctx, params, false)
+			},
+			"mitigated_reads": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return s.runExperiment(ctx, params, true)
+			},
+			"configure": s.configure,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database and Redis and seeds the products.
+func (s *HotKeysScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.WebProduct{})
+	if err != nil {
+		return err
+	}
+	s.catalog = productcache.NewMySQLCatalog(db, codePrefix)
+
+	ctx := context.Background()
+	redisClient, err := infra.OpenRedis(ctx)
+	if err != nil {
+		return err
+	}
+	s.cache = productcache.NewRedisCache(redisClient, keyPrefix)
+	if err := s.seed(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live = newCacheManager(s.cache, s.catalog, s.opts)
+	return nil
+}
+
+// seed creates the products that are missing.
+func (s *HotKeysScenario) seed(ctx context.Context) error {
+	ids, err := s.catalog.Seed(ctx, products)
+	if err != nil {
+		return fmt.Errorf("failed to seed products: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids = ids
+	return nil
+}
+
+// runExperiment runs the skewed reads, with the mitigations of params if mitigated, and
+// reports the hot key as demonstrated when one key took a large share of the Redis reads
+// without mitigations.
+func (s *HotKeysScenario) runExperiment(ctx context.Context, params map[string]interface{}, mitigated bool) (interface{}, error) {
+	skew, err := intParam(params, "skew", defaultSkew, 0, 300)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	ids, opts := s.ids, s.opts
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return nil, scenario.UnprocessableError("no products are seeded; reset the scenario")
+	}
+	opts.LocalPromotion, opts.Replication, opts.Coalescing = false, false, false
+	if mitigated {
+		for name, on := range map[string]*bool{
+			"local_promotion": &opts.LocalPromotion,
+			"replication":     &opts.Replication,
+			"coalescing":      &opts.Coalescing,
+		} {
+			*on = true
+			if v, ok := params[name].(bool); ok {
+				*on = v
+			}
+		}
+		if opts.mitigations() == "none" {
+			return nil, scenario.InvalidParamsError("choose at least one mitigation")
+		}
+	}
+
+	res, err := experiment(ctx, s.cache, s.catalog, ids, opts, float64(skew)/100)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "skewed reads failed")
+	}
+	s.record(res)
+	log := scenario.Logger(ctx)
+	log.Info("Ran skewed reads", "mitigations", res.Mitigations, "requests", res.Requests, "hot_keys", len(res.HotKeys),
+		"top_redis_key", res.TopKey, "top_redis_key_pct", res.TopKeyPct, "hottest_shard_pct", res.HottestShardPct)
+	if !mitigated && res.TopKeyPct >= demonstratedPct {
+		log.Warn("One key took a large share of the Redis reads", "key", res.TopKey, "pct", res.TopKeyPct)
+		return nil, scenario.DemonstratedError("%s took %d%% of the Redis reads; its shard served %d%% of the commands of %d shards",
+			res.TopKey, res.TopKeyPct, res.HottestShardPct, shards)
+	}
+	return res, nil
+}
+
+// record keeps res for the dashboard.
+func (s *HotKeysScenario) record(res *run) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[res.Mitigations] = res
+	s.last = res
+}
+
+// readProducts reads one product, ranked by a Zipf distribution, through the caches of
+// the workload.
+func (s *HotKeysScenario) readProducts(ctx context.Context, params map[string]interface{}) error {
+	skew, err := intParam(params, "skew", defaultSkew, 0, 300)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	ids, m := s.ids, s.live
+	z := s.zipfs[skew]
+	if z == nil && len(ids) > 0 {
+		z = newZipf(len(ids), float64(skew)/100)
+		s.zipfs[skew] = z
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 || m == nil {
+		return scenario.UnprocessableError("no products are seeded; reset the scenario")
+	}
+	id := ids[z.next()]
+	if _, err := m.get(ctx, id); err != nil {
+		return scenario.DependencyError(err, "failed to read product %d", id)
+	}
+	return nil
+}
+
+// configure sets the mitigations and the detector of the workload, and starts its
+// caches and counters afresh.
+func (s *HotKeysScenario) configure(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	o := s.opts
+	s.mu.Unlock()
+	for name, on := range map[string]*bool{
+		"local_promotion": &o.LocalPromotion,
+		"replication":     &o.Replication,
+		"coalescing":      &o.Coalescing,
+	} {
+		if v, ok := params[name].(bool); ok {
+			*on = v
+		}
+	}
+	var err error
+	if o.Threshold, err = intParam(params, "threshold", o.Threshold, 1, 1000000); err != nil {
+		return nil, err
+	}
+	if o.LocalTTLMs, err = intParam(params, "local_ttl_ms", o.LocalTTLMs, 10, 60000); err != nil {
+		return nil, err
+	}
+	if o.Replicas, err = intParam(params, "replicas", o.Replicas, 2, 64); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.opts, s.live = o, newCacheManager(s.cache, s.catalog, o)
+	s.mu.Unlock()
+	scenario.Logger(ctx).Info("Configured reads", "mitigations", o.mitigations(), "threshold", o.Threshold,
+		"local_ttl_ms", o.LocalTTLMs, "replicas", o.Replicas)
+	return o, nil
+}
+
+func (s *HotKeysScenario) FetchState() (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	experiments := make(map[string]interface{}, len(s.runs))
+	for mitigations, r := range s.runs {
+		experiments[mitigations] = map[string]interface{}{
+			"requests":          r.Requests,
+			"hot_keys":          len(r.HotKeys),
+			"top_redis_key_pct": r.TopKeyPct,
+			"hottest_shard_pct": r.HottestShardPct,
+			"tiers":             r.Tiers,
+		}
+	}
+	hotKeys := map[string]interface{}{"live": []hotkey.Key{}, "last_run": []hotkey.Key{}}
+	tiers := map[string]interface{}{"experiments": experiments}
+	shardLoad := map[string]interface{}{}
+	config := map[string]interface{}{"options": s.opts, "shards": shards, "products": len(s.ids)}
+	if s.live != nil {
+		hotKeys["live"] = s.live.detector.Hot(now)
+		tiers["live"] = s.live.load.tiers()
+		tiers["live_stats"] = s.live.load.snapshot()
+		shardLoad["live"] = s.live.load.shardLoad()
+		config["local_cache_entries"] = s.live.local.size(now)
+	}
+	state := map[string]interface{}{
+		"hot_keys": hotKeys,
+		"tiers":    tiers,
+		"shards":   shardLoad,
+		"config":   config,
+		"last_run": "no run yet",
+	}
+	if s.last != nil {
+		hotKeys["last_run"] = s.last.HotKeys
+		tiers["last_run"] = s.last.Tiers
+		shardLoad["last_run"] = s.last.Shards
+		state["last_run"] = *s.last
+	}
+	return state, nil
+}
+
+// resetState clears the cache and the records, restores the default configuration and
+// seeds the products again.
+func (s *HotKeysScenario) resetState(ctx context.Context) error {
+	if err := s.cache.Clear(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to clear the cache")
+	}
+	if err := s.catalog.Reset(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to delete products")
+	}
+	if err := s.seed(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to seed products")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = defaultOptions()
+	s.live = newCacheManager(s.cache, s.catalog, s.opts)
+	s.runs, s.last = make(map[string]*run), nil
+	return nil
+}
+
+// intParam returns the integer param name, or def if it is absent.
+func intParam(params map[string]interface{}, name string, def, min, max int) (int, error) {
+	v := def
+	switch p := params[name].(type) {
+	case nil:
+	case float64:
+		v = int(p)
+	case int:
+		v = p
+	default:
+		return 0, scenario.InvalidParamsError("%s must be a number", name).WithDetail("field", name)
+	}
+	if v < min || v > max {
+		return 0, scenario.InvalidParamsError("%s must be between %d and %d", name, min, max).WithDetail("field", name)
+	}
+	return v, nil
+}
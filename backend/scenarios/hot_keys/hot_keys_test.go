@@ -0,0 +1,118 @@
+package hotkeys
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/hotkey"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/productcache"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestScenario(t *testing.T) *HotKeysScenario {
+	s := newScenario()
+	s.catalog = productcache.NewMemoryCatalog(codePrefix)
+	s.cache = productcache.NewMemoryCache(time.Millisecond)
+	assert.NoError(t, s.resetState(context.Background()))
+	return s
+}
+
+func TestSlot(t *testing.T) {
+	// The check value of CRC16/XMODEM, 0x31C3.
+	assert.Equal(t, 0x31C3, slot("123456789"))
+	assert.Equal(t, slot("user1000"), slot("{user1000}.following"))
+	assert.Equal(t, slot("foo{}{bar}"), slot("foo{}{bar}"))
+	assert.NotEqual(t, slot("bar"), slot("foo{}{bar}"))
+	assert.Equal(t, 0, shard("", shards))
+	assert.Equal(t, shards-1, shard("123456789", shards))
+}
+
+func TestSkewedReads(t *testing.T) {
+	s := newTestScenario(t)
+	_, err := s.runExperiment(context.Background(), nil, false)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	res := s.last
+	assert.Equal(t, "none", res.Mitigations)
+	assert.Equal(t, productKey(s.ids[0]), res.TopKey)
+	assert.GreaterOrEqual(t, res.TopKeyPct, demonstratedPct)
+	// Every read went to Redis, and the products were warmed up beforehand.
+	assert.Equal(t, res.Requests, res.Tiers[tierRedis])
+	assert.Zero(t, res.Tiers[tierLocal])
+	assert.Zero(t, res.Tiers[tierMySQL])
+	// The detector found the head of the distribution.
+	assert.NotEmpty(t, res.HotKeys)
+	assert.Equal(t, productKey(s.ids[0]), res.HotKeys[0].Key)
+
+	// Uniform reads have no hot key.
+	out, err := s.runExperiment(context.Background(), map[string]interface{}{"skew": float64(0)}, false)
+	assert.NoError(t, err)
+	assert.Less(t, out.(*run).TopKeyPct, demonstratedPct)
+}
+
+func TestMitigations(t *testing.T) {
+	s := newTestScenario(t)
+	_, err := s.runExperiment(context.Background(), nil, false)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	naive := s.runs["none"]
+
+	only := func(name string) map[string]interface{} {
+		params := map[string]interface{}{"local_promotion": false, "replication": false, "coalescing": false}
+		params[name] = true
+		return params
+	}
+	for _, name := range []string{"local_promotion", "replication", "coalescing"} {
+		out, err := s.runExperiment(context.Background(), only(name), true)
+		assert.NoError(t, err, name)
+		res := out.(*run)
+		assert.Equal(t, name, res.Mitigations)
+		assert.Less(t, res.TopKeyPct, naive.TopKeyPct, name)
+		assert.Zero(t, res.Stats["errors"], name)
+	}
+	// Hot keys are served by the local cache...
+	promoted := s.runs["local_promotion"]
+	assert.Greater(t, promoted.Tiers[tierLocal], promoted.Requests/3)
+	assert.Less(t, promoted.TopKeyPct, demonstratedPct)
+	// ...or spread over replicas on other slots...
+	replicated := s.runs["replication"]
+	assert.Positive(t, replicated.Stats["replica_reads"])
+	assert.Less(t, replicated.TopKeyPct, demonstratedPct)
+	// ...or read once for the concurrent requests.
+	assert.Positive(t, s.runs["coalescing"].Stats["coalesced"])
+
+	out, err := s.runExperiment(context.Background(), nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "local_promotion+replication+coalescing", out.(*run).Mitigations)
+
+	_, err = s.runExperiment(context.Background(), map[string]interface{}{"local_promotion": false, "replication": false, "coalescing": false}, true)
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+}
+
+func TestReadProducts(t *testing.T) {
+	s := newTestScenario(t)
+	ctx := context.Background()
+	_, err := s.configure(ctx, map[string]interface{}{"local_promotion": true, "threshold": float64(5)})
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, s.readProducts(ctx, map[string]interface{}{"skew": float64(300)}))
+	}
+	stats := s.live.load.snapshot()
+	assert.Equal(t, int64(100), stats["requests"])
+	assert.Positive(t, stats["local_hits"])
+	// Nothing was cached yet: the first read of each product queried MySQL.
+	assert.Equal(t, stats["db_queries"], stats["redis_writes"])
+
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	hot := state["hot_keys"].(map[string]interface{})["live"].([]hotkey.Key)
+	assert.NotEmpty(t, hot)
+	assert.Equal(t, productKey(s.ids[0]), hot[0].Key)
+	tiers := state["tiers"].(map[string]interface{})["live"].(map[string]int64)
+	assert.Equal(t, stats["local_hits"], tiers[tierLocal])
+	assert.Positive(t, state["config"].(map[string]interface{})["local_cache_entries"])
+
+	_, err = s.configure(ctx, map[string]interface{}{"replicas": float64(1)})
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(s.readProducts(ctx, map[string]interface{}{"skew": "high"})))
+}
@@ -0,0 +1,38 @@
+package hotkeys
+
+// slot returns the Redis Cluster hash slot of key: CRC16 (XMODEM) of the key, or of its
+// {hash tag} if it has one, modulo 16384.
+func slot(key string) int {
+	for i := 0; i < len(key); i++ {
+		if key[i] != '{' {
+			continue
+		}
+		for j := i + 1; j < len(key); j++ {
+			if key[j] == '}' {
+				if j > i+1 {
+					key = key[i+1 : j]
+				}
+				break
+			}
+		}
+		break
+	}
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for b := 0; b < 8; b++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % 16384
+}
+
+// shard returns which of shards nodes, splitting the slots into equal ranges as a
+// cluster does by default, holds key.
+func shard(key string, shards int) int {
+	return slot(key) * shards / 16384
+}
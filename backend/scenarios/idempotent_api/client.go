@@ -0,0 +1,356 @@
+package idempotentapi
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/idempotency"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Outcomes of an attempt, as seen by the client.
+const (
+	outcomeCreated    = "created"
+	outcomeReplayed   = "replayed"
+	outcomeTimeout    = "timeout"
+	outcomeInProgress = "in_progress"
+	outcomeMismatch   = "mismatch"
+	outcomeFailed     = "failed"
+)
+
+const (
+	// retryBackoff is how long the client waits after a timeout before it retries.
+	retryBackoff = 100 * time.Millisecond
+	// settleMargin is how long after the slow request should have finished the client
+	// looks at the orders, so that it is counted too.
+	settleMargin = 300 * time.Millisecond
+	// submitLatency is how long each request of double_submit takes.
+	submitLatency  = 300 * time.Millisecond
+	maxSubmissions = 20
+)
+
+// attempt is one request of the client.
+type attempt struct {
+	Attempt   int    `json:"attempt"`
+	Status    int    `json:"status,omitempty"`
+	Outcome   string `json:"outcome"`
+	OrderNo   string `json:"order_no,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+
+	retryAfter time.Duration
+}
+
+// done reports whether the client stops retrying after a.
+func (a attempt) done() bool {
+	switch a.Outcome {
+	case outcomeCreated, outcomeReplayed, outcomeMismatch:
+		return true
+	}
+	return false
+}
+
+// checkoutRun is the outcome of an action, as shown on the dashboard.
+type checkoutRun struct {
+	Action      string    `json:"action"`
+	CheckoutID  string    `json:"checkout_id"`
+	Store       string    `json:"store"`
+	Attempts    []attempt `json:"attempts"`
+	Orders      int       `json:"orders"`
+	ChargedOnce bool      `json:"charged_once"`
+}
+
+// request is a call of the client to the payment service.
+type request struct {
+	path    string // route of the payment service, e.g. /orders/redis
+	key     string // Idempotency-Key, empty for none
+	body    orderRequest
+	latency time.Duration // simulated latency of the handler
+	timeout time.Duration
+}
+
+// send makes one request and classifies its response.
+func (s *IdempotentAPIScenario) send(ctx context.Context, n int, r request) (a attempt) {
+	a.Attempt = n
+	start := time.Now()
+	defer func() { a.ElapsedMs = time.Since(start).Milliseconds() }()
+
+	body, err := json.Marshal(r.body)
+	if err != nil {
+		a.Outcome = outcomeFailed
+		return a
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.service.baseURL+r.path, bytes.NewReader(body))
+	if err != nil {
+		a.Outcome = outcomeFailed
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.key != "" {
+		req.Header.Set(idempotency.DefaultHeader, r.key)
+	}
+	if r.latency > 0 {
+		req.Header.Set(latencyHeader, strconv.FormatInt(r.latency.Milliseconds(), 10))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		a.Outcome = outcomeTimeout
+		return a
+	}
+	if err != nil {
+		a.Outcome = outcomeFailed
+		return a
+	}
+	defer resp.Body.Close()
+	a.Status = resp.StatusCode
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case resp.StatusCode == http.StatusCreated:
+		var o orderResponse
+		if err := json.NewDecoder(resp.Body).Decode(&o); err == nil {
+			a.OrderNo = o.OrderNo
+		}
+		a.Outcome = outcomeCreated
+		if resp.Header.Get(idempotency.ReplayedHeader) == "true" {
+			a.Outcome = outcomeReplayed
+			s.replays++
+		}
+	case resp.StatusCode == http.StatusConflict:
+		a.Outcome = outcomeInProgress
+		a.retryAfter = time.Second
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			a.retryAfter = time.Duration(secs) * time.Second
+		}
+		s.busy++
+	case resp.StatusCode == http.StatusUnprocessableEntity:
+		a.Outcome = outcomeMismatch
+		s.reused++
+	default:
+		a.Outcome = outcomeFailed
+	}
+	return a
+}
+
+// checkout sends r until it gets an answer or has retried retries times. Only the first
+// request is slow. After a timeout the client retries at once; after a 409 it waits as
+// long as Retry-After says.
+func (s *IdempotentAPIScenario) checkout(ctx context.Context, r request, retries int) []attempt {
+	var attempts []attempt
+	for n := 1; n <= retries+1; n++ {
+		a := s.send(ctx, n, r)
+		attempts = append(attempts, a)
+		if a.done() || n > retries {
+			break
+		}
+		r.latency = 0
+		wait := retryBackoff
+		if a.Outcome == outcomeInProgress {
+			wait = a.retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return attempts
+		case <-time.After(wait):
+		}
+	}
+	return attempts
+}
+
+// checkoutParams are the params of the checkout actions.
+type checkoutParams struct {
+	amount  int64
+	timeout time.Duration
+	slow    time.Duration
+	retries int
+	store   string
+	wait    bool
+}
+
+func parseCheckoutParams(params map[string]interface{}) (checkoutParams, error) {
+	var p checkoutParams
+	amount, err := intParam(params, "amount", defaultAmount, 1, 1000000)
+	if err != nil {
+		return p, err
+	}
+	timeout, err := intParam(params, "timeout_ms", 500, 50, 5000)
+	if err != nil {
+		return p, err
+	}
+	slow, err := intParam(params, "slow_ms", 1500, 0, int(maxLatency.Milliseconds()))
+	if err != nil {
+		return p, err
+	}
+	retries, err := intParam(params, "retries", 3, 0, 10)
+	if err != nil {
+		return p, err
+	}
+	p = checkoutParams{
+		amount:  int64(amount),
+		timeout: time.Duration(timeout) * time.Millisecond,
+		slow:    time.Duration(slow) * time.Millisecond,
+		retries: retries,
+		store:   storeRedis,
+	}
+	if v, ok := params["store"].(string); ok && v != "" {
+		p.store = v
+	}
+	p.wait, _ = params["wait_in_flight"].(bool)
+	return p, nil
+}
+
+// route returns the route of the payment service that uses store.
+func (s *IdempotentAPIScenario) route(store string, wait bool) (string, error) {
+	if _, ok := s.backends[store]; !ok {
+		return "", scenario.InvalidParamsError("store must be memory, redis or mysql").WithDetail("field", "store")
+	}
+	if wait {
+		return "/orders/" + store + "/wait", nil
+	}
+	return "/orders/" + store, nil
+}
+
+// checkoutNaive pays for a checkout without an Idempotency-Key. The first request is
+// slower than the client's timeout; the retry is charged as a new order, and so is the
+// first request once it finishes.
+func (s *IdempotentAPIScenario) checkoutNaive(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p, err := parseCheckoutParams(params)
+	if err != nil {
+		return nil, err
+	}
+	id := newCheckoutID()
+	r := request{path: "/orders/none", body: orderRequest{CheckoutID: id, Amount: p.amount}, latency: p.slow, timeout: p.timeout}
+	run, err := s.runCheckout(ctx, "checkout_naive", "none", r, p)
+	if err != nil {
+		return nil, err
+	}
+	if !run.ChargedOnce {
+		return run, scenario.DemonstratedError("Checkout %s was charged %d times after %d attempts", id, run.Orders, len(run.Attempts))
+	}
+	return run, nil
+}
+
+// checkoutIdempotent pays for a checkout with its ID as the Idempotency-Key. A retry
+// that arrives while the first request runs gets 409, or waits for it with
+// wait_in_flight; once it has finished, retries get its response replayed.
+func (s *IdempotentAPIScenario) checkoutIdempotent(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p, err := parseCheckoutParams(params)
+	if err != nil {
+		return nil, err
+	}
+	path, err := s.route(p.store, p.wait)
+	if err != nil {
+		return nil, err
+	}
+	id := newCheckoutID()
+	r := request{path: path, key: id, body: orderRequest{CheckoutID: id, Amount: p.amount}, latency: p.slow, timeout: p.timeout}
+	return s.runCheckout(ctx, "checkout_idempotent", p.store, r, p)
+}
+
+// runCheckout runs the checkout of r, waits for its slow request to finish and counts
+// the orders charged for it.
+func (s *IdempotentAPIScenario) runCheckout(ctx context.Context, action, store string, r request, p checkoutParams) (*checkoutRun, error) {
+	start := time.Now()
+	attempts := s.checkout(ctx, r, p.retries)
+	time.Sleep(time.Until(start.Add(p.slow + settleMargin)))
+	return s.finish(ctx, &checkoutRun{Action: action, CheckoutID: r.body.CheckoutID, Store: store, Attempts: attempts})
+}
+
+// finish counts the orders of run and records it as the last run.
+func (s *IdempotentAPIScenario) finish(ctx context.Context, run *checkoutRun) (*checkoutRun, error) {
+	o := q.PaymentOrder
+	n, err := o.WithContext(ctx).Where(o.CheckoutID.Eq(run.CheckoutID)).Count()
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to count orders")
+	}
+	run.Orders = int(n)
+	run.ChargedOnce = n == 1
+	scenario.Logger(ctx).Info("Checkout finished", "checkout_id", run.CheckoutID, "store", run.Store, "attempts", len(run.Attempts), "orders", n)
+	s.mu.Lock()
+	s.last = run
+	s.mu.Unlock()
+	return run, nil
+}
+
+// doubleSubmit sends the same checkout several times at once, as a double click or a
+// second tab does.
+func (s *IdempotentAPIScenario) doubleSubmit(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	n, err := intParam(params, "submissions", 5, 2, maxSubmissions)
+	if err != nil {
+		return nil, err
+	}
+	useKey := true
+	if v, ok := params["idempotency_key"].(bool); ok {
+		useKey = v
+	}
+	store, _ := params["store"].(string)
+	if store == "" {
+		store = storeRedis
+	}
+	wait, _ := params["wait_in_flight"].(bool)
+
+	id := newCheckoutID()
+	r := request{path: "/orders/none", body: orderRequest{CheckoutID: id, Amount: defaultAmount}, latency: submitLatency, timeout: maxLatency}
+	if useKey {
+		if r.path, err = s.route(store, wait); err != nil {
+			return nil, err
+		}
+		r.key = id
+	} else {
+		store = "none"
+	}
+
+	attempts := make([]attempt, n)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			attempts[i] = s.send(ctx, i+1, r)
+		}(i)
+	}
+	wg.Wait()
+	run, err := s.finish(ctx, &checkoutRun{Action: "double_submit", CheckoutID: id, Store: store, Attempts: attempts})
+	if err != nil {
+		return nil, err
+	}
+	if !run.ChargedOnce {
+		return run, scenario.DemonstratedError("%d simultaneous submissions of checkout %s were charged %d times", n, id, run.Orders)
+	}
+	return run, nil
+}
+
+// reuseKey pays for a checkout and then sends its key again with another amount, as a
+// client that reuses keys across operations would. The store rejects it with 422.
+func (s *IdempotentAPIScenario) reuseKey(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	store, _ := params["store"].(string)
+	if store == "" {
+		store = storeRedis
+	}
+	path, err := s.route(store, false)
+	if err != nil {
+		return nil, err
+	}
+	id := newCheckoutID()
+	r := request{path: path, key: id, body: orderRequest{CheckoutID: id, Amount: defaultAmount}, timeout: maxLatency}
+	attempts := []attempt{s.send(ctx, 1, r)}
+	r.body.Amount *= 2
+	attempts = append(attempts, s.send(ctx, 2, r))
+	run, err := s.finish(ctx, &checkoutRun{Action: "reuse_key", CheckoutID: id, Store: store, Attempts: attempts})
+	if err != nil {
+		return nil, err
+	}
+	if attempts[1].Outcome != outcomeMismatch {
+		return nil, fmt.Errorf("key reused with another amount was answered %d", attempts[1].Status)
+	}
+	return run, nil
+}
@@ -0,0 +1,270 @@
+package idempotentapi
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/idempotency"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in idempotent_api.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario()
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "idempotent_api"
+
+	// checkoutPrefix marks the checkouts of the scenario; a checkout ID doubles as the
+	// idempotency key of its requests.
+	checkoutPrefix = "co-"
+	defaultUID     = 1001
+	defaultAmount  = 1999 // cents
+
+	storeMemory = "memory"
+	storeRedis  = "redis"
+	storeMySQL  = "mysql"
+
+	// purgeInterval is how often the expired rows of the MySQL store are deleted.
+	purgeInterval = time.Minute
+)
+
+// logger is used by the payment service, which handles requests outside of any action.
+var logger = logs.For(scenarioID)
+
+var q *query.Query
+
+// backend is an idempotency store.
+type backend struct {
+	store idempotency.Store
+	// clear deletes the responses stored by the scenario.
+	clear func(ctx context.Context) error
+}
+
+// IdempotentAPIScenario holds the handlers of the scenario on duplicate submissions. A
+// payment service in the same process charges orders over HTTP, as rows of
+// payment_order; a client with a timeout calls it and retries, with or without an
+// Idempotency-Key, against each idempotency store.
+type IdempotentAPIScenario struct {
+	backends map[string]backend
+	service  *paymentService
+
+	mu      sync.Mutex
+	last    *checkoutRun // outcome of the last run
+	replays int64        // responses replayed from a store
+	busy    int64        // retries turned away with 409 while the first request ran
+	reused  int64        // keys reused for a different request, answered 422
+	runs    atomic.Int64 // runs of the order handler
+}
+
+func newScenario() *IdempotentAPIScenario {
+	memoryStore := idempotency.NewMemory()
+	s := &IdempotentAPIScenario{backends: map[string]backend{
+		storeMemory: {store: memoryStore, clear: func(ctx context.Context) error {
+			memoryStore.Clear()
+			return nil
+		}},
+	}}
+	s.service = newPaymentService(s)
+	return s
+}
+
+func (s *IdempotentAPIScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Actions: map[string]registry.ActionFunc{
+			"checkout_naive":      s.checkoutNaive,
+			"checkout_idempotent": s.checkoutIdempotent,
+			"double_submit":       s.doubleSubmit,
+			"reuse_key":           s.reuseKey,
+			"create_order":        s.createOrderAction,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database and Redis, creates the stores and starts the
+// payment service.
+func (s *IdempotentAPIScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.PaymentOrder{})
+	if err != nil {
+		return err
+	}
+	q = query.Use(db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	ctx := context.Background()
+	if err := idempotency.CreateMySQLTable(ctx, sqlDB); err != nil {
+		return err
+	}
+	mysqlStore := idempotency.NewMySQL(sqlDB)
+	go mysqlStore.PurgeEvery(ctx, purgeInterval, func(err error) {
+		logger.Warn("Failed to purge expired idempotency records", "error", err)
+	})
+	s.backends[storeMySQL] = backend{store: mysqlStore, clear: func(ctx context.Context) error {
+		_, err := mysqlStore.DeletePrefix(ctx, scenarioID+":")
+		return err
+	}}
+
+	redisClient, err := infra.OpenRedis(ctx)
+	if err != nil {
+		return err
+	}
+	redisStore := idempotency.NewRedis(redisClient, "idempotency:")
+	s.backends[storeRedis] = backend{store: redisStore, clear: func(ctx context.Context) error {
+		_, err := redisStore.DeletePrefix(ctx, scenarioID+":")
+		return err
+	}}
+
+	s.service.insert = func(ctx context.Context, o *model.PaymentOrder) error {
+		return q.PaymentOrder.WithContext(ctx).Create(o)
+	}
+	return s.service.start()
+}
+
+// newOrder returns an order of amount for checkoutID, sent with the idempotency key.
+func newOrder(checkoutID string, amount int64, key string) *model.PaymentOrder {
+	return &model.PaymentOrder{
+		OrderNo:        "ord-" + uuid.New().String()[:8],
+		CheckoutID:     checkoutID,
+		UserID:         defaultUID,
+		Amount:         amount,
+		IdempotencyKey: key,
+	}
+}
+
+func newCheckoutID() string {
+	return checkoutPrefix + uuid.New().String()[:8]
+}
+
+// createOrderAction charges one order directly, without the payment service. Sent with
+// an Idempotency-Key header, the action endpoint itself replays its response to retries.
+func (s *IdempotentAPIScenario) createOrderAction(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	amount, err := intParam(params, "amount", defaultAmount, 1, 1000000)
+	if err != nil {
+		return nil, err
+	}
+	checkoutID, _ := params["checkout_id"].(string)
+	if checkoutID == "" {
+		checkoutID = newCheckoutID()
+	}
+	if len(checkoutID) > 64 {
+		return nil, scenario.InvalidParamsError("checkout_id is longer than 64 characters").WithDetail("field", "checkout_id")
+	}
+	o := newOrder(checkoutID, int64(amount), "")
+	if err := s.service.insert(ctx, o); err != nil {
+		return nil, scenario.DependencyError(err, "failed to create order")
+	}
+	scenario.Logger(ctx).Info("Charged order", "order_no", o.OrderNo, "checkout_id", checkoutID, "amount", o.Amount)
+	return map[string]interface{}{"order_no": o.OrderNo, "checkout_id": checkoutID, "amount": o.Amount}, nil
+}
+
+func (s *IdempotentAPIScenario) FetchState() (map[string]interface{}, error) {
+	ctx := context.Background()
+	o := q.PaymentOrder
+	orders, err := o.WithContext(ctx).Select(o.CheckoutID, o.Amount).Find()
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read orders")
+	}
+	stats := orderStats(orders)
+
+	stores := make([]string, 0, len(s.backends))
+	for name := range s.backends {
+		stores = append(stores, name)
+	}
+	sort.Strings(stores)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := map[string]interface{}{
+		"orders": stats,
+		"idempotency": map[string]interface{}{
+			"handler_runs":      s.runs.Load(),
+			"replayed":          s.replays,
+			"rejected_busy":     s.busy,
+			"rejected_mismatch": s.reused,
+			"stores":            stores,
+		},
+		"last_run": "no run yet",
+	}
+	if s.last != nil {
+		state["last_run"] = *s.last
+	}
+	return state, nil
+}
+
+// orderStats counts the orders, the checkouts they belong to and the charges beyond
+// the first of each checkout.
+func orderStats(orders []*model.PaymentOrder) map[string]interface{} {
+	checkouts := make(map[string]bool, len(orders))
+	var charged, overcharged int64
+	for _, o := range orders {
+		charged += o.Amount
+		if checkouts[o.CheckoutID] {
+			overcharged += o.Amount
+		}
+		checkouts[o.CheckoutID] = true
+	}
+	return map[string]interface{}{
+		"orders":            len(orders),
+		"checkouts":         len(checkouts),
+		"duplicate_charges": len(orders) - len(checkouts),
+		"charged":           charged,
+		"overcharged":       overcharged,
+	}
+}
+
+// resetState deletes the orders and the stored responses and clears the counters.
+func (s *IdempotentAPIScenario) resetState(ctx context.Context) error {
+	if _, err := q.PaymentOrder.WithContext(ctx).Where(q.PaymentOrder.ID.Gt(0)).Delete(); err != nil {
+		return scenario.DependencyError(err, "failed to delete orders")
+	}
+	for name, b := range s.backends {
+		if err := b.clear(ctx); err != nil {
+			return scenario.DependencyError(err, "failed to clear the %s store", name)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = nil
+	s.replays, s.busy, s.reused = 0, 0, 0
+	s.runs.Store(0)
+	return nil
+}
+
+// intParam returns the integer param name, or def if it is absent.
+func intParam(params map[string]interface{}, name string, def, min, max int) (int, error) {
+	v := def
+	switch p := params[name].(type) {
+	case nil:
+	case float64:
+		v = int(p)
+	case int:
+		v = p
+	default:
+		return 0, scenario.InvalidParamsError("%s must be a number", name).WithDetail("field", name)
+	}
+	if v < min || v > max {
+		return 0, scenario.InvalidParamsError("%s must be between %d and %d", name, min, max).WithDetail("field", name)
+	}
+	return v, nil
+}
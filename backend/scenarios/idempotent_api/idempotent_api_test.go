@@ -0,0 +1,125 @@
+package idempotentapi
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// orderBook stands in for the payment_order table.
+type orderBook struct {
+	mu     sync.Mutex
+	orders []*model.PaymentOrder
+}
+
+func (b *orderBook) insert(ctx context.Context, o *model.PaymentOrder) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.orders = append(b.orders, o)
+	return nil
+}
+
+func (b *orderBook) list() []*model.PaymentOrder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*model.PaymentOrder(nil), b.orders...)
+}
+
+// newTestScenario starts the payment service with the memory store only.
+func newTestScenario(t *testing.T) (*IdempotentAPIScenario, *orderBook) {
+	gin.SetMode(gin.TestMode)
+	s := newScenario()
+	book := &orderBook{}
+	s.service.insert = book.insert
+	assert.NoError(t, s.service.start())
+	return s, book
+}
+
+func outcomes(attempts []attempt) []string {
+	var out []string
+	for _, a := range attempts {
+		out = append(out, a.Outcome)
+	}
+	return out
+}
+
+func TestCheckoutWithoutKeyChargesTwice(t *testing.T) {
+	s, book := newTestScenario(t)
+	r := request{path: "/orders/none", body: orderRequest{CheckoutID: "co-1", Amount: 100}, latency: 300 * time.Millisecond, timeout: 100 * time.Millisecond}
+
+	attempts := s.checkout(context.Background(), r, 3)
+	assert.Equal(t, []string{outcomeTimeout, outcomeCreated}, outcomes(attempts))
+	// The first request carries on after the client gave up.
+	assert.Eventually(t, func() bool { return len(book.list()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]interface{}{
+		"orders": 2, "checkouts": 1, "duplicate_charges": 1, "charged": int64(200), "overcharged": int64(100),
+	}, orderStats(book.list()))
+}
+
+func TestCheckoutWithKeyChargesOnce(t *testing.T) {
+	s, book := newTestScenario(t)
+	r := request{path: "/orders/memory", key: "co-2", body: orderRequest{CheckoutID: "co-2", Amount: 100}, latency: 300 * time.Millisecond, timeout: 100 * time.Millisecond}
+
+	// The retry finds the first request running, waits as told and gets its response.
+	attempts := s.checkout(context.Background(), r, 3)
+	assert.Equal(t, []string{outcomeTimeout, outcomeInProgress, outcomeReplayed}, outcomes(attempts))
+	orders := book.list()
+	if assert.Len(t, orders, 1) {
+		assert.Equal(t, orders[0].OrderNo, attempts[2].OrderNo)
+		assert.Equal(t, "co-2", orders[0].IdempotencyKey)
+	}
+
+	// The /wait route holds the retry until the first request is done.
+	r.key, r.body.CheckoutID, r.path, r.timeout = "co-3", "co-3", "/orders/memory/wait", time.Second
+	var wg sync.WaitGroup
+	attempts = make([]attempt, 3)
+	for i := range attempts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			attempts[i] = s.send(context.Background(), i+1, r)
+		}(i)
+	}
+	wg.Wait()
+	assert.ElementsMatch(t, []string{outcomeCreated, outcomeReplayed, outcomeReplayed}, outcomes(attempts))
+	assert.Len(t, book.list(), 2)
+
+	// The key of a paid checkout cannot pay for another amount.
+	r.body.Amount = 200
+	assert.Equal(t, outcomeMismatch, s.send(context.Background(), 1, r).Outcome)
+	assert.Equal(t, int64(1), s.reused)
+	assert.Equal(t, int64(3), s.replays)
+	assert.Equal(t, int64(1), s.busy)
+}
+
+func TestParseCheckoutParams(t *testing.T) {
+	p, err := parseCheckoutParams(map[string]interface{}{"timeout_ms": float64(200), "store": "mysql", "wait_in_flight": true})
+	assert.NoError(t, err)
+	assert.Equal(t, 200*time.Millisecond, p.timeout)
+	assert.Equal(t, 1500*time.Millisecond, p.slow)
+	assert.Equal(t, int64(defaultAmount), p.amount)
+	assert.Equal(t, "mysql", p.store)
+	assert.True(t, p.wait)
+
+	for _, params := range []map[string]interface{}{
+		{"amount": 0},
+		{"timeout_ms": "fast"},
+		{"retries": 11},
+	} {
+		_, err := parseCheckoutParams(params)
+		assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err), "%v", params)
+	}
+
+	s := newScenario()
+	_, err = s.route("etcd", false)
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+	path, err := s.route(storeMemory, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "/orders/memory/wait", path)
+}
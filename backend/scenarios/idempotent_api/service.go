@@ -0,0 +1,121 @@
+package idempotentapi
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/idempotency"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// latencyHeader makes the order handler take that many milliseconds, as if the
+	// database were slow. It is not part of the fingerprint of a request.
+	latencyHeader = "X-Simulated-Latency-Ms"
+	maxLatency    = 10 * time.Second
+
+	// claimTTL is how long a key stays in flight while its request runs; it outlasts
+	// the slowest request.
+	claimTTL = maxLatency + 5*time.Second
+	// waitInFlight is how long a retry on the /wait routes waits for the request in
+	// progress.
+	waitInFlight = maxLatency
+)
+
+// paymentService is the HTTP service that the client of the scenario calls. It listens
+// on a loopback port, so that the client times out on a real connection while the
+// handler carries on, as a server does when its client hangs up.
+//
+//	POST /orders/none          no idempotency
+//	POST /orders/{store}       Idempotency-Key in the store, 409 while in progress
+//	POST /orders/{store}/wait  the same, but retries wait for the request in progress
+type paymentService struct {
+	s       *IdempotentAPIScenario
+	engine  *gin.Engine
+	baseURL string
+	// insert writes an order; Initialize points it at MySQL.
+	insert func(ctx context.Context, o *model.PaymentOrder) error
+}
+
+// orderRequest is the body of POST /orders.
+type orderRequest struct {
+	CheckoutID string `json:"checkout_id" binding:"required"`
+	Amount     int64  `json:"amount" binding:"required"`
+}
+
+// orderResponse is the body of a created order.
+type orderResponse struct {
+	OrderNo    string `json:"order_no"`
+	CheckoutID string `json:"checkout_id"`
+	Amount     int64  `json:"amount"`
+}
+
+func newPaymentService(s *IdempotentAPIScenario) *paymentService {
+	return &paymentService{s: s}
+}
+
+// routes builds the engine from the backends of the scenario.
+func (p *paymentService) routes() *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/orders/none", p.createOrder)
+	for name, b := range p.s.backends {
+		cfg := idempotency.Config{
+			Store:     b.store,
+			Scope:     func(*gin.Context) string { return scenarioID },
+			Retention: time.Hour,
+			ClaimTTL:  claimTTL,
+			Logger:    logger,
+		}
+		r.POST("/orders/"+name, idempotency.Middleware(cfg), p.createOrder)
+		cfg.Wait = waitInFlight
+		r.POST("/orders/"+name+"/wait", idempotency.Middleware(cfg), p.createOrder)
+	}
+	return r
+}
+
+// start serves the routes on a loopback port for as long as the process runs.
+func (p *paymentService) start() error {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to start payment service: %w", err)
+	}
+	p.engine = p.routes()
+	p.baseURL = "http://" + ln.Addr().String()
+	go func() {
+		if err := http.Serve(ln, p.engine); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Error("Payment service stopped", "error", err)
+		}
+	}()
+	logger.Info("Payment service started", "url", p.baseURL)
+	return nil
+}
+
+// createOrder charges the order of a request. It finishes even if the client has given
+// up on the response.
+func (p *paymentService) createOrder(c *gin.Context) {
+	var req orderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.s.runs.Add(1)
+	if ms, _ := strconv.Atoi(c.GetHeader(latencyHeader)); ms > 0 {
+		time.Sleep(min(time.Duration(ms)*time.Millisecond, maxLatency))
+	}
+	key := c.GetHeader(idempotency.DefaultHeader)
+	o := newOrder(req.CheckoutID, req.Amount, key)
+	if err := p.insert(context.WithoutCancel(c.Request.Context()), o); err != nil {
+		logger.Error("Failed to charge order", "checkout_id", req.CheckoutID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logger.Info("Charged order", "order_no", o.OrderNo, "checkout_id", req.CheckoutID, "amount", o.Amount, "idempotency_key", key)
+	c.JSON(http.StatusCreated, orderResponse{OrderNo: o.OrderNo, CheckoutID: o.CheckoutID, Amount: o.Amount})
+}
@@ -0,0 +1,54 @@
+// Package infra connects the scenarios to the MySQL and Redis of the playground, with
+// their queries and commands traced.
+package infra
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+const (
+	mysqlDSN  = "root:rootpassword@tcp(mysql:3306)/playground?charset=utf8mb4&parseTime=True&loc=Local"
+	redisAddr = "redis:6379"
+)
+
+// OpenMySQL opens the playground database and creates the tables of models that are
+// missing. The tables are normally created from pkg/repo/sql.
+func OpenMySQL(models ...interface{}) (*gorm.DB, error) {
+	db, err := gorm.Open(mysql.Open(mysqlDSN), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 gormlogger.Default.LogMode(gormlogger.Warn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	if err := db.Use(telemetry.GormPlugin()); err != nil {
+		return nil, fmt.Errorf("failed to instrument mysql: %w", err)
+	}
+	for _, m := range models {
+		if !db.Migrator().HasTable(m) {
+			if err := db.Migrator().CreateTable(m); err != nil {
+				return nil, fmt.Errorf("failed to create table: %w", err)
+			}
+		}
+	}
+	return db, nil
+}
+
+// OpenRedis connects to the playground Redis.
+func OpenRedis(ctx context.Context) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+	client.AddHook(telemetry.RedisHook())
+	if _, err := client.Ping(ctx).Result(); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,93 @@
+package productcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryCatalog is a Catalog in memory that stands in for web_product in tests. Seed
+// numbers the products from 1000.
+type MemoryCatalog struct {
+	codePrefix string
+
+	mu   sync.Mutex
+	rows map[int64]*Product
+}
+
+// NewMemoryCatalog returns an empty catalog whose products have codes starting with
+// codePrefix.
+func NewMemoryCatalog(codePrefix string) *MemoryCatalog {
+	return &MemoryCatalog{codePrefix: codePrefix, rows: make(map[int64]*Product)}
+}
+
+func (m *MemoryCatalog) Product(ctx context.Context, id int64) (*Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rows[id], nil
+}
+
+func (m *MemoryCatalog) Seed(ctx context.Context, n int) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []int64
+	for i := 0; i < n; i++ {
+		id := int64(1000 + i)
+		m.rows[id] = &Product{ID: id, Code: fmt.Sprintf("%s%d", m.codePrefix, i), Version: 1}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (m *MemoryCatalog) Reset(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows = make(map[int64]*Product)
+	return nil
+}
+
+// MemoryCache is a Cache in memory that stands in for Redis in tests.
+type MemoryCache struct {
+	latency time.Duration
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+// NewMemoryCache returns an empty cache whose reads take latency, as a round trip to
+// Redis does. Zero answers them at once.
+func NewMemoryCache(latency time.Duration) *MemoryCache {
+	return &MemoryCache{latency: latency, values: make(map[string]string), expires: make(map[string]time.Time)}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, bool, error) {
+	time.Sleep(c.latency)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at, ok := c.expires[key]; ok && !time.Now().Before(at) {
+		delete(c.values, key)
+		delete(c.expires, key)
+	}
+	v, ok := c.values[key]
+	return v, ok, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	delete(c.expires, key)
+	if ttl > 0 {
+		c.expires[key] = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (c *MemoryCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values, c.expires = make(map[string]string), make(map[string]time.Time)
+	return nil
+}
@@ -0,0 +1,141 @@
+// Package productcache is the product catalog in web_product and the Redis cache in
+// front of it, shared by the scenarios on cache failures and hot keys. Each scenario
+// keeps its products under a code prefix and its cache keys under a key prefix of its
+// own, so that resetting one leaves the others alone.
+package productcache
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// Product is the cached view of a web_product row.
+type Product struct {
+	ID      int64  `json:"id"`
+	Code    string `json:"code"`
+	Name    string `json:"name"`
+	Version int32  `json:"version"`
+}
+
+// Catalog reads and seeds the products of a scenario.
+type Catalog interface {
+	// Product returns the product with id, or nil if there is none.
+	Product(ctx context.Context, id int64) (*Product, error)
+	// Seed creates the products with the codes prefix0 to prefix(n-1) that are missing
+	// and returns the IDs of all n.
+	Seed(ctx context.Context, n int) ([]int64, error)
+	// Reset deletes the products of the scenario.
+	Reset(ctx context.Context) error
+}
+
+// MySQLCatalog is the Catalog over web_product.
+type MySQLCatalog struct {
+	q          *query.Query
+	codePrefix string
+}
+
+// NewMySQLCatalog returns the catalog of the products in db whose codes start with
+// codePrefix.
+func NewMySQLCatalog(db *gorm.DB, codePrefix string) *MySQLCatalog {
+	return &MySQLCatalog{q: query.Use(db), codePrefix: codePrefix}
+}
+
+func (m *MySQLCatalog) Product(ctx context.Context, id int64) (*Product, error) {
+	p := m.q.WebProduct
+	row, err := p.WithContext(ctx).Where(p.ID.Eq(id)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Product{ID: row.ID, Code: row.Code, Name: row.Name, Version: row.Version}, nil
+}
+
+func (m *MySQLCatalog) Seed(ctx context.Context, n int) ([]int64, error) {
+	p := m.q.WebProduct
+	existing, err := p.WithContext(ctx).Where(p.Code.Like(m.codePrefix + "%")).Find()
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]int64, len(existing))
+	for _, row := range existing {
+		byCode[row.Code] = row.ID
+	}
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		code := fmt.Sprintf("%s%d", m.codePrefix, i)
+		if id, ok := byCode[code]; ok {
+			ids = append(ids, id)
+			continue
+		}
+		row := &model.WebProduct{Code: code, Name: fmt.Sprintf("Product %d", i), Mode: 1, Extra: "{}", Version: 1}
+		if err := p.WithContext(ctx).Create(row); err != nil {
+			return nil, err
+		}
+		ids = append(ids, row.ID)
+	}
+	return ids, nil
+}
+
+func (m *MySQLCatalog) Reset(ctx context.Context) error {
+	p := m.q.WebProduct
+	_, err := p.WithContext(ctx).Unscoped().Where(p.Code.Like(m.codePrefix + "%")).Delete()
+	return err
+}
+
+// Cache is the cache in front of the catalog. Values expire after their TTL; zero keeps
+// them until the cache is cleared.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, bool, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// Clear deletes every key of the scenario.
+	Clear(ctx context.Context) error
+}
+
+// RedisCache is the Cache in Redis. The keys of the scenario are those under its key
+// prefix.
+type RedisCache struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisCache returns the cache in client whose Clear deletes the keys starting with
+// keyPrefix.
+func NewRedisCache(client *redis.Client, keyPrefix string) *RedisCache {
+	return &RedisCache{client: client, keyPrefix: keyPrefix}
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) (string, bool, error) {
+	v, err := r.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	return v, err == nil, err
+}
+
+func (r *RedisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *RedisCache) Clear(ctx context.Context) error {
+	iter := r.client.Scan(ctx, 0, r.keyPrefix+"*", 500).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
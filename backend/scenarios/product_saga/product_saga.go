@@ -0,0 +1,450 @@
+package productsaga
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/saga"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in product_saga.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario()
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "product_saga"
+
+	// codePrefix marks the products created by the scenario, so that reset finds them.
+	codePrefix = "ps-"
+	// quotaType is the product_type of the quota reservations in product_cost.
+	quotaType    = "quota"
+	defaultUID   = 1001
+	defaultQuota = 1000
+
+	approachNaive = "naive"
+	approachSaga  = "saga"
+)
+
+// logger is used for the sagas recovered at startup, which run outside of any action.
+var logger = logs.For(scenarioID)
+
+// ProductSagaScenario holds the handlers of the scenario on keeping a business
+// transaction across services consistent. Creating a product takes three steps in
+// three services: the product in web_product, its quota in product_cost, and a
+// notification to its owner. Run one after another they leave partial products behind
+// when a step fails; run as a saga, the completed steps are compensated.
+type ProductSagaScenario struct {
+	services     services
+	store        saga.Store
+	orchestrator *saga.Orchestrator
+	// clearSagas deletes the sagas of the scenario and their log.
+	clearSagas func(ctx context.Context) error
+
+	mu       sync.Mutex
+	notified map[string]bool // codes of the products whose owner was notified
+	last     *lastRun
+}
+
+// lastRun is the outcome of the last create action.
+type lastRun struct {
+	Approach string   `json:"approach"`
+	Code     string   `json:"code"`
+	SagaID   string   `json:"saga_id,omitempty"`
+	Status   string   `json:"status"`
+	Steps    []string `json:"steps"`
+	Result   string   `json:"result"`
+}
+
+func newScenario() *ProductSagaScenario {
+	return &ProductSagaScenario{notified: make(map[string]bool)}
+}
+
+// use points the scenario at its services and saga store.
+func (s *ProductSagaScenario) use(svc services, store saga.Store, clearSagas func(ctx context.Context) error) {
+	s.services, s.store, s.clearSagas = svc, store, clearSagas
+	s.orchestrator = saga.New(saga.Config{Store: store, Retries: 2, Backoff: 50 * time.Millisecond})
+	s.orchestrator.Register(s.definition())
+}
+
+func (s *ProductSagaScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Actions: map[string]registry.ActionFunc{
+			"create_naive":     s.createNaive,
+			"create_with_saga": s.createWithSaga,
+			"recover_sagas":    s.recoverSagas,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database, creates the saga tables and resumes the sagas
+// that a restart interrupted.
+func (s *ProductSagaScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.WebProduct{}, &model.WebProductUserRelation{}, &model.ProductCost{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	if err := saga.CreateMySQLTables(context.Background(), sqlDB); err != nil {
+		return err
+	}
+	store := saga.NewMySQL(sqlDB)
+	s.use(mysqlServices{q: query.Use(db)}, store, func(ctx context.Context) error {
+		_, err := store.DeleteSaga(ctx, sagaName)
+		return err
+	})
+
+	go func() {
+		n, err := s.orchestrator.Recover(context.Background())
+		if err != nil {
+			logger.Error("Failed to recover sagas", "error", err)
+			return
+		}
+		if n > 0 {
+			logger.Info("Recovered sagas interrupted by a restart", "sagas", n)
+		}
+	}()
+	return nil
+}
+
+// createNaive runs the steps one after another, each in its own local transaction, and
+// stops at the first failure. The steps before it stay applied.
+func (s *ProductSagaScenario) createNaive(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p, err := parseCreateParams(params, false)
+	if err != nil {
+		return nil, err
+	}
+	data := p.data()
+	ctx = withOptions(ctx, runOptions{failAt: p.failAt})
+	run := lastRun{Approach: approachNaive, Code: data["code"]}
+	for i, step := range s.definition().Steps {
+		if err := step.Action(ctx, data); err != nil {
+			run.Steps = append(run.Steps, fmt.Sprintf("%s: failed (%v)", step.Name, err))
+			run.Status = "partial"
+			run.Result = fmt.Sprintf("Step %s failed for %s and nothing undid %s: the product is left half created",
+				step.Name, data["code"], strings.Join(stepNames[:i], " and "))
+			if i == 0 {
+				run.Status = "failed"
+				run.Result = fmt.Sprintf("Step %s failed for %s before anything was applied", step.Name, data["code"])
+			}
+			scenario.Logger(ctx).Warn("Step failed without compensation", "code", data["code"], "step", step.Name, "error", err)
+			s.record(run)
+			if errors.Is(err, errInjected) {
+				return nil, scenario.DemonstratedError("%s", run.Result)
+			}
+			return nil, scenario.DependencyError(err, "step %s failed", step.Name)
+		}
+		run.Steps = append(run.Steps, step.Name+": ok")
+	}
+	run.Status = "completed"
+	run.Result = fmt.Sprintf("Product %s created, quota reserved and owner notified", data["code"])
+	s.record(run)
+	return run.Result, nil
+}
+
+// createWithSaga runs the steps as a saga. When a step fails, the orchestrator undoes
+// the completed steps in reverse order; when the process crashes, the saga stays in
+// saga_instance and recover_sagas finishes it.
+func (s *ProductSagaScenario) createWithSaga(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	p, err := parseCreateParams(params, true)
+	if err != nil {
+		return nil, err
+	}
+	runCtx, crash := context.WithCancel(ctx)
+	defer crash()
+	runCtx = withOptions(runCtx, runOptions{failAt: p.failAt, crashAfter: p.crashAfter, failCompensation: p.failCompensation, crash: crash})
+	data := p.data()
+	inst, err := s.orchestrator.Start(runCtx, sagaName, data)
+	if inst == nil {
+		return nil, scenario.DependencyError(err, "failed to start saga")
+	}
+	// The crash cancelled runCtx only; the log is read with the context of the action.
+	entries, logErr := s.store.Log(ctx, inst.ID)
+	if logErr != nil {
+		return nil, scenario.DependencyError(logErr, "failed to read saga log")
+	}
+	run := lastRun{Approach: approachSaga, Code: data["code"], SagaID: inst.ID, Status: string(inst.Status), Steps: stepLines(entries)}
+	switch {
+	case errors.Is(err, saga.ErrInterrupted):
+		run.Status = "interrupted"
+		run.Result = fmt.Sprintf("The process crashed after %s: saga %s is saved as %s after %d of %d steps, and recover_sagas finishes it",
+			p.crashAfter, short(inst.ID), inst.Status, inst.Done, len(stepNames))
+	case inst.Status == saga.StatusCompleted:
+		run.Result = fmt.Sprintf("Product %s created, quota reserved and owner notified", data["code"])
+	case inst.Status == saga.StatusCompensated:
+		run.Result = fmt.Sprintf("A step failed (%v); the saga undid the steps before it and nothing of %s is left", err, data["code"])
+	case inst.Status == saga.StatusFailed:
+		run.Result = fmt.Sprintf("A step failed and a compensation kept failing: saga %s is marked failed with %d steps still applied, for recover_sagas to retry",
+			short(inst.ID), inst.Done)
+	}
+	scenario.Logger(ctx).Info("Saga finished", "saga_id", inst.ID, "code", data["code"], "status", run.Status)
+	s.record(run)
+	if err != nil && !errors.Is(err, errInjected) && !errors.Is(err, saga.ErrInterrupted) {
+		return nil, scenario.DependencyError(err, "saga %s %s", short(inst.ID), inst.Status)
+	}
+	return run.Result, nil
+}
+
+// recoverSagas resumes the sagas left running or compensating by a crash, and retries
+// the compensations of the failed ones, as an operator would once the services are back.
+func (s *ProductSagaScenario) recoverSagas(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	unfinished, err := s.store.Unfinished(ctx)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read sagas")
+	}
+	outcomes := make(map[string]string, len(unfinished))
+	for _, u := range unfinished {
+		inst, err := s.orchestrator.Resume(ctx, u.ID)
+		switch {
+		case errors.Is(err, saga.ErrBusy):
+			outcomes[short(u.ID)] = "still running"
+		case inst == nil:
+			return nil, scenario.DependencyError(err, "failed to resume saga %s", short(u.ID))
+		default:
+			outcomes[short(u.ID)] = fmt.Sprintf("%s -> %s", u.Status, inst.Status)
+		}
+	}
+	scenario.Logger(ctx).Info("Recovered sagas", "sagas", len(unfinished))
+	return map[string]interface{}{"resumed": len(unfinished), "sagas": outcomes}, nil
+}
+
+// record keeps the outcome of a create action for the dashboard.
+func (s *ProductSagaScenario) record(run lastRun) {
+	s.mu.Lock()
+	s.last = &run
+	s.mu.Unlock()
+}
+
+func (s *ProductSagaScenario) FetchState() (map[string]interface{}, error) {
+	ctx := context.Background()
+	products, err := s.services.products(ctx)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read products")
+	}
+	quotas, err := s.services.quotas(ctx)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read quotas")
+	}
+	recent, err := s.store.Recent(ctx, 100)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read sagas")
+	}
+	sagas := map[string]interface{}{}
+	for _, st := range []saga.Status{saga.StatusRunning, saga.StatusCompleted, saga.StatusCompensating, saga.StatusCompensated, saga.StatusFailed} {
+		sagas[string(st)] = 0
+	}
+	var newest []string
+	for i, inst := range recent {
+		sagas[string(inst.Status)] = sagas[string(inst.Status)].(int) + 1
+		if i < 5 {
+			newest = append(newest, fmt.Sprintf("%s %s %s (%d/%d steps applied)", short(inst.ID), inst.Data["code"], inst.Status, inst.Done, len(stepNames)))
+		}
+	}
+	sagas["newest"] = newest
+
+	s.mu.Lock()
+	notified := make(map[string]bool, len(s.notified))
+	for code := range s.notified {
+		notified[code] = true
+	}
+	var last interface{} = "no run yet"
+	logID := ""
+	if s.last != nil {
+		last, logID = *s.last, s.last.SagaID
+	}
+	s.mu.Unlock()
+
+	// The log of the saga of the last run, or of the newest saga.
+	if logID == "" && len(recent) > 0 {
+		logID = recent[0].ID
+	}
+	sagaLog := []string{}
+	if logID != "" {
+		entries, err := s.store.Log(ctx, logID)
+		if err != nil {
+			return nil, scenario.DependencyError(err, "failed to read saga log")
+		}
+		for _, e := range entries {
+			sagaLog = append(sagaLog, e.At.Format("15:04:05.000")+" "+entryLine(e))
+		}
+	}
+	return map[string]interface{}{
+		"consistency": consistency(products, quotas, notified),
+		"sagas":       sagas,
+		"saga_log":    map[string]interface{}{"saga_id": logID, "entries": sagaLog},
+		"last_run":    last,
+	}, nil
+}
+
+// consistency checks the products against the other services: a product is complete
+// with a quota and a notified owner. Anything else is left over from a partial run.
+func consistency(products []*model.WebProduct, quotas []*model.ProductCost, notified map[string]bool) map[string]interface{} {
+	withQuota := make(map[int64]bool, len(quotas))
+	for _, q := range quotas {
+		withQuota[q.ProductID] = true
+	}
+	live := make(map[int64]bool, len(products))
+	complete := 0
+	withoutQuota, unnotified, orphanQuotas := []string{}, []string{}, []string{}
+	for _, p := range products {
+		live[p.ID] = true
+		switch {
+		case !withQuota[p.ID]:
+			withoutQuota = append(withoutQuota, p.Code)
+		case !notified[p.Code]:
+			unnotified = append(unnotified, p.Code)
+		default:
+			complete++
+		}
+	}
+	for _, q := range quotas {
+		if !live[q.ProductID] {
+			orphanQuotas = append(orphanQuotas, strconv.FormatInt(q.ProductID, 10))
+		}
+	}
+	slices.Sort(withoutQuota)
+	slices.Sort(unnotified)
+	return map[string]interface{}{
+		"products":               len(products),
+		"complete":               complete,
+		"without_quota":          withoutQuota,
+		"owner_not_notified":     unnotified,
+		"quotas_without_product": orphanQuotas,
+		"inconsistent":           len(withoutQuota) + len(unnotified) + len(orphanQuotas),
+	}
+}
+
+// resetState deletes the products, quotas and sagas of the scenario.
+func (s *ProductSagaScenario) resetState(ctx context.Context) error {
+	if err := s.services.reset(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to delete products")
+	}
+	if err := s.clearSagas(ctx); err != nil {
+		return scenario.DependencyError(err, "failed to delete sagas")
+	}
+	s.mu.Lock()
+	s.notified = make(map[string]bool)
+	s.last = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// createParams are the params of the create actions.
+type createParams struct {
+	name             string
+	quota            int64
+	failAt           string
+	crashAfter       string
+	failCompensation bool
+}
+
+// data returns the initial data of a run, with a new product code.
+func (p createParams) data() saga.Data {
+	return saga.Data{
+		"code":    codePrefix + uuid.New().String()[:8],
+		"name":    p.name,
+		"user_id": strconv.Itoa(defaultUID),
+		"quota":   strconv.FormatInt(p.quota, 10),
+	}
+}
+
+// parseCreateParams parses the params of a create action; crash_after and
+// fail_compensation only apply to sagas.
+func parseCreateParams(params map[string]interface{}, withSaga bool) (createParams, error) {
+	p := createParams{quota: defaultQuota}
+	p.name, _ = params["name"].(string)
+	if p.name == "" {
+		p.name = "Product " + time.Now().Format("15:04:05.000")
+	}
+	if len(p.name) > 100 {
+		return p, scenario.InvalidParamsError("name is longer than 100 characters").WithDetail("field", "name")
+	}
+	switch v := params["quota"].(type) {
+	case nil:
+	case float64:
+		p.quota = int64(v)
+	case int:
+		p.quota = int64(v)
+	default:
+		return p, scenario.InvalidParamsError("quota must be a number").WithDetail("field", "quota")
+	}
+	if p.quota <= 0 || p.quota > 1000000 {
+		return p, scenario.InvalidParamsError("quota must be between 1 and 1000000").WithDetail("field", "quota")
+	}
+	var err error
+	if p.failAt, err = stepParam(params, "fail_at"); err != nil {
+		return p, err
+	}
+	if !withSaga {
+		return p, nil
+	}
+	if p.crashAfter, err = stepParam(params, "crash_after"); err != nil {
+		return p, err
+	}
+	p.failCompensation, _ = params["fail_compensation"].(bool)
+	return p, nil
+}
+
+// stepParam returns the step named by the param, or "" for none.
+func stepParam(params map[string]interface{}, name string) (string, error) {
+	v, _ := params[name].(string)
+	if v == "" || v == "none" {
+		return "", nil
+	}
+	if !slices.Contains(stepNames, v) {
+		return "", scenario.InvalidParamsError("%s must be none or one of %s", name, strings.Join(stepNames, ", ")).WithDetail("field", name)
+	}
+	return v, nil
+}
+
+// stepLines renders a saga log for the last run.
+func stepLines(entries []saga.Entry) []string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = entryLine(e)
+	}
+	return lines
+}
+
+func entryLine(e saga.Entry) string {
+	line := fmt.Sprintf("%s %s", e.Phase, e.Step)
+	if e.Attempt > 1 {
+		line += fmt.Sprintf(" (attempt %d)", e.Attempt)
+	}
+	if e.OK {
+		return line + ": ok"
+	}
+	return line + ": failed (" + e.Error + ")"
+}
+
+// short returns the first part of a saga ID, enough to tell sagas apart.
+func short(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,202 @@
+package productsaga
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/saga"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServices stand in for the tables in MySQL.
+type fakeServices struct {
+	mu       sync.Mutex
+	nextID   int64
+	rows     map[int64]*model.WebProduct
+	reserved map[int64]*model.ProductCost // by product ID
+}
+
+func newFakeServices() *fakeServices {
+	return &fakeServices{rows: map[int64]*model.WebProduct{}, reserved: map[int64]*model.ProductCost{}}
+}
+
+func (f *fakeServices) createProduct(ctx context.Context, code, name string, uid int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, p := range f.rows {
+		if p.Code == code {
+			return p.ID, nil
+		}
+	}
+	f.nextID++
+	f.rows[f.nextID] = &model.WebProduct{ID: f.nextID, Code: code, Name: name}
+	return f.nextID, nil
+}
+
+func (f *fakeServices) deleteProduct(ctx context.Context, id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.rows, id)
+	return nil
+}
+
+func (f *fakeServices) reserveQuota(ctx context.Context, productID, uid, quota int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if q, ok := f.reserved[productID]; ok {
+		return q.ID, nil
+	}
+	f.nextID++
+	f.reserved[productID] = &model.ProductCost{ID: f.nextID, ProductID: productID, UserID: uid, ProductType: quotaType, TotalCost7Day: quota}
+	return f.nextID, nil
+}
+
+func (f *fakeServices) releaseQuota(ctx context.Context, productID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.reserved, productID)
+	return nil
+}
+
+func (f *fakeServices) products(ctx context.Context) ([]*model.WebProduct, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*model.WebProduct
+	for _, p := range f.rows {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeServices) quotas(ctx context.Context) ([]*model.ProductCost, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*model.ProductCost
+	for _, q := range f.reserved {
+		out = append(out, q)
+	}
+	return out, nil
+}
+
+func (f *fakeServices) reset(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rows, f.reserved = map[int64]*model.WebProduct{}, map[int64]*model.ProductCost{}
+	return nil
+}
+
+func newTestScenario() (*ProductSagaScenario, *fakeServices) {
+	s := newScenario()
+	svc := newFakeServices()
+	store := saga.NewMemory()
+	s.use(svc, store, func(ctx context.Context) error {
+		store.Clear()
+		return nil
+	})
+	return s, svc
+}
+
+func fetchConsistency(t *testing.T, s *ProductSagaScenario) map[string]interface{} {
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	return state["consistency"].(map[string]interface{})
+}
+
+func TestCreateNaiveLeavesPartialProduct(t *testing.T) {
+	s, _ := newTestScenario()
+	ctx := context.Background()
+
+	_, err := s.createNaive(ctx, map[string]interface{}{"fail_at": stepNotify})
+	assert.Equal(t, scenario.CodeDemonstrated, scenario.CodeOf(err))
+	_, err = s.createNaive(ctx, map[string]interface{}{"fail_at": stepReserveQuota})
+	assert.Equal(t, scenario.CodeDemonstrated, scenario.CodeOf(err))
+	_, err = s.createNaive(ctx, map[string]interface{}{"fail_at": "none"})
+	assert.NoError(t, err)
+
+	c := fetchConsistency(t, s)
+	assert.Equal(t, 3, c["products"])
+	assert.Equal(t, 1, c["complete"])
+	assert.Len(t, c["without_quota"], 1)
+	assert.Len(t, c["owner_not_notified"], 1)
+	assert.Equal(t, 2, c["inconsistent"])
+}
+
+func TestCreateWithSagaCompensates(t *testing.T) {
+	s, svc := newTestScenario()
+	ctx := context.Background()
+
+	for _, step := range stepNames {
+		_, err := s.createWithSaga(ctx, map[string]interface{}{"fail_at": step})
+		assert.NoError(t, err, step)
+		assert.Equal(t, string(saga.StatusCompensated), s.last.Status, step)
+	}
+	assert.Equal(t, []string{
+		"action create_product: ok", "action reserve_quota: ok", "action notify: failed (service unavailable (injected))",
+		"compensate reserve_quota: ok", "compensate create_product: ok",
+	}, s.last.Steps)
+	assert.Empty(t, svc.rows)
+	assert.Empty(t, svc.reserved)
+
+	_, err := s.createWithSaga(ctx, map[string]interface{}{})
+	assert.NoError(t, err)
+	c := fetchConsistency(t, s)
+	assert.Equal(t, 1, c["complete"])
+	assert.Equal(t, 0, c["inconsistent"])
+
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	sagas := state["sagas"].(map[string]interface{})
+	assert.Equal(t, 3, sagas["compensated"])
+	assert.Equal(t, 1, sagas["completed"])
+	assert.Len(t, state["saga_log"].(map[string]interface{})["entries"], 3)
+}
+
+func TestCreateWithSagaRecovers(t *testing.T) {
+	s, svc := newTestScenario()
+	ctx := context.Background()
+
+	// A crash after the first step leaves the saga running.
+	_, err := s.createWithSaga(ctx, map[string]interface{}{"crash_after": stepCreateProduct})
+	assert.NoError(t, err)
+	assert.Equal(t, "interrupted", s.last.Status)
+	// A compensation that keeps failing leaves the saga failed.
+	_, err = s.createWithSaga(ctx, map[string]interface{}{"fail_at": stepNotify, "fail_compensation": true})
+	assert.NoError(t, err)
+	assert.Equal(t, string(saga.StatusFailed), s.last.Status)
+	assert.Equal(t, 2, fetchConsistency(t, s)["inconsistent"])
+
+	out, err := s.recoverSagas(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, out.(map[string]interface{})["resumed"])
+	c := fetchConsistency(t, s)
+	assert.Equal(t, 1, c["complete"])
+	assert.Equal(t, 0, c["inconsistent"])
+	assert.Len(t, svc.reserved, 1)
+
+	assert.NoError(t, s.resetState(ctx))
+	assert.Empty(t, svc.rows)
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	assert.Equal(t, "no run yet", state["last_run"])
+}
+
+func TestParseCreateParams(t *testing.T) {
+	p, err := parseCreateParams(map[string]interface{}{"quota": float64(50), "fail_at": stepReserveQuota, "crash_after": "none"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), p.quota)
+	assert.Equal(t, stepReserveQuota, p.failAt)
+	assert.Empty(t, p.crashAfter)
+
+	for _, params := range []map[string]interface{}{
+		{"quota": 0},
+		{"quota": "lots"},
+		{"fail_at": "pay"},
+		{"crash_after": "notify_owner"},
+	} {
+		_, err := parseCreateParams(params, true)
+		assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err), "%v", params)
+	}
+}
@@ -0,0 +1,130 @@
+package productsaga
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// services are the services that creating a product spans. The catalog owns web_product
+// and its user relation, the quota service owns the product_cost rows of quotaType. Each
+// call is a local transaction of its own; nothing spans two of them.
+type services interface {
+	// createProduct creates the product with code and returns its ID. It returns the
+	// existing product if one with code was created before.
+	createProduct(ctx context.Context, code, name string, uid int64) (int64, error)
+	// deleteProduct deletes the product and its user relation.
+	deleteProduct(ctx context.Context, id int64) error
+	// reserveQuota reserves quota for the product and returns the ID of the reservation.
+	// It returns the existing reservation if there is one.
+	reserveQuota(ctx context.Context, productID, uid, quota int64) (int64, error)
+	// releaseQuota deletes the reservation of the product.
+	releaseQuota(ctx context.Context, productID int64) error
+	// products returns the products of the scenario that are not deleted.
+	products(ctx context.Context) ([]*model.WebProduct, error)
+	// quotas returns the reservations of quotaType.
+	quotas(ctx context.Context) ([]*model.ProductCost, error)
+	// reset deletes the products and reservations of the scenario.
+	reset(ctx context.Context) error
+}
+
+// mysqlServices are the services over the tables in MySQL.
+type mysqlServices struct {
+	q *query.Query
+}
+
+func (m mysqlServices) createProduct(ctx context.Context, code, name string, uid int64) (int64, error) {
+	p := m.q.WebProduct
+	existing, err := p.WithContext(ctx).Where(p.Code.Eq(code)).First()
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	var id int64
+	err = m.q.Transaction(func(tx *query.Query) error {
+		product := &model.WebProduct{Code: code, Name: name, Mode: 1, Extra: "{}", Version: 1}
+		if err := tx.WebProduct.WithContext(ctx).Create(product); err != nil {
+			return err
+		}
+		id = product.ID
+		return tx.WebProductUserRelation.WithContext(ctx).Create(&model.WebProductUserRelation{ProductID: id, UserID: uid})
+	})
+	return id, err
+}
+
+// deleteProduct soft-deletes the product, as the catalog does when a product is
+// withdrawn, and deletes its user relation.
+func (m mysqlServices) deleteProduct(ctx context.Context, id int64) error {
+	return m.q.Transaction(func(tx *query.Query) error {
+		r := tx.WebProductUserRelation
+		if _, err := r.WithContext(ctx).Where(r.ProductID.Eq(id)).Delete(); err != nil {
+			return err
+		}
+		_, err := tx.WebProduct.WithContext(ctx).Where(tx.WebProduct.ID.Eq(id)).Delete()
+		return err
+	})
+}
+
+func (m mysqlServices) reserveQuota(ctx context.Context, productID, uid, quota int64) (int64, error) {
+	c := m.q.ProductCost
+	existing, err := c.WithContext(ctx).Where(c.ProductType.Eq(quotaType), c.ProductID.Eq(productID)).First()
+	if err == nil {
+		return existing.ID, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	row := &model.ProductCost{
+		UserID:        uid,
+		ProductType:   quotaType,
+		ProductID:     productID,
+		PDate:         time.Now().Format("2006-01-02"),
+		TotalCost7Day: quota,
+	}
+	if err := c.WithContext(ctx).Create(row); err != nil {
+		return 0, err
+	}
+	return row.ID, nil
+}
+
+func (m mysqlServices) releaseQuota(ctx context.Context, productID int64) error {
+	c := m.q.ProductCost
+	_, err := c.WithContext(ctx).Where(c.ProductType.Eq(quotaType), c.ProductID.Eq(productID)).Delete()
+	return err
+}
+
+func (m mysqlServices) products(ctx context.Context) ([]*model.WebProduct, error) {
+	p := m.q.WebProduct
+	return p.WithContext(ctx).Select(p.ID, p.Code).Where(p.Code.Like(codePrefix + "%")).Find()
+}
+
+func (m mysqlServices) quotas(ctx context.Context) ([]*model.ProductCost, error) {
+	c := m.q.ProductCost
+	return c.WithContext(ctx).Select(c.ID, c.ProductID, c.TotalCost7Day).Where(c.ProductType.Eq(quotaType)).Find()
+}
+
+func (m mysqlServices) reset(ctx context.Context) error {
+	return m.q.Transaction(func(tx *query.Query) error {
+		p, r, c := tx.WebProduct, tx.WebProductUserRelation, tx.ProductCost
+		var ids []int64
+		if err := p.WithContext(ctx).Unscoped().Where(p.Code.Like(codePrefix+"%")).Pluck(p.ID, &ids); err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if _, err := r.WithContext(ctx).Where(r.ProductID.In(ids...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := p.WithContext(ctx).Unscoped().Where(p.ID.In(ids...)).Delete(); err != nil {
+				return err
+			}
+		}
+		_, err := c.WithContext(ctx).Where(c.ProductType.Eq(quotaType)).Delete()
+		return err
+	})
+}
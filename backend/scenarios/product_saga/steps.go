@@ -0,0 +1,156 @@
+package productsaga
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/saga"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	sagaName = "create_product"
+
+	stepCreateProduct = "create_product"
+	stepReserveQuota  = "reserve_quota"
+	stepNotify        = "notify"
+
+	// faultPrefix followed by a step name makes that step fail.
+	faultPrefix = scenarioID + ".fail_"
+	// faultCompensation makes every compensation fail, as if the services were down.
+	faultCompensation = scenarioID + ".fail_compensation"
+)
+
+// stepNames are the steps in the order they run.
+var stepNames = []string{stepCreateProduct, stepReserveQuota, stepNotify}
+
+// errInjected is the error of a step that fails on purpose.
+var errInjected = errors.New("service unavailable (injected)")
+
+// runOptions are the failures asked for by the params of one run. They travel in the
+// context, not in the saga data, so that a recovered saga runs without them.
+type runOptions struct {
+	failAt           string
+	crashAfter       string
+	failCompensation bool
+	// crash cancels the context of the run, as if the process had died.
+	crash context.CancelFunc
+}
+
+type optionsKey struct{}
+
+func withOptions(ctx context.Context, o runOptions) context.Context {
+	return context.WithValue(ctx, optionsKey{}, o)
+}
+
+func optionsOf(ctx context.Context) runOptions {
+	o, _ := ctx.Value(optionsKey{}).(runOptions)
+	return o
+}
+
+// definition returns the saga that creates a product: create it in the catalog,
+// reserve its quota, notify its owner. Notifying is the last step and cannot be undone,
+// so it has no compensation.
+func (s *ProductSagaScenario) definition() saga.Definition {
+	return saga.Definition{Name: sagaName, Steps: []saga.Step{
+		{Name: stepCreateProduct, Action: s.action(stepCreateProduct, s.createProduct), Compensate: s.compensation(s.deleteProduct)},
+		{Name: stepReserveQuota, Action: s.action(stepReserveQuota, s.reserveQuota), Compensate: s.compensation(s.releaseQuota)},
+		{Name: stepNotify, Action: s.action(stepNotify, s.notify)},
+	}}
+}
+
+// action wraps the action of a step with the failure and the crash asked for.
+func (s *ProductSagaScenario) action(name string, fn func(ctx context.Context, data saga.Data) error) func(ctx context.Context, data saga.Data) error {
+	return func(ctx context.Context, data saga.Data) error {
+		o := optionsOf(ctx)
+		if o.failAt == name || fault.EnabledContext(ctx, faultPrefix+name) {
+			return errInjected
+		}
+		if err := fn(ctx, data); err != nil {
+			return err
+		}
+		if o.crashAfter == name && o.crash != nil {
+			o.crash()
+		}
+		return nil
+	}
+}
+
+// compensation wraps a compensation with the failure asked for.
+func (s *ProductSagaScenario) compensation(fn func(ctx context.Context, data saga.Data) error) func(ctx context.Context, data saga.Data) error {
+	return func(ctx context.Context, data saga.Data) error {
+		if optionsOf(ctx).failCompensation || fault.EnabledContext(ctx, faultCompensation) {
+			return errInjected
+		}
+		return fn(ctx, data)
+	}
+}
+
+func (s *ProductSagaScenario) createProduct(ctx context.Context, data saga.Data) error {
+	uid, err := int64Data(data, "user_id")
+	if err != nil {
+		return err
+	}
+	id, err := s.services.createProduct(ctx, data["code"], data["name"], uid)
+	if err != nil {
+		return err
+	}
+	data["product_id"] = strconv.FormatInt(id, 10)
+	return nil
+}
+
+func (s *ProductSagaScenario) deleteProduct(ctx context.Context, data saga.Data) error {
+	id, err := int64Data(data, "product_id")
+	if err != nil {
+		return err
+	}
+	return s.services.deleteProduct(ctx, id)
+}
+
+func (s *ProductSagaScenario) reserveQuota(ctx context.Context, data saga.Data) error {
+	productID, err := int64Data(data, "product_id")
+	if err != nil {
+		return err
+	}
+	uid, err := int64Data(data, "user_id")
+	if err != nil {
+		return err
+	}
+	quota, err := int64Data(data, "quota")
+	if err != nil {
+		return err
+	}
+	id, err := s.services.reserveQuota(ctx, productID, uid, quota)
+	if err != nil {
+		return err
+	}
+	data["quota_id"] = strconv.FormatInt(id, 10)
+	return nil
+}
+
+func (s *ProductSagaScenario) releaseQuota(ctx context.Context, data saga.Data) error {
+	productID, err := int64Data(data, "product_id")
+	if err != nil {
+		return err
+	}
+	return s.services.releaseQuota(ctx, productID)
+}
+
+// notify tells the owner that the product is ready. The notification service keeps one
+// notification per product, so sending it again is harmless.
+func (s *ProductSagaScenario) notify(ctx context.Context, data saga.Data) error {
+	s.mu.Lock()
+	s.notified[data["code"]] = true
+	s.mu.Unlock()
+	logger.Info("Owner notified", "code", data["code"], "user_id", data["user_id"])
+	return nil
+}
+
+func int64Data(data saga.Data, key string) (int64, error) {
+	v, err := strconv.ParseInt(data[key], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("saga data %s: %w", key, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,339 @@
+package ratelimiter
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/ratelimit"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in rate_limiter.scenario.md, which binds to them by name.
+func init() {
+	s := newScenario()
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "rate_limiter"
+
+	backendLocal = "local"
+	backendRedis = "redis"
+
+	defaultLimit    = 10
+	defaultWindowMs = 1000
+)
+
+// settings is the configuration of the live limiters.
+type settings struct {
+	Backend  string `json:"backend"`
+	Limit    int    `json:"limit"`
+	WindowMs int    `json:"window_ms"`
+	// Burst is the capacity of the buckets; zero means the limit.
+	Burst int `json:"burst"`
+}
+
+func (c settings) config(a ratelimit.Algorithm) ratelimit.Config {
+	return ratelimit.Config{Algorithm: a, Limit: c.Limit, Window: c.window(), Burst: c.Burst}
+}
+
+func (c settings) window() time.Duration {
+	return time.Duration(c.WindowMs) * time.Millisecond
+}
+
+// comparison is the outcome of the last simulated pattern.
+type comparison struct {
+	Pattern  string                             `json:"pattern"`
+	Backend  string                             `json:"backend"`
+	Limit    int                                `json:"limit"`
+	WindowMs int                                `json:"window_ms"`
+	Requests int                                `json:"requests"`
+	Results  map[ratelimit.Algorithm]simulation `json:"results"`
+}
+
+// RateLimiterScenario holds the handlers of the scenario comparing rate limiting
+// algorithms. The requests of the workload go through a limiter of every algorithm
+// at once, so that their timelines show how each treats the same traffic; the actions
+// replay patterns of requests on a clock of their own.
+type RateLimiterScenario struct {
+	redis *redis.Client // nil until Initialize
+
+	mu        sync.Mutex
+	settings  settings
+	limiters  map[ratelimit.Algorithm]ratelimit.Limiter
+	timelines map[ratelimit.Algorithm]*timeline
+	last      *comparison
+}
+
+func newScenario() *RateLimiterScenario {
+	s := &RateLimiterScenario{timelines: make(map[ratelimit.Algorithm]*timeline)}
+	for _, a := range ratelimit.Algorithms {
+		s.timelines[a] = &timeline{}
+	}
+	if err := s.configure(settings{Backend: backendLocal, Limit: defaultLimit, WindowMs: defaultWindowMs}); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func (s *RateLimiterScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Workloads: []scenario.Workload{{
+			ID:          "request",
+			Name:        "Request",
+			Description: "Sends a request of one client through a limiter of every algorithm; the guard algorithm decides whether it is served.",
+			Params: []scenario.ActionParam{
+				{Name: "pattern", Type: "string", Description: "bursty (at 10% and 90% of every window) or steady", Default: "bursty"},
+				{Name: "guard", Type: "string", Description: "Algorithm whose decision answers the request", Default: string(ratelimit.FixedWindow)},
+			},
+			Run: s.request,
+		}},
+		Actions: map[string]registry.ActionFunc{
+			"boundary_burst":     s.boundaryBurst,
+			"compare_algorithms": s.compareAlgorithms,
+			"configure":          s.configureAction,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState()
+			},
+		},
+	}
+}
+
+// Initialize connects to Redis, which the redis backend keeps the counters in.
+func (s *RateLimiterScenario) Initialize() error {
+	client, err := infra.OpenRedis(context.Background())
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redis = client
+	return nil
+}
+
+// newLimiters returns a limiter of every algorithm configured by c. Redis limiters keep
+// their counters under prefix.
+func (s *RateLimiterScenario) newLimiters(c settings, prefix string) (map[ratelimit.Algorithm]ratelimit.Limiter, error) {
+	limiters := make(map[ratelimit.Algorithm]ratelimit.Limiter, len(ratelimit.Algorithms))
+	for _, a := range ratelimit.Algorithms {
+		var l ratelimit.Limiter
+		var err error
+		if c.Backend == backendRedis {
+			if s.redis == nil {
+				return nil, scenario.DependencyError(nil, "redis is not connected")
+			}
+			l, err = ratelimit.NewRedis(s.redis, prefix+string(a)+":", c.config(a))
+		} else {
+			l, err = ratelimit.NewLocal(c.config(a))
+		}
+		if err != nil {
+			return nil, scenario.InvalidParamsError("%v", err)
+		}
+		limiters[a] = l
+	}
+	return limiters, nil
+}
+
+// configure replaces the live limiters by fresh ones configured by c and clears the
+// timelines. The counters of Redis limiters live under a new prefix, so nothing of the
+// previous configuration is left to count.
+func (s *RateLimiterScenario) configure(c settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	limiters, err := s.newLimiters(c, "ratelimit:scenario:"+uuid.New().String()[:8]+":")
+	if err != nil {
+		return err
+	}
+	s.settings, s.limiters = c, limiters
+	for _, t := range s.timelines {
+		t.reset()
+	}
+	return nil
+}
+
+// live returns the live limiters and their window.
+func (s *RateLimiterScenario) live() (map[ratelimit.Algorithm]ratelimit.Limiter, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limiters, s.settings.window()
+}
+
+func (s *RateLimiterScenario) configureAction(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	c := s.settings
+	s.mu.Unlock()
+	var err error
+	if backend, ok := params["backend"].(string); ok && backend != "" {
+		if backend != backendLocal && backend != backendRedis {
+			return nil, scenario.InvalidParamsError("backend must be %s or %s", backendLocal, backendRedis).WithDetail("field", "backend")
+		}
+		c.Backend = backend
+	}
+	if c.Limit, err = intParam(params, "limit", c.Limit, 1, 1000); err != nil {
+		return nil, err
+	}
+	if c.WindowMs, err = intParam(params, "window_ms", c.WindowMs, 100, 60000); err != nil {
+		return nil, err
+	}
+	if c.Burst, err = intParam(params, "burst", c.Burst, 0, 10000); err != nil {
+		return nil, err
+	}
+	if err := s.configure(c); err != nil {
+		return nil, err
+	}
+	scenario.Logger(ctx).Info("Configured limiters", "backend", c.Backend, "limit", c.Limit, "window_ms", c.WindowMs, "burst", c.Burst)
+	return c, nil
+}
+
+// boundaryBurst sends a full window's worth of requests just before a boundary of a
+// fixed window and another just after it, on a clock of its own.
+func (s *RateLimiterScenario) boundaryBurst(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	s.mu.Lock()
+	c := s.settings
+	s.mu.Unlock()
+	// The simulation runs in the process; the backend makes no difference to it.
+	c.Backend = backendLocal
+	res, err := s.compare(ctx, c, patternBoundary, []ratelimit.Algorithm{ratelimit.FixedWindow})
+	if err != nil {
+		return nil, err
+	}
+	sim := res.Results[ratelimit.FixedWindow]
+	if sim.Peak <= c.Limit {
+		return res, nil
+	}
+	scenario.Logger(ctx).Warn("Fixed window let a double burst through", "accepted", sim.Accepted, "peak_in_any_window", sim.Peak, "limit", c.Limit)
+	return nil, scenario.DemonstratedError("fixed window accepted %d requests within %dms around a boundary, with a limit of %d per window", sim.Peak, c.WindowMs, c.Limit)
+}
+
+// compareAlgorithms replays a pattern of requests through fresh limiters of every
+// algorithm.
+func (s *RateLimiterScenario) compareAlgorithms(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	pattern, _ := params["pattern"].(string)
+	if pattern == "" {
+		pattern = patternBoundary
+	}
+	known := false
+	for _, p := range patterns {
+		known = known || p == pattern
+	}
+	if !known {
+		return nil, scenario.InvalidParamsError("unknown pattern %q", pattern).WithDetail("field", "pattern")
+	}
+	s.mu.Lock()
+	c := s.settings
+	s.mu.Unlock()
+	res, err := s.compare(ctx, c, pattern, ratelimit.Algorithms)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range ratelimit.Algorithms {
+		sim := res.Results[a]
+		scenario.Logger(ctx).Info("Simulated pattern", "pattern", pattern, "algorithm", a, "accepted", sim.Accepted, "rejected", sim.Rejected, "peak_in_any_window", sim.Peak)
+	}
+	return res, nil
+}
+
+// compare runs pattern through fresh limiters of algorithms and keeps the outcome for
+// the dashboard.
+func (s *RateLimiterScenario) compare(ctx context.Context, c settings, pattern string, algorithms []ratelimit.Algorithm) (*comparison, error) {
+	s.mu.Lock()
+	limiters, err := s.newLimiters(c, "ratelimit:scenario:sim:"+uuid.New().String()[:8]+":")
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	window := c.window()
+	offsets, span := arrivals(pattern, c.Limit, window)
+	// Windows start at multiples of the window; so does the simulation. Redis expires
+	// keys by its own clock, so the simulated clock starts at the present.
+	start := time.Now().Truncate(window).Add(window)
+	res := &comparison{Pattern: pattern, Backend: c.Backend, Limit: c.Limit, WindowMs: c.WindowMs, Requests: len(offsets),
+		Results: make(map[ratelimit.Algorithm]simulation, len(algorithms))}
+	for _, a := range algorithms {
+		sim, err := simulate(ctx, limiters[a], "client", start, offsets, span)
+		if err != nil {
+			return nil, scenario.DependencyError(err, "rate limiter %s failed", a)
+		}
+		res.Results[a] = sim
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = res
+	return res, nil
+}
+
+func (s *RateLimiterScenario) FetchState() (map[string]interface{}, error) {
+	s.mu.Lock()
+	c, last := s.settings, s.last
+	s.mu.Unlock()
+
+	now := time.Now()
+	algorithms := make(map[string]interface{}, len(ratelimit.Algorithms))
+	timelines := make(map[string]interface{}, len(ratelimit.Algorithms))
+	for _, a := range ratelimit.Algorithms {
+		st := s.timelines[a].stats(now, c.window())
+		timelines[string(a)] = st["per_second"]
+		delete(st, "per_second")
+		algorithms[string(a)] = st
+	}
+	state := map[string]interface{}{
+		"algorithms": algorithms,
+		"timeline":   timelines,
+		"config":     c,
+		"simulation": "no simulation yet",
+	}
+	if last != nil {
+		state["simulation"] = *last
+	}
+	return state, nil
+}
+
+// resetState restores the default configuration and forgets the requests.
+func (s *RateLimiterScenario) resetState() error {
+	s.mu.Lock()
+	backend := s.settings.Backend
+	s.last = nil
+	s.mu.Unlock()
+	return s.configure(settings{Backend: backend, Limit: defaultLimit, WindowMs: defaultWindowMs})
+}
+
+// algorithmParam returns the algorithm named by the param name, or def if it is absent.
+func algorithmParam(params map[string]interface{}, name string, def ratelimit.Algorithm) (ratelimit.Algorithm, error) {
+	v, _ := params[name].(string)
+	if v == "" {
+		return def, nil
+	}
+	for _, a := range ratelimit.Algorithms {
+		if ratelimit.Algorithm(v) == a {
+			return a, nil
+		}
+	}
+	return "", scenario.InvalidParamsError("unknown algorithm %q", v).WithDetail("field", name)
+}
+
+// intParam returns the integer param name, or def if it is absent.
+func intParam(params map[string]interface{}, name string, def, min, max int) (int, error) {
+	v := def
+	switch p := params[name].(type) {
+	case nil:
+	case float64:
+		v = int(p)
+	case int:
+		v = p
+	default:
+		return 0, scenario.InvalidParamsError("%s must be a number", name).WithDetail("field", name)
+	}
+	if v < min || v > max {
+		return 0, scenario.InvalidParamsError("%s must be between %d and %d", name, min, max).WithDetail("field", name)
+	}
+	return v, nil
+}
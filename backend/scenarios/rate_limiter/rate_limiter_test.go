@@ -0,0 +1,85 @@
+package ratelimiter
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/ratelimit"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundaryBurst(t *testing.T) {
+	s := newScenario()
+	_, err := s.boundaryBurst(context.Background(), nil)
+	assert.True(t, scenario.IsDemonstrated(err), "%v", err)
+	assert.Equal(t, 2*defaultLimit, s.last.Results[ratelimit.FixedWindow].Peak)
+}
+
+func TestCompareAlgorithms(t *testing.T) {
+	s := newScenario()
+	out, err := s.compareAlgorithms(context.Background(), map[string]interface{}{"pattern": patternBoundary})
+	assert.NoError(t, err)
+	res := out.(*comparison)
+	assert.Equal(t, 2*defaultLimit, res.Requests)
+	for _, a := range ratelimit.Algorithms {
+		want := defaultLimit
+		if a == ratelimit.FixedWindow {
+			want = 2 * defaultLimit
+		}
+		assert.Equal(t, want, res.Results[a].Peak, a)
+	}
+
+	// A burst passes a token bucket at once, and a leaky bucket at the steady rate.
+	out, err = s.compareAlgorithms(context.Background(), map[string]interface{}{"pattern": patternBurst})
+	assert.NoError(t, err)
+	res = out.(*comparison)
+	assert.Zero(t, res.Results[ratelimit.TokenBucket].MaxDelayMs)
+	assert.Equal(t, "10 0 0 0 0 0 0 0 0 0 0 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1 1", res.Results[ratelimit.TokenBucket].Timeline)
+	assert.Equal(t, int64(900), res.Results[ratelimit.LeakyBucket].MaxDelayMs)
+	assert.LessOrEqual(t, res.Results[ratelimit.LeakyBucket].Peak, defaultLimit+1)
+
+	_, err = s.compareAlgorithms(context.Background(), map[string]interface{}{"pattern": "flood"})
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+}
+
+func TestRequestIsAnsweredByGuard(t *testing.T) {
+	s := newScenario()
+	_, err := s.configureAction(context.Background(), map[string]interface{}{"limit": float64(3), "window_ms": float64(60000)})
+	assert.NoError(t, err)
+	params := map[string]interface{}{"pattern": patternSteady, "guard": string(ratelimit.SlidingLog)}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.request(context.Background(), params))
+	}
+	err = s.request(context.Background(), params)
+	assert.Equal(t, scenario.CodeRateLimited, scenario.CodeOf(err))
+
+	state, err := s.FetchState()
+	assert.NoError(t, err)
+	algorithms := state["algorithms"].(map[string]interface{})
+	for _, a := range ratelimit.Algorithms {
+		st := algorithms[string(a)].(map[string]interface{})
+		assert.Equal(t, 4, st["accepted"].(int)+st["rejected"].(int), a)
+	}
+	assert.Equal(t, 1, algorithms[string(ratelimit.SlidingLog)].(map[string]interface{})["rejected"])
+
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(s.request(context.Background(), map[string]interface{}{"guard": "gcra"})))
+}
+
+func TestConfigure(t *testing.T) {
+	s := newScenario()
+	_, err := s.configureAction(context.Background(), map[string]interface{}{"limit": float64(0)})
+	assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err))
+	// Redis is not connected in tests.
+	_, err = s.configureAction(context.Background(), map[string]interface{}{"backend": backendRedis})
+	assert.Equal(t, scenario.CodeDependencyUnavailable, scenario.CodeOf(err))
+	assert.Equal(t, backendLocal, s.settings.Backend)
+}
+
+func TestNextBurst(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, start.Add(100*time.Millisecond), nextBurst(start, time.Second))
+	assert.Equal(t, start.Add(900*time.Millisecond), nextBurst(start.Add(100*time.Millisecond), time.Second))
+	assert.Equal(t, start.Add(1100*time.Millisecond), nextBurst(start.Add(950*time.Millisecond), time.Second))
+}
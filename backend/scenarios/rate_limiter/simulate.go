@@ -0,0 +1,122 @@
+package ratelimiter
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/ratelimit"
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	patternBoundary = "boundary"
+	patternBurst    = "burst"
+	patternSteady   = "steady"
+
+	// buckets is the number of timeline buckets per window.
+	buckets = 10
+)
+
+var patterns = []string{patternBoundary, patternBurst, patternSteady}
+
+// arrivals returns the times of the requests of pattern, as offsets from the start of a
+// window, and the length of the simulation.
+//
+//	boundary  limit requests in the last 5% of a window, limit more in the first 5% of the next
+//	burst     3*limit requests at once, then the steady rate for two windows
+//	steady    twice the rate for three windows
+func arrivals(pattern string, limit int, window time.Duration) ([]time.Duration, time.Duration) {
+	var out []time.Duration
+	switch pattern {
+	case patternBoundary:
+		step := window / 20 / time.Duration(limit)
+		for i := 0; i < limit; i++ {
+			out = append(out, window-window/20+time.Duration(i)*step)
+		}
+		for i := 0; i < limit; i++ {
+			out = append(out, window+time.Duration(i)*step)
+		}
+		return out, 2 * window
+	case patternBurst:
+		for i := 0; i < 3*limit; i++ {
+			out = append(out, 0)
+		}
+		step := window / time.Duration(limit)
+		for i := 1; i <= 2*limit; i++ {
+			out = append(out, window+time.Duration(i)*step)
+		}
+		return out, 3 * window
+	default:
+		step := window / time.Duration(2*limit)
+		for i := 0; i < 6*limit; i++ {
+			out = append(out, time.Duration(i)*step)
+		}
+		return out, 3 * window
+	}
+}
+
+// simulation is the outcome of a pattern under one algorithm.
+type simulation struct {
+	Accepted int `json:"accepted"`
+	Rejected int `json:"rejected"`
+	// Peak is the most requests accepted in any interval of one window.
+	Peak int `json:"peak_in_any_window"`
+	// MaxDelayMs is the longest a leaky bucket held a request.
+	MaxDelayMs int64 `json:"max_delay_ms,omitempty"`
+	// Timeline counts the accepted requests per tenth of a window, by when they were
+	// served.
+	Timeline string `json:"timeline"`
+}
+
+// simulate runs the requests at the offsets from start through l, on a clock of its
+// own, and summarizes the outcome.
+func simulate(ctx context.Context, l ratelimit.Limiter, key string, start time.Time, offsets []time.Duration, span time.Duration) (simulation, error) {
+	window := l.Config().Window
+	var sim simulation
+	var served []time.Duration
+	for _, at := range offsets {
+		res, err := l.Allow(ctx, key, start.Add(at))
+		if err != nil {
+			return sim, err
+		}
+		if !res.Allowed {
+			sim.Rejected++
+			continue
+		}
+		sim.Accepted++
+		served = append(served, at+res.Delay)
+		sim.MaxDelayMs = max(sim.MaxDelayMs, res.Delay.Milliseconds())
+	}
+	sortDurations(served)
+	sim.Peak = peak(served, window)
+
+	counts := make([]int, int(span/(window/buckets)))
+	for _, at := range served {
+		if i := int(at / (window / buckets)); i < len(counts) {
+			counts[i]++
+		}
+	}
+	parts := make([]string, len(counts))
+	for i, n := range counts {
+		parts[i] = strconv.Itoa(n)
+	}
+	sim.Timeline = strings.Join(parts, " ")
+	return sim, nil
+}
+
+// peak returns the most of the sorted times that fall in any interval of length window.
+func peak(sorted []time.Duration, window time.Duration) int {
+	most, j := 0, 0
+	for i := range sorted {
+		for sorted[i]-sorted[j] >= window {
+			j++
+		}
+		most = max(most, i-j+1)
+	}
+	return most
+}
+
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
@@ -0,0 +1,155 @@
+package ratelimiter
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/ratelimit"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// loadClient is the client of every request of the workload: the load generator
+	// stands for one client sending too much.
+	loadClient = "load"
+
+	// history is how long the outcomes of live requests are kept, and maxEvents how
+	// many per algorithm.
+	history   = time.Minute
+	maxEvents = 50000
+	// timelineSeconds is the number of seconds shown on the dashboard.
+	timelineSeconds = 10
+)
+
+// event is the outcome of a live request under one algorithm.
+type event struct {
+	at time.Time // when it was decided, or served if a leaky bucket delayed it
+	ok bool
+}
+
+// timeline keeps the outcomes of the live requests of one algorithm, oldest first.
+type timeline struct {
+	mu     sync.Mutex
+	events []event
+}
+
+func (t *timeline) add(e event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = append(t.events, e)
+	if len(t.events) > maxEvents || (len(t.events) > 0 && e.at.Sub(t.events[0].at) > 2*history) {
+		t.trim(e.at)
+	}
+}
+
+// trim drops the events older than history and keeps at most maxEvents.
+func (t *timeline) trim(now time.Time) {
+	i := 0
+	for i < len(t.events) && now.Sub(t.events[i].at) > history {
+		i++
+	}
+	i = max(i, len(t.events)-maxEvents)
+	t.events = append([]event(nil), t.events[i:]...)
+}
+
+// stats summarizes the events of the last minute and the seconds up to now.
+func (t *timeline) stats(now time.Time, window time.Duration) map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trim(now)
+	accepted, rejected := 0, 0
+	var served []time.Duration
+	seconds := make([]map[string]interface{}, timelineSeconds)
+	first := now.Truncate(time.Second).Add(-(timelineSeconds - 1) * time.Second)
+	for i := range seconds {
+		seconds[i] = map[string]interface{}{"second": first.Add(time.Duration(i) * time.Second).Format("15:04:05"), "accepted": 0, "rejected": 0}
+	}
+	for _, e := range t.events {
+		if e.ok {
+			accepted++
+			served = append(served, e.at.Sub(now))
+		} else {
+			rejected++
+		}
+		if i := int(e.at.Sub(first) / time.Second); i >= 0 && i < len(seconds) {
+			if e.ok {
+				seconds[i]["accepted"] = seconds[i]["accepted"].(int) + 1
+			} else {
+				seconds[i]["rejected"] = seconds[i]["rejected"].(int) + 1
+			}
+		}
+	}
+	// Delayed requests are served out of order.
+	sortDurations(served)
+	return map[string]interface{}{
+		"accepted":           accepted,
+		"rejected":           rejected,
+		"peak_in_any_window": peak(served, window),
+		"per_second":         seconds,
+	}
+}
+
+func (t *timeline) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = nil
+}
+
+// nextBurst returns when the next burst of the bursty pattern starts after now: bursts
+// come at 10% and 90% of every window, so that every other pair straddles a boundary.
+func nextBurst(now time.Time, window time.Duration) time.Time {
+	start := now.Truncate(window)
+	for _, at := range []time.Time{start.Add(window / 10), start.Add(window - window/10), start.Add(window + window/10)} {
+		if at.After(now) {
+			return at
+		}
+	}
+	return start.Add(window + window/10)
+}
+
+// request sends one request of the workload through the limiters of every algorithm,
+// so that their timelines show the same traffic. The request is answered by the
+// algorithm named by guard: it fails with rate_limited if that one rejects it, and
+// waits in the queue if it is the leaky bucket.
+func (s *RateLimiterScenario) request(ctx context.Context, params map[string]interface{}) error {
+	guard, err := algorithmParam(params, "guard", ratelimit.FixedWindow)
+	if err != nil {
+		return err
+	}
+	limiters, window := s.live()
+	if pattern, _ := params["pattern"].(string); pattern != patternSteady {
+		if err := sleep(ctx, time.Until(nextBurst(time.Now(), window))); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	var answer ratelimit.Result
+	for _, a := range ratelimit.Algorithms {
+		res, err := limiters[a].Allow(ctx, loadClient, now)
+		if err != nil {
+			return scenario.DependencyError(err, "rate limiter %s failed", a)
+		}
+		s.timelines[a].add(event{at: now.Add(res.Delay), ok: res.Allowed})
+		if a == guard {
+			answer = res
+		}
+	}
+	if !answer.Allowed {
+		return scenario.RateLimitedError("rejected by %s, retry in %dms", guard, answer.RetryAfter.Milliseconds())
+	}
+	return sleep(ctx, answer.Delay)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,9 +3,9 @@ package simplecrud
 import (
 	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
 	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -103,7 +103,7 @@ func CheckBeforeChangeProductName(ctx context.Context, uid int64, code string, n
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			fmt.Printf("Record Not Found, Name is %v", name)
+			scenario.Logger(ctx).Debug("Record not found", "name", name)
 			return true, err
 		}
 		return false, err
@@ -0,0 +1,149 @@
+package transmsg
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// billingGroup charges a setup fee for every new product, as a product_cost row.
+	billingGroup = "billing"
+	setupFeeType = "setup_fee"
+	setupFee     = 100
+
+	maxReplay = 50
+)
+
+// charge is the mq.Handler of the billing group. Unlike indexing, a charge is not
+// idempotent by itself: every delivery would insert another row. With deduplication on,
+// the message ID is recorded with mq.MarkConsumed in the transaction of the charge, so
+// a duplicate finds its ID taken and charges nothing, and no crash can separate the two.
+func (s *TransMsgScenario) charge(ctx context.Context, msg *mq.Message) error {
+	var ev productEvent
+	if err := json.Unmarshal(msg.Body, &ev); err != nil {
+		logger.Error("Dropping undecodable product event", "consumer", billingGroup, "message_id", msg.ID, "error", err)
+		return nil
+	}
+	dedup := !s.dedupOff.Load()
+	duplicate := false
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		if dedup {
+			conn := tx.ProductCost.WithContext(ctx).UnderlyingDB().Statement.ConnPool
+			first, err := mq.MarkConsumed(ctx, conn, billingGroup, msg.ID)
+			if err != nil {
+				return err
+			}
+			if !first {
+				duplicate = true
+				return nil
+			}
+		}
+		p, err := tx.WebProduct.WithContext(ctx).Where(tx.WebProduct.Code.Eq(ev.Code)).First()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn("No product to charge", "code", ev.Code, "message_id", msg.ID)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return tx.ProductCost.WithContext(ctx).Create(&model.ProductCost{
+			UserID:        ev.UserID,
+			ProductType:   setupFeeType,
+			ProductID:     p.ID,
+			PDate:         time.Now().Format("2006-01-02"),
+			TotalCost7Day: setupFee,
+		})
+	})
+	if err != nil {
+		logger.Error("Failed to charge setup fee", "code", ev.Code, "message_id", msg.ID, "error", err)
+		return err
+	}
+	if duplicate {
+		s.billingDuplicates.Add(1)
+		logger.Info("Skipped duplicate product event", "consumer", billingGroup, "message_id", msg.ID, "key", msg.Key)
+		return nil
+	}
+	logger.Info("Charged setup fee", "code", ev.Code, "message_id", msg.ID, "deliveries", msg.Deliveries)
+	return nil
+}
+
+// withDedup returns a handler that runs deduped, or raw while deduplication is off.
+func (s *TransMsgScenario) withDedup(deduped, raw mq.Handler) mq.Handler {
+	return func(ctx context.Context, msg *mq.Message) error {
+		if s.dedupOff.Load() {
+			return raw(ctx, msg)
+		}
+		return deduped(ctx, msg)
+	}
+}
+
+// billingStats counts the setup fees charged for the products with the given IDs.
+func billingStats(ctx context.Context, productIDs []int64) (map[string]interface{}, error) {
+	var charged []int64
+	if len(productIDs) > 0 {
+		c := query.ProductCost
+		if err := c.WithContext(ctx).Where(c.ProductType.Eq(setupFeeType), c.ProductID.In(productIDs...)).Pluck(c.ProductID, &charged); err != nil {
+			return nil, scenario.DependencyError(err, "failed to read charges")
+		}
+	}
+	distinct := make(map[int64]bool, len(charged))
+	for _, id := range charged {
+		distinct[id] = true
+	}
+	return map[string]interface{}{
+		"charges":           len(charged),
+		"products_charged":  len(distinct),
+		"duplicate_charges": len(charged) - len(distinct),
+		"total_fees":        len(charged) * setupFee,
+	}, nil
+}
+
+// replayBatch publishes the newest messages of the outbox again under their IDs, as a
+// broker does after a consumer offset is rewound or a rebalance. dedup switches the
+// deduplication of the consumers on or off until the next replay or reset.
+func (s *TransMsgScenario) replayBatch(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	count := 5
+	switch v := params["count"].(type) {
+	case nil:
+	case float64:
+		count = int(v)
+	case int:
+		count = v
+	default:
+		return nil, scenario.InvalidParamsError("count must be a number").WithDetail("field", "count")
+	}
+	if count < 1 || count > maxReplay {
+		return nil, scenario.InvalidParamsError("count must be between 1 and %d", maxReplay).WithDetail("field", "count")
+	}
+	dedup := true
+	if v, ok := params["dedup"].(bool); ok {
+		dedup = v
+	}
+	s.dedupOff.Store(!dedup)
+
+	o := query.OutboxMessage
+	rows, err := o.WithContext(ctx).Where(o.Topic.Eq(productTopic), o.Status.Eq(outboxSent)).Order(o.ID.Desc()).Limit(count).Find()
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read outbox")
+	}
+	if len(rows) == 0 {
+		return nil, scenario.ConflictError("no sent outbox messages to replay; create products with the outbox first")
+	}
+	for i := len(rows) - 1; i >= 0; i-- {
+		if err := s.publish(ctx, outboxMessage(rows[i])); err != nil {
+			return nil, scenario.DependencyError(err, "failed to replay message %s", rows[i].MessageID)
+		}
+	}
+	if dedup {
+		return fmt.Sprintf("Replayed %d messages; the consumers drop them as duplicates", len(rows)), nil
+	}
+	return nil, scenario.DemonstratedError("Replayed %d messages with deduplication off; billing charges their products again", len(rows))
+}
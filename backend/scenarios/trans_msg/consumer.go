@@ -0,0 +1,66 @@
+package transmsg
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// searchIndex is a consumer of the product events: a search index of product names by
+// code, standing in for a downstream service. Applying an event twice only writes the
+// same document again, but it still counts every application, so that duplicates that
+// got past deduplication show up on the dashboard.
+type searchIndex struct {
+	mu         sync.Mutex
+	docs       map[string]string // product name by code
+	applied    int64
+	duplicates int64 // events dropped by deduplication
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{docs: make(map[string]string)}
+}
+
+// handle is the mq.Handler of the search indexer group.
+func (i *searchIndex) handle(ctx context.Context, msg *mq.Message) error {
+	var ev productEvent
+	if err := json.Unmarshal(msg.Body, &ev); err != nil {
+		// A message that cannot be decoded never will be; retrying it is pointless.
+		logger.Error("Dropping undecodable product event", "message_id", msg.ID, "error", err)
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.docs[ev.Code] = ev.Name
+	i.applied++
+	logger.Info("Indexed product", "code", ev.Code, "message_id", msg.ID, "deliveries", msg.Deliveries)
+	return nil
+}
+
+// skipped is the mq.Dedup.OnDuplicate callback of the indexer.
+func (i *searchIndex) skipped(ctx context.Context, msg *mq.Message) {
+	i.mu.Lock()
+	i.duplicates++
+	i.mu.Unlock()
+	logger.Info("Skipped duplicate product event", "consumer", indexerGroup, "message_id", msg.ID, "key", msg.Key)
+}
+
+// snapshot returns a copy of the indexed documents and the message counts.
+func (i *searchIndex) snapshot() (docs map[string]string, applied, duplicates int64) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	docs = make(map[string]string, len(i.docs))
+	for k, v := range i.docs {
+		docs[k] = v
+	}
+	return docs, i.applied, i.duplicates
+}
+
+func (i *searchIndex) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.docs = make(map[string]string)
+	i.applied, i.duplicates = 0, 0
+}
@@ -0,0 +1,127 @@
+package transmsg
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Approaches to publishing the product.created event, as shown on the dashboard.
+const (
+	approachNaive       = "naive"
+	approachOutbox      = "outbox"
+	approachHalfMessage = "half_message"
+)
+
+// createWithHalfMessage publishes the event as a transactional message: it sends a half
+// message that consumers do not see yet, creates the product in the local transaction,
+// and then commits the message. If the commit never arrives, the broker checks back and
+// checkProduct tells it whether the product exists.
+func (s *TransMsgScenario) createWithHalfMessage(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	name, uid, err := parseCreateParams(params)
+	if err != nil {
+		return nil, err
+	}
+	crash := crashAfterCommit(ctx, params)
+	if s.brokerUnavailable(ctx) {
+		return nil, scenario.DependencyError(mq.ErrUnavailable, "failed to send the half message; no product was created")
+	}
+
+	// The event is sent before the product exists, so its code is chosen up front.
+	code := newProductCode()
+	msg, err := productCreated(&model.WebProduct{Code: code, Name: name}, uid)
+	if err != nil {
+		return nil, err
+	}
+	var txErr error
+	state, err := s.txProducer.PublishTx(ctx, msg, func(ctx context.Context, msg *mq.Message) mq.TxState {
+		txErr = query.Q.Transaction(func(tx *query.Query) error {
+			_, err := createProduct(ctx, tx, code, name, uid)
+			return err
+		})
+		switch {
+		case txErr != nil:
+			return mq.TxRollback
+		case crash:
+			// The process dies before it can tell the broker the outcome.
+			return mq.TxUnknown
+		}
+		return mq.TxCommit
+	})
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to send the half message; no product was created")
+	}
+	if txErr != nil {
+		return nil, scenario.DependencyError(txErr, "failed to create product; the half message was rolled back")
+	}
+
+	result := fmt.Sprintf("Product %s committed and its half message committed", code)
+	if state == mq.TxUnknown {
+		result = fmt.Sprintf("Product %s committed, then the process crashed before committing the half message; the broker checks back and delivers it", code)
+	}
+	s.created(code, approachHalfMessage, result)
+	return result, nil
+}
+
+// checkProduct is the TxChecker of the half messages: the message commits if its
+// product exists. The broker only checks back after a timeout, by which time a local
+// transaction that has not committed has failed or died.
+func (s *TransMsgScenario) checkProduct(ctx context.Context, msg *mq.Message) mq.TxState {
+	var ev productEvent
+	if err := json.Unmarshal(msg.Body, &ev); err != nil {
+		logger.Error("Rolling back undecodable half message", "message_id", msg.ID, "error", err)
+		return mq.TxRollback
+	}
+	p := query.WebProduct
+	_, err := p.WithContext(ctx).Where(p.Code.Eq(ev.Code)).First()
+	switch {
+	case err == nil:
+		logger.Info("Check-back committed half message", "code", ev.Code, "message_id", msg.ID)
+		return mq.TxCommit
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		logger.Info("Check-back rolled back half message", "code", ev.Code, "message_id", msg.ID)
+		return mq.TxRollback
+	}
+	logger.Warn("Check-back failed", "code", ev.Code, "message_id", msg.ID, "error", err)
+	return mq.TxUnknown
+}
+
+// approachStats counts the products of one approach and how many of them the search
+// index knows.
+type approachStats struct {
+	Created    int `json:"created"`
+	Indexed    int `json:"indexed"`
+	NotIndexed int `json:"not_indexed"`
+}
+
+// compareApproaches counts the products in codes by the approach that created them.
+// Products created before the last restart count as "unknown".
+func compareApproaches(codes []string, docs map[string]string, approaches map[string]string) map[string]approachStats {
+	stats := map[string]approachStats{
+		approachNaive:       {},
+		approachOutbox:      {},
+		approachHalfMessage: {},
+	}
+	for _, code := range codes {
+		approach, ok := approaches[code]
+		if !ok {
+			approach = "unknown"
+		}
+		st := stats[approach]
+		st.Created++
+		if _, ok := docs[code]; ok {
+			st.Indexed++
+		} else {
+			st.NotIndexed++
+		}
+		stats[approach] = st
+	}
+	return stats
+}
@@ -0,0 +1,197 @@
+package transmsg
+
+import (
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"context"
+	"fmt"
+	"time"
+)
+
+// Status of an outbox row.
+const (
+	outboxPending int32 = iota
+	outboxSent
+	outboxFailed
+)
+
+const (
+	relayInterval  = 200 * time.Millisecond
+	relayBatchSize = 50
+	// maxAttempts is how often the relay tries to publish a row before marking it failed.
+	maxAttempts = 5
+	baseBackoff = 100 * time.Millisecond
+	maxBackoff  = 5 * time.Second
+
+	maxErrorLen   = 512 // size of outbox_message.last_error
+	recentRowsLen = 10
+)
+
+var statusNames = map[int32]string{outboxPending: "pending", outboxSent: "sent", outboxFailed: "failed"}
+
+// outboxRow returns the pending outbox row that publishes msg.
+func outboxRow(msg *mq.Message) *model.OutboxMessage {
+	return &model.OutboxMessage{
+		MessageID:  msg.ID,
+		Topic:      msg.Topic,
+		MessageKey: msg.Key,
+		Payload:    string(msg.Body),
+		Status:     outboxPending,
+	}
+}
+
+// outboxMessage returns the message that row publishes. Its ID is that of the row, so
+// that consumers recognise a message the relay publishes again.
+func outboxMessage(row *model.OutboxMessage) *mq.Message {
+	return &mq.Message{ID: row.MessageID, Topic: row.Topic, Key: row.MessageKey, Tag: "product.created", Body: []byte(row.Payload)}
+}
+
+// backoff returns how long the relay waits after the given number of failed attempts.
+func backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
+
+// runRelay polls the outbox for as long as the process runs. Only one relay runs here;
+// several would need SELECT ... FOR UPDATE SKIP LOCKED to share the rows, or a binlog
+// reader such as pkg/xdc in place of polling.
+func (s *TransMsgScenario) runRelay() {
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.relayBatch(context.Background()); err != nil {
+			logger.Warn("Outbox relay failed", "error", err)
+		}
+	}
+}
+
+// relayBatch publishes the pending rows that are due and returns how many it marked sent.
+func (s *TransMsgScenario) relayBatch(ctx context.Context) (int, error) {
+	o := query.OutboxMessage
+	rows, err := o.WithContext(ctx).
+		Where(o.Status.Eq(outboxPending), o.NextAttemptAt.Lte(time.Now())).
+		Order(o.ID).Limit(relayBatchSize).Find()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	sent := 0
+	for _, row := range rows {
+		if err := s.publish(ctx, outboxMessage(row)); err != nil {
+			s.relayErrors.Add(1)
+			if err := markFailedAttempt(ctx, row, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		s.published.Add(1)
+		if n := s.relayCrashes.Load(); n > 0 && s.relayCrashes.CompareAndSwap(n, n-1) {
+			// The row stays pending, so the next poll publishes the message again.
+			logger.Warn("Relay crashed after publishing", "message_id", row.MessageID, "key", row.MessageKey)
+			return sent, nil
+		}
+		if _, err := o.WithContext(ctx).Where(o.ID.Eq(row.ID)).
+			UpdateSimple(o.Status.Value(outboxSent), o.Attempts.Add(1), o.LastError.Value("")); err != nil {
+			return sent, fmt.Errorf("failed to mark message %s sent: %w", row.MessageID, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// markFailedAttempt records a failed publish of row and schedules the next attempt, or
+// marks the row failed once it has used up maxAttempts.
+func markFailedAttempt(ctx context.Context, row *model.OutboxMessage, cause error) error {
+	o := query.OutboxMessage
+	attempts := row.Attempts + 1
+	status := outboxPending
+	if attempts >= maxAttempts {
+		status = outboxFailed
+		logger.Error("Outbox message failed", "message_id", row.MessageID, "key", row.MessageKey, "attempts", attempts, "error", cause)
+	}
+	msg := cause.Error()
+	if len(msg) > maxErrorLen {
+		msg = msg[:maxErrorLen]
+	}
+	_, err := o.WithContext(ctx).Where(o.ID.Eq(row.ID)).UpdateSimple(
+		o.Status.Value(status),
+		o.Attempts.Value(attempts),
+		o.LastError.Value(msg),
+		o.NextAttemptAt.Value(time.Now().Add(backoff(attempts))),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record attempt of message %s: %w", row.MessageID, err)
+	}
+	return nil
+}
+
+// retryFailed puts the failed rows back in the queue with their attempts cleared.
+func (s *TransMsgScenario) retryFailed(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	o := query.OutboxMessage
+	res, err := o.WithContext(ctx).Where(o.Topic.Eq(productTopic), o.Status.Eq(outboxFailed)).UpdateSimple(
+		o.Status.Value(outboxPending),
+		o.Attempts.Value(0),
+		o.NextAttemptAt.Value(time.Now()),
+	)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to requeue outbox messages")
+	}
+	return fmt.Sprintf("Requeued %d failed messages", res.RowsAffected), nil
+}
+
+// outboxStats counts the outbox rows of the scenario by status.
+type outboxStats struct {
+	pending, sent, failed int64
+	pendingCodes          []string // product codes of the pending rows
+}
+
+func outboxCounts(ctx context.Context) (outboxStats, error) {
+	o := query.OutboxMessage
+	var stats outboxStats
+	for status, n := range map[int32]*int64{outboxPending: &stats.pending, outboxSent: &stats.sent, outboxFailed: &stats.failed} {
+		count, err := o.WithContext(ctx).Where(o.Topic.Eq(productTopic), o.Status.Eq(status)).Count()
+		if err != nil {
+			return stats, scenario.DependencyError(err, "failed to count outbox messages")
+		}
+		*n = count
+	}
+	if err := o.WithContext(ctx).Where(o.Topic.Eq(productTopic), o.Status.Eq(outboxPending)).Pluck(o.MessageKey, &stats.pendingCodes); err != nil {
+		return stats, scenario.DependencyError(err, "failed to read outbox")
+	}
+	return stats, nil
+}
+
+// outboxEntry is an outbox row as shown on the dashboard.
+type outboxEntry struct {
+	Key           string    `json:"key"`
+	Status        string    `json:"status"`
+	Attempts      int32     `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+}
+
+// recentOutboxRows returns the newest outbox rows of the scenario.
+func recentOutboxRows(ctx context.Context) ([]outboxEntry, error) {
+	o := query.OutboxMessage
+	rows, err := o.WithContext(ctx).Where(o.Topic.Eq(productTopic)).Order(o.ID.Desc()).Limit(recentRowsLen).Find()
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read outbox")
+	}
+	entries := make([]outboxEntry, 0, len(rows))
+	for _, row := range rows {
+		e := outboxEntry{Key: row.MessageKey, Status: statusNames[row.Status], Attempts: row.Attempts, LastError: row.LastError}
+		if row.NextAttemptAt != nil {
+			e.NextAttemptAt = *row.NextAttemptAt
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,484 @@
+package transmsg
+
+import (
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
+	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/pkg/fault"
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/query"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
+	"SYS_DESIGN_PLAYGROUND/scenarios/internal/infra"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// init registers the Go handlers of the scenario. Its metadata, actions and dashboard
+// are defined in trans_msg.scenario.md, which binds to them by name.
+func init() {
+	s := &TransMsgScenario{index: newSearchIndex(), approaches: make(map[string]string)}
+	h := s.handlers()
+	h.Initialize = s.Initialize
+	registry.RegisterHandlers(scenarioID, h)
+}
+
+const (
+	scenarioID = "trans_msg"
+
+	// productTopic carries the product.created events that the search indexer consumes.
+	productTopic    = "product_events"
+	indexerGroup    = "search_indexer"
+	producerGroup   = "trans_msg_producer"
+	txProducerGroup = "trans_msg_tx_producer"
+
+	// codePrefix marks the products created by the scenario, so that reset finds them.
+	codePrefix = "tm-"
+	defaultUID = 1001
+
+	// faultCrashAfterCommit makes the create actions stop between the commit of the
+	// product and the publishing of its event, as if the process had crashed there.
+	faultCrashAfterCommit = "trans_msg.crash_after_commit"
+	// faultBrokerDown makes every publish fail, as if the broker could not be reached.
+	faultBrokerDown = "trans_msg.broker_down"
+
+	// nameserversEnv selects RocketMQ: a comma-separated list of nameserver addresses.
+	// Without it the scenario uses an in-memory broker.
+	nameserversEnv = "PLAYGROUND_MQ_NAMESERVERS"
+)
+
+// logger is used by the relay and the consumers, which run outside of any action.
+var logger = logs.For(scenarioID)
+
+var sqlDB *sql.DB
+
+// TransMsgScenario holds the handlers of the scenario on keeping a database write and
+// the message announcing it consistent. Products are rows of web_product whose code
+// starts with codePrefix; their product.created events are published directly, written
+// to the outbox table or sent as half messages, and consumed by an in-memory search index.
+type TransMsgScenario struct {
+	broker     mq.Broker
+	txProducer mq.TxProducer
+	backend    string
+	index      *searchIndex
+
+	brokerDown   atomic.Bool  // outage toggled by the broker_outage action
+	relayCrashes atomic.Int32 // crashes after publish still to simulate
+
+	published   atomic.Int64 // messages published by the relay
+	relayErrors atomic.Int64 // failed publish attempts of the relay
+
+	indexDedup        *mq.MemoryDedup
+	dedupOff          atomic.Bool  // consumers process duplicates, set by replay_batch
+	billingDuplicates atomic.Int64 // events dropped by the billing consumer
+
+	mu         sync.Mutex
+	lastRun    string            // outcome of the last create action
+	lostSoFar  int64             // events lost by create_naive since the last reset
+	approaches map[string]string // approach by product code, since the last reset
+}
+
+// productEvent is the body of the messages on productTopic.
+type productEvent struct {
+	Type   string `json:"type"`
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	UserID int64  `json:"user_id"`
+}
+
+func (s *TransMsgScenario) handlers() registry.Handlers {
+	return registry.Handlers{
+		FetchState: s.FetchState,
+		Actions: map[string]registry.ActionFunc{
+			"create_naive":             s.createNaive,
+			"create_with_outbox":       s.createWithOutbox,
+			"create_with_half_message": s.createWithHalfMessage,
+			"broker_outage": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				down, _ := params["down"].(bool)
+				s.brokerDown.Store(down)
+				if down {
+					return "Broker is down: publishing fails", nil
+				}
+				return "Broker is back up", nil
+			},
+			"crash_relay": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				s.relayCrashes.Add(1)
+				return "The relay will crash after its next publish, before marking the row sent", nil
+			},
+			"retry_failed": s.retryFailed,
+			"replay_batch": s.replayBatch,
+			"reset": func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				return "State reset", s.resetState(ctx)
+			},
+		},
+	}
+}
+
+// Initialize connects to the database and the broker, subscribes the search indexer and
+// starts the outbox relay.
+func (s *TransMsgScenario) Initialize() error {
+	db, err := infra.OpenMySQL(&model.WebProduct{}, &model.WebProductUserRelation{}, &model.OutboxMessage{}, &model.ProductCost{})
+	if err != nil {
+		return err
+	}
+	query.SetDefault(db)
+	sqlDB, err = db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to mysql: %w", err)
+	}
+	if err := mq.CreateDedupTable(context.Background(), sqlDB); err != nil {
+		return err
+	}
+
+	if ns := os.Getenv(nameserversEnv); ns != "" {
+		s.broker, err = mq.NewRocketMQ(strings.Split(ns, ","), producerGroup)
+		if err != nil {
+			return fmt.Errorf("failed to connect to rocketmq: %w", err)
+		}
+		s.txProducer, err = mq.NewRocketMQTx(strings.Split(ns, ","), txProducerGroup, s.checkProduct)
+		if err != nil {
+			return fmt.Errorf("failed to connect to rocketmq: %w", err)
+		}
+		s.backend = "rocketmq"
+	} else {
+		m := mq.NewMemory(mq.MemoryOptions{})
+		s.broker, s.txProducer = m, m.NewTxProducer(s.checkProduct)
+		s.backend = "memory"
+	}
+	// The index lives in this process, so its dedup store can too; billing writes to
+	// MySQL and records the messages it processed in the same transactions.
+	s.indexDedup = mq.NewMemoryDedup(time.Hour)
+	indexer := mq.Dedup{Store: s.indexDedup, OnDuplicate: s.index.skipped}.Wrap(s.index.handle)
+	if err := s.broker.Subscribe(productTopic, indexerGroup, s.withDedup(indexer, s.index.handle)); err != nil {
+		return fmt.Errorf("failed to subscribe %s: %w", indexerGroup, err)
+	}
+	if err := s.broker.Subscribe(productTopic, billingGroup, s.charge); err != nil {
+		return fmt.Errorf("failed to subscribe %s: %w", billingGroup, err)
+	}
+	go s.runRelay()
+	logger.Info("Outbox relay started", "broker", s.backend)
+	return nil
+}
+
+// createWithOutbox creates a product and writes its event to the outbox in the same
+// transaction. The relay publishes it later, so a crash after the commit loses nothing.
+func (s *TransMsgScenario) createWithOutbox(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	name, uid, err := parseCreateParams(params)
+	if err != nil {
+		return nil, err
+	}
+	code, err := CreateWebProduct(ctx, name, uid)
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to create product")
+	}
+	result := fmt.Sprintf("Product %s committed with its outbox row; the relay publishes the event", code)
+	if crashAfterCommit(ctx, params) {
+		result = fmt.Sprintf("Product %s committed, then the process crashed; the outbox row survives and the relay publishes it", code)
+	}
+	s.created(code, approachOutbox, result)
+	return result, nil
+}
+
+// createNaive commits the product and then publishes its event straight to the broker.
+// A crash or a broker failure between the two leaves a product that no consumer hears of.
+func (s *TransMsgScenario) createNaive(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	name, uid, err := parseCreateParams(params)
+	if err != nil {
+		return nil, err
+	}
+	var p *model.WebProduct
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		p, err = createProduct(ctx, tx, newProductCode(), name, uid)
+		return err
+	})
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to create product")
+	}
+
+	if crashAfterCommit(ctx, params) {
+		return nil, s.lose(p.Code, fmt.Sprintf("Product %s committed, then the process crashed before publishing: the event is lost", p.Code))
+	}
+	msg, err := productCreated(p, uid)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.publish(ctx, msg); err != nil {
+		scenario.Logger(ctx).Warn("Publish after commit failed", "code", p.Code, "error", err)
+		return nil, s.lose(p.Code, fmt.Sprintf("Product %s committed, but publishing failed (%v): the event is lost", p.Code, err))
+	}
+	result := fmt.Sprintf("Product %s committed and its event published", p.Code)
+	s.created(p.Code, approachNaive, result)
+	return result, nil
+}
+
+// lose records an event lost by createNaive and returns the error demonstrating it.
+func (s *TransMsgScenario) lose(code, result string) error {
+	s.created(code, approachNaive, result)
+	s.mu.Lock()
+	s.lostSoFar++
+	s.mu.Unlock()
+	return scenario.DemonstratedError("%s", result)
+}
+
+// created records the approach that created the product code and the outcome of the action.
+func (s *TransMsgScenario) created(code, approach, result string) {
+	s.mu.Lock()
+	s.approaches[code] = approach
+	s.lastRun = result
+	s.mu.Unlock()
+}
+
+// CreateWebProduct creates a product owned by uid and returns its code. The product,
+// its user relation and the product.created event in the outbox are written in one
+// transaction, so either all of them exist or none does.
+func CreateWebProduct(ctx context.Context, name string, uid int64) (string, error) {
+	var productCode string
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		p, err := createProduct(ctx, tx, newProductCode(), name, uid)
+		if err != nil {
+			return err
+		}
+		msg, err := productCreated(p, uid)
+		if err != nil {
+			return err
+		}
+		if err := tx.OutboxMessage.WithContext(ctx).Create(outboxRow(msg)); err != nil {
+			return err
+		}
+		productCode = p.Code
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return productCode, nil
+}
+
+func newProductCode() string {
+	return codePrefix + uuid.New().String()[:8]
+}
+
+// createProduct writes a product with code and its relation to uid within tx.
+func createProduct(ctx context.Context, tx *query.Query, code, name string, uid int64) (*model.WebProduct, error) {
+	p := &model.WebProduct{
+		Code:    code,
+		Name:    name,
+		Mode:    1,
+		Extra:   "{}",
+		Version: 1,
+	}
+	if err := tx.WebProduct.WithContext(ctx).Create(p); err != nil {
+		return nil, err
+	}
+	if err := tx.WebProductUserRelation.WithContext(ctx).Create(&model.WebProductUserRelation{ProductID: p.ID, UserID: uid}); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// productCreated returns the event announcing p.
+func productCreated(p *model.WebProduct, uid int64) (*mq.Message, error) {
+	body, err := json.Marshal(productEvent{Type: "product.created", Code: p.Code, Name: p.Name, UserID: uid})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event of %s: %w", p.Code, err)
+	}
+	return &mq.Message{ID: mq.NewID(), Topic: productTopic, Key: p.Code, Tag: "product.created", Body: body}, nil
+}
+
+// publish sends msg to the broker, unless an outage is simulated.
+func (s *TransMsgScenario) publish(ctx context.Context, msg *mq.Message) error {
+	if s.brokerUnavailable(ctx) {
+		return fmt.Errorf("publish to %s: %w", msg.Topic, mq.ErrUnavailable)
+	}
+	return s.broker.Publish(ctx, msg)
+}
+
+// brokerUnavailable reports whether a broker outage is simulated.
+func (s *TransMsgScenario) brokerUnavailable(ctx context.Context) bool {
+	return s.brokerDown.Load() || fault.EnabledContext(ctx, faultBrokerDown)
+}
+
+// crashAfterCommit reports whether a create action should crash after its commit, as
+// asked by the crash_after_commit param or the fault of the same name.
+func crashAfterCommit(ctx context.Context, params map[string]interface{}) bool {
+	crash, _ := params["crash_after_commit"].(bool)
+	return crash || fault.EnabledContext(ctx, faultCrashAfterCommit)
+}
+
+func parseCreateParams(params map[string]interface{}) (string, int64, error) {
+	name, _ := params["name"].(string)
+	if name == "" {
+		name = "Product " + time.Now().Format("15:04:05.000")
+	}
+	if len(name) > 100 {
+		return "", 0, scenario.InvalidParamsError("name is longer than 100 characters").WithDetail("field", "name")
+	}
+	uid := int64(defaultUID)
+	switch v := params["user_id"].(type) {
+	case nil:
+	case float64:
+		uid = int64(v)
+	case int:
+		uid = int64(v)
+	default:
+		return "", 0, scenario.InvalidParamsError("user_id must be a number").WithDetail("field", "user_id")
+	}
+	if uid <= 0 {
+		return "", 0, scenario.InvalidParamsError("user_id must be positive").WithDetail("field", "user_id")
+	}
+	return name, uid, nil
+}
+
+func (s *TransMsgScenario) FetchState() (map[string]interface{}, error) {
+	ctx := context.Background()
+	p := query.WebProduct
+	products, err := p.WithContext(ctx).Select(p.ID, p.Code).Where(p.Code.Like(codePrefix + "%")).Find()
+	if err != nil {
+		return nil, scenario.DependencyError(err, "failed to read products")
+	}
+	codes := make([]string, len(products))
+	ids := make([]int64, len(products))
+	for i, product := range products {
+		codes[i], ids[i] = product.Code, product.ID
+	}
+	billing, err := billingStats(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	billing["duplicates_skipped"] = s.billingDuplicates.Load()
+	counts, err := outboxCounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := recentOutboxRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, applied, duplicates := s.index.snapshot()
+	var unindexed []string
+	for _, code := range codes {
+		if _, ok := docs[code]; !ok {
+			unindexed = append(unindexed, code)
+		}
+	}
+	// Products whose event still waits in the outbox are late, not missing.
+	missing := subtract(unindexed, counts.pendingCodes)
+
+	s.mu.Lock()
+	lastRun, lost := s.lastRun, s.lostSoFar
+	approaches := compareApproaches(codes, docs, s.approaches)
+	s.mu.Unlock()
+	if lastRun == "" {
+		lastRun = "no run yet"
+	}
+
+	broker := map[string]interface{}{
+		"backend": s.backend,
+		"down":    s.brokerUnavailable(ctx),
+	}
+	if m, ok := s.broker.(*mq.Memory); ok {
+		broker["stats"] = m.Stats()
+	}
+	return map[string]interface{}{
+		"products": map[string]interface{}{
+			"in_database":    len(codes),
+			"in_index":       len(codes) - len(unindexed),
+			"awaiting_relay": len(unindexed) - len(missing),
+			"missing":        missing,
+			"lost_by_naive":  lost,
+		},
+		"outbox": map[string]interface{}{
+			"pending": counts.pending,
+			"sent":    counts.sent,
+			"failed":  counts.failed,
+		},
+		"recent_outbox": recent,
+		"relay": map[string]interface{}{
+			"published":       s.published.Load(),
+			"failed_attempts": s.relayErrors.Load(),
+			"crashes_pending": s.relayCrashes.Load(),
+		},
+		"consumer": map[string]interface{}{
+			"indexed":            len(docs),
+			"applied":            applied,
+			"duplicates_skipped": duplicates,
+			"dedup":              !s.dedupOff.Load(),
+		},
+		"billing":    billing,
+		"approaches": approaches,
+		"broker":     broker,
+		"last_run":   lastRun,
+	}, nil
+}
+
+// resetState deletes the products, charges and outbox rows of the scenario, empties the
+// search index and the dedup records, turns deduplication back on and brings the broker
+// back up.
+func (s *TransMsgScenario) resetState(ctx context.Context) error {
+	p, r, o := query.WebProduct, query.WebProductUserRelation, query.OutboxMessage
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		var ids []int64
+		if err := tx.WebProduct.WithContext(ctx).Unscoped().Where(p.Code.Like(codePrefix+"%")).Pluck(p.ID, &ids); err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if _, err := tx.WebProductUserRelation.WithContext(ctx).Where(r.ProductID.In(ids...)).Delete(); err != nil {
+				return err
+			}
+			if _, err := tx.WebProduct.WithContext(ctx).Unscoped().Where(p.ID.In(ids...)).Delete(); err != nil {
+				return err
+			}
+		}
+		if len(ids) > 0 {
+			c := query.ProductCost
+			if _, err := tx.ProductCost.WithContext(ctx).Where(c.ProductType.Eq(setupFeeType), c.ProductID.In(ids...)).Delete(); err != nil {
+				return err
+			}
+		}
+		_, err := tx.OutboxMessage.WithContext(ctx).Where(o.Topic.Eq(productTopic)).Delete()
+		return err
+	})
+	if err != nil {
+		return scenario.DependencyError(err, "failed to delete products")
+	}
+	if _, err := sqlDB.ExecContext(ctx, "DELETE FROM consumed_message WHERE consumer_group = ?", billingGroup); err != nil {
+		return scenario.DependencyError(err, "failed to delete consumed messages")
+	}
+	s.index.reset()
+	s.indexDedup.Clear()
+	s.dedupOff.Store(false)
+	s.billingDuplicates.Store(0)
+	s.brokerDown.Store(false)
+	s.relayCrashes.Store(0)
+	s.published.Store(0)
+	s.relayErrors.Store(0)
+	s.mu.Lock()
+	s.lastRun, s.lostSoFar = "", 0
+	s.approaches = make(map[string]string)
+	s.mu.Unlock()
+	return nil
+}
+
+// subtract returns the elements of a that are not in b.
+func subtract(a, b []string) []string {
+	drop := make(map[string]bool, len(b))
+	for _, v := range b {
+		drop[v] = true
+	}
+	out := []string{}
+	for _, v := range a {
+		if !drop[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
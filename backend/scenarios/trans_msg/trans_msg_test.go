@@ -1,35 +1,114 @@
 package transmsg
 
 import (
-	"SYS_DESIGN_PLAYGROUND/pkg/repo"
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
+	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
+	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func init() {
-	repo.Init()
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, backoff(1))
+	assert.Equal(t, 200*time.Millisecond, backoff(2))
+	assert.Equal(t, 800*time.Millisecond, backoff(4))
+	assert.Equal(t, maxBackoff, backoff(10))
+	assert.Equal(t, maxBackoff, backoff(100))
 }
 
-func TestValidateWebProduct(t *testing.T) {
-	var (
-		ctx = context.TODO()
+func TestParseCreateParams(t *testing.T) {
+	name, uid, err := parseCreateParams(map[string]interface{}{"name": "Widget", "user_id": float64(7)})
+	assert.NoError(t, err)
+	assert.Equal(t, "Widget", name)
+	assert.Equal(t, int64(7), uid)
 
-		// TODO: 将 uid name 改成随机数 便于重复测试
-		uid1  int64 = 123
-		name1       = "name1"
-		name2       = "name2"
-		name3       = "name3"
-	)
+	name, uid, err = parseCreateParams(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, name)
+	assert.Equal(t, int64(defaultUID), uid)
 
-	code1, err := CreateWebProduct(ctx, name1, uid1)
-	assert.Nil(t, err)
+	for _, params := range []map[string]interface{}{
+		{"user_id": 0},
+		{"user_id": "7"},
+		{"name": string(make([]byte, 101))},
+	} {
+		_, _, err := parseCreateParams(params)
+		assert.Equal(t, scenario.CodeInvalidParams, scenario.CodeOf(err), "%v", params)
+	}
+}
+
+func TestOutboxRow(t *testing.T) {
+	msg, err := productCreated(&model.WebProduct{Code: "tm-1", Name: "Widget"}, 7)
+	assert.NoError(t, err)
+	row := outboxRow(msg)
+	assert.Equal(t, outboxPending, row.Status)
+	assert.Equal(t, "tm-1", row.MessageKey)
+	assert.JSONEq(t, `{"type":"product.created","code":"tm-1","name":"Widget","user_id":7}`, row.Payload)
+
+	// Publishing a row again keeps the ID of the message.
+	again := outboxMessage(row)
+	assert.Equal(t, msg.ID, again.ID)
+	assert.Equal(t, msg.Body, again.Body)
+	assert.Equal(t, productTopic, again.Topic)
+}
+
+func TestSearchIndexSkipsDuplicates(t *testing.T) {
+	ctx := context.Background()
+	broker := mq.NewMemory(mq.MemoryOptions{})
+	defer broker.Close()
+	s := &TransMsgScenario{index: newSearchIndex()}
+	indexer := mq.Dedup{Store: mq.NewMemoryDedup(0), OnDuplicate: s.index.skipped}.Wrap(s.index.handle)
+	assert.NoError(t, broker.Subscribe(productTopic, indexerGroup, s.withDedup(indexer, s.index.handle)))
+
+	msg, err := productCreated(&model.WebProduct{Code: "tm-1", Name: "Widget"}, 7)
+	assert.NoError(t, err)
+	assert.NoError(t, broker.Publish(ctx, msg))
+	assert.NoError(t, broker.Publish(ctx, outboxMessage(outboxRow(msg))))
+	assert.NoError(t, broker.Publish(ctx, &mq.Message{Topic: productTopic, Body: []byte("not json")}))
 
-	code2, err = CreateWebProduct(ctx, name2, uid1)
-	assert.Nil(t, err)
+	// The undecodable message is dropped.
+	handled := func(n int64) func() bool {
+		return func() bool {
+			_, applied, duplicates := s.index.snapshot()
+			return applied+duplicates == n && broker.Stats().Delivered == n+1
+		}
+	}
+	assert.Eventually(t, handled(2), time.Second, time.Millisecond)
+	docs, applied, duplicates := s.index.snapshot()
+	assert.Equal(t, map[string]string{"tm-1": "Widget"}, docs)
+	assert.Equal(t, int64(1), applied)
+	assert.Equal(t, int64(1), duplicates)
+
+	// With deduplication off the duplicate is applied again.
+	s.dedupOff.Store(true)
+	assert.NoError(t, broker.Publish(ctx, outboxMessage(outboxRow(msg))))
+	assert.Eventually(t, handled(3), time.Second, time.Millisecond)
+	_, applied, duplicates = s.index.snapshot()
+	assert.Equal(t, int64(2), applied)
+	assert.Equal(t, int64(1), duplicates)
+
+	s.index.reset()
+	docs, applied, _ = s.index.snapshot()
+	assert.Empty(t, docs)
+	assert.Zero(t, applied)
+}
+
+func TestSubtract(t *testing.T) {
+	assert.Equal(t, []string{"a", "c"}, subtract([]string{"a", "b", "c"}, []string{"b", "d"}))
+	assert.Equal(t, []string{}, subtract(nil, []string{"b"}))
+}
 
-	assert.True(t, ValidateWebProduct(ctx, uid1, code1, name1))
-	assert.False(t, ValidateWebProduct(ctx, uid1, code1, name2))
-	assert.True(t, ValidateWebProduct(ctx, uid1, code1, name3))
+func TestCompareApproaches(t *testing.T) {
+	codes := []string{"tm-1", "tm-2", "tm-3", "tm-4"}
+	docs := map[string]string{"tm-2": "b", "tm-3": "c"}
+	approaches := map[string]string{"tm-1": approachNaive, "tm-2": approachOutbox, "tm-3": approachHalfMessage}
+	assert.Equal(t, map[string]approachStats{
+		approachNaive:       {Created: 1, NotIndexed: 1},
+		approachOutbox:      {Created: 1, Indexed: 1},
+		approachHalfMessage: {Created: 1, Indexed: 1},
+		"unknown":           {Created: 1, NotIndexed: 1},
+	}, compareApproaches(codes, docs, approaches))
 }
@@ -1,8 +1,8 @@
 package xdccachesync
 
 import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
@@ -65,7 +65,7 @@ func (bl *BinlogListener) Start() error {
 		pos := mysql.Position{Name: "", Pos: 4}
 		err := bl.canal.RunFrom(pos)
 		if err != nil {
-			log.Printf("Canal run error: %v", err)
+			logger.Error("Canal run error", "error", err)
 		}
 	}()
 
@@ -115,7 +115,7 @@ func (bl *BinlogListener) OnRow(e *canal.RowsEvent) error {
 	// Convert canal event to our CDC event format
 	for i, row := range e.Rows {
 		cdcEvent := &CDCEvent{
-			Timestamp: time.Now(),
+			Timestamp: eventTime(e.Header),
 			Schema:    e.Table.Schema,
 			Table:     e.Table.Name,
 			Operation: e.Action,
@@ -147,8 +147,9 @@ func (bl *BinlogListener) OnRow(e *canal.RowsEvent) error {
 				case <-bl.stopChan:
 					return nil
 				default:
-					log.Printf("Event channel is full, dropping event")
+					logger.Warn("Event channel is full, dropping event", "table", tableKey)
 				}
+				telemetry.SetQueueDepth(scenarioID, "cdc_events", len(bl.eventChan))
 			}
 			continue
 
@@ -164,14 +165,24 @@ func (bl *BinlogListener) OnRow(e *canal.RowsEvent) error {
 			case <-bl.stopChan:
 				return nil
 			default:
-				log.Printf("Event channel is full, dropping event")
+				logger.Warn("Event channel is full, dropping event", "table", tableKey)
 			}
+			telemetry.SetQueueDepth(scenarioID, "cdc_events", len(bl.eventChan))
 		}
 	}
 
 	return nil
 }
 
+// eventTime returns when the change was written to the binlog, so that the CDC lag
+// includes the replication delay. The binlog only has second precision.
+func eventTime(header *replication.EventHeader) time.Time {
+	if header == nil || header.Timestamp == 0 {
+		return time.Now()
+	}
+	return time.Unix(int64(header.Timestamp), 0)
+}
+
 // OnXID handles transaction commit events
 func (bl *BinlogListener) OnXID(header *replication.EventHeader, nextPos mysql.Position) error {
 	return nil
@@ -1,6 +1,7 @@
 package xdccachesync
 
 import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
 	"sync"
 
 	"github.com/go-redis/redis/v8"
@@ -61,6 +62,35 @@ func (lc *LocalCache) Keys() []string {
 	return keys
 }
 
+// Snapshot copies the cache contents and returns a function that puts the copy back.
+func (lc *LocalCache) Snapshot() (restore func()) {
+	lc.mu.RLock()
+	saved := make(map[string]interface{}, len(lc.data))
+	for k, v := range lc.data {
+		saved[k] = v
+	}
+	lc.mu.RUnlock()
+
+	return func() {
+		lc.mu.Lock()
+		defer lc.mu.Unlock()
+		lc.data = make(map[string]interface{}, len(saved))
+		for k, v := range saved {
+			lc.data[k] = v
+		}
+	}
+}
+
+// SnapshotStats copies the statistics and returns a function that puts the copy back.
+func (cm *CacheManager) SnapshotStats() (restore func()) {
+	saved := cm.GetStats()
+	return func() {
+		cm.mu.Lock()
+		defer cm.mu.Unlock()
+		cm.stats = saved
+	}
+}
+
 func (cm *CacheManager) ResetStats() {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
@@ -68,24 +98,28 @@ func (cm *CacheManager) ResetStats() {
 }
 
 func (cm *CacheManager) IncrementLocalHit() {
+	telemetry.CacheLookup(scenarioID, "local", true)
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	cm.stats.LocalHits++
 }
 
 func (cm *CacheManager) IncrementLocalMiss() {
+	telemetry.CacheLookup(scenarioID, "local", false)
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	cm.stats.LocalMisses++
 }
 
 func (cm *CacheManager) IncrementRedisHit() {
+	telemetry.CacheLookup(scenarioID, "redis", true)
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	cm.stats.RedisHits++
 }
 
 func (cm *CacheManager) IncrementRedisMiss() {
+	telemetry.CacheLookup(scenarioID, "redis", false)
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 	cm.stats.RedisMisses++
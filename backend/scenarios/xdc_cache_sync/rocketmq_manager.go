@@ -1,10 +1,12 @@
 package xdccachesync
 
 import (
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
+	"SYS_DESIGN_PLAYGROUND/pkg/mq"
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/apache/rocketmq-client-go/v2"
 	"github.com/apache/rocketmq-client-go/v2/consumer"
@@ -45,7 +47,7 @@ func (rmq *RocketMQManager) StartProducer() error {
 	return rmq.producer.Start()
 }
 
-func (rmq *RocketMQManager) SendInvalidationMessage(msg *InvalidationMessage) error {
+func (rmq *RocketMQManager) SendInvalidationMessage(ctx context.Context, msg *InvalidationMessage) (err error) {
 	msgBytes, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("failed to marshal invalidation message: %w", err)
@@ -59,8 +61,12 @@ func (rmq *RocketMQManager) SendInvalidationMessage(msg *InvalidationMessage) er
 	// Set message tag for filtering
 	rocketMsg.WithTag("cache_invalidation")
 
+	// Carry the trace context in the message properties so the consumer continues the trace
+	ctx, span := telemetry.StartPublishSpan(ctx, "rocketmq", rmq.topic, messageCarrier{rocketMsg})
+	defer func() { telemetry.EndSpan(span, err) }()
+
 	// Send async message
-	_, err = rmq.producer.SendSync(context.Background(), rocketMsg)
+	_, err = rmq.producer.SendSync(ctx, rocketMsg)
 	if err != nil {
 		return fmt.Errorf("failed to send message: %w", err)
 	}
@@ -68,21 +74,38 @@ func (rmq *RocketMQManager) SendInvalidationMessage(msg *InvalidationMessage) er
 	return nil
 }
 
+// dedupRetention is how long the consumer remembers the invalidation messages it has handled.
+const dedupRetention = time.Hour
+
 func (rmq *RocketMQManager) StartConsuming(handler func(*InvalidationMessage) error) error {
+	// In broadcast mode every instance consumes every message for its own local cache,
+	// so each instance keeps its own record of the messages it has handled. A message
+	// redelivered to the instance is skipped instead of invalidating the cache again.
+	handle := mq.Dedup{
+		Store: mq.NewMemoryDedup(dedupRetention),
+		OnDuplicate: func(ctx context.Context, msg *mq.Message) {
+			logger.Info("Skipped duplicate invalidation message", "msg_id", msg.ID)
+		},
+	}.Wrap(func(ctx context.Context, msg *mq.Message) error {
+		var invalidationMsg InvalidationMessage
+		if err := json.Unmarshal(msg.Body, &invalidationMsg); err != nil {
+			logger.Error("Failed to unmarshal invalidation message", "error", err)
+			return nil
+		}
+		return handler(&invalidationMsg)
+	})
+
 	// Subscribe to topic
 	err := rmq.consumer.Subscribe(rmq.topic, consumer.MessageSelector{
 		Type:       consumer.TAG,
 		Expression: "cache_invalidation",
 	}, func(ctx context.Context, msgs ...*primitive.MessageExt) (consumer.ConsumeResult, error) {
 		for _, msg := range msgs {
-			var invalidationMsg InvalidationMessage
-			if err := json.Unmarshal(msg.Body, &invalidationMsg); err != nil {
-				log.Printf("Failed to unmarshal invalidation message: %v", err)
-				continue
-			}
-
-			if err := handler(&invalidationMsg); err != nil {
-				log.Printf("Failed to handle invalidation message: %v", err)
+			ctx, span := telemetry.StartProcessSpan(ctx, "rocketmq", rmq.topic, messageCarrier{&msg.Message})
+			err := handle(ctx, &mq.Message{ID: msg.MsgId, Topic: msg.Topic, Body: msg.Body, Deliveries: int(msg.ReconsumeTimes) + 1})
+			telemetry.EndSpan(span, err)
+			if err != nil {
+				logger.Error("Failed to handle invalidation message", "error", err)
 				// Continue processing other messages even if one fails
 				continue
 			}
@@ -101,15 +124,37 @@ func (rmq *RocketMQManager) StartConsuming(handler func(*InvalidationMessage) er
 func (rmq *RocketMQManager) Stop() error {
 	if rmq.producer != nil {
 		if err := rmq.producer.Shutdown(); err != nil {
-			log.Printf("Failed to shutdown producer: %v", err)
+			logger.Error("Failed to shutdown producer", "error", err)
 		}
 	}
 
 	if rmq.consumer != nil {
 		if err := rmq.consumer.Shutdown(); err != nil {
-			log.Printf("Failed to shutdown consumer: %v", err)
+			logger.Error("Failed to shutdown consumer", "error", err)
 		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// messageCarrier exposes the properties of a RocketMQ message as an OpenTelemetry TextMapCarrier.
+type messageCarrier struct {
+	msg *primitive.Message
+}
+
+func (c messageCarrier) Get(key string) string {
+	return c.msg.GetProperty(key)
+}
+
+func (c messageCarrier) Set(key, value string) {
+	c.msg.WithProperty(key, value)
+}
+
+func (c messageCarrier) Keys() []string {
+	props := c.msg.GetProperties()
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	return keys
+}
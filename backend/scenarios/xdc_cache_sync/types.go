@@ -1,6 +1,7 @@
 package xdccachesync
 
 import (
+	"context"
 	"time"
 
 	"github.com/apache/rocketmq-client-go/v2"
@@ -50,7 +51,7 @@ type RocketMQManager struct {
 }
 
 type MessageProducer interface {
-	SendInvalidationMessage(msg *InvalidationMessage) error
+	SendInvalidationMessage(ctx context.Context, msg *InvalidationMessage) error
 }
 
 type MessageConsumer interface {
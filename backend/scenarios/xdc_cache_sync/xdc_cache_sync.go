@@ -1,23 +1,33 @@
 package xdccachesync
 
 import (
+	"SYS_DESIGN_PLAYGROUND/internal/logs"
 	"SYS_DESIGN_PLAYGROUND/internal/registry"
+	"SYS_DESIGN_PLAYGROUND/internal/snapshot"
+	"SYS_DESIGN_PLAYGROUND/internal/telemetry"
 	"SYS_DESIGN_PLAYGROUND/pkg/repo/model/model"
 	"SYS_DESIGN_PLAYGROUND/pkg/scenario"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ scenario.Scenario = (*XDCCacheSyncScenario)(nil)
 
+// scenarioID labels the scenario's metrics and logs.
+const scenarioID = "xdc_cache_sync"
+
+// logger is used by the background CDC and RocketMQ processing, which runs outside of any action.
+// Actions log through scenario.Logger(ctx) instead so that their entries carry the action fields.
+var logger = logs.For(scenarioID)
+
 func init() {
 	registry.Register(&XDCCacheSyncScenario{})
 }
@@ -32,14 +42,17 @@ type XDCCacheSyncScenario struct {
 	rocketmqProcessor *CDCEventProcessor
 	rocketmqManager   *RocketMQManager
 
-	mu            sync.RWMutex
-	logs          []string
+	// pendingTraces maps a cache key to the span context of the write that changed it.
+	// The binlog carries no trace context, so the CDC processor uses it to continue
+	// the trace of the action that caused the change.
+	pendingTraces sync.Map
+
 	testProductID int64
 	ctx           context.Context
 }
 
 func (s *XDCCacheSyncScenario) ID() string {
-	return "xdc_cache_sync"
+	return scenarioID
 }
 
 func (s *XDCCacheSyncScenario) Name() string {
@@ -50,6 +63,10 @@ func (s *XDCCacheSyncScenario) Category() string {
 	return "Distributed Systems"
 }
 
+func (s *XDCCacheSyncScenario) Tags() []string {
+	return []string{"cache", "cdc", "mq"}
+}
+
 func (s *XDCCacheSyncScenario) ProblemDescription() string {
 	return "In a multi-DC setup, writes happen in DC A but reads happen in DC B. When data is updated in DC A, the caches (Redis + local) in DC B become stale for up to 30 minutes due to TTL expiration. This causes inconsistent user experience as ToC service in DC B serves outdated data."
 }
@@ -64,7 +81,7 @@ func (s *XDCCacheSyncScenario) DeepDiveLink() string {
 
 func (s *XDCCacheSyncScenario) Actions() []scenario.Action {
 	return []scenario.Action{
-		{ID: "initialize", Name: "Initialize System", Description: "启动 Mysql LocalCache Redis BinlogListener 和 CacheInvalidationEventProcessor"},
+		{ID: "initialize", Name: "Initialize System", Role: "admin", Description: "启动 Mysql LocalCache Redis BinlogListener 和 CacheInvalidationEventProcessor"},
 		{ID: "read_first", Name: "Read First", Description: "创建一条web_product测试数据(如果不存在的话) 读取这条数据 同时确保数据能从 Mysql 填充到 Redis和 LocalCache"},
 		{ID: "update_record", Name: "Update Record", Description: "更新测试数据的 extra 字段 触发mysql 的 Binlog, BinlogListener 会接收这条 Binlog 并转换成 CDCEvent"},
 		{ID: "read_second", Name: "Read Second", Description: "再次读取测试记录 预期会直接读取 Mysql得到最新的结果(同时也会将新结果填充到 Redis 和 Local Cache)"},
@@ -81,10 +98,21 @@ func (s *XDCCacheSyncScenario) DashboardComponents() []scenario.DashboardCompone
 	}
 }
 
+// SnapshotResources returns the product table, both cache tiers and the cache statistics.
+// Restoring the table is itself a binlog event, so the CDC pipeline invalidates the
+// restored product's cache entries shortly afterwards, as it would for any write.
+func (s *XDCCacheSyncScenario) SnapshotResources() []snapshot.Resource {
+	return []snapshot.Resource{
+		snapshot.MySQLTable(s.db, "web_product"),
+		snapshot.RedisKeys(s.redisClient, "web_product:*"),
+		snapshot.Memory("local_cache", s.localCache.Snapshot),
+		snapshot.Memory("cache_stats", s.cacheMgr.SnapshotStats),
+	}
+}
+
 func (s *XDCCacheSyncScenario) Initialize() error {
 	s.ctx = context.Background()
 	s.testProductID = 10001
-	s.logs = make([]string, 0)
 
 	var err error
 	// Connect to MySQL
@@ -95,40 +123,41 @@ func (s *XDCCacheSyncScenario) Initialize() error {
 	if err = s.db.Ping(); err != nil {
 		return fmt.Errorf("failed to ping mysql: %w", err)
 	}
-	s.addLog("Connected to MySQL successfully")
+	logger.Info("Connected to MySQL")
 
 	// Connect to Redis
 	s.redisClient = redis.NewClient(&redis.Options{
 		Addr: "redis:6379",
 	})
+	s.redisClient.AddHook(telemetry.RedisHook())
 	if _, err = s.redisClient.Ping(s.ctx).Result(); err != nil {
 		return fmt.Errorf("failed to connect to redis: %w", err)
 	}
-	s.addLog("Connected to Redis successfully")
+	logger.Info("Connected to Redis")
 
 	// Initialize LocalCache
 	s.localCache = NewLocalCache()
-	s.addLog("LocalCache initialized")
+	logger.Info("LocalCache initialized")
 
 	// Initialize CacheManager
 	s.cacheMgr = NewCacheManager(s.redisClient, s.localCache)
-	s.addLog("CacheManager initialized")
+	logger.Info("CacheManager initialized")
 
 	return nil
 }
 
-func (s *XDCCacheSyncScenario) ExecuteAction(actionID string, params map[string]interface{}) (interface{}, error) {
+func (s *XDCCacheSyncScenario) ExecuteAction(ctx context.Context, actionID string, params map[string]interface{}) (interface{}, error) {
 	switch actionID {
 	case "initialize":
-		return s.initializeSystem()
+		return s.initializeSystem(ctx)
 	case "read_first":
-		return s.readFirst()
+		return s.readFirst(ctx)
 	case "update_record":
-		return s.updateRecord()
+		return s.updateRecord(ctx)
 	case "read_second":
-		return s.readSecond()
+		return s.readSecond(ctx)
 	default:
-		return nil, fmt.Errorf("unknown action: %s", actionID)
+		return nil, scenario.UnknownActionError(actionID)
 	}
 }
 
@@ -136,17 +165,10 @@ func (s *XDCCacheSyncScenario) FetchState() (map[string]interface{}, error) {
 	return nil, nil
 }
 
-func (s *XDCCacheSyncScenario) addLog(message string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	timestamp := time.Now().Format("15:04:05.000")
-	logEntry := fmt.Sprintf("[%s] %s", timestamp, message)
-	s.logs = append(s.logs, logEntry)
-	log.Println(logEntry)
-}
-
-func (s *XDCCacheSyncScenario) initializeSystem() (string, error) {
-	s.addLog("Starting BinlogListener...")
+func (s *XDCCacheSyncScenario) initializeSystem(ctx context.Context) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Starting BinlogListener")
+	scenario.ReportProgress(ctx, 0, "Starting BinlogListener")
 
 	// Create canal config
 	cfg := CreateDefaultCanalConfig()
@@ -155,8 +177,8 @@ func (s *XDCCacheSyncScenario) initializeSystem() (string, error) {
 	var err error
 	s.binlogListener, err = NewBinlogListener(cfg)
 	if err != nil {
-		s.addLog(fmt.Sprintf("Failed to create BinlogListener: %v", err))
-		return "Failed to create BinlogListener", err
+		logger.Error("Failed to create BinlogListener", "error", err)
+		return "Failed to create BinlogListener", scenario.DependencyError(err, "failed to create BinlogListener")
 	}
 
 	// Add table filter for web_product
@@ -164,10 +186,11 @@ func (s *XDCCacheSyncScenario) initializeSystem() (string, error) {
 
 	// Start binlog listener
 	if err := s.binlogListener.Start(); err != nil {
-		s.addLog(fmt.Sprintf("Failed to start BinlogListener: %v", err))
-		return "Failed to start BinlogListener", err
+		logger.Error("Failed to start BinlogListener", "error", err)
+		return "Failed to start BinlogListener", scenario.DependencyError(err, "failed to start BinlogListener")
 	}
-	s.addLog("BinlogListener started successfully")
+	logger.Info("BinlogListener started")
+	scenario.ReportProgress(ctx, 40, "BinlogListener started")
 
 	// Start CDC Event Processor
 	s.rocketmqProcessor = &CDCEventProcessor{
@@ -178,9 +201,10 @@ func (s *XDCCacheSyncScenario) initializeSystem() (string, error) {
 	}
 
 	go s.startCDCEventProcessor()
-	s.addLog("CacheInvalidationEventProcessor started")
+	logger.Info("CacheInvalidationEventProcessor started")
 
 	// Initialize RocketMQ Manager
+	scenario.ReportProgress(ctx, 60, "Connecting to RocketMQ")
 	var err2 error
 	s.rocketmqManager, err2 = NewRocketMQManager(
 		[]string{"rocketmq-nameserver:9876"}, // RocketMQ nameserver address
@@ -188,30 +212,32 @@ func (s *XDCCacheSyncScenario) initializeSystem() (string, error) {
 		"cache_invalidation_group",
 	)
 	if err2 != nil {
-		s.addLog(fmt.Sprintf("Failed to create RocketMQ manager: %v", err2))
-		return "Failed to create RocketMQ manager", err2
+		logger.Error("Failed to create RocketMQ manager", "error", err2)
+		return "Failed to create RocketMQ manager", scenario.DependencyError(err2, "failed to create RocketMQ manager")
 	}
 
 	// Start RocketMQ producer
 	if err2 := s.rocketmqManager.StartProducer(); err2 != nil {
-		s.addLog(fmt.Sprintf("Failed to start RocketMQ producer: %v", err2))
-		return "Failed to start RocketMQ producer", err2
+		logger.Error("Failed to start RocketMQ producer", "error", err2)
+		return "Failed to start RocketMQ producer", scenario.DependencyError(err2, "failed to start RocketMQ producer")
 	}
-	s.addLog("RocketMQ producer started")
+	logger.Info("RocketMQ producer started")
 
 	// Start RocketMQ consumer
 	if err2 := s.rocketmqManager.StartConsuming(s.handleInvalidationMessage); err2 != nil {
-		s.addLog(fmt.Sprintf("Failed to start RocketMQ consumer: %v", err2))
-		return "Failed to start RocketMQ consumer", err2
+		logger.Error("Failed to start RocketMQ consumer", "error", err2)
+		return "Failed to start RocketMQ consumer", scenario.DependencyError(err2, "failed to start RocketMQ consumer")
 	}
-	s.addLog("RocketMQ consumer started")
+	logger.Info("RocketMQ consumer started")
+	scenario.ReportProgress(ctx, 100, "RocketMQ consumer started")
 
 	return "System initialized successfully", nil
 }
 
 // readFirst creates test data and reads it, populating both Redis and LocalCache
-func (s *XDCCacheSyncScenario) readFirst() (string, error) {
-	s.addLog("Creating/Reading test web_product data...")
+func (s *XDCCacheSyncScenario) readFirst(ctx context.Context) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Creating/Reading test web_product data", "product_id", s.testProductID)
 
 	// Create test product if not exists
 	testProduct := &model.WebProduct{
@@ -224,7 +250,7 @@ func (s *XDCCacheSyncScenario) readFirst() (string, error) {
 	}
 
 	// Insert or update test data
-	_, err := s.db.Exec(`
+	_, err := s.db.ExecContext(ctx, `
 		INSERT INTO web_product (id, code, name, mode, extra, version)
 		VALUES (?, ?, ?, ?, ?, ?)
 		ON DUPLICATE KEY UPDATE
@@ -232,69 +258,82 @@ func (s *XDCCacheSyncScenario) readFirst() (string, error) {
 	`, testProduct.ID, testProduct.Code, testProduct.Name, testProduct.Mode, testProduct.Extra, testProduct.Version)
 
 	if err != nil {
-		s.addLog(fmt.Sprintf("Failed to create test data: %v", err))
-		return "Failed to create test data", err
+		logger.Error("Failed to create test data", "error", err)
+		return "Failed to create test data", scenario.DependencyError(err, "failed to create test data")
 	}
-	s.addLog("Test data created/updated in MySQL")
+	logger.Info("Test data created/updated in MySQL")
 
 	// Read from MySQL and populate caches
-	product, err := s.readProductWithCaching(s.testProductID)
+	product, err := s.readProductWithCaching(ctx, s.testProductID)
 	if err != nil {
-		s.addLog(fmt.Sprintf("Failed to read product: %v", err))
-		return "Failed to read product", err
+		logger.Error("Failed to read product", "error", err)
+		return "Failed to read product", scenario.DependencyError(err, "failed to read product")
 	}
 
-	s.addLog(fmt.Sprintf("Product read successfully: %+v", product))
-	s.addLog("Data populated to both Redis and LocalCache")
+	logger.Info("Product read; data populated to both Redis and LocalCache", "product", product)
 
 	return fmt.Sprintf("Product read: %+v", product), nil
 }
 
 // updateRecord updates the extra field to trigger binlog
-func (s *XDCCacheSyncScenario) updateRecord() (string, error) {
-	s.addLog("Updating test product extra field...")
+func (s *XDCCacheSyncScenario) updateRecord(ctx context.Context) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Updating test product extra field", "product_id", s.testProductID)
 
 	newExtra := fmt.Sprintf(`{"description": "Updated test data", "version": 2, "timestamp": "%s"}`, time.Now().Format(time.RFC3339))
 
-	_, err := s.db.Exec(`UPDATE web_product SET extra = ?, version = version + 1 WHERE id = ?`, newExtra, s.testProductID)
+	_, err := s.db.ExecContext(ctx, `UPDATE web_product SET extra = ?, version = version + 1 WHERE id = ?`, newExtra, s.testProductID)
 	if err != nil {
-		s.addLog(fmt.Sprintf("Failed to update product: %v", err))
-		return "Failed to update product", err
+		logger.Error("Failed to update product", "error", err)
+		return "Failed to update product", scenario.DependencyError(err, "failed to update product")
 	}
 
-	s.addLog("Product updated in MySQL, binlog event should be triggered")
-	s.addLog("Waiting for CDC event processing...")
+	s.pendingTraces.Store(fmt.Sprintf("web_product:%d", s.testProductID), trace.SpanContextFromContext(ctx))
+	logger.Info("Product updated in MySQL, binlog event should be triggered")
+	logger.Info("Waiting for CDC event processing")
 
-	// Give some time for binlog processing
-	time.Sleep(2 * time.Second)
+	// Give some time for binlog processing, unless the caller gives up first
+	const wait = 2 * time.Second
+	const tick = 200 * time.Millisecond
+	for waited := time.Duration(0); waited < wait; waited += tick {
+		scenario.ReportProgress(ctx, int(waited*100/wait), "Waiting for CDC event processing...")
+		select {
+		case <-time.After(tick):
+		case <-ctx.Done():
+			logger.Warn("Update cancelled while waiting for CDC event processing")
+			return "Product updated, wait for CDC processing cancelled", ctx.Err()
+		}
+	}
+	scenario.ReportProgress(ctx, 100, "CDC event processing window elapsed")
 
 	return "Product updated, binlog event triggered", nil
 }
 
 // readSecond reads the updated data, should get latest from MySQL
-func (s *XDCCacheSyncScenario) readSecond() (string, error) {
-	s.addLog("Reading product after update...")
+func (s *XDCCacheSyncScenario) readSecond(ctx context.Context) (string, error) {
+	logger := scenario.Logger(ctx)
+	logger.Info("Reading product after update", "product_id", s.testProductID)
 
-	product, err := s.readProductWithCaching(s.testProductID)
+	product, err := s.readProductWithCaching(ctx, s.testProductID)
 	if err != nil {
-		s.addLog(fmt.Sprintf("Failed to read updated product: %v", err))
-		return "Failed to read updated product", err
+		logger.Error("Failed to read updated product", "error", err)
+		return "Failed to read updated product", scenario.DependencyError(err, "failed to read updated product")
 	}
 
-	s.addLog(fmt.Sprintf("Updated product read: %+v", product))
-	s.addLog("Fresh data retrieved from MySQL and cached")
+	logger.Info("Updated product read", "product", product)
 
 	return fmt.Sprintf("Updated product: %+v", product), nil
 }
 
 // readProductWithCaching implements cache-aside pattern
-func (s *XDCCacheSyncScenario) readProductWithCaching(productID int64) (*model.WebProduct, error) {
+func (s *XDCCacheSyncScenario) readProductWithCaching(ctx context.Context, productID int64) (*model.WebProduct, error) {
+	logger := scenario.Logger(ctx)
 	cacheKey := fmt.Sprintf("web_product:%d", productID)
 
 	// 1. Try LocalCache first
 	if val, found := s.localCache.Get(cacheKey); found {
 		s.cacheMgr.IncrementLocalHit()
-		s.addLog("Cache HIT: LocalCache")
+		logger.Info("Cache hit", "tier", "local", "key", cacheKey)
 		if product, ok := val.(*model.WebProduct); ok {
 			return product, nil
 		}
@@ -302,10 +341,10 @@ func (s *XDCCacheSyncScenario) readProductWithCaching(productID int64) (*model.W
 	s.cacheMgr.IncrementLocalMiss()
 
 	// 2. Try Redis
-	val, err := s.redisClient.Get(s.ctx, cacheKey).Result()
+	val, err := s.redisClient.Get(ctx, cacheKey).Result()
 	if err == nil {
 		s.cacheMgr.IncrementRedisHit()
-		s.addLog("Cache HIT: Redis")
+		logger.Info("Cache hit", "tier", "redis", "key", cacheKey)
 
 		var product model.WebProduct
 		if err := json.Unmarshal([]byte(val), &product); err == nil {
@@ -317,12 +356,12 @@ func (s *XDCCacheSyncScenario) readProductWithCaching(productID int64) (*model.W
 
 	// 3. Query MySQL
 	s.cacheMgr.IncrementDBQuery()
-	s.addLog("Cache MISS: Querying MySQL")
+	logger.Info("Cache miss, querying MySQL", "key", cacheKey)
 
 	var product model.WebProduct
 	var createdAt, updatedAt, deletedAt sql.NullTime
 
-	err = s.db.QueryRow(`
+	err = s.db.QueryRowContext(ctx, `
 		SELECT id, code, name, mode, extra, version, created_at, updated_at, deleted_at
 		FROM web_product WHERE id = ?
 	`, productID).Scan(
@@ -343,23 +382,24 @@ func (s *XDCCacheSyncScenario) readProductWithCaching(productID int64) (*model.W
 
 	// 4. Cache the result
 	productJSON, _ := json.Marshal(product)
-	s.redisClient.Set(s.ctx, cacheKey, productJSON, 10*time.Minute)
+	s.redisClient.Set(ctx, cacheKey, productJSON, 10*time.Minute)
 	s.localCache.Set(cacheKey, &product)
-	s.addLog("Data cached to both Redis and LocalCache")
+	logger.Info("Data cached to both Redis and LocalCache", "key", cacheKey)
 
 	return &product, nil
 }
 
 // startCDCEventProcessor processes CDC events from binlog
 func (s *XDCCacheSyncScenario) startCDCEventProcessor() {
-	s.addLog("CDC Event Processor started")
+	logger.Info("CDC Event Processor started")
 
 	for {
 		select {
 		case event := <-s.rocketmqProcessor.eventChan:
+			telemetry.SetQueueDepth(scenarioID, "cdc_events", len(s.rocketmqProcessor.eventChan))
 			s.processCDCEvent(event)
 		case <-s.rocketmqProcessor.stopChan:
-			s.addLog("CDC Event Processor stopped")
+			logger.Info("CDC Event Processor stopped")
 			return
 		}
 	}
@@ -367,7 +407,8 @@ func (s *XDCCacheSyncScenario) startCDCEventProcessor() {
 
 // processCDCEvent handles individual CDC events
 func (s *XDCCacheSyncScenario) processCDCEvent(event *CDCEvent) {
-	s.addLog(fmt.Sprintf("Processing CDC Event: %s %s.%s", event.Operation, event.Schema, event.Table))
+	logger.Info("Processing CDC event", "operation", event.Operation, "table", event.Schema+"."+event.Table)
+	telemetry.ObserveCDCLag(scenarioID, time.Since(event.Timestamp))
 
 	if event.Table != "web_product" {
 		return
@@ -376,17 +417,24 @@ func (s *XDCCacheSyncScenario) processCDCEvent(event *CDCEvent) {
 	// Extract product ID from primary key
 	productID, ok := event.PrimaryKey["id"]
 	if !ok {
-		s.addLog("Failed to extract product ID from CDC event")
+		logger.Warn("Failed to extract product ID from CDC event")
 		return
 	}
 
 	cacheKey := fmt.Sprintf("web_product:%v", productID)
 
+	ctx := s.ctx
+	if sc, ok := s.pendingTraces.LoadAndDelete(cacheKey); ok {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, sc.(trace.SpanContext))
+	}
+	ctx, span := telemetry.Tracer().Start(ctx, "cdc "+event.Operation+" "+event.Table)
+	defer span.End()
+
 	// 1. Delete from Redis
-	if err := s.redisClient.Del(s.ctx, cacheKey).Err(); err != nil {
-		s.addLog(fmt.Sprintf("Failed to delete Redis key %s: %v", cacheKey, err))
+	if err := s.redisClient.Del(ctx, cacheKey).Err(); err != nil {
+		logger.ErrorContext(ctx, "Failed to delete Redis key", "key", cacheKey, "error", err)
 	} else {
-		s.addLog(fmt.Sprintf("Deleted Redis key: %s", cacheKey))
+		logger.InfoContext(ctx, "Deleted Redis key", "key", cacheKey)
 	}
 
 	// Send broadcast message via RocketMQ
@@ -396,25 +444,24 @@ func (s *XDCCacheSyncScenario) processCDCEvent(event *CDCEvent) {
 		Table:     event.Table,
 		Keys:      []string{cacheKey},
 		Version:   "1.0",
-		TraceID:   fmt.Sprintf("cdc-%d", time.Now().UnixNano()),
+		TraceID:   span.SpanContext().TraceID().String(),
 	}
 
-	if err := s.rocketmqManager.SendInvalidationMessage(invalidationMsg); err != nil {
-		s.addLog(fmt.Sprintf("Failed to send invalidation message: %v", err))
+	if err := s.rocketmqManager.SendInvalidationMessage(ctx, invalidationMsg); err != nil {
+		logger.ErrorContext(ctx, "Failed to send invalidation message", "error", err)
 	} else {
-		s.addLog(fmt.Sprintf("Sent invalidation message via RocketMQ: %s", cacheKey))
+		logger.InfoContext(ctx, "Sent invalidation message via RocketMQ", "key", cacheKey)
 	}
 }
 
 // handleInvalidationMessage processes cache invalidation messages from RocketMQ
 func (s *XDCCacheSyncScenario) handleInvalidationMessage(msg *InvalidationMessage) error {
-	s.addLog(fmt.Sprintf("Received invalidation message: table=%s, keys=%v, reason=%s",
-		msg.Table, msg.Keys, msg.Reason))
+	logger.Info("Received invalidation message", "table", msg.Table, "keys", msg.Keys, "reason", msg.Reason)
 
 	// Delete from local cache
 	for _, key := range msg.Keys {
 		s.localCache.Delete(key)
-		s.addLog(fmt.Sprintf("Deleted from LocalCache: %s", key))
+		logger.Info("Deleted from LocalCache", "key", key)
 	}
 
 	return nil
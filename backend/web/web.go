@@ -0,0 +1,27 @@
+// Package web embeds the built frontend so that the playground can be shipped as a
+// single binary. The assets are written to dist by the frontend build:
+//
+//	cd frontend && npm run build:embed
+//
+// A binary built without them serves the API only.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// FS returns the built frontend, or nil if it was not built before the binary.
+func FS() fs.FS {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil
+	}
+	if _, err := fs.Stat(sub, "index.html"); err != nil {
+		return nil
+	}
+	return sub
+}
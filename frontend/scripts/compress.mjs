@@ -0,0 +1,28 @@
+// Writes a .br and a .gz copy next to every text file of a build, so that the Go
+// server can send compressed assets without compressing them on each request.
+// Usage: node scripts/compress.mjs <dir>
+import { readdirSync, readFileSync, statSync, writeFileSync } from 'node:fs';
+import { extname, join } from 'node:path';
+import { brotliCompressSync, gzipSync, constants } from 'node:zlib';
+
+const compressible = new Set(['.html', '.js', '.mjs', '.css', '.json', '.map', '.svg', '.txt', '.xml', '.webmanifest']);
+const minSize = 1024;
+
+const walk = dir => {
+    for (const name of readdirSync(dir)) {
+        const path = join(dir, name);
+        if (statSync(path).isDirectory()) {
+            walk(path);
+            continue;
+        }
+        if (!compressible.has(extname(name))) continue;
+        const data = readFileSync(path);
+        if (data.length < minSize) continue;
+        writeFileSync(`${path}.br`, brotliCompressSync(data, {
+            params: { [constants.BROTLI_PARAM_QUALITY]: constants.BROTLI_MAX_QUALITY },
+        }));
+        writeFileSync(`${path}.gz`, gzipSync(data, { level: 9 }));
+    }
+};
+
+walk(process.argv[2] || 'dist');
@@ -1,23 +1,57 @@
 import React, { useState, useEffect } from 'react';
 import axios from 'axios';
 import ScenarioViewer from './components/ScenarioViewer';
+import { getToken, setToken, withAuth } from './auth';
 
 // Configure axios to proxy requests to the backend container
 const apiClient = axios.create({
     baseURL: '/api',
 });
+apiClient.interceptors.request.use(withAuth);
+
+// TokenBox shows who the caller is and lets them enter an API token, when the server requires one.
+const TokenBox = () => {
+    const [whoami, setWhoami] = useState(null);
+    const [token, setTokenInput] = useState(getToken());
+
+    const refresh = () => {
+        apiClient.get('/auth/whoami')
+            .then(res => setWhoami(res.data))
+            .catch(() => setWhoami({ auth_enabled: true, role: null }));
+    };
+    useEffect(refresh, []);
+
+    if (!whoami || !whoami.auth_enabled) return null;
+    const save = () => {
+        setToken(token.trim());
+        refresh();
+    };
+    return (
+        <div style={{ marginBottom: '15px', fontSize: '0.9em' }}>
+            <div>{whoami.role ? `Signed in as ${whoami.name} (${whoami.role})` : 'Invalid token'}</div>
+            <input
+                type="password"
+                placeholder="API token"
+                value={token}
+                onChange={e => setTokenInput(e.target.value)}
+                style={{ width: '70%', marginRight: '5px' }}
+            />
+            <button onClick={save}>Save</button>
+        </div>
+    );
+};
 
 function App() {
-    const [scenarios, setScenarios] = useState([]);
+    const [categories, setCategories] = useState([]);
     const [selectedScenarioId, setSelectedScenarioId] = useState(null);
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState(null);
 
-    // Fetch all available scenarios on component mount
+    // Fetch the scenarios, grouped by category, on component mount
     useEffect(() => {
-        apiClient.get('/scenarios')
+        apiClient.get('/categories')
             .then(response => {
-                setScenarios(response.data);
+                setCategories(response.data);
                 setLoading(false);
             })
             .catch(err => {
@@ -35,27 +69,34 @@ function App() {
         <div style={{ display: 'flex', minHeight: '100vh' }}>
             <div style={{ width: '300px', borderRight: '1px solid #ddd', padding: '20px' }}>
                 <h3>SYS DESIGN PLAYGROUND</h3>
+                <TokenBox />
                 {loading ? (
                     <div>Loading...</div>
                 ) : (
-                    <ul style={{ listStyle: 'none', padding: 0 }}>
-                        {scenarios.map(s => (
-                            <li key={s.id} style={{ marginBottom: '10px' }}>
-                                <button
-                                    onClick={() => setSelectedScenarioId(s.id)}
-                                    style={{
-                                        width: '100%',
-                                        padding: '10px',
-                                        border: '1px solid #ddd',
-                                        backgroundColor: selectedScenarioId === s.id ? '#f0f0f0' : 'white',
-                                        cursor: 'pointer'
-                                    }}
-                                >
-                                    {s.title}
-                                </button>
-                            </li>
-                        ))}
-                    </ul>
+                    categories.map(category => (
+                        <div key={category.name}>
+                            <h4 style={{ margin: '10px 0' }}>{category.name}</h4>
+                            <ul style={{ listStyle: 'none', padding: 0 }}>
+                                {category.scenarios.map(s => (
+                                    <li key={s.id} style={{ marginBottom: '10px' }}>
+                                        <button
+                                            onClick={() => setSelectedScenarioId(s.id)}
+                                            title={s.tags.join(', ')}
+                                            style={{
+                                                width: '100%',
+                                                padding: '10px',
+                                                border: '1px solid #ddd',
+                                                backgroundColor: selectedScenarioId === s.id ? '#f0f0f0' : 'white',
+                                                cursor: 'pointer'
+                                            }}
+                                        >
+                                            {s.title}
+                                        </button>
+                                    </li>
+                                ))}
+                            </ul>
+                        </div>
+                    ))
                 )}
             </div>
             <div style={{ flex: 1, padding: '20px' }}>
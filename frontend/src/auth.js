@@ -0,0 +1,30 @@
+// The API token entered in the sidebar, kept in localStorage. It is sent as a bearer
+// token by axios and as the access_token query parameter by EventSource, which cannot
+// set headers.
+const TOKEN_KEY = 'playground_token';
+
+export const getToken = () => localStorage.getItem(TOKEN_KEY) || '';
+
+export const setToken = token => {
+    if (token) {
+        localStorage.setItem(TOKEN_KEY, token);
+    } else {
+        localStorage.removeItem(TOKEN_KEY);
+    }
+};
+
+// withAuth is an axios request interceptor adding the Authorization header.
+export const withAuth = config => {
+    const token = getToken();
+    if (token) {
+        config.headers = { ...config.headers, Authorization: `Bearer ${token}` };
+    }
+    return config;
+};
+
+// streamURL adds the token to the URL of a server-sent event stream.
+export const streamURL = url => {
+    const token = getToken();
+    if (!token) return url;
+    return `${url}${url.includes('?') ? '&' : '?'}access_token=${encodeURIComponent(token)}`;
+};
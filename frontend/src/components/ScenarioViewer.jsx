@@ -1,9 +1,170 @@
 import React, { useState, useEffect } from 'react';
 import axios from 'axios';
+import { withAuth, streamURL } from '../auth';
 
 const apiClient = axios.create({
     baseURL: '/api',
 });
+apiClient.interceptors.request.use(withAuth);
+
+const outcomeColors = {
+    success: 'green',
+    demonstrated: 'darkorange',
+    error: 'red',
+};
+
+const levelColors = {
+    DEBUG: 'gray',
+    INFO: 'black',
+    WARN: 'darkorange',
+    ERROR: 'red',
+};
+
+// LogStream tails the structured logs of a scenario over server-sent events.
+const LogStream = ({ scenarioId, name }) => {
+    const [entries, setEntries] = useState([]);
+
+    useEffect(() => {
+        setEntries([]);
+        const source = new EventSource(streamURL(`/api/scenarios/${scenarioId}/logs/stream?tail=50`));
+        source.addEventListener('log', event => {
+            const entry = JSON.parse(event.data);
+            setEntries(prev => [...prev.slice(-199), entry]);
+        });
+        source.onerror = err => console.error("Log stream failed:", err);
+        return () => source.close();
+    }, [scenarioId]);
+
+    return (
+        <div style={{ marginBottom: '16px', padding: '10px', border: '1px solid #ddd' }}>
+            <strong>{name}:</strong>
+            <pre style={{ margin: '5px 0 0 0', maxHeight: '300px', overflowY: 'auto', whiteSpace: 'pre-wrap', wordBreak: 'break-all', fontSize: '12px' }}>
+                {entries.map(e => (
+                    <div key={e.seq} style={{ color: levelColors[e.level] || 'black' }}>
+                        [{new Date(e.time).toLocaleTimeString()}] {e.level} {e.action ? `(${e.action}) ` : ''}{e.message}
+                        {e.fields ? ' ' + Object.entries(e.fields).map(([k, v]) => `${k}=${typeof v === 'object' ? JSON.stringify(v) : v}`).join(' ') : ''}
+                    </div>
+                ))}
+            </pre>
+        </div>
+    );
+};
+
+// CompareSide renders the run of one action in comparison mode: its outcome and the
+// state of its fork before and after, with the fields that differ highlighted.
+const CompareSide = ({ title, side, diffPaths }) => (
+    <div style={{ flex: 1, padding: '10px', border: '1px solid #ddd' }}>
+        <h4 style={{ marginTop: 0 }}>{title}: {side.action}</h4>
+        <div style={{ color: side.error_code ? (outcomeColors[side.error_code] || outcomeColors.error) : outcomeColors.success }}>
+            {side.error ? `${side.error_code}: ${side.error}` : 'success'} ({side.duration_ms} ms)
+        </div>
+        {side.timeline.map(point => (
+            <div key={point.label} style={{ marginTop: '10px' }}>
+                <strong>{point.label}</strong> ({new Date(point.time).toLocaleTimeString()})
+                {Object.entries(point.state || {}).map(([key, value]) => (
+                    <pre key={key} style={{
+                        margin: '5px 0 0 0',
+                        whiteSpace: 'pre-wrap',
+                        wordBreak: 'break-all',
+                        fontSize: '12px',
+                        backgroundColor: point.label === 'after' && diffPaths.some(p => p === key || p.startsWith(key + '.') || p.startsWith(key + '[')) ? '#fff3cd' : 'transparent',
+                    }}>
+                        {key}: {typeof value === 'object' ? JSON.stringify(value, null, 2) : value}
+                    </pre>
+                ))}
+            </div>
+        ))}
+    </div>
+);
+
+// LoadTest starts a load test of an action or workload and streams its report.
+const LoadTest = ({ scenario, onError }) => {
+    const targets = [
+        ...scenario.actions.map(a => ({ key: `action:${a.id}`, name: a.name })),
+        ...(scenario.workloads || []).map(w => ({ key: `workload:${w.id}`, name: `${w.name} (workload)` })),
+    ];
+    const [target, setTarget] = useState(targets.length ? targets[0].key : '');
+    const [spec, setSpec] = useState({ qps: 0, concurrency: 10, duration: '10s', ramp_up: '0s' });
+    const [jobId, setJobId] = useState(null);
+    const [report, setReport] = useState(null);
+
+    useEffect(() => {
+        if (!jobId) return;
+        const source = new EventSource(streamURL(`/api/load/${jobId}/stream`));
+        source.addEventListener('stats', event => {
+            const next = JSON.parse(event.data);
+            setReport(next);
+            if (!next.running && next.started_at !== '0001-01-01T00:00:00Z') source.close();
+        });
+        source.onerror = () => source.close();
+        return () => source.close();
+    }, [jobId]);
+
+    const handleStart = () => {
+        const [kind, id] = target.split(/:(.*)/);
+        setReport(null);
+        apiClient.post(`/scenarios/${scenario.id}/load`, {
+            [kind]: id,
+            qps: Number(spec.qps),
+            concurrency: Number(spec.concurrency),
+            duration: spec.duration,
+            ramp_up: spec.ramp_up,
+        }).then(res => setJobId(res.data.id)).catch(onError);
+    };
+
+    const handleStop = () => {
+        apiClient.delete(`/jobs/${jobId}`).catch(onError);
+    };
+
+    const field = (name, label) => (
+        <label style={{ marginRight: '10px' }}>
+            {label} <input value={spec[name]} onChange={e => setSpec({ ...spec, [name]: e.target.value })} style={{ width: '60px' }} />
+        </label>
+    );
+    const maxBucket = report ? Math.max(1, ...report.latency.buckets.map(b => b.count)) : 1;
+
+    return (
+        <div>
+            <h4>Load Test</h4>
+            <div style={{ marginBottom: '10px' }}>
+                <select value={target} onChange={e => setTarget(e.target.value)} style={{ marginRight: '10px' }}>
+                    {targets.map(t => <option key={t.key} value={t.key}>{t.name}</option>)}
+                </select>
+                {field('qps', 'QPS')}
+                {field('concurrency', 'Concurrency')}
+                {field('duration', 'Duration')}
+                {field('ramp_up', 'Ramp-up')}
+                <button onClick={handleStart} disabled={!target || (report && report.running)}>Start</button>
+                {report && report.running && <button onClick={handleStop} style={{ marginLeft: '10px' }}>Stop</button>}
+            </div>
+            {report && (
+                <div style={{ padding: '10px', border: '1px solid #ddd', fontSize: '12px' }}>
+                    <div>
+                        {report.running ? 'Running' : 'Finished'}: {report.requests} requests in {(report.elapsed_ms / 1000).toFixed(1)}s,
+                        {' '}{report.throughput.toFixed(1)} req/s, {(report.error_rate * 100).toFixed(1)}% errors, {report.in_flight} in flight
+                    </div>
+                    <div>
+                        Latency: p50 {report.latency.p50_ms.toFixed(1)}ms, p90 {report.latency.p90_ms.toFixed(1)}ms,
+                        {' '}p99 {report.latency.p99_ms.toFixed(1)}ms, max {report.latency.max_ms.toFixed(1)}ms
+                    </div>
+                    {Object.keys(report.error_codes).length > 0 && (
+                        <div>Errors: {Object.entries(report.error_codes).map(([code, n]) => `${code}=${n}`).join(', ')}</div>
+                    )}
+                    {report.latency.buckets.filter(b => b.count > 0).map(b => (
+                        <div key={b.upper_ms || 'inf'} style={{ display: 'flex', alignItems: 'center' }}>
+                            <span style={{ width: '80px' }}>{b.upper_ms ? `≤ ${b.upper_ms}ms` : 'slower'}</span>
+                            <span style={{ background: 'steelblue', height: '10px', width: `${200 * b.count / maxBucket}px`, marginRight: '5px' }} />
+                            {b.count}
+                        </div>
+                    ))}
+                    <div style={{ marginTop: '5px' }}>
+                        Per second: {report.series.slice(-20).map(p => `${p.requests}${p.errors ? `/${p.errors}!` : ''}`).join(' ')}
+                    </div>
+                </div>
+            )}
+        </div>
+    );
+};
 
 const ScenarioViewer = ({ scenarioId }) => {
     const [scenario, setScenario] = useState(null);
@@ -11,6 +172,10 @@ const ScenarioViewer = ({ scenarioId }) => {
     const [loading, setLoading] = useState(true);
     const [error, setError] = useState(null);
     const [actionLoading, setActionLoading] = useState(false);
+    const [outcome, setOutcome] = useState(null);
+    const [snapshots, setSnapshots] = useState([]);
+    const [snapshotName, setSnapshotName] = useState('before_bug');
+    const [comparison, setComparison] = useState(null);
 
     // Fetch scenario details and initial state
     useEffect(() => {
@@ -41,20 +206,69 @@ const ScenarioViewer = ({ scenarioId }) => {
         return () => clearInterval(interval);
     }, [scenarioId]);
 
+    // Load the snapshots of the scenario
+    useEffect(() => {
+        if (!scenarioId) return;
+        setComparison(null);
+        apiClient.get(`/scenarios/${scenarioId}/snapshots`)
+            .then(res => setSnapshots(res.data))
+            .catch(err => console.error("Failed to fetch snapshots:", err));
+    }, [scenarioId]);
+
+    const reportError = (err) => {
+        const body = err.response && err.response.data && err.response.data.error;
+        setOutcome({
+            kind: 'error',
+            message: body ? `${body.code}: ${body.message} (trace ${body.trace_id})` : err.message,
+        });
+    };
+
+    const handleSnapshot = () => {
+        apiClient.post(`/scenarios/${scenarioId}/snapshots`, { name: snapshotName })
+            .then(res => {
+                setSnapshots(prev => [...prev.filter(s => s.name !== res.data.name), res.data]);
+                setOutcome({ kind: 'success', message: `Snapshot "${res.data.name}" taken` });
+            })
+            .catch(reportError);
+    };
+
+    const handleRestore = (name) => {
+        apiClient.post(`/scenarios/${scenarioId}/restore`, { name })
+            .then(() => setOutcome({ kind: 'success', message: `Snapshot "${name}" restored` }))
+            .catch(reportError);
+    };
+
+    const handleCompare = () => {
+        setActionLoading(true);
+        apiClient.post(`/scenarios/${scenarioId}/compare`, {})
+            .then(res => {
+                setComparison(res.data);
+                setActionLoading(false);
+            })
+            .catch(err => {
+                reportError(err);
+                setActionLoading(false);
+            });
+    };
+
     const handleActionClick = (actionId) => {
         setActionLoading(true);
         apiClient.post(`/scenarios/${scenarioId}/actions/${actionId}`)
-            .then(() => {
-                // State will update on the next poll
+            .then(res => {
+                // State will update on the next poll.
+                // "demonstrated" means the action failed on purpose to show the problem.
+                setOutcome({ kind: res.data.status, message: res.data.message });
                 setActionLoading(false);
             })
             .catch(err => {
                 console.error(`Action ${actionId} failed:`, err);
+                reportError(err);
                 setActionLoading(false);
             });
     };
 
     if (!scenarioId) return null;
+    const canCompare = scenario && ['problem', 'solution'].every(kind => scenario.actions.some(a => a.kind === kind));
     if (loading) return <div>Loading...</div>;
     if (error) return <div style={{ color: 'red', padding: '10px', border: '1px solid red' }}>{error}</div>;
 
@@ -86,10 +300,43 @@ const ScenarioViewer = ({ scenarioId }) => {
                                 {action.name}
                             </button>
                         ))}
+                        {canCompare && (
+                            <button onClick={handleCompare} disabled={actionLoading} style={{ padding: '8px 16px' }}>
+                                Compare Problem vs Solution
+                            </button>
+                        )}
+                    </div>
+                    <h4>Snapshots</h4>
+                    <div style={{ marginBottom: '10px' }}>
+                        <input value={snapshotName} onChange={e => setSnapshotName(e.target.value)} style={{ marginRight: '10px' }} />
+                        <button onClick={handleSnapshot} disabled={!snapshotName}>Take Snapshot</button>
                     </div>
+                    {snapshots.map(snap => (
+                        <div key={snap.name} style={{ marginBottom: '5px' }}>
+                            <button onClick={() => handleRestore(snap.name)} style={{ marginRight: '10px' }}>Restore</button>
+                            {snap.name} ({new Date(snap.created_at).toLocaleTimeString()})
+                        </div>
+                    ))}
+                    <LoadTest key={scenarioId} scenario={scenario} onError={reportError} />
+                    {outcome && (
+                        <div style={{
+                            marginTop: '10px',
+                            padding: '10px',
+                            border: '1px solid',
+                            borderColor: outcomeColors[outcome.kind] || outcomeColors.error,
+                            color: outcomeColors[outcome.kind] || outcomeColors.error,
+                        }}>
+                            <strong>{outcome.kind}</strong>: {outcome.message}
+                        </div>
+                    )}
                 </div>
                 <div style={{ flex: 1 }}>
                     <h4>Live Dashboard</h4>
+                    {(scenario.dashboard_components || [])
+                        .filter(component => component.type === 'log_stream')
+                        .map(component => (
+                            <LogStream key={component.id} scenarioId={scenarioId} name={component.name} />
+                        ))}
                     {state && Object.entries(state).map(([key, value]) => (
                         <div key={key} style={{ marginBottom: '16px', padding: '10px', border: '1px solid #ddd' }}>
                             <strong>{key}:</strong>
@@ -100,6 +347,24 @@ const ScenarioViewer = ({ scenarioId }) => {
                     ))}
                 </div>
             </div>
+
+            {comparison && (
+                <div>
+                    <h3>Comparison</h3>
+                    <div style={{ display: 'flex', gap: '20px' }}>
+                        <CompareSide title="Problem" side={comparison.problem} diffPaths={comparison.diff.map(d => d.path)} />
+                        <CompareSide title="Solution" side={comparison.solution} diffPaths={comparison.diff.map(d => d.path)} />
+                    </div>
+                    <h4>Differences</h4>
+                    {comparison.diff.length === 0 ? <p>Both forks ended in the same state.</p> : (
+                        <ul>
+                            {comparison.diff.map(d => (
+                                <li key={d.path}><code>{d.path}</code>: {JSON.stringify(d.problem)} vs {JSON.stringify(d.solution)}</li>
+                            ))}
+                        </ul>
+                    )}
+                </div>
+            )}
         </div>
     );
 };